@@ -0,0 +1,155 @@
+// Command import loads an export of another note app into a user's notes: a
+// folder of markdown files or an Obsidian vault, an Evernote .enex file or a
+// Notion "Markdown & CSV" export, as a directory or a zip archive. Unlike the
+// import endpoint it runs in the foreground, without an import job.
+//
+// Usage:
+//
+//	go run ./cmd/import -user <user id> [-format markdown|enex|notion] [-notebook <notebook id>] [-dry-run] [-json] <path>
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-note/internal/database"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func main() {
+	userID := flag.String("user", "", "ID of the user who gets the notes (required)")
+	notebookID := flag.String("notebook", "", "ID of a notebook to put the notes in")
+	dryRun := flag.Bool("dry-run", false, "only report what would be imported")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	format := flag.String("format", "", "markdown, enex or notion (default enex for .enex files, otherwise markdown)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s -user <user id> [flags] <export directory or file>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *userID == "" || flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(*userID); err != nil {
+		log.Fatalf("Invalid user ID %q", *userID)
+	}
+
+	exportPath := flag.Arg(0)
+	if *format == "" {
+		*format = services.ImportFormatMarkdown
+		if strings.EqualFold(filepath.Ext(exportPath), ".enex") {
+			*format = services.ImportFormatEnex
+		}
+	}
+
+	batch, err := readExport(*format, exportPath)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", exportPath, err)
+	}
+
+	ctx := context.Background()
+	db := database.New()
+	defer db.Close()
+
+	var notebookUUID pgtype.UUID
+	if *notebookID != "" {
+		if err := notebookUUID.Scan(*notebookID); err != nil {
+			log.Fatalf("Invalid notebook ID %q", *notebookID)
+		}
+		if _, err := db.GetQueries().GetNotebook(ctx, db_sqlc.GetNotebookParams{ID: notebookUUID, UserID: userUUID}); err != nil {
+			log.Fatalf("Notebook %s not found for user %s", *notebookID, *userID)
+		}
+	}
+
+	report, err := services.NewImportService(db.GetPool()).Import(ctx, userUUID, batch, services.ImportOptions{
+		DryRun:     *dryRun,
+		NotebookID: notebookUUID,
+	})
+	if err != nil {
+		log.Fatalf("Import failed: %v", err)
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	printReport(report)
+}
+
+// readExport reads an export from a directory, or from a file as uploaded to the import endpoint
+func readExport(format, exportPath string) (*services.ImportBatch, error) {
+	info, err := os.Stat(exportPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(exportPath)
+		if err != nil {
+			return nil, err
+		}
+		return services.ReadImport(format, filepath.Base(exportPath), data)
+	}
+
+	switch format {
+	case services.ImportFormatMarkdown:
+		return services.ReadMarkdownVault(os.DirFS(exportPath))
+	case services.ImportFormatNotion:
+		return services.ReadNotionExport(os.DirFS(exportPath))
+	}
+	return nil, fmt.Errorf("%s imports are read from a file, not a directory", format)
+}
+
+// printReport writes a human-readable summary of an import
+func printReport(report *services.ImportReport) {
+	verb := "Imported"
+	if report.DryRun {
+		verb = "Would import"
+	}
+	fmt.Printf("%s %d notes\n", verb, len(report.Created))
+	for _, note := range report.Created {
+		fmt.Printf("  + %s (%s)", note.Path, note.Title)
+		if len(note.Tags) > 0 {
+			fmt.Printf(" #%s", strings.Join(note.Tags, " #"))
+		}
+		fmt.Println()
+	}
+
+	if len(report.Duplicates) > 0 {
+		fmt.Printf("Skipped %d duplicates\n", len(report.Duplicates))
+		for _, dup := range report.Duplicates {
+			of := dup.DuplicateOfPath
+			if dup.DuplicateOfID != "" {
+				of = "note " + dup.DuplicateOfID
+			}
+			fmt.Printf("  = %s, same as %s\n", dup.Path, of)
+		}
+	}
+
+	if len(report.Skipped) > 0 {
+		fmt.Printf("Skipped %d files\n", len(report.Skipped))
+		for _, skip := range report.Skipped {
+			fmt.Printf("  - %s: %s\n", skip.Path, skip.Reason)
+		}
+	}
+
+	if !report.DryRun {
+		fmt.Printf("Queued %d notes for embedding\n", report.EmbeddingQueued)
+	}
+}
@@ -20,7 +20,7 @@ func mustStartPostgresContainer() (func(context.Context, ...testcontainers.Termi
 
 	dbContainer, err := postgres.Run(
 		context.Background(),
-		"postgres:latest",
+		"pgvector/pgvector:pg17", // the migrations need the vector extension
 		postgres.WithDatabase(dbName),
 		postgres.WithUsername(dbUser),
 		postgres.WithPassword(dbPwd),
@@ -36,6 +36,7 @@ func mustStartPostgresContainer() (func(context.Context, ...testcontainers.Termi
 	database = dbName
 	password = dbPwd
 	username = dbUser
+	sslmode = "disable"
 
 	dbHost, err := dbContainer.Host(context.Background())
 	if err != nil {
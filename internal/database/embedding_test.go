@@ -0,0 +1,25 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+)
+
+func TestValidateSchemaChecksEmbeddingDimension(t *testing.T) {
+	queries := db_sqlc.New(migratedPool(t))
+
+	matching := services.NewEmbeddingServiceWithEmbedder(services.NewLocalEmbedder(768))
+	if err := matching.ValidateSchema(context.Background(), queries); err != nil {
+		t.Errorf("expected 768 dimensions to match the migrations: %v", err)
+	}
+
+	mismatched := services.NewEmbeddingServiceWithEmbedder(services.NewLocalEmbedder(384))
+	err := mismatched.ValidateSchema(context.Background(), queries)
+	if err == nil || !strings.Contains(err.Error(), "VECTOR(768)") {
+		t.Errorf("expected a dimension mismatch error, got %v", err)
+	}
+}
@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+)
+
+// supabaseAuthStub stands in for the parts of Supabase's auth schema the migrations use
+const supabaseAuthStub = `
+CREATE SCHEMA IF NOT EXISTS auth;
+CREATE TABLE IF NOT EXISTS auth.users (id UUID PRIMARY KEY DEFAULT gen_random_uuid());
+CREATE OR REPLACE FUNCTION auth.uid() RETURNS UUID AS $$ SELECT NULL::UUID $$ LANGUAGE sql STABLE;
+`
+
+var (
+	migrateOnce sync.Once
+	migrateErr  error
+)
+
+// migratedPool returns a pool to the test database with every migration applied
+func migratedPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	pool := New().GetPool()
+
+	migrateOnce.Do(func() {
+		ctx := context.Background()
+		if _, migrateErr = pool.Exec(ctx, supabaseAuthStub); migrateErr != nil {
+			return
+		}
+		files, err := filepath.Glob("../../supabase/migrations/*.sql")
+		if err != nil {
+			migrateErr = err
+			return
+		}
+		sort.Strings(files)
+		for _, file := range files {
+			sql, err := os.ReadFile(file)
+			if err != nil {
+				migrateErr = err
+				return
+			}
+			if _, err := pool.Exec(ctx, string(sql)); err != nil {
+				migrateErr = fmt.Errorf("failed to apply %s: %w", filepath.Base(file), err)
+				return
+			}
+		}
+	})
+	if migrateErr != nil {
+		t.Fatalf("failed to migrate test database: %v", migrateErr)
+	}
+	return pool
+}
+
+// createTestUser adds a user to the auth stub
+func createTestUser(t *testing.T, pool *pgxpool.Pool) pgtype.UUID {
+	t.Helper()
+	var userID pgtype.UUID
+	if err := pool.QueryRow(context.Background(), "INSERT INTO auth.users DEFAULT VALUES RETURNING id").Scan(&userID); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return userID
+}
+
+// noteUpdatedAt reads the updated_at of a note
+func noteUpdatedAt(t *testing.T, pool *pgxpool.Pool, noteID pgtype.UUID) time.Time {
+	t.Helper()
+	var updatedAt time.Time
+	if err := pool.QueryRow(context.Background(), "SELECT updated_at FROM notes WHERE id = $1", noteID).Scan(&updatedAt); err != nil {
+		t.Fatalf("failed to read updated_at: %v", err)
+	}
+	return updatedAt
+}
+
+// importOldNote imports a note last changed long ago and returns its ID and timestamp
+func importOldNote(t *testing.T, pool *pgxpool.Pool, userID pgtype.UUID) (pgtype.UUID, time.Time) {
+	t.Helper()
+	old := time.Date(2019, 3, 4, 5, 6, 7, 0, time.UTC)
+	content := "Links to [[Somewhere]] and [[Elsewhere]]"
+	report, err := services.NewImportService(pool).Import(context.Background(), userID, &services.ImportBatch{
+		Notes: []services.ImportedNote{{
+			Path:      "old.md",
+			Title:     "Old note",
+			Content:   content,
+			CreatedAt: old,
+			UpdatedAt: old,
+			Hash:      services.ContentHash(content),
+		}},
+	}, services.ImportOptions{})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	var noteID pgtype.UUID
+	if err := noteID.Scan(report.Created[0].NoteID); err != nil {
+		t.Fatalf("invalid note ID: %v", err)
+	}
+	return noteID, old
+}
+
+func TestImportKeepsUpdatedAt(t *testing.T) {
+	pool := migratedPool(t)
+	noteID, old := importOldNote(t, pool, createTestUser(t, pool))
+
+	if got := noteUpdatedAt(t, pool, noteID); !got.Equal(old) {
+		t.Errorf("expected the imported updated_at %v to survive, got %v", old, got)
+	}
+}
+
+func TestEditsTouchUpdatedAt(t *testing.T) {
+	pool := migratedPool(t)
+	noteID, old := importOldNote(t, pool, createTestUser(t, pool))
+
+	if _, err := pool.Exec(context.Background(), "UPDATE notes SET tags = '{edited}' WHERE id = $1", noteID); err != nil {
+		t.Fatalf("failed to edit note: %v", err)
+	}
+	if got := noteUpdatedAt(t, pool, noteID); !got.After(old) {
+		t.Errorf("expected an edit to move updated_at past %v, got %v", old, got)
+	}
+}
+
+func TestEmbeddingWritesKeepUpdatedAt(t *testing.T) {
+	pool := migratedPool(t)
+	noteID, old := importOldNote(t, pool, createTestUser(t, pool))
+	ctx := context.Background()
+	queries := db_sqlc.New(pool)
+
+	if err := queries.SetNoteEmbeddingStatus(ctx, db_sqlc.SetNoteEmbeddingStatusParams{ID: noteID, EmbeddingStatus: "failed"}); err != nil {
+		t.Fatalf("failed to set embedding status: %v", err)
+	}
+	if err := queries.UpdateNoteEmbedding(ctx, db_sqlc.UpdateNoteEmbeddingParams{ID: noteID, Embedding: pgvector.NewVector(make([]float32, 768))}); err != nil {
+		t.Fatalf("failed to store embedding: %v", err)
+	}
+	if got := noteUpdatedAt(t, pool, noteID); !got.Equal(old) {
+		t.Errorf("expected embedding writes to keep updated_at %v, got %v", old, got)
+	}
+}
+
+func TestSearchTextBackfillKeepsUpdatedAt(t *testing.T) {
+	pool := migratedPool(t)
+	noteID, old := importOldNote(t, pool, createTestUser(t, pool))
+	ctx := context.Background()
+
+	if _, err := pool.Exec(ctx, "UPDATE notes SET search_title = NULL, search_content = NULL WHERE id = $1", noteID); err != nil {
+		t.Fatalf("failed to clear search text: %v", err)
+	}
+	if err := services.NewSearchService(pool, nil).BackfillSearchText(ctx); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+	if got := noteUpdatedAt(t, pool, noteID); !got.Equal(old) {
+		t.Errorf("expected the search text backfill to keep updated_at %v, got %v", old, got)
+	}
+}
+
+func TestLinkBackfillKeepsUpdatedAt(t *testing.T) {
+	pool := migratedPool(t)
+	noteID, old := importOldNote(t, pool, createTestUser(t, pool))
+	ctx := context.Background()
+
+	if _, err := pool.Exec(ctx, "UPDATE notes SET links_indexed_at = NULL WHERE id = $1", noteID); err != nil {
+		t.Fatalf("failed to reset link indexing: %v", err)
+	}
+	if err := services.NewLinkService(pool).BackfillLinks(ctx); err != nil {
+		t.Fatalf("backfill failed: %v", err)
+	}
+	if got := noteUpdatedAt(t, pool, noteID); !got.Equal(old) {
+		t.Errorf("expected link indexing to keep updated_at %v, got %v", old, got)
+	}
+}
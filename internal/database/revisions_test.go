@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// lockedNote is what services.LockNote returned in a concurrent transaction
+type lockedNote struct {
+	title    string
+	revision int32
+	err      error
+}
+
+func TestLockNoteSeesConcurrentRevision(t *testing.T) {
+	pool := migratedPool(t)
+	userID := createTestUser(t, pool)
+	noteID, _ := importOldNote(t, pool, userID)
+	ctx := context.Background()
+
+	first, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	defer first.Rollback(ctx)
+	firstQueries := db_sqlc.New(first)
+	if _, revision, err := services.LockNote(ctx, firstQueries, noteID, userID); err != nil || revision != 1 {
+		t.Fatalf("expected revision 1, got %d (%v)", revision, err)
+	}
+
+	// The second writer holds the same ETag and has to wait for the first
+	second, err := pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin: %v", err)
+	}
+	defer second.Rollback(ctx)
+	locked := make(chan lockedNote, 1)
+	go func() {
+		note, revision, err := services.LockNote(ctx, db_sqlc.New(second), noteID, userID)
+		locked <- lockedNote{note.Title, revision, err}
+	}()
+
+	waitForLockWait(t, ctx, pool)
+
+	if _, err := firstQueries.UpdateNote(ctx, db_sqlc.UpdateNoteParams{
+		ID:            noteID,
+		UserID:        userID,
+		Title:         "First writer",
+		Content:       "Edited",
+		SearchTitle:   services.SearchText("First writer"),
+		SearchContent: services.SearchText("Edited"),
+	}); err != nil {
+		t.Fatalf("failed to update note: %v", err)
+	}
+	if _, err := firstQueries.CreateNoteRevision(ctx, db_sqlc.CreateNoteRevisionParams{NoteID: noteID}); err != nil {
+		t.Fatalf("failed to record revision: %v", err)
+	}
+	if err := first.Commit(ctx); err != nil {
+		t.Fatalf("failed to commit: %v", err)
+	}
+
+	got := <-locked
+	if got.err != nil {
+		t.Fatalf("second lock failed: %v", got.err)
+	}
+	if got.revision != 2 || got.title != "First writer" {
+		t.Errorf("expected the second writer to see revision 2 titled %q, got revision %d titled %q", "First writer", got.revision, got.title)
+	}
+}
+
+// waitForLockWait waits until a session of the test database is blocked on a lock
+func waitForLockWait(t *testing.T, ctx context.Context, pool *pgxpool.Pool) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		var waiting bool
+		if err := pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM pg_stat_activity WHERE wait_event_type = 'Lock')").Scan(&waiting); err != nil {
+			t.Fatalf("failed to check for lock waits: %v", err)
+		}
+		if waiting {
+			return
+		}
+	}
+	t.Fatal("the second transaction never waited for the lock")
+}
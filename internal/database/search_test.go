@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"go-note/internal/services"
+)
+
+func TestSearchFacetsCountEveryMatch(t *testing.T) {
+	pool := migratedPool(t)
+	userID := createTestUser(t, pool)
+	ctx := context.Background()
+
+	batch := &services.ImportBatch{}
+	for i := range 150 {
+		content := fmt.Sprintf("Gophers dig tunnel number %d", i)
+		tags := []string{"go"}
+		if i%3 == 0 {
+			tags = append(tags, "db")
+		}
+		batch.Notes = append(batch.Notes, services.ImportedNote{
+			Path:    fmt.Sprintf("gopher-%d.md", i),
+			Title:   fmt.Sprintf("Gopher %d", i),
+			Content: content,
+			Tags:    tags,
+			Hash:    services.ContentHash(content),
+		})
+	}
+	if _, err := services.NewImportService(pool).Import(ctx, userID, batch, services.ImportOptions{}); err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+
+	search, err := services.NewSearchService(pool, nil).Search(ctx, userID, services.SearchOptions{
+		Query: "gophers",
+		Mode:  services.SearchModeKeyword,
+		Limit: 5,
+	})
+	if err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	if len(search.Results) != 5 {
+		t.Errorf("expected 5 results, got %d", len(search.Results))
+	}
+
+	want := []services.TagFacet{{Tag: "go", Count: 150}, {Tag: "db", Count: 50}}
+	if len(search.TagFacets) != len(want) {
+		t.Fatalf("expected facets %v, got %v", want, search.TagFacets)
+	}
+	for i := range want {
+		if search.TagFacets[i] != want[i] {
+			t.Errorf("facet %d = %+v, want %+v", i, search.TagFacets[i], want[i])
+		}
+	}
+}
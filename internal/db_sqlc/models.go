@@ -9,17 +9,156 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+type CardSchedule struct {
+	FlashcardID    pgtype.UUID        `json:"flashcard_id"`
+	UserID         pgtype.UUID        `json:"user_id"`
+	Algorithm      string             `json:"algorithm"`
+	EaseFactor     float64            `json:"ease_factor"`
+	IntervalDays   int32              `json:"interval_days"`
+	Repetitions    int32              `json:"repetitions"`
+	Lapses         int32              `json:"lapses"`
+	DueAt          pgtype.Timestamptz `json:"due_at"`
+	LastReviewedAt pgtype.Timestamptz `json:"last_reviewed_at"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt      pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Deck struct {
+	ID          pgtype.UUID        `json:"id"`
+	UserID      pgtype.UUID        `json:"user_id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt   pgtype.Timestamptz `json:"updated_at"`
+}
+
+type EmbeddingJob struct {
+	ID         pgtype.UUID        `json:"id"`
+	NoteID     pgtype.UUID        `json:"note_id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Status     string             `json:"status"`
+	Generation int32              `json:"generation"`
+	Attempts   int32              `json:"attempts"`
+	LastError  pgtype.Text        `json:"last_error"`
+	RunAt      pgtype.Timestamptz `json:"run_at"`
+	LockedAt   pgtype.Timestamptz `json:"locked_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
+type Flashcard struct {
+	ID            pgtype.UUID        `json:"id"`
+	DeckID        pgtype.UUID        `json:"deck_id"`
+	UserID        pgtype.UUID        `json:"user_id"`
+	Question      string             `json:"question"`
+	Answer        string             `json:"answer"`
+	Explanation   string             `json:"explanation"`
+	Difficulty    string             `json:"difficulty"`
+	Tags          []string           `json:"tags"`
+	SourceNoteIds []pgtype.UUID      `json:"source_note_ids"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+}
+
+type ImportJob struct {
+	ID         pgtype.UUID        `json:"id"`
+	UserID     pgtype.UUID        `json:"user_id"`
+	Format     string             `json:"format"`
+	Filename   string             `json:"filename"`
+	DryRun     bool               `json:"dry_run"`
+	NotebookID pgtype.UUID        `json:"notebook_id"`
+	Status     string             `json:"status"`
+	Payload    []byte             `json:"payload"`
+	Total      int32              `json:"total"`
+	Processed  int32              `json:"processed"`
+	Report     []byte             `json:"report"`
+	Error      pgtype.Text        `json:"error"`
+	Attempts   int32              `json:"attempts"`
+	LockedAt   pgtype.Timestamptz `json:"locked_at"`
+	StartedAt  pgtype.Timestamptz `json:"started_at"`
+	FinishedAt pgtype.Timestamptz `json:"finished_at"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+}
+
 type Note struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Embedding       pgvector.Vector    `json:"embedding"`
+	Tags            []string           `json:"tags"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	SearchTitle     pgtype.Text        `json:"search_title"`
+	SearchContent   pgtype.Text        `json:"search_content"`
+	SearchVector    interface{}        `json:"search_vector"`
+	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	LinksIndexedAt  pgtype.Timestamptz `json:"links_indexed_at"`
+}
+
+type NoteChunk struct {
+	ID          pgtype.UUID        `json:"id"`
+	NoteID      pgtype.UUID        `json:"note_id"`
+	UserID      pgtype.UUID        `json:"user_id"`
+	ChunkIndex  int32              `json:"chunk_index"`
+	Heading     string             `json:"heading"`
+	Content     string             `json:"content"`
+	StartOffset int32              `json:"start_offset"`
+	EndOffset   int32              `json:"end_offset"`
+	Embedding   pgvector.Vector    `json:"embedding"`
+	CreatedAt   pgtype.Timestamptz `json:"created_at"`
+}
+
+type NoteLink struct {
+	ID           pgtype.UUID        `json:"id"`
+	SourceNoteID pgtype.UUID        `json:"source_note_id"`
+	UserID       pgtype.UUID        `json:"user_id"`
+	TargetNoteID pgtype.UUID        `json:"target_note_id"`
+	TargetTitle  string             `json:"target_title"`
+	Kind         string             `json:"kind"`
+	Alias        string             `json:"alias"`
+	Position     int32              `json:"position"`
+	CreatedAt    pgtype.Timestamptz `json:"created_at"`
+}
+
+type NoteRevision struct {
+	ID             pgtype.UUID        `json:"id"`
+	NoteID         pgtype.UUID        `json:"note_id"`
+	UserID         pgtype.UUID        `json:"user_id"`
+	RevisionNumber int32              `json:"revision_number"`
+	Title          string             `json:"title"`
+	Content        string             `json:"content"`
+	Tags           []string           `json:"tags"`
+	RestoredFrom   pgtype.Int4        `json:"restored_from"`
+	CreatedAt      pgtype.Timestamptz `json:"created_at"`
+}
+
+type Notebook struct {
 	ID        pgtype.UUID        `json:"id"`
 	UserID    pgtype.UUID        `json:"user_id"`
-	Title     string             `json:"title"`
-	Content   string             `json:"content"`
-	Embedding pgvector.Vector    `json:"embedding"`
-	Tags      []string           `json:"tags"`
+	ParentID  pgtype.UUID        `json:"parent_id"`
+	Name      string             `json:"name"`
+	Position  int32              `json:"position"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
 }
 
+type ReviewLog struct {
+	ID                   pgtype.UUID        `json:"id"`
+	FlashcardID          pgtype.UUID        `json:"flashcard_id"`
+	UserID               pgtype.UUID        `json:"user_id"`
+	Algorithm            string             `json:"algorithm"`
+	Grade                int16              `json:"grade"`
+	EaseFactor           float64            `json:"ease_factor"`
+	PreviousIntervalDays int32              `json:"previous_interval_days"`
+	IntervalDays         int32              `json:"interval_days"`
+	DueAt                pgtype.Timestamptz `json:"due_at"`
+	ReviewedAt           pgtype.Timestamptz `json:"reviewed_at"`
+}
+
 type UserProfile struct {
 	ID          pgtype.UUID        `json:"id"`
 	Username    pgtype.Text        `json:"username"`
@@ -12,28 +12,181 @@ import (
 	"github.com/pgvector/pgvector-go"
 )
 
+const addNoteTags = `-- name: AddNoteTags :execrows
+UPDATE notes
+SET tags = COALESCE(tags, '{}') || ARRAY(
+    SELECT DISTINCT t
+    FROM unnest($1::text[]) AS t
+    WHERE NOT t = ANY(COALESCE(tags, '{}'))
+)
+WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL
+`
+
+type AddNoteTagsParams struct {
+	Tags   []string    `json:"tags"`
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+// Appends the tags the note does not carry yet, keeping the existing order
+func (q *Queries) AddNoteTags(ctx context.Context, arg AddNoteTagsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, addNoteTags, arg.Tags, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const countSearchTags = `-- name: CountSearchTags :many
+SELECT t.tag::text AS tag, COUNT(DISTINCT n.id)::int AS count
+FROM notes n
+CROSS JOIN LATERAL unnest(n.tags) AS t(tag)
+WHERE
+    n.user_id = $1
+    AND n.deleted_at IS NULL
+    AND (
+        ($2::vector IS NOT NULL AND EXISTS (
+            SELECT 1
+            FROM note_chunks c
+            WHERE
+                c.note_id = n.id
+                AND 1 - (c.embedding <=> $2::vector) > $3::float
+        ))
+        OR ($4::text IS NOT NULL AND n.search_vector @@ to_tsquery('simple', $4::text))
+    )
+    AND ($5::text[] IS NULL OR n.tags && $5::text[])
+    AND ($6::text[] IS NULL OR n.tags @> $6::text[])
+    AND ($7::timestamptz IS NULL OR n.created_at >= $7::timestamptz)
+    AND ($8::timestamptz IS NULL OR n.created_at < $8::timestamptz)
+    AND ($9::timestamptz IS NULL OR n.updated_at >= $9::timestamptz)
+    AND ($10::timestamptz IS NULL OR n.updated_at < $10::timestamptz)
+    AND ($11::uuid[] IS NULL OR n.notebook_id = ANY($11::uuid[]))
+GROUP BY t.tag
+ORDER BY count DESC, tag
+`
+
+type CountSearchTagsParams struct {
+	UserID         pgtype.UUID        `json:"user_id"`
+	QueryEmbedding *pgvector.Vector   `json:"query_embedding"`
+	Threshold      float64            `json:"threshold"`
+	Query          pgtype.Text        `json:"query"`
+	TagsAny        []string           `json:"tags_any"`
+	TagsAll        []string           `json:"tags_all"`
+	CreatedAfter   pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore  pgtype.Timestamptz `json:"created_before"`
+	UpdatedAfter   pgtype.Timestamptz `json:"updated_after"`
+	UpdatedBefore  pgtype.Timestamptz `json:"updated_before"`
+	NotebookIds    []pgtype.UUID      `json:"notebook_ids"`
+}
+
+type CountSearchTagsRow struct {
+	Tag   string `json:"tag"`
+	Count int32  `json:"count"`
+}
+
+// Counts the tags of every note a search matches, not only of its top results:
+// notes with a chunk above threshold when query_embedding is set, or matching
+// the keyword query when it is set, narrowed by the same filters as the searches
+func (q *Queries) CountSearchTags(ctx context.Context, arg CountSearchTagsParams) ([]CountSearchTagsRow, error) {
+	rows, err := q.db.Query(ctx, countSearchTags,
+		arg.UserID,
+		arg.QueryEmbedding,
+		arg.Threshold,
+		arg.Query,
+		arg.TagsAny,
+		arg.TagsAll,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.NotebookIds,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []CountSearchTagsRow{}
+	for rows.Next() {
+		var i CountSearchTagsRow
+		if err := rows.Scan(
+			&i.Tag,
+			&i.Count,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countUserNotes = `-- name: CountUserNotes :one
+SELECT COUNT(*)
+FROM notes
+WHERE user_id = $1
+    AND deleted_at IS NULL
+    AND ($2::text[] IS NULL OR tags && $2::text[])
+    AND ($3::text[] IS NULL OR tags @> $3::text[])
+    AND ($4::timestamptz IS NULL OR created_at >= $4::timestamptz)
+    AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
+    AND ($6::timestamptz IS NULL OR updated_at >= $6::timestamptz)
+    AND ($7::timestamptz IS NULL OR updated_at < $7::timestamptz)
+`
+
+type CountUserNotesParams struct {
+	UserID        pgtype.UUID        `json:"user_id"`
+	TagsAny       []string           `json:"tags_any"`
+	TagsAll       []string           `json:"tags_all"`
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+	UpdatedAfter  pgtype.Timestamptz `json:"updated_after"`
+	UpdatedBefore pgtype.Timestamptz `json:"updated_before"`
+}
+
+// Counts the notes GetUserNotes pages through with the same filters
+func (q *Queries) CountUserNotes(ctx context.Context, arg CountUserNotesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserNotes,
+		arg.UserID,
+		arg.TagsAny,
+		arg.TagsAll,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createNote = `-- name: CreateNote :one
-INSERT INTO notes (user_id, title, content, embedding, tags)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, user_id, title, content, tags, created_at, updated_at
+INSERT INTO notes (user_id, title, content, tags, search_title, search_content, notebook_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
 `
 
 type CreateNoteParams struct {
-	UserID    pgtype.UUID     `json:"user_id"`
-	Title     string          `json:"title"`
-	Content   string          `json:"content"`
-	Embedding pgvector.Vector `json:"embedding"`
-	Tags      []string        `json:"tags"`
+	UserID        pgtype.UUID `json:"user_id"`
+	Title         string      `json:"title"`
+	Content       string      `json:"content"`
+	Tags          []string    `json:"tags"`
+	SearchTitle   pgtype.Text `json:"search_title"`
+	SearchContent pgtype.Text `json:"search_content"`
+	NotebookID    pgtype.UUID `json:"notebook_id"`
 }
 
 type CreateNoteRow struct {
-	ID        pgtype.UUID        `json:"id"`
-	UserID    pgtype.UUID        `json:"user_id"`
-	Title     string             `json:"title"`
-	Content   string             `json:"content"`
-	Tags      []string           `json:"tags"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Tags            []string           `json:"tags"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
 
 func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (CreateNoteRow, error) {
@@ -41,8 +194,10 @@ func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (CreateN
 		arg.UserID,
 		arg.Title,
 		arg.Content,
-		arg.Embedding,
 		arg.Tags,
+		arg.SearchTitle,
+		arg.SearchContent,
+		arg.NotebookID,
 	)
 	var i CreateNoteRow
 	err := row.Scan(
@@ -51,15 +206,18 @@ func (q *Queries) CreateNote(ctx context.Context, arg CreateNoteParams) (CreateN
 		&i.Title,
 		&i.Content,
 		&i.Tags,
+		&i.EmbeddingStatus,
+		&i.NotebookID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
-const deleteNote = `-- name: DeleteNote :exec
-DELETE FROM notes
-WHERE id = $1 AND user_id = $2
+const deleteNote = `-- name: DeleteNote :execrows
+UPDATE notes
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 `
 
 type DeleteNoteParams struct {
@@ -67,25 +225,87 @@ type DeleteNoteParams struct {
 	UserID pgtype.UUID `json:"user_id"`
 }
 
-func (q *Queries) DeleteNote(ctx context.Context, arg DeleteNoteParams) error {
-	_, err := q.db.Exec(ctx, deleteNote, arg.ID, arg.UserID)
-	return err
+// Moves the note to the trash; PurgeNote or the purge job deletes it for good
+func (q *Queries) DeleteNote(ctx context.Context, arg DeleteNoteParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteNote, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const emptyTrash = `-- name: EmptyTrash :execrows
+DELETE FROM notes
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+`
+
+func (q *Queries) EmptyTrash(ctx context.Context, userID pgtype.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, emptyTrash, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const findNotesByContentHash = `-- name: FindNotesByContentHash :many
+SELECT id, encode(sha256(convert_to(content, 'UTF8')), 'hex')::text AS content_hash
+FROM notes
+WHERE
+    user_id = $1
+    AND deleted_at IS NULL
+    AND encode(sha256(convert_to(content, 'UTF8')), 'hex') = ANY($2::text[])
+`
+
+type FindNotesByContentHashParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Hashes []string    `json:"hashes"`
+}
+
+type FindNotesByContentHashRow struct {
+	ID          pgtype.UUID `json:"id"`
+	ContentHash string      `json:"content_hash"`
+}
+
+// Finds the user's live notes whose content has one of the given SHA-256 hex digests
+func (q *Queries) FindNotesByContentHash(ctx context.Context, arg FindNotesByContentHashParams) ([]FindNotesByContentHashRow, error) {
+	rows, err := q.db.Query(ctx, findNotesByContentHash, arg.UserID, arg.Hashes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []FindNotesByContentHashRow{}
+	for rows.Next() {
+		var i FindNotesByContentHashRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ContentHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
 }
 
 const getNote = `-- name: GetNote :one
-SELECT id, user_id, title, content, tags, created_at, updated_at
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
 FROM notes
-WHERE id = $1
+WHERE id = $1 AND deleted_at IS NULL
 `
 
 type GetNoteRow struct {
-	ID        pgtype.UUID        `json:"id"`
-	UserID    pgtype.UUID        `json:"user_id"`
-	Title     string             `json:"title"`
-	Content   string             `json:"content"`
-	Tags      []string           `json:"tags"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Tags            []string           `json:"tags"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
 
 func (q *Queries) GetNote(ctx context.Context, id pgtype.UUID) (GetNoteRow, error) {
@@ -97,16 +317,45 @@ func (q *Queries) GetNote(ctx context.Context, id pgtype.UUID) (GetNoteRow, erro
 		&i.Title,
 		&i.Content,
 		&i.Tags,
+		&i.EmbeddingStatus,
+		&i.NotebookID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
 
+const getNoteChunkEmbeddingDimension = `-- name: GetNoteChunkEmbeddingDimension :one
+SELECT atttypmod::int AS dimension
+FROM pg_attribute
+WHERE attrelid = 'note_chunks'::regclass AND attname = 'embedding'
+`
+
+func (q *Queries) GetNoteChunkEmbeddingDimension(ctx context.Context) (int32, error) {
+	row := q.db.QueryRow(ctx, getNoteChunkEmbeddingDimension)
+	var dimension int32
+	err := row.Scan(&dimension)
+	return dimension, err
+}
+
+const getNoteEmbeddingDimension = `-- name: GetNoteEmbeddingDimension :one
+SELECT atttypmod::int AS dimension
+FROM pg_attribute
+WHERE attrelid = 'notes'::regclass AND attname = 'embedding'
+`
+
+// pgvector stores the declared VECTOR(n) size as the column's type modifier
+func (q *Queries) GetNoteEmbeddingDimension(ctx context.Context) (int32, error) {
+	row := q.db.QueryRow(ctx, getNoteEmbeddingDimension)
+	var dimension int32
+	err := row.Scan(&dimension)
+	return dimension, err
+}
+
 const getNoteForFlashcard = `-- name: GetNoteForFlashcard :one
 SELECT id, user_id, title, content, tags, created_at
 FROM notes
-WHERE id = $1 AND user_id = $2
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 LIMIT 1
 `
 
@@ -139,31 +388,90 @@ func (q *Queries) GetNoteForFlashcard(ctx context.Context, arg GetNoteForFlashca
 }
 
 const getUserNotes = `-- name: GetUserNotes :many
-SELECT id, user_id, title, content, tags, created_at, updated_at
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
 FROM notes
 WHERE user_id = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3
+    AND deleted_at IS NULL
+    AND ($2::text[] IS NULL OR tags && $2::text[])
+    AND ($3::text[] IS NULL OR tags @> $3::text[])
+    AND ($4::timestamptz IS NULL OR created_at >= $4::timestamptz)
+    AND ($5::timestamptz IS NULL OR created_at < $5::timestamptz)
+    AND ($6::timestamptz IS NULL OR updated_at >= $6::timestamptz)
+    AND ($7::timestamptz IS NULL OR updated_at < $7::timestamptz)
+    AND ($8::uuid IS NULL OR CASE
+        WHEN $9::text = 'title' AND $10::bool
+            THEN (title, id) < ($11::text, $8::uuid)
+        WHEN $9::text = 'title'
+            THEN (title, id) > ($11::text, $8::uuid)
+        WHEN $9::text = 'updated_at' AND $10::bool
+            THEN (updated_at, id) < ($12::timestamptz, $8::uuid)
+        WHEN $9::text = 'updated_at'
+            THEN (updated_at, id) > ($12::timestamptz, $8::uuid)
+        WHEN $10::bool
+            THEN (created_at, id) < ($12::timestamptz, $8::uuid)
+        ELSE (created_at, id) > ($12::timestamptz, $8::uuid)
+    END)
+ORDER BY
+    CASE WHEN $9::text = 'title' AND NOT $10::bool THEN title END ASC,
+    CASE WHEN $9::text = 'title' AND $10::bool THEN title END DESC,
+    CASE WHEN $9::text = 'updated_at' AND NOT $10::bool THEN updated_at END ASC,
+    CASE WHEN $9::text = 'updated_at' AND $10::bool THEN updated_at END DESC,
+    CASE WHEN $9::text = 'created_at' AND NOT $10::bool THEN created_at END ASC,
+    CASE WHEN $9::text = 'created_at' AND $10::bool THEN created_at END DESC,
+    CASE WHEN NOT $10::bool THEN id END ASC,
+    CASE WHEN $10::bool THEN id END DESC
+LIMIT $13 OFFSET $14
 `
 
 type GetUserNotesParams struct {
-	UserID pgtype.UUID `json:"user_id"`
-	Limit  int32       `json:"limit"`
-	Offset int32       `json:"offset"`
+	UserID        pgtype.UUID        `json:"user_id"`
+	TagsAny       []string           `json:"tags_any"`
+	TagsAll       []string           `json:"tags_all"`
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+	UpdatedAfter  pgtype.Timestamptz `json:"updated_after"`
+	UpdatedBefore pgtype.Timestamptz `json:"updated_before"`
+	CursorID      pgtype.UUID        `json:"cursor_id"`
+	Sort          string             `json:"sort"`
+	Descending    bool               `json:"descending"`
+	CursorTitle   pgtype.Text        `json:"cursor_title"`
+	CursorTime    pgtype.Timestamptz `json:"cursor_time"`
+	Limit         int32              `json:"limit"`
+	Offset        int32              `json:"offset"`
 }
 
 type GetUserNotesRow struct {
-	ID        pgtype.UUID        `json:"id"`
-	UserID    pgtype.UUID        `json:"user_id"`
-	Title     string             `json:"title"`
-	Content   string             `json:"content"`
-	Tags      []string           `json:"tags"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Tags            []string           `json:"tags"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
 }
 
+// Sorts by sort (created_at, updated_at or title) with id breaking ties. Pages
+// after the first pass the last row's id with its sort value as cursor_time or
+// cursor_title, and get the rows following it (keyset pagination).
 func (q *Queries) GetUserNotes(ctx context.Context, arg GetUserNotesParams) ([]GetUserNotesRow, error) {
-	rows, err := q.db.Query(ctx, getUserNotes, arg.UserID, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, getUserNotes,
+		arg.UserID,
+		arg.TagsAny,
+		arg.TagsAll,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.CursorID,
+		arg.Sort,
+		arg.Descending,
+		arg.CursorTitle,
+		arg.CursorTime,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -177,6 +485,8 @@ func (q *Queries) GetUserNotes(ctx context.Context, arg GetUserNotesParams) ([]G
 			&i.Title,
 			&i.Content,
 			&i.Tags,
+			&i.EmbeddingStatus,
+			&i.NotebookID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
 		); err != nil {
@@ -190,66 +500,207 @@ func (q *Queries) GetUserNotes(ctx context.Context, arg GetUserNotesParams) ([]G
 	return items, nil
 }
 
-const searchNotesBySimilarity = `-- name: SearchNotesBySimilarity :many
-SELECT 
-    n.id,
-    n.user_id,
-    n.title,
-    n.content,
-    n.tags,
-    n.created_at,
-    n.updated_at,
-    (1 - (n.embedding <=> $1::vector))::float AS similarity
-FROM notes n
-WHERE 
-    n.user_id = $2
-    AND n.embedding IS NOT NULL
-    AND 1 - (n.embedding <=> $1::vector) > $3::float
-ORDER BY n.embedding <=> $1::vector
-LIMIT $4
+const importNote = `-- name: ImportNote :one
+INSERT INTO notes (user_id, title, content, tags, search_title, search_content, notebook_id, created_at, updated_at)
+VALUES (
+    $1,
+    $2,
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    COALESCE($8::timestamptz, NOW()),
+    COALESCE($9::timestamptz, $8::timestamptz, NOW())
+)
+RETURNING id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
 `
 
-type SearchNotesBySimilarityParams struct {
-	Column1 pgvector.Vector `json:"column_1"`
-	UserID  pgtype.UUID     `json:"user_id"`
-	Column3 float64         `json:"column_3"`
-	Limit   int32           `json:"limit"`
+type ImportNoteParams struct {
+	UserID        pgtype.UUID        `json:"user_id"`
+	Title         string             `json:"title"`
+	Content       string             `json:"content"`
+	Tags          []string           `json:"tags"`
+	SearchTitle   pgtype.Text        `json:"search_title"`
+	SearchContent pgtype.Text        `json:"search_content"`
+	NotebookID    pgtype.UUID        `json:"notebook_id"`
+	CreatedAt     pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
 }
 
-type SearchNotesBySimilarityRow struct {
+type ImportNoteRow struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Tags            []string           `json:"tags"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Creates a note keeping the timestamps it had where it was imported from
+func (q *Queries) ImportNote(ctx context.Context, arg ImportNoteParams) (ImportNoteRow, error) {
+	row := q.db.QueryRow(ctx, importNote,
+		arg.UserID,
+		arg.Title,
+		arg.Content,
+		arg.Tags,
+		arg.SearchTitle,
+		arg.SearchContent,
+		arg.NotebookID,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+	)
+	var i ImportNoteRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.Tags,
+		&i.EmbeddingStatus,
+		&i.NotebookID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listGraphNotes = `-- name: ListGraphNotes :many
+SELECT id, title, tags, notebook_id
+FROM notes
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY updated_at DESC
+LIMIT $2
+`
+
+type ListGraphNotesParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Limit  int32       `json:"limit"`
+}
+
+type ListGraphNotesRow struct {
+	ID         pgtype.UUID `json:"id"`
+	Title      string      `json:"title"`
+	Tags       []string    `json:"tags"`
+	NotebookID pgtype.UUID `json:"notebook_id"`
+}
+
+func (q *Queries) ListGraphNotes(ctx context.Context, arg ListGraphNotesParams) ([]ListGraphNotesRow, error) {
+	rows, err := q.db.Query(ctx, listGraphNotes, arg.UserID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListGraphNotesRow{}
+	for rows.Next() {
+		var i ListGraphNotesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Tags,
+			&i.NotebookID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listGraphNotesByID = `-- name: ListGraphNotesByID :many
+SELECT id, title, tags, notebook_id
+FROM notes
+WHERE user_id = $1 AND id = ANY($2::uuid[]) AND deleted_at IS NULL
+`
+
+type ListGraphNotesByIDParams struct {
+	UserID  pgtype.UUID   `json:"user_id"`
+	NoteIds []pgtype.UUID `json:"note_ids"`
+}
+
+type ListGraphNotesByIDRow struct {
+	ID         pgtype.UUID `json:"id"`
+	Title      string      `json:"title"`
+	Tags       []string    `json:"tags"`
+	NotebookID pgtype.UUID `json:"notebook_id"`
+}
+
+func (q *Queries) ListGraphNotesByID(ctx context.Context, arg ListGraphNotesByIDParams) ([]ListGraphNotesByIDRow, error) {
+	rows, err := q.db.Query(ctx, listGraphNotesByID, arg.UserID, arg.NoteIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListGraphNotesByIDRow{}
+	for rows.Next() {
+		var i ListGraphNotesByIDRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Tags,
+			&i.NotebookID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listNotesForExport = `-- name: ListNotesForExport :many
+SELECT id, title, content, tags, notebook_id, created_at, updated_at
+FROM notes
+WHERE user_id = $1
+    AND deleted_at IS NULL
+    AND ($2::uuid IS NULL OR id > $2::uuid)
+ORDER BY id
+LIMIT $3
+`
+
+type ListNotesForExportParams struct {
+	UserID  pgtype.UUID `json:"user_id"`
+	AfterID pgtype.UUID `json:"after_id"`
+	Limit   int32       `json:"limit"`
+}
+
+type ListNotesForExportRow struct {
 	ID         pgtype.UUID        `json:"id"`
-	UserID     pgtype.UUID        `json:"user_id"`
 	Title      string             `json:"title"`
 	Content    string             `json:"content"`
 	Tags       []string           `json:"tags"`
+	NotebookID pgtype.UUID        `json:"notebook_id"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
-	Similarity float64            `json:"similarity"`
 }
 
-func (q *Queries) SearchNotesBySimilarity(ctx context.Context, arg SearchNotesBySimilarityParams) ([]SearchNotesBySimilarityRow, error) {
-	rows, err := q.db.Query(ctx, searchNotesBySimilarity,
-		arg.Column1,
-		arg.UserID,
-		arg.Column3,
-		arg.Limit,
-	)
+// Pages through all of the user's live notes in id order, after_id being the
+// last id of the previous page
+func (q *Queries) ListNotesForExport(ctx context.Context, arg ListNotesForExportParams) ([]ListNotesForExportRow, error) {
+	rows, err := q.db.Query(ctx, listNotesForExport, arg.UserID, arg.AfterID, arg.Limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []SearchNotesBySimilarityRow{}
+	items := []ListNotesForExportRow{}
 	for rows.Next() {
-		var i SearchNotesBySimilarityRow
+		var i ListNotesForExportRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.UserID,
 			&i.Title,
 			&i.Content,
 			&i.Tags,
+			&i.NotebookID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
-			&i.Similarity,
 		); err != nil {
 			return nil, err
 		}
@@ -261,45 +712,929 @@ func (q *Queries) SearchNotesBySimilarity(ctx context.Context, arg SearchNotesBy
 	return items, nil
 }
 
-const updateNote = `-- name: UpdateNote :one
-UPDATE notes
-SET 
-    title = COALESCE($2, title),
-    content = COALESCE($3, content),
-    embedding = COALESCE($4, embedding),
-    tags = COALESCE($5, tags),
-    updated_at = NOW()
-WHERE id = $1 AND user_id = $6
-RETURNING id, user_id, title, content, tags, created_at, updated_at
+const listOwnedNoteIDs = `-- name: ListOwnedNoteIDs :many
+SELECT id
+FROM notes
+WHERE user_id = $1 AND id = ANY($2::uuid[])
 `
 
-type UpdateNoteParams struct {
-	ID        pgtype.UUID     `json:"id"`
-	Title     string          `json:"title"`
-	Content   string          `json:"content"`
-	Embedding pgvector.Vector `json:"embedding"`
-	Tags      []string        `json:"tags"`
-	UserID    pgtype.UUID     `json:"user_id"`
-}
-
-type UpdateNoteRow struct {
-	ID        pgtype.UUID        `json:"id"`
-	UserID    pgtype.UUID        `json:"user_id"`
-	Title     string             `json:"title"`
-	Content   string             `json:"content"`
-	Tags      []string           `json:"tags"`
-	CreatedAt pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+type ListOwnedNoteIDsParams struct {
+	UserID pgtype.UUID   `json:"user_id"`
+	Ids    []pgtype.UUID `json:"ids"`
 }
 
-func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (UpdateNoteRow, error) {
-	row := q.db.QueryRow(ctx, updateNote,
-		arg.ID,
-		arg.Title,
-		arg.Content,
-		arg.Embedding,
-		arg.Tags,
-		arg.UserID,
+// Returns which of the given note IDs belong to the user, trashed notes included
+func (q *Queries) ListOwnedNoteIDs(ctx context.Context, arg ListOwnedNoteIDsParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listOwnedNoteIDs, arg.UserID, arg.Ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []pgtype.UUID{}
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listRelatedNotes = `-- name: ListRelatedNotes :many
+SELECT
+    m.id,
+    m.title,
+    m.tags,
+    m.notebook_id,
+    m.updated_at,
+    (1 - (m.embedding <=> n.embedding))::float AS similarity,
+    l.linked
+FROM notes n
+JOIN notes m ON m.user_id = n.user_id
+    AND m.id <> n.id
+    AND m.deleted_at IS NULL
+    AND m.embedding IS NOT NULL
+CROSS JOIN LATERAL (
+    SELECT EXISTS (
+        SELECT 1
+        FROM note_links nl
+        WHERE (nl.source_note_id = n.id AND nl.target_note_id = m.id)
+            OR (nl.source_note_id = m.id AND nl.target_note_id = n.id)
+    ) AS linked
+) l
+WHERE n.id = $1
+    AND n.user_id = $2
+    AND n.embedding IS NOT NULL
+    AND (NOT $3::bool OR NOT l.linked)
+    AND 1 - (m.embedding <=> n.embedding) >= $4::float
+ORDER BY m.embedding <=> n.embedding
+LIMIT $5
+`
+
+type ListRelatedNotesParams struct {
+	NoteID        pgtype.UUID `json:"note_id"`
+	UserID        pgtype.UUID `json:"user_id"`
+	ExcludeLinked bool        `json:"exclude_linked"`
+	Threshold     float64     `json:"threshold"`
+	Limit         int32       `json:"limit"`
+}
+
+type ListRelatedNotesRow struct {
+	ID         pgtype.UUID        `json:"id"`
+	Title      string             `json:"title"`
+	Tags       []string           `json:"tags"`
+	NotebookID pgtype.UUID        `json:"notebook_id"`
+	UpdatedAt  pgtype.Timestamptz `json:"updated_at"`
+	Similarity float64            `json:"similarity"`
+	Linked     bool               `json:"linked"`
+}
+
+// Ranks the user's other notes by similarity to the note's stored embedding;
+// linked is set when either note links to the other
+func (q *Queries) ListRelatedNotes(ctx context.Context, arg ListRelatedNotesParams) ([]ListRelatedNotesRow, error) {
+	rows, err := q.db.Query(ctx, listRelatedNotes,
+		arg.NoteID,
+		arg.UserID,
+		arg.ExcludeLinked,
+		arg.Threshold,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListRelatedNotesRow{}
+	for rows.Next() {
+		var i ListRelatedNotesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Title,
+			&i.Tags,
+			&i.NotebookID,
+			&i.UpdatedAt,
+			&i.Similarity,
+			&i.Linked,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listSemanticNeighbors = `-- name: ListSemanticNeighbors :many
+SELECT
+    n.id AS note_id,
+    nb.id AS neighbor_id,
+    nb.similarity
+FROM notes n
+CROSS JOIN LATERAL (
+    SELECT
+        m.id,
+        (1 - (m.embedding <=> n.embedding))::float AS similarity
+    FROM notes m
+    WHERE m.user_id = n.user_id
+        AND m.id <> n.id
+        AND m.deleted_at IS NULL
+        AND m.embedding IS NOT NULL
+    ORDER BY m.embedding <=> n.embedding
+    LIMIT $1
+) nb
+WHERE n.user_id = $2
+    AND n.id = ANY($3::uuid[])
+    AND n.deleted_at IS NULL
+    AND n.embedding IS NOT NULL
+    AND nb.similarity >= $4::float
+ORDER BY n.id, nb.similarity DESC
+`
+
+type ListSemanticNeighborsParams struct {
+	K         int32         `json:"k"`
+	UserID    pgtype.UUID   `json:"user_id"`
+	NoteIds   []pgtype.UUID `json:"note_ids"`
+	Threshold float64       `json:"threshold"`
+}
+
+type ListSemanticNeighborsRow struct {
+	NoteID     pgtype.UUID `json:"note_id"`
+	NeighborID pgtype.UUID `json:"neighbor_id"`
+	Similarity float64     `json:"similarity"`
+}
+
+// Returns up to k nearest notes by stored embedding for each of the given notes
+func (q *Queries) ListSemanticNeighbors(ctx context.Context, arg ListSemanticNeighborsParams) ([]ListSemanticNeighborsRow, error) {
+	rows, err := q.db.Query(ctx, listSemanticNeighbors,
+		arg.K,
+		arg.UserID,
+		arg.NoteIds,
+		arg.Threshold,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListSemanticNeighborsRow{}
+	for rows.Next() {
+		var i ListSemanticNeighborsRow
+		if err := rows.Scan(
+			&i.NoteID,
+			&i.NeighborID,
+			&i.Similarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTagCounts = `-- name: ListTagCounts :many
+SELECT t.tag::text AS tag, COUNT(*) AS note_count
+FROM notes n, unnest(n.tags) AS t(tag)
+WHERE
+    n.user_id = $1
+    AND n.deleted_at IS NULL
+    AND (
+        $2::text IS NULL
+        OR t.tag = $2::text
+        OR left(t.tag, length($2::text) + 1) = $2::text || '/'
+    )
+GROUP BY t.tag
+ORDER BY t.tag
+`
+
+type ListTagCountsParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Prefix pgtype.Text `json:"prefix"`
+}
+
+type ListTagCountsRow struct {
+	Tag       string `json:"tag"`
+	NoteCount int64  `json:"note_count"`
+}
+
+// Counts the user's live notes per tag, optionally only prefix and its descendants
+func (q *Queries) ListTagCounts(ctx context.Context, arg ListTagCountsParams) ([]ListTagCountsRow, error) {
+	rows, err := q.db.Query(ctx, listTagCounts, arg.UserID, arg.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTagCountsRow{}
+	for rows.Next() {
+		var i ListTagCountsRow
+		if err := rows.Scan(
+			&i.Tag,
+			&i.NoteCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTaggedGraphNoteIDs = `-- name: ListTaggedGraphNoteIDs :many
+SELECT id
+FROM notes
+WHERE
+    user_id = $1
+    AND deleted_at IS NULL
+    AND tags && $2::text[]
+    AND NOT id = ANY($3::uuid[])
+ORDER BY updated_at DESC
+LIMIT $4
+`
+
+type ListTaggedGraphNoteIDsParams struct {
+	UserID  pgtype.UUID   `json:"user_id"`
+	Tags    []string      `json:"tags"`
+	Exclude []pgtype.UUID `json:"exclude"`
+	Limit   int32         `json:"limit"`
+}
+
+// Returns up to limit of the user's most recent live notes carrying any of the
+// tags, leaving out the excluded notes
+func (q *Queries) ListTaggedGraphNoteIDs(ctx context.Context, arg ListTaggedGraphNoteIDsParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, listTaggedGraphNoteIDs,
+		arg.UserID,
+		arg.Tags,
+		arg.Exclude,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []pgtype.UUID{}
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listTrashedNotes = `-- name: ListTrashedNotes :many
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at, deleted_at
+FROM notes
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListTrashedNotesParams struct {
+	UserID pgtype.UUID `json:"user_id"`
+	Limit  int32       `json:"limit"`
+	Offset int32       `json:"offset"`
+}
+
+type ListTrashedNotesRow struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Tags            []string           `json:"tags"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+	DeletedAt       pgtype.Timestamptz `json:"deleted_at"`
+}
+
+func (q *Queries) ListTrashedNotes(ctx context.Context, arg ListTrashedNotesParams) ([]ListTrashedNotesRow, error) {
+	rows, err := q.db.Query(ctx, listTrashedNotes, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListTrashedNotesRow{}
+	for rows.Next() {
+		var i ListTrashedNotesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.Tags,
+			&i.EmbeddingStatus,
+			&i.NotebookID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUnsegmentedNotes = `-- name: ListUnsegmentedNotes :many
+SELECT id, title, content
+FROM notes
+WHERE search_content IS NULL
+LIMIT $1
+`
+
+type ListUnsegmentedNotesRow struct {
+	ID      pgtype.UUID `json:"id"`
+	Title   string      `json:"title"`
+	Content string      `json:"content"`
+}
+
+func (q *Queries) ListUnsegmentedNotes(ctx context.Context, limit int32) ([]ListUnsegmentedNotesRow, error) {
+	rows, err := q.db.Query(ctx, listUnsegmentedNotes, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListUnsegmentedNotesRow{}
+	for rows.Next() {
+		var i ListUnsegmentedNotesRow
+		if err := rows.Scan(&i.ID, &i.Title, &i.Content); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockNote = `-- name: LockNote :one
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
+FROM notes
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE
+`
+
+type LockNoteParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+type LockNoteRow struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Tags            []string           `json:"tags"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Locks the user's live note until the transaction ends. The row returned is
+// its latest version, even when the lock had to wait for another edit.
+func (q *Queries) LockNote(ctx context.Context, arg LockNoteParams) (LockNoteRow, error) {
+	row := q.db.QueryRow(ctx, lockNote, arg.ID, arg.UserID)
+	var i LockNoteRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.Tags,
+		&i.EmbeddingStatus,
+		&i.NotebookID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const purgeExpiredNotes = `-- name: PurgeExpiredNotes :execrows
+DELETE FROM notes
+WHERE id IN (
+    SELECT id FROM notes
+    WHERE deleted_at < $1
+    LIMIT $2
+)
+`
+
+type PurgeExpiredNotesParams struct {
+	DeletedBefore pgtype.Timestamptz `json:"deleted_before"`
+	BatchSize     int32              `json:"batch_size"`
+}
+
+// Permanently deletes up to batch_size notes trashed before deleted_before
+func (q *Queries) PurgeExpiredNotes(ctx context.Context, arg PurgeExpiredNotesParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeExpiredNotes, arg.DeletedBefore, arg.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const purgeNote = `-- name: PurgeNote :execrows
+DELETE FROM notes
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+`
+
+type PurgeNoteParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) PurgeNote(ctx context.Context, arg PurgeNoteParams) (int64, error) {
+	result, err := q.db.Exec(ctx, purgeNote, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const removeNoteTags = `-- name: RemoveNoteTags :execrows
+UPDATE notes
+SET tags = ARRAY(
+    SELECT t
+    FROM unnest(COALESCE(tags, '{}')) AS t
+    WHERE NOT t = ANY($1::text[])
+)
+WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL
+`
+
+type RemoveNoteTagsParams struct {
+	Tags   []string    `json:"tags"`
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+func (q *Queries) RemoveNoteTags(ctx context.Context, arg RemoveNoteTagsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, removeNoteTags, arg.Tags, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const restoreNote = `-- name: RestoreNote :one
+UPDATE notes
+SET deleted_at = NULL
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
+`
+
+type RestoreNoteParams struct {
+	ID     pgtype.UUID `json:"id"`
+	UserID pgtype.UUID `json:"user_id"`
+}
+
+type RestoreNoteRow struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Tags            []string           `json:"tags"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+func (q *Queries) RestoreNote(ctx context.Context, arg RestoreNoteParams) (RestoreNoteRow, error) {
+	row := q.db.QueryRow(ctx, restoreNote, arg.ID, arg.UserID)
+	var i RestoreNoteRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Title,
+		&i.Content,
+		&i.Tags,
+		&i.EmbeddingStatus,
+		&i.NotebookID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const retagNotes = `-- name: RetagNotes :many
+WITH retagged AS (
+    SELECT
+        n.id,
+        ARRAY(
+            SELECT deduplicated.tag
+            FROM (
+                SELECT mapped.tag, MIN(mapped.position) AS first_position
+                FROM (
+                    SELECT
+                        CASE
+                            WHEN t.tag = ANY($1::text[]) THEN $2::text
+                            WHEN $3::bool AND parent.source IS NOT NULL
+                                THEN $2::text || substr(t.tag, length(parent.source) + 1)
+                            ELSE t.tag
+                        END AS tag,
+                        t.position
+                    FROM unnest(n.tags) WITH ORDINALITY AS t(tag, position)
+                    LEFT JOIN LATERAL (
+                        SELECT s.source
+                        FROM unnest($1::text[]) AS s(source)
+                        WHERE left(t.tag, length(s.source) + 1) = s.source || '/'
+                        ORDER BY length(s.source) DESC
+                        LIMIT 1
+                    ) parent ON true
+                ) mapped
+                WHERE mapped.tag IS NOT NULL
+                GROUP BY mapped.tag
+            ) deduplicated
+            ORDER BY deduplicated.first_position
+        ) AS tags
+    FROM notes n
+    WHERE n.user_id = $4 AND cardinality(n.tags) > 0
+)
+UPDATE notes n
+SET tags = retagged.tags
+FROM retagged
+WHERE n.id = retagged.id AND n.tags IS DISTINCT FROM retagged.tags
+RETURNING n.id
+`
+
+type RetagNotesParams struct {
+	Sources         []string    `json:"sources"`
+	Target          pgtype.Text `json:"target"`
+	IncludeChildren bool        `json:"include_children"`
+	UserID          pgtype.UUID `json:"user_id"`
+}
+
+// Replaces the source tags on all of the user's notes, trashed ones included,
+// by target, or removes them when target is NULL. With include_children their
+// descendants move along (lang/go/generics becomes golang/generics when lang/go
+// is renamed to golang) or are removed too. Tags the replacement duplicates
+// keep their first position. Returns the IDs of the notes that changed.
+func (q *Queries) RetagNotes(ctx context.Context, arg RetagNotesParams) ([]pgtype.UUID, error) {
+	rows, err := q.db.Query(ctx, retagNotes,
+		arg.Sources,
+		arg.Target,
+		arg.IncludeChildren,
+		arg.UserID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []pgtype.UUID{}
+	for rows.Next() {
+		var id pgtype.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchNotesByKeyword = `-- name: SearchNotesByKeyword :many
+SELECT
+    n.id,
+    n.user_id,
+    n.title,
+    n.content,
+    n.tags,
+    n.created_at,
+    n.updated_at,
+    ts_rank_cd(n.search_vector, query)::float AS rank
+FROM notes n, to_tsquery('simple', $1) query
+WHERE
+    n.user_id = $2
+    AND n.deleted_at IS NULL
+    AND n.search_vector @@ query
+    AND ($3::text[] IS NULL OR n.tags && $3::text[])
+    AND ($4::text[] IS NULL OR n.tags @> $4::text[])
+    AND ($5::timestamptz IS NULL OR n.created_at >= $5::timestamptz)
+    AND ($6::timestamptz IS NULL OR n.created_at < $6::timestamptz)
+    AND ($7::timestamptz IS NULL OR n.updated_at >= $7::timestamptz)
+    AND ($8::timestamptz IS NULL OR n.updated_at < $8::timestamptz)
+    AND ($9::uuid[] IS NULL OR n.notebook_id = ANY($9::uuid[]))
+ORDER BY rank DESC, n.updated_at DESC
+LIMIT $10
+`
+
+type SearchNotesByKeywordParams struct {
+	Query         string             `json:"query"`
+	UserID        pgtype.UUID        `json:"user_id"`
+	TagsAny       []string           `json:"tags_any"`
+	TagsAll       []string           `json:"tags_all"`
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+	UpdatedAfter  pgtype.Timestamptz `json:"updated_after"`
+	UpdatedBefore pgtype.Timestamptz `json:"updated_before"`
+	NotebookIds   []pgtype.UUID      `json:"notebook_ids"`
+	Limit         int32              `json:"limit"`
+}
+
+type SearchNotesByKeywordRow struct {
+	ID        pgtype.UUID        `json:"id"`
+	UserID    pgtype.UUID        `json:"user_id"`
+	Title     string             `json:"title"`
+	Content   string             `json:"content"`
+	Tags      []string           `json:"tags"`
+	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	Rank      float64            `json:"rank"`
+}
+
+// query is a to_tsquery expression over segmented text, see services.BuildSearchQuery
+func (q *Queries) SearchNotesByKeyword(ctx context.Context, arg SearchNotesByKeywordParams) ([]SearchNotesByKeywordRow, error) {
+	rows, err := q.db.Query(ctx, searchNotesByKeyword,
+		arg.Query,
+		arg.UserID,
+		arg.TagsAny,
+		arg.TagsAll,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.NotebookIds,
+		arg.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchNotesByKeywordRow{}
+	for rows.Next() {
+		var i SearchNotesByKeywordRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.Tags,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchNotesBySimilarity = `-- name: SearchNotesBySimilarity :many
+WITH chunk_matches AS (
+    SELECT
+        c.note_id,
+        c.chunk_index,
+        c.heading,
+        c.content,
+        c.start_offset,
+        c.end_offset,
+        (1 - (c.embedding <=> $1::vector))::float AS similarity
+    FROM note_chunks c
+    JOIN notes fn ON fn.id = c.note_id
+    WHERE
+        c.user_id = $2
+        AND fn.deleted_at IS NULL
+        AND 1 - (c.embedding <=> $1::vector) > $3::float
+        AND ($4::text[] IS NULL OR fn.tags && $4::text[])
+        AND ($5::text[] IS NULL OR fn.tags @> $5::text[])
+        AND ($6::timestamptz IS NULL OR fn.created_at >= $6::timestamptz)
+        AND ($7::timestamptz IS NULL OR fn.created_at < $7::timestamptz)
+        AND ($8::timestamptz IS NULL OR fn.updated_at >= $8::timestamptz)
+        AND ($9::timestamptz IS NULL OR fn.updated_at < $9::timestamptz)
+        AND ($10::uuid[] IS NULL OR fn.notebook_id = ANY($10::uuid[]))
+),
+ranked_chunks AS (
+    SELECT
+        cm.note_id,
+        cm.chunk_index,
+        cm.heading,
+        cm.content,
+        cm.start_offset,
+        cm.end_offset,
+        cm.similarity,
+        ROW_NUMBER() OVER (PARTITION BY cm.note_id ORDER BY cm.similarity DESC) AS passage_rank,
+        MAX(cm.similarity) OVER (PARTITION BY cm.note_id) AS note_similarity
+    FROM chunk_matches cm
+),
+top_notes AS (
+    SELECT rc.note_id, rc.note_similarity
+    FROM ranked_chunks rc
+    WHERE rc.passage_rank = 1
+    ORDER BY rc.note_similarity DESC
+    LIMIT $11
+)
+SELECT 
+    n.id,
+    n.user_id,
+    n.title,
+    n.content,
+    n.tags,
+    n.created_at,
+    n.updated_at,
+    t.note_similarity::float AS similarity,
+    rc.chunk_index,
+    rc.heading,
+    rc.content AS passage,
+    rc.start_offset,
+    rc.end_offset,
+    rc.similarity AS passage_similarity
+FROM top_notes t
+JOIN notes n ON n.id = t.note_id
+JOIN ranked_chunks rc ON rc.note_id = t.note_id AND rc.passage_rank <= $12
+ORDER BY t.note_similarity DESC, n.id, rc.passage_rank
+`
+
+type SearchNotesBySimilarityParams struct {
+	QueryEmbedding  pgvector.Vector    `json:"query_embedding"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Threshold       float64            `json:"threshold"`
+	TagsAny         []string           `json:"tags_any"`
+	TagsAll         []string           `json:"tags_all"`
+	CreatedAfter    pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore   pgtype.Timestamptz `json:"created_before"`
+	UpdatedAfter    pgtype.Timestamptz `json:"updated_after"`
+	UpdatedBefore   pgtype.Timestamptz `json:"updated_before"`
+	NotebookIds     []pgtype.UUID      `json:"notebook_ids"`
+	Limit           int32              `json:"limit"`
+	PassagesPerNote int64              `json:"passages_per_note"`
+}
+
+type SearchNotesBySimilarityRow struct {
+	ID                pgtype.UUID        `json:"id"`
+	UserID            pgtype.UUID        `json:"user_id"`
+	Title             string             `json:"title"`
+	Content           string             `json:"content"`
+	Tags              []string           `json:"tags"`
+	CreatedAt         pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt         pgtype.Timestamptz `json:"updated_at"`
+	Similarity        float64            `json:"similarity"`
+	ChunkIndex        int32              `json:"chunk_index"`
+	Heading           string             `json:"heading"`
+	Passage           string             `json:"passage"`
+	StartOffset       int32              `json:"start_offset"`
+	EndOffset         int32              `json:"end_offset"`
+	PassageSimilarity float64            `json:"passage_similarity"`
+}
+
+// Matches chunks of notes passing the optional filters, ranks notes by their
+// best chunk and returns up to passages_per_note passages for each of the top notes
+func (q *Queries) SearchNotesBySimilarity(ctx context.Context, arg SearchNotesBySimilarityParams) ([]SearchNotesBySimilarityRow, error) {
+	rows, err := q.db.Query(ctx, searchNotesBySimilarity,
+		arg.QueryEmbedding,
+		arg.UserID,
+		arg.Threshold,
+		arg.TagsAny,
+		arg.TagsAll,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.NotebookIds,
+		arg.Limit,
+		arg.PassagesPerNote,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SearchNotesBySimilarityRow{}
+	for rows.Next() {
+		var i SearchNotesBySimilarityRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Title,
+			&i.Content,
+			&i.Tags,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Similarity,
+			&i.ChunkIndex,
+			&i.Heading,
+			&i.Passage,
+			&i.StartOffset,
+			&i.EndOffset,
+			&i.PassageSimilarity,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setNoteEmbeddingStatus = `-- name: SetNoteEmbeddingStatus :exec
+UPDATE notes
+SET embedding_status = $2
+WHERE id = $1
+`
+
+type SetNoteEmbeddingStatusParams struct {
+	ID              pgtype.UUID `json:"id"`
+	EmbeddingStatus string      `json:"embedding_status"`
+}
+
+func (q *Queries) SetNoteEmbeddingStatus(ctx context.Context, arg SetNoteEmbeddingStatusParams) error {
+	_, err := q.db.Exec(ctx, setNoteEmbeddingStatus, arg.ID, arg.EmbeddingStatus)
+	return err
+}
+
+const setNoteNotebook = `-- name: SetNoteNotebook :execrows
+UPDATE notes
+SET notebook_id = $3
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+`
+
+type SetNoteNotebookParams struct {
+	ID         pgtype.UUID `json:"id"`
+	UserID     pgtype.UUID `json:"user_id"`
+	NotebookID pgtype.UUID `json:"notebook_id"`
+}
+
+// A NULL notebook_id moves the note out of every notebook
+func (q *Queries) SetNoteNotebook(ctx context.Context, arg SetNoteNotebookParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setNoteNotebook, arg.ID, arg.UserID, arg.NotebookID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateNote = `-- name: UpdateNote :one
+UPDATE notes
+SET 
+    title = $2,
+    content = $3,
+    tags = COALESCE($4, tags),
+    search_title = $6,
+    search_content = $7,
+    embedding_status = CASE
+        WHEN title <> $2 OR content <> $3 THEN 'pending'
+        ELSE embedding_status
+    END,
+    updated_at = NOW()
+WHERE id = $1 AND user_id = $5 AND deleted_at IS NULL
+RETURNING id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
+`
+
+type UpdateNoteParams struct {
+	ID            pgtype.UUID `json:"id"`
+	Title         string      `json:"title"`
+	Content       string      `json:"content"`
+	Tags          []string    `json:"tags"`
+	UserID        pgtype.UUID `json:"user_id"`
+	SearchTitle   pgtype.Text `json:"search_title"`
+	SearchContent pgtype.Text `json:"search_content"`
+}
+
+type UpdateNoteRow struct {
+	ID              pgtype.UUID        `json:"id"`
+	UserID          pgtype.UUID        `json:"user_id"`
+	Title           string             `json:"title"`
+	Content         string             `json:"content"`
+	Tags            []string           `json:"tags"`
+	EmbeddingStatus string             `json:"embedding_status"`
+	NotebookID      pgtype.UUID        `json:"notebook_id"`
+	CreatedAt       pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt       pgtype.Timestamptz `json:"updated_at"`
+}
+
+// Changing the title or content marks the embedding as stale until the worker refreshes it
+func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (UpdateNoteRow, error) {
+	row := q.db.QueryRow(ctx, updateNote,
+		arg.ID,
+		arg.Title,
+		arg.Content,
+		arg.Tags,
+		arg.UserID,
+		arg.SearchTitle,
+		arg.SearchContent,
 	)
 	var i UpdateNoteRow
 	err := row.Scan(
@@ -308,8 +1643,43 @@ func (q *Queries) UpdateNote(ctx context.Context, arg UpdateNoteParams) (UpdateN
 		&i.Title,
 		&i.Content,
 		&i.Tags,
+		&i.EmbeddingStatus,
+		&i.NotebookID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 	)
 	return i, err
 }
+
+const updateNoteEmbedding = `-- name: UpdateNoteEmbedding :exec
+UPDATE notes
+SET embedding = $2, embedding_status = 'ready'
+WHERE id = $1
+`
+
+type UpdateNoteEmbeddingParams struct {
+	ID        pgtype.UUID     `json:"id"`
+	Embedding pgvector.Vector `json:"embedding"`
+}
+
+func (q *Queries) UpdateNoteEmbedding(ctx context.Context, arg UpdateNoteEmbeddingParams) error {
+	_, err := q.db.Exec(ctx, updateNoteEmbedding, arg.ID, arg.Embedding)
+	return err
+}
+
+const updateNoteSearchText = `-- name: UpdateNoteSearchText :exec
+UPDATE notes
+SET search_title = $2, search_content = $3
+WHERE id = $1
+`
+
+type UpdateNoteSearchTextParams struct {
+	ID            pgtype.UUID `json:"id"`
+	SearchTitle   pgtype.Text `json:"search_title"`
+	SearchContent pgtype.Text `json:"search_content"`
+}
+
+func (q *Queries) UpdateNoteSearchText(ctx context.Context, arg UpdateNoteSearchTextParams) error {
+	_, err := q.db.Exec(ctx, updateNoteSearchText, arg.ID, arg.SearchTitle, arg.SearchContent)
+	return err
+}
@@ -11,21 +11,171 @@ import (
 )
 
 type Querier interface {
+	// Appends the tags the note does not carry yet, keeping the existing order
+	AddNoteTags(ctx context.Context, arg AddNoteTagsParams) (int64, error)
 	CheckUsernameExists(ctx context.Context, username pgtype.Text) (bool, error)
+	// Jobs stuck in processing since before stale_before belong to a crashed worker and are reclaimed
+	ClaimEmbeddingJobs(ctx context.Context, arg ClaimEmbeddingJobsParams) ([]ClaimEmbeddingJobsRow, error)
+	// Claims the oldest waiting job. Jobs whose worker stopped reporting progress
+	// before stale_before belong to a crashed worker and are reclaimed.
+	ClaimImportJob(ctx context.Context, staleBefore pgtype.Timestamptz) (ClaimImportJobRow, error)
+	CompleteEmbeddingJob(ctx context.Context, arg CompleteEmbeddingJobParams) (int64, error)
+	CompleteImportJob(ctx context.Context, arg CompleteImportJobParams) error
+	// Counts the tags of every note a search matches, not only of its top results:
+	// notes with a chunk above threshold when query_embedding is set, or matching
+	// the keyword query when it is set, narrowed by the same filters as the searches
+	CountSearchTags(ctx context.Context, arg CountSearchTagsParams) ([]CountSearchTagsRow, error)
+	// Counts the notes GetUserNotes pages through with the same filters
+	CountUserNotes(ctx context.Context, arg CountUserNotesParams) (int64, error)
+	// Counts the profiles ListUserProfiles pages through with the same filters
+	CountUserProfiles(ctx context.Context, arg CountUserProfilesParams) (int64, error)
+	CreateDeck(ctx context.Context, arg CreateDeckParams) (Deck, error)
+	CreateFlashcard(ctx context.Context, arg CreateFlashcardParams) (Flashcard, error)
+	CreateImportJob(ctx context.Context, arg CreateImportJobParams) (CreateImportJobRow, error)
 	CreateNote(ctx context.Context, arg CreateNoteParams) (CreateNoteRow, error)
+	CreateNoteChunk(ctx context.Context, arg CreateNoteChunkParams) error
+	// Resolves the target by ID when the link names one, otherwise by case-insensitive title
+	CreateNoteLink(ctx context.Context, arg CreateNoteLinkParams) error
+	// Snapshots the note's current title, content and tags as its next revision
+	CreateNoteRevision(ctx context.Context, arg CreateNoteRevisionParams) (NoteRevision, error)
+	// Snapshots many notes at once, see CreateNoteRevision
+	CreateNoteRevisions(ctx context.Context, noteIds []pgtype.UUID) error
+	// New notebooks go after their existing siblings
+	CreateNotebook(ctx context.Context, arg CreateNotebookParams) (Notebook, error)
+	CreateReviewLog(ctx context.Context, arg CreateReviewLogParams) (ReviewLog, error)
 	CreateUserProfile(ctx context.Context, arg CreateUserProfileParams) (UserProfile, error)
-	DeleteNote(ctx context.Context, arg DeleteNoteParams) error
+	DeadLetterEmbeddingJob(ctx context.Context, arg DeadLetterEmbeddingJobParams) (int64, error)
+	DeleteDeck(ctx context.Context, arg DeleteDeckParams) (int64, error)
+	DeleteFlashcard(ctx context.Context, arg DeleteFlashcardParams) (int64, error)
+	// Moves the note to the trash; PurgeNote or the purge job deletes it for good
+	DeleteNote(ctx context.Context, arg DeleteNoteParams) (int64, error)
+	DeleteNoteChunks(ctx context.Context, noteID pgtype.UUID) error
+	DeleteNoteLinks(ctx context.Context, sourceNoteID pgtype.UUID) error
+	// Child notebooks are deleted by the parent_id foreign key
+	DeleteNotebook(ctx context.Context, arg DeleteNotebookParams) (int64, error)
 	DeleteUserProfile(ctx context.Context, id pgtype.UUID) error
+	EmptyTrash(ctx context.Context, userID pgtype.UUID) (int64, error)
+	// Re-enqueueing a note resets its job and bumps the generation
+	EnqueueEmbeddingJob(ctx context.Context, arg EnqueueEmbeddingJobParams) error
+	// Enqueues many notes in one statement, see EnqueueEmbeddingJob
+	EnqueueEmbeddingJobs(ctx context.Context, arg EnqueueEmbeddingJobsParams) error
+	FailImportJob(ctx context.Context, arg FailImportJobParams) error
+	// Finds the user's live notes whose content has one of the given SHA-256 hex digests
+	FindNotesByContentHash(ctx context.Context, arg FindNotesByContentHashParams) ([]FindNotesByContentHashRow, error)
+	GetCardSchedule(ctx context.Context, arg GetCardScheduleParams) (CardSchedule, error)
+	GetDeck(ctx context.Context, arg GetDeckParams) (Deck, error)
+	GetFlashcard(ctx context.Context, arg GetFlashcardParams) (Flashcard, error)
+	GetImportJob(ctx context.Context, arg GetImportJobParams) (GetImportJobRow, error)
 	GetNote(ctx context.Context, id pgtype.UUID) (GetNoteRow, error)
+	GetNoteChunkEmbeddingDimension(ctx context.Context) (int32, error)
+	// pgvector stores the declared VECTOR(n) size as the column's type modifier
+	GetNoteEmbeddingDimension(ctx context.Context) (int32, error)
 	GetNoteForFlashcard(ctx context.Context, arg GetNoteForFlashcardParams) (GetNoteForFlashcardRow, error)
+	GetNoteRevision(ctx context.Context, arg GetNoteRevisionParams) (NoteRevision, error)
+	// Returns the note's latest revision number, which versions it for ETags
+	GetNoteRevisionNumber(ctx context.Context, noteID pgtype.UUID) (int32, error)
+	GetNotebook(ctx context.Context, arg GetNotebookParams) (Notebook, error)
+	// Returns the notebook and all of its descendants. UNION stops at notebooks
+	// already visited, so even a parent cycle cannot recurse forever.
+	GetNotebookSubtreeIDs(ctx context.Context, arg GetNotebookSubtreeIDsParams) ([]pgtype.UUID, error)
+	// Sorts by sort (created_at, updated_at or title) with id breaking ties. Pages
+	// after the first pass the last row's id with its sort value as cursor_time or
+	// cursor_title, and get the rows following it (keyset pagination).
 	GetUserNotes(ctx context.Context, arg GetUserNotesParams) ([]GetUserNotesRow, error)
 	GetUserProfile(ctx context.Context, id pgtype.UUID) (UserProfile, error)
 	GetUserProfileByUsername(ctx context.Context, username pgtype.Text) (UserProfile, error)
+	// Creates a note keeping the timestamps it had where it was imported from
+	ImportNote(ctx context.Context, arg ImportNoteParams) (ImportNoteRow, error)
+	// Returns the notes linking to a note, with the text around each link
+	ListBacklinks(ctx context.Context, arg ListBacklinksParams) ([]ListBacklinksRow, error)
+	ListCardReviewLogs(ctx context.Context, arg ListCardReviewLogsParams) ([]ReviewLog, error)
+	ListDeckFlashcards(ctx context.Context, arg ListDeckFlashcardsParams) ([]Flashcard, error)
+	// Cards without a schedule have never been reviewed and are always due.
+	ListDueFlashcards(ctx context.Context, arg ListDueFlashcardsParams) ([]ListDueFlashcardsRow, error)
+	ListGraphNotes(ctx context.Context, arg ListGraphNotesParams) ([]ListGraphNotesRow, error)
+	ListGraphNotesByID(ctx context.Context, arg ListGraphNotesByIDParams) ([]ListGraphNotesByIDRow, error)
+	ListImportJobs(ctx context.Context, arg ListImportJobsParams) ([]ListImportJobsRow, error)
+	// Returns the resolved links between the user's live notes from or to any of
+	// the given notes, once per pair
+	ListLinkEdges(ctx context.Context, arg ListLinkEdgesParams) ([]ListLinkEdgesRow, error)
+	ListNoteRevisions(ctx context.Context, arg ListNoteRevisionsParams) ([]ListNoteRevisionsRow, error)
+	ListNotebookNotes(ctx context.Context, arg ListNotebookNotesParams) ([]ListNotebookNotesRow, error)
+	// note_count only counts notes directly in the notebook, not in its children
+	ListNotebooks(ctx context.Context, userID pgtype.UUID) ([]ListNotebooksRow, error)
+	// Pages through all of the user's live notes in id order, after_id being the
+	// last id of the previous page
+	ListNotesForExport(ctx context.Context, arg ListNotesForExportParams) ([]ListNotesForExportRow, error)
+	// Links to trashed notes are reported without a target, like unresolved links
+	ListOutgoingLinks(ctx context.Context, arg ListOutgoingLinksParams) ([]ListOutgoingLinksRow, error)
+	// Returns which of the given note IDs belong to the user, trashed notes included
+	ListOwnedNoteIDs(ctx context.Context, arg ListOwnedNoteIDsParams) ([]pgtype.UUID, error)
+	// Ranks the user's other notes by similarity to the note's stored embedding;
+	// linked is set when either note links to the other
+	ListRelatedNotes(ctx context.Context, arg ListRelatedNotesParams) ([]ListRelatedNotesRow, error)
+	// Returns up to k nearest notes by stored embedding for each of the given notes
+	ListSemanticNeighbors(ctx context.Context, arg ListSemanticNeighborsParams) ([]ListSemanticNeighborsRow, error)
+	// Counts the user's live notes per tag, optionally only prefix and its descendants
+	ListTagCounts(ctx context.Context, arg ListTagCountsParams) ([]ListTagCountsRow, error)
+	// Returns up to limit of the user's most recent live notes carrying any of the
+	// tags, leaving out the excluded notes
+	ListTaggedGraphNoteIDs(ctx context.Context, arg ListTaggedGraphNoteIDsParams) ([]pgtype.UUID, error)
+	ListTrashedNotes(ctx context.Context, arg ListTrashedNotesParams) ([]ListTrashedNotesRow, error)
+	ListUnindexedLinkNotes(ctx context.Context, limit int32) ([]ListUnindexedLinkNotesRow, error)
+	// Groups links with no live target by title, most referenced first
+	ListUnresolvedLinks(ctx context.Context, arg ListUnresolvedLinksParams) ([]ListUnresolvedLinksRow, error)
+	ListUnsegmentedNotes(ctx context.Context, limit int32) ([]ListUnsegmentedNotesRow, error)
+	ListUserDecks(ctx context.Context, arg ListUserDecksParams) ([]ListUserDecksRow, error)
+	// Keyset pagination like GetUserNotes; sort is created_at, updated_at or
+	// username, with profiles lacking a username sorted as an empty one
 	ListUserProfiles(ctx context.Context, arg ListUserProfilesParams) ([]UserProfile, error)
+	// Locks the user's flashcard until the transaction ends, so concurrent reviews
+	// of it apply one after another even before it has a schedule
+	LockFlashcard(ctx context.Context, arg LockFlashcardParams) (Flashcard, error)
+	// Locks the user's live note until the transaction ends. The row returned is
+	// its latest version, even when the lock had to wait for another edit.
+	LockNote(ctx context.Context, arg LockNoteParams) (LockNoteRow, error)
+	// Locks the user's notebooks so concurrent moves see each other's new parents
+	LockNotebooks(ctx context.Context, userID pgtype.UUID) error
+	// Locks the profile until the transaction ends and returns when it last changed
+	LockUserProfile(ctx context.Context, id pgtype.UUID) (pgtype.Timestamptz, error)
+	MarkNoteLinksIndexed(ctx context.Context, id pgtype.UUID) error
+	// Moves the notebook under a new parent, after its new siblings
+	MoveNotebook(ctx context.Context, arg MoveNotebookParams) (Notebook, error)
+	// Permanently deletes up to batch_size notes trashed before deleted_before
+	PurgeExpiredNotes(ctx context.Context, arg PurgeExpiredNotesParams) (int64, error)
+	PurgeNote(ctx context.Context, arg PurgeNoteParams) (int64, error)
+	RemoveNoteTags(ctx context.Context, arg RemoveNoteTagsParams) (int64, error)
+	// Points unresolved links whose title matches at the given note
+	ResolveNoteLinks(ctx context.Context, arg ResolveNoteLinksParams) (int64, error)
+	RestoreNote(ctx context.Context, arg RestoreNoteParams) (RestoreNoteRow, error)
+	// Replaces the source tags on all of the user's notes, trashed ones included,
+	// by target, or removes them when target is NULL. With include_children their
+	// descendants move along (lang/go/generics becomes golang/generics when lang/go
+	// is renamed to golang) or are removed too. Tags the replacement duplicates
+	// keep their first position. Returns the IDs of the notes that changed.
+	RetagNotes(ctx context.Context, arg RetagNotesParams) ([]pgtype.UUID, error)
+	RetryEmbeddingJob(ctx context.Context, arg RetryEmbeddingJobParams) error
+	// query is a to_tsquery expression over segmented text, see services.BuildSearchQuery
+	SearchNotesByKeyword(ctx context.Context, arg SearchNotesByKeywordParams) ([]SearchNotesByKeywordRow, error)
+	// Matches chunks of notes passing the optional filters, ranks notes by their
+	// best chunk and returns up to passages_per_note passages for each of the top notes
 	SearchNotesBySimilarity(ctx context.Context, arg SearchNotesBySimilarityParams) ([]SearchNotesBySimilarityRow, error)
+	SetNoteEmbeddingStatus(ctx context.Context, arg SetNoteEmbeddingStatusParams) error
+	// A NULL notebook_id moves the note out of every notebook
+	SetNoteNotebook(ctx context.Context, arg SetNoteNotebookParams) (int64, error)
+	TrashNotebookNotes(ctx context.Context, arg TrashNotebookNotesParams) (int64, error)
+	UpdateDeck(ctx context.Context, arg UpdateDeckParams) (Deck, error)
+	UpdateFlashcard(ctx context.Context, arg UpdateFlashcardParams) (Flashcard, error)
+	// Also renews the job's lock, so a long import is not taken for a crashed one
+	UpdateImportJobProgress(ctx context.Context, arg UpdateImportJobProgressParams) error
+	// Changing the title or content marks the embedding as stale until the worker refreshes it
 	UpdateNote(ctx context.Context, arg UpdateNoteParams) (UpdateNoteRow, error)
+	UpdateNoteEmbedding(ctx context.Context, arg UpdateNoteEmbeddingParams) error
+	UpdateNoteSearchText(ctx context.Context, arg UpdateNoteSearchTextParams) error
+	UpdateNotebook(ctx context.Context, arg UpdateNotebookParams) (Notebook, error)
 	//  COALESCE is used to update the user profile with the new values if they are not null, if they are null, the old value will be kept.
 	UpdateUserProfile(ctx context.Context, arg UpdateUserProfileParams) (UserProfile, error)
+	UpsertCardSchedule(ctx context.Context, arg UpsertCardScheduleParams) (CardSchedule, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -24,6 +24,35 @@ func (q *Queries) CheckUsernameExists(ctx context.Context, username pgtype.Text)
 	return exists, err
 }
 
+const countUserProfiles = `-- name: CountUserProfiles :one
+SELECT COUNT(*)
+FROM user_profiles
+WHERE ($1::timestamptz IS NULL OR created_at >= $1::timestamptz)
+    AND ($2::timestamptz IS NULL OR created_at < $2::timestamptz)
+    AND ($3::timestamptz IS NULL OR updated_at >= $3::timestamptz)
+    AND ($4::timestamptz IS NULL OR updated_at < $4::timestamptz)
+`
+
+type CountUserProfilesParams struct {
+	CreatedAfter  pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore pgtype.Timestamptz `json:"created_before"`
+	UpdatedAfter  pgtype.Timestamptz `json:"updated_after"`
+	UpdatedBefore pgtype.Timestamptz `json:"updated_before"`
+}
+
+// Counts the profiles ListUserProfiles pages through with the same filters
+func (q *Queries) CountUserProfiles(ctx context.Context, arg CountUserProfilesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countUserProfiles,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createUserProfile = `-- name: CreateUserProfile :one
 INSERT INTO user_profiles (id, username, display_name, avatar_url, preferences)
 VALUES ($1, $2, $3, $4, $5)
@@ -114,17 +143,65 @@ func (q *Queries) GetUserProfileByUsername(ctx context.Context, username pgtype.
 const listUserProfiles = `-- name: ListUserProfiles :many
 SELECT id, username, display_name, avatar_url, preferences, created_at, updated_at
 FROM user_profiles
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2
+WHERE ($1::timestamptz IS NULL OR created_at >= $1::timestamptz)
+    AND ($2::timestamptz IS NULL OR created_at < $2::timestamptz)
+    AND ($3::timestamptz IS NULL OR updated_at >= $3::timestamptz)
+    AND ($4::timestamptz IS NULL OR updated_at < $4::timestamptz)
+    AND ($5::uuid IS NULL OR CASE
+        WHEN $6::text = 'username' AND $7::bool
+            THEN (COALESCE(username, ''), id) < ($8::text, $5::uuid)
+        WHEN $6::text = 'username'
+            THEN (COALESCE(username, ''), id) > ($8::text, $5::uuid)
+        WHEN $6::text = 'updated_at' AND $7::bool
+            THEN (updated_at, id) < ($9::timestamptz, $5::uuid)
+        WHEN $6::text = 'updated_at'
+            THEN (updated_at, id) > ($9::timestamptz, $5::uuid)
+        WHEN $7::bool
+            THEN (created_at, id) < ($9::timestamptz, $5::uuid)
+        ELSE (created_at, id) > ($9::timestamptz, $5::uuid)
+    END)
+ORDER BY
+    CASE WHEN $6::text = 'username' AND NOT $7::bool THEN COALESCE(username, '') END ASC,
+    CASE WHEN $6::text = 'username' AND $7::bool THEN COALESCE(username, '') END DESC,
+    CASE WHEN $6::text = 'updated_at' AND NOT $7::bool THEN updated_at END ASC,
+    CASE WHEN $6::text = 'updated_at' AND $7::bool THEN updated_at END DESC,
+    CASE WHEN $6::text = 'created_at' AND NOT $7::bool THEN created_at END ASC,
+    CASE WHEN $6::text = 'created_at' AND $7::bool THEN created_at END DESC,
+    CASE WHEN NOT $7::bool THEN id END ASC,
+    CASE WHEN $7::bool THEN id END DESC
+LIMIT $10 OFFSET $11
 `
 
 type ListUserProfilesParams struct {
-	Limit  int32 `json:"limit"`
-	Offset int32 `json:"offset"`
+	CreatedAfter   pgtype.Timestamptz `json:"created_after"`
+	CreatedBefore  pgtype.Timestamptz `json:"created_before"`
+	UpdatedAfter   pgtype.Timestamptz `json:"updated_after"`
+	UpdatedBefore  pgtype.Timestamptz `json:"updated_before"`
+	CursorID       pgtype.UUID        `json:"cursor_id"`
+	Sort           string             `json:"sort"`
+	Descending     bool               `json:"descending"`
+	CursorUsername pgtype.Text        `json:"cursor_username"`
+	CursorTime     pgtype.Timestamptz `json:"cursor_time"`
+	Limit          int32              `json:"limit"`
+	Offset         int32              `json:"offset"`
 }
 
+// Keyset pagination like GetUserNotes; sort is created_at, updated_at or
+// username, with profiles lacking a username sorted as an empty one
 func (q *Queries) ListUserProfiles(ctx context.Context, arg ListUserProfilesParams) ([]UserProfile, error) {
-	rows, err := q.db.Query(ctx, listUserProfiles, arg.Limit, arg.Offset)
+	rows, err := q.db.Query(ctx, listUserProfiles,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+		arg.UpdatedAfter,
+		arg.UpdatedBefore,
+		arg.CursorID,
+		arg.Sort,
+		arg.Descending,
+		arg.CursorUsername,
+		arg.CursorTime,
+		arg.Limit,
+		arg.Offset,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -151,6 +228,21 @@ func (q *Queries) ListUserProfiles(ctx context.Context, arg ListUserProfilesPara
 	return items, nil
 }
 
+const lockUserProfile = `-- name: LockUserProfile :one
+SELECT updated_at
+FROM user_profiles
+WHERE id = $1
+FOR UPDATE
+`
+
+// Locks the profile until the transaction ends and returns when it last changed
+func (q *Queries) LockUserProfile(ctx context.Context, id pgtype.UUID) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, lockUserProfile, id)
+	var updated_at pgtype.Timestamptz
+	err := row.Scan(&updated_at)
+	return updated_at, err
+}
+
 const updateUserProfile = `-- name: UpdateUserProfile :one
 UPDATE user_profiles
 SET 
@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxBatchOperations caps the operations accepted in one batch request
+const maxBatchOperations = 100
+
+// Batch operation types
+const (
+	batchOpCreate     = "create"
+	batchOpUpdate     = "update"
+	batchOpDelete     = "delete"
+	batchOpAddTags    = "add_tags"
+	batchOpRemoveTags = "remove_tags"
+	batchOpMove       = "move"
+)
+
+// Batch operation outcomes
+const (
+	batchStatusOK         = "ok"
+	batchStatusError      = "error"
+	batchStatusRolledBack = "rolled_back" // succeeded, then undone because a later operation failed
+	batchStatusSkipped    = "skipped"     // not attempted because an earlier operation failed
+)
+
+// BatchHandler handles bulk note operations
+type BatchHandler struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewBatchHandler creates a new batch handler
+func NewBatchHandler(db *pgxpool.Pool) *BatchHandler {
+	return &BatchHandler{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// BatchOperation is one operation of a batch request. Which fields are used
+// depends on Op: create takes title, content, tags and notebook_id; update
+// takes id and any of title, content, tags and notebook_id; delete takes id;
+// add_tags and remove_tags take id and tags; move takes id and notebook_id
+// ("" for no notebook).
+type BatchOperation struct {
+	Op         string   `json:"op" binding:"required"`
+	ID         string   `json:"id,omitempty"`
+	Title      *string  `json:"title,omitempty"`
+	Content    *string  `json:"content,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	NotebookID *string  `json:"notebook_id,omitempty"`
+}
+
+// BatchRequest represents the request body for bulk note operations
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" binding:"required,min=1"`
+	Atomic     *bool            `json:"atomic,omitempty"` // default true: all operations succeed or none do
+}
+
+// BatchResult reports the outcome of one operation
+type BatchResult struct {
+	Index  int    `json:"index"`
+	Op     string `json:"op"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"` // the note the operation applied to, or created
+	Code   int    `json:"code"`         // HTTP status the operation would have had on its own
+	Error  string `json:"error,omitempty"`
+}
+
+// batchError is an operation failure with the HTTP status it maps to
+type batchError struct {
+	code    int
+	message string
+}
+
+func (e *batchError) Error() string {
+	return e.message
+}
+
+// batchOutcome is what a successful operation changed
+type batchOutcome struct {
+	noteID      pgtype.UUID
+	code        int
+	textChanged bool // title or content changed, so the note needs embedding
+}
+
+// ApplyBatch handles POST /api/notes/batch
+// Operations run in order in one transaction, each behind a savepoint. In
+// atomic mode (the default) the first failure rolls everything back and the
+// response is 422; otherwise failed operations are undone on their own and the
+// rest is committed. Notes whose text changed are queued for embedding together.
+func (h *BatchHandler) ApplyBatch(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req BatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if len(req.Operations) > maxBatchOperations {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A batch holds at most " + strconv.Itoa(maxBatchOperations) + " operations"})
+		return
+	}
+	atomic := req.Atomic == nil || *req.Atomic
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply batch"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	results := make([]BatchResult, len(req.Operations))
+	var toEmbed []pgtype.UUID
+	embedQueued := make(map[pgtype.UUID]bool)
+	failed := -1
+
+	for i, op := range req.Operations {
+		results[i] = BatchResult{Index: i, Op: op.Op, ID: op.ID}
+		if failed >= 0 && atomic {
+			results[i].Status = batchStatusSkipped
+			continue
+		}
+
+		outcome, err := h.applyInSavepoint(ctx, tx, userUUID, op)
+		var opErr *batchError
+		if errors.As(err, &opErr) {
+			results[i].Status = batchStatusError
+			results[i].Code = opErr.code
+			results[i].Error = opErr.message
+			if failed < 0 {
+				failed = i
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("Failed to apply batch operation %d (%s): %v", i, op.Op, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply batch"})
+			return
+		}
+
+		results[i].Status = batchStatusOK
+		results[i].Code = outcome.code
+		results[i].ID = outcome.noteID.String()
+		if outcome.textChanged && !embedQueued[outcome.noteID] {
+			embedQueued[outcome.noteID] = true
+			toEmbed = append(toEmbed, outcome.noteID)
+		}
+	}
+
+	if failed >= 0 && atomic {
+		for i := range failed {
+			results[i].Status = batchStatusRolledBack
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":     "Operation " + strconv.Itoa(failed) + " failed, nothing was changed",
+			"committed": false,
+			"results":   results,
+		})
+		return
+	}
+
+	if len(toEmbed) > 0 {
+		if err := h.queries.WithTx(tx).EnqueueEmbeddingJobs(ctx, db_sqlc.EnqueueEmbeddingJobsParams{
+			UserID:  userUUID,
+			NoteIds: toEmbed,
+		}); err != nil {
+			log.Printf("Failed to enqueue embedding jobs: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply batch"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply batch"})
+		return
+	}
+
+	succeeded := 0
+	for _, result := range results {
+		if result.Status == batchStatusOK {
+			succeeded++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"committed": true,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// applyInSavepoint runs one operation so that its failure can be undone
+// without losing the operations before it
+func (h *BatchHandler) applyInSavepoint(ctx context.Context, tx pgx.Tx, userUUID pgtype.UUID, op BatchOperation) (batchOutcome, error) {
+	savepoint, err := tx.Begin(ctx)
+	if err != nil {
+		return batchOutcome{}, err
+	}
+	defer savepoint.Rollback(ctx)
+
+	outcome, err := h.apply(ctx, h.queries.WithTx(savepoint), userUUID, op)
+	if err != nil {
+		return outcome, err
+	}
+	return outcome, savepoint.Commit(ctx)
+}
+
+// apply runs one operation. Every change except a delete is recorded as a
+// revision, like the single-note endpoints do.
+func (h *BatchHandler) apply(ctx context.Context, qtx *db_sqlc.Queries, userUUID pgtype.UUID, op BatchOperation) (batchOutcome, error) {
+	if op.Op == batchOpCreate {
+		return h.create(ctx, qtx, userUUID, op)
+	}
+
+	var noteUUID pgtype.UUID
+	if err := noteUUID.Scan(op.ID); err != nil {
+		return batchOutcome{}, &batchError{http.StatusBadRequest, "Invalid note ID format"}
+	}
+	outcome := batchOutcome{noteID: noteUUID, code: http.StatusOK}
+	notFound := &batchError{http.StatusNotFound, "Note not found"}
+
+	// Lock the note so no concurrent edit lands between reading it and its new revision
+	current, _, err := services.LockNote(ctx, qtx, noteUUID, userUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return outcome, notFound
+	}
+	if err != nil {
+		return outcome, err
+	}
+
+	var rows int64
+	switch op.Op {
+	case batchOpUpdate:
+		return h.update(ctx, qtx, userUUID, current, op)
+
+	case batchOpDelete:
+		rows, err = qtx.DeleteNote(ctx, db_sqlc.DeleteNoteParams{ID: noteUUID, UserID: userUUID})
+		if err != nil {
+			return outcome, err
+		}
+		if rows == 0 {
+			return outcome, notFound
+		}
+		outcome.code = http.StatusNoContent
+		return outcome, nil
+
+	case batchOpAddTags, batchOpRemoveTags:
+		tags := services.NormalizeTags(op.Tags)
+		if len(tags) == 0 {
+			return outcome, &batchError{http.StatusBadRequest, "tags is required"}
+		}
+		params := db_sqlc.AddNoteTagsParams{Tags: tags, ID: noteUUID, UserID: userUUID}
+		if op.Op == batchOpAddTags {
+			rows, err = qtx.AddNoteTags(ctx, params)
+		} else {
+			rows, err = qtx.RemoveNoteTags(ctx, db_sqlc.RemoveNoteTagsParams(params))
+		}
+
+	case batchOpMove:
+		if op.NotebookID == nil {
+			return outcome, &batchError{http.StatusBadRequest, "notebook_id is required"}
+		}
+		notebookUUID, opErr := h.findNotebook(ctx, qtx, *op.NotebookID, userUUID)
+		if opErr != nil {
+			return outcome, opErr
+		}
+		rows, err = qtx.SetNoteNotebook(ctx, db_sqlc.SetNoteNotebookParams{
+			ID:         noteUUID,
+			UserID:     userUUID,
+			NotebookID: notebookUUID,
+		})
+
+	default:
+		return outcome, &batchError{http.StatusBadRequest, "Unknown operation " + strconv.Quote(op.Op)}
+	}
+
+	if err != nil {
+		return outcome, err
+	}
+	if rows == 0 {
+		return outcome, notFound
+	}
+	if _, err := qtx.CreateNoteRevision(ctx, db_sqlc.CreateNoteRevisionParams{NoteID: noteUUID}); err != nil {
+		return outcome, err
+	}
+	return outcome, nil
+}
+
+// create adds a note, like NotesHandler.CreateNote
+func (h *BatchHandler) create(ctx context.Context, qtx *db_sqlc.Queries, userUUID pgtype.UUID, op BatchOperation) (batchOutcome, error) {
+	if op.Title == nil || *op.Title == "" || op.Content == nil || *op.Content == "" {
+		return batchOutcome{}, &batchError{http.StatusBadRequest, "title and content are required"}
+	}
+
+	var notebookUUID pgtype.UUID
+	if op.NotebookID != nil {
+		var opErr *batchError
+		if notebookUUID, opErr = h.findNotebook(ctx, qtx, *op.NotebookID, userUUID); opErr != nil {
+			return batchOutcome{}, opErr
+		}
+	}
+
+	note, err := qtx.CreateNote(ctx, db_sqlc.CreateNoteParams{
+		UserID:        userUUID,
+		Title:         *op.Title,
+		Content:       *op.Content,
+		Tags:          services.NormalizeTags(op.Tags),
+		SearchTitle:   services.SearchText(*op.Title),
+		SearchContent: services.SearchText(*op.Content),
+		NotebookID:    notebookUUID,
+	})
+	if err != nil {
+		return batchOutcome{}, err
+	}
+	if _, err := qtx.CreateNoteRevision(ctx, db_sqlc.CreateNoteRevisionParams{NoteID: note.ID}); err != nil {
+		return batchOutcome{}, err
+	}
+	if err := services.IndexNoteLinks(ctx, qtx, note.ID, userUUID, note.Title, note.Content); err != nil {
+		return batchOutcome{}, err
+	}
+
+	return batchOutcome{noteID: note.ID, code: http.StatusCreated, textChanged: true}, nil
+}
+
+// update changes a note, like NotesHandler.UpdateNote, keeping the fields
+// that were not sent from current, the locked note
+func (h *BatchHandler) update(ctx context.Context, qtx *db_sqlc.Queries, userUUID pgtype.UUID, current db_sqlc.LockNoteRow, op BatchOperation) (batchOutcome, error) {
+	noteUUID := current.ID
+	outcome := batchOutcome{noteID: noteUUID, code: http.StatusOK}
+
+	params := db_sqlc.UpdateNoteParams{
+		ID:      noteUUID,
+		UserID:  userUUID,
+		Title:   current.Title,
+		Content: current.Content,
+		Tags:    services.NormalizeTags(op.Tags),
+	}
+	if op.Title != nil {
+		params.Title = *op.Title
+	}
+	if op.Content != nil {
+		params.Content = *op.Content
+	}
+	params.SearchTitle = services.SearchText(params.Title)
+	params.SearchContent = services.SearchText(params.Content)
+	outcome.textChanged = params.Title != current.Title || params.Content != current.Content
+
+	if _, err := qtx.UpdateNote(ctx, params); err != nil {
+		return outcome, err
+	}
+
+	if op.NotebookID != nil {
+		notebookUUID, opErr := h.findNotebook(ctx, qtx, *op.NotebookID, userUUID)
+		if opErr != nil {
+			return outcome, opErr
+		}
+		if _, err := qtx.SetNoteNotebook(ctx, db_sqlc.SetNoteNotebookParams{
+			ID:         noteUUID,
+			UserID:     userUUID,
+			NotebookID: notebookUUID,
+		}); err != nil {
+			return outcome, err
+		}
+	}
+
+	if _, err := qtx.CreateNoteRevision(ctx, db_sqlc.CreateNoteRevisionParams{NoteID: noteUUID}); err != nil {
+		return outcome, err
+	}
+	if outcome.textChanged {
+		if err := services.IndexNoteLinks(ctx, qtx, noteUUID, userUUID, params.Title, params.Content); err != nil {
+			return outcome, err
+		}
+	}
+	return outcome, nil
+}
+
+// findNotebook resolves a notebook ID of the user; "" means no notebook
+func (h *BatchHandler) findNotebook(ctx context.Context, qtx *db_sqlc.Queries, notebookID string, userUUID pgtype.UUID) (pgtype.UUID, *batchError) {
+	var notebookUUID pgtype.UUID
+	if notebookID == "" {
+		return notebookUUID, nil
+	}
+	if err := notebookUUID.Scan(notebookID); err != nil {
+		return notebookUUID, &batchError{http.StatusBadRequest, "Invalid notebook ID format"}
+	}
+	if _, err := qtx.GetNotebook(ctx, db_sqlc.GetNotebookParams{ID: notebookUUID, UserID: userUUID}); err != nil {
+		return notebookUUID, &batchError{http.StatusNotFound, "Notebook not found"}
+	}
+	return notebookUUID, nil
+}
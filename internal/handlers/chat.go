@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ChatHandler handles questions answered from the user's notes
+type ChatHandler struct {
+	queries       *db_sqlc.Queries
+	searchService *services.SearchService
+	chatService   *services.ChatService
+}
+
+// NewChatHandler creates a new chat handler answering with the flashcard service's LLM
+func NewChatHandler(db *pgxpool.Pool, embeddingService *services.EmbeddingService, flashcardService *services.FlashcardService) *ChatHandler {
+	return &ChatHandler{
+		queries:       db_sqlc.New(db),
+		searchService: services.NewSearchService(db, embeddingService),
+		chatService:   services.NewChatService(flashcardService.Model()),
+	}
+}
+
+// ChatRequest represents a question about the user's notes
+type ChatRequest struct {
+	Question   string                 `json:"question" binding:"required"`
+	History    []services.ChatMessage `json:"history,omitempty"`     // Earlier messages of the conversation, oldest first
+	NotebookID string                 `json:"notebook_id,omitempty"` // Only use notes of this notebook and its descendants
+}
+
+// Chat handles POST /api/chat
+// Finds the passages of the user's notes most related to the question and
+// streams an answer grounded in them over SSE. The final complete event has
+// the claims of the answer with the note passages each one cites, or a
+// refusal when no relevant notes are found.
+func (h *ChatHandler) Chat(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req ChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	req.Question = strings.TrimSpace(req.Question)
+	if req.Question == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Question cannot be empty"})
+		return
+	}
+	for _, message := range req.History {
+		if message.Role != "user" && message.Role != "assistant" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "History roles must be user or assistant"})
+			return
+		}
+	}
+
+	// Parse user UUID
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	notebookIDs, ok := resolveNotebookScope(c, h.queries, req.NotebookID, userUUID)
+	if !ok {
+		return
+	}
+
+	search, err := h.searchService.Search(c.Request.Context(), userUUID, services.SearchOptions{
+		Query:           req.Question,
+		Mode:            services.SearchModeSemantic,
+		Threshold:       0.6,
+		Limit:           5,
+		PassagesPerNote: 2,
+		Filters:         services.SearchFilters{NotebookIDs: notebookIDs},
+	})
+	if err != nil {
+		log.Printf("Failed to search notes for chat: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search notes"})
+		return
+	}
+	sources := services.BuildChatSources(search.Results)
+
+	// Set SSE headers
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	responseChan := make(chan string, 100)
+
+	go func() {
+		defer close(responseChan)
+		if _, err := h.chatService.StreamAnswer(c.Request.Context(), req.Question, req.History, sources, responseChan); err != nil {
+			log.Printf("Failed to answer chat question: %v", err)
+		}
+	}()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case message, ok := <-responseChan:
+			if !ok {
+				return false
+			}
+			_, _ = w.Write([]byte(message))
+			if f, ok := c.Writer.(http.Flusher); ok {
+				f.Flush()
+			}
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
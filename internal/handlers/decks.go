@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DeckHandler handles deck-related HTTP requests
+type DeckHandler struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewDeckHandler creates a new deck handler
+func NewDeckHandler(db *pgxpool.Pool) *DeckHandler {
+	return &DeckHandler{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// CreateDeckRequest represents the request body for creating a deck
+type CreateDeckRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// UpdateDeckRequest represents the request body for updating a deck
+type UpdateDeckRequest struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+}
+
+// DeckResponse represents the response format for decks
+type DeckResponse struct {
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	CardCount   *int64 `json:"card_count,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	UpdatedAt   string `json:"updated_at"`
+}
+
+// CreateDeck handles POST /api/decks
+func (h *DeckHandler) CreateDeck(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req CreateDeckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	deck, err := h.queries.CreateDeck(c.Request.Context(), db_sqlc.CreateDeckParams{
+		UserID:      userUUID,
+		Name:        req.Name,
+		Description: req.Description,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create deck"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, convertDeckToResponse(deck))
+}
+
+// GetDeck handles GET /api/decks/:id
+func (h *DeckHandler) GetDeck(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var deckUUID, userUUID pgtype.UUID
+	if err := deckUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	deck, err := h.queries.GetDeck(c.Request.Context(), db_sqlc.GetDeckParams{
+		ID:     deckUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertDeckToResponse(deck))
+}
+
+// ListDecks handles GET /api/decks
+func (h *DeckHandler) ListDecks(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	// Parse query parameters
+	limitStr := c.DefaultQuery("limit", "20")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	decks, err := h.queries.ListUserDecks(c.Request.Context(), db_sqlc.ListUserDecksParams{
+		UserID: userUUID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch decks"})
+		return
+	}
+
+	var responses []DeckResponse
+	for _, deck := range decks {
+		cardCount := deck.CardCount
+		responses = append(responses, DeckResponse{
+			ID:          deck.ID.String(),
+			UserID:      deck.UserID.String(),
+			Name:        deck.Name,
+			Description: deck.Description,
+			CardCount:   &cardCount,
+			CreatedAt:   deck.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			UpdatedAt:   deck.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"decks":  responses,
+		"limit":  limit,
+		"offset": offset,
+		"count":  len(responses),
+	})
+}
+
+// UpdateDeck handles PUT /api/decks/:id
+func (h *DeckHandler) UpdateDeck(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req UpdateDeckRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var deckUUID, userUUID pgtype.UUID
+	if err := deckUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	params := db_sqlc.UpdateDeckParams{
+		ID:     deckUUID,
+		UserID: userUUID,
+	}
+	if req.Name != nil {
+		params.Name.Scan(*req.Name)
+	}
+	if req.Description != nil {
+		params.Description.Scan(*req.Description)
+	}
+
+	deck, err := h.queries.UpdateDeck(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertDeckToResponse(deck))
+}
+
+// DeleteDeck handles DELETE /api/decks/:id
+// Deleting a deck also deletes all of its flashcards
+func (h *DeckHandler) DeleteDeck(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var deckUUID, userUUID pgtype.UUID
+	if err := deckUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	rows, err := h.queries.DeleteDeck(c.Request.Context(), db_sqlc.DeleteDeckParams{
+		ID:     deckUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete deck"})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// ListDeckFlashcards handles GET /api/decks/:id/flashcards
+func (h *DeckHandler) ListDeckFlashcards(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	// Parse query parameters
+	limitStr := c.DefaultQuery("limit", "50")
+	offsetStr := c.DefaultQuery("offset", "0")
+
+	limit, err := strconv.Atoi(limitStr)
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(offsetStr)
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	var deckUUID, userUUID pgtype.UUID
+	if err := deckUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	if _, err := h.queries.GetDeck(c.Request.Context(), db_sqlc.GetDeckParams{
+		ID:     deckUUID,
+		UserID: userUUID,
+	}); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return
+	}
+
+	flashcards, err := h.queries.ListDeckFlashcards(c.Request.Context(), db_sqlc.ListDeckFlashcardsParams{
+		DeckID: deckUUID,
+		UserID: userUUID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch flashcards"})
+		return
+	}
+
+	var responses []FlashcardResponse
+	for _, flashcard := range flashcards {
+		responses = append(responses, convertFlashcardToResponse(flashcard))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flashcards": responses,
+		"limit":      limit,
+		"offset":     offset,
+		"count":      len(responses),
+	})
+}
+
+// convertDeckToResponse converts a database Deck to API response format
+func convertDeckToResponse(deck db_sqlc.Deck) DeckResponse {
+	return DeckResponse{
+		ID:          deck.ID.String(),
+		UserID:      deck.UserID.String(),
+		Name:        deck.Name,
+		Description: deck.Description,
+		CreatedAt:   deck.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:   deck.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// noteETag versions a note by its latest revision, which changes with every
+// edit and with nothing else, so background work on the note keeps it
+func noteETag(revision int32) string {
+	return fmt.Sprintf(`"r%d"`, revision)
+}
+
+// profileETag versions a user profile by when it last changed
+func profileETag(updatedAt pgtype.Timestamptz) string {
+	return `"` + strconv.FormatInt(updatedAt.Time.UnixMicro(), 36) + `"`
+}
+
+// etagList splits an If-Match or If-None-Match header into its entity tags
+func etagList(header string) []string {
+	var tags []string
+	for _, tag := range strings.Split(header, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// notModified answers 304 Not Modified when If-None-Match lists etag (weak
+// comparison), and otherwise sets the ETag header for the response to come
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	for _, tag := range etagList(c.GetHeader("If-None-Match")) {
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// preconditionFailed answers 412 Precondition Failed, with the current ETag,
+// unless the If-Match header is missing or lists a tag that matches
+func preconditionFailed(c *gin.Context, etag string, matches func(tag string) bool) bool {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		return false
+	}
+	for _, tag := range etagList(header) {
+		// Weak tags never match for If-Match
+		if tag == "*" || (!strings.HasPrefix(tag, "W/") && matches(tag)) {
+			return false
+		}
+	}
+
+	c.Header("ETag", etag)
+	c.JSON(http.StatusPreconditionFailed, gin.H{"error": "The resource was modified by another request"})
+	return true
+}
+
+// notePreconditionFailed checks If-Match against a note at revision
+func notePreconditionFailed(c *gin.Context, revision int32) bool {
+	etag := noteETag(revision)
+	return preconditionFailed(c, etag, func(tag string) bool {
+		return tag == etag
+	})
+}
+
+// profilePreconditionFailed checks If-Match against a profile last updated at updatedAt
+func profilePreconditionFailed(c *gin.Context, updatedAt pgtype.Timestamptz) bool {
+	etag := profileETag(updatedAt)
+	return preconditionFailed(c, etag, func(tag string) bool {
+		return tag == etag
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-note/internal/auth"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// exportTimeout is how long streaming an export may take, well beyond the server's WriteTimeout
+const exportTimeout = 10 * time.Minute
+
+// ExportHandler handles exporting a user's account
+type ExportHandler struct {
+	exportService *services.ExportService
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(db *pgxpool.Pool) *ExportHandler {
+	return &ExportHandler{
+		exportService: services.NewExportService(db),
+	}
+}
+
+// ExportAccount handles GET /api/export
+// Streams a zip archive with every note as a markdown file with front matter
+// and go-note.json, a JSON dump of the profile, notebooks and notes. With
+// html=true the archive also contains a static HTML site under site/.
+func (h *ExportHandler) ExportAccount(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	withHTML, err := strconv.ParseBool(c.DefaultQuery("html", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "html must be true or false"})
+		return
+	}
+
+	// Large accounts take longer to stream than the server's WriteTimeout allows
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(exportTimeout)); err != nil {
+		log.Printf("Failed to extend write deadline for export: %v", err)
+	}
+
+	filename := "go-note-export-" + time.Now().UTC().Format("2006-01-02") + ".zip"
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	err = h.exportService.WriteExport(c.Request.Context(), c.Writer, userUUID, services.ExportOptions{HTML: withHTML})
+	if err == nil {
+		return
+	}
+	log.Printf("Export for user %s failed: %v", userID, err)
+	if c.Writer.Written() {
+		// Part of the archive is out already. It lacks the zip's central
+		// directory, so the client cannot mistake it for a complete export.
+		return
+	}
+	c.Header("Content-Disposition", "")
+	c.Header("Content-Type", "")
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export account"})
+}
@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// FlashcardHandler handles saved flashcard HTTP requests
+type FlashcardHandler struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewFlashcardHandler creates a new flashcard handler
+func NewFlashcardHandler(db *pgxpool.Pool) *FlashcardHandler {
+	return &FlashcardHandler{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// CreateFlashcardRequest represents the request body for creating a flashcard
+type CreateFlashcardRequest struct {
+	DeckID        string   `json:"deck_id" binding:"required"`
+	Question      string   `json:"question" binding:"required"`
+	Answer        string   `json:"answer" binding:"required"`
+	Explanation   string   `json:"explanation,omitempty"`
+	Difficulty    string   `json:"difficulty,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+	SourceNoteIDs []string `json:"source_note_ids,omitempty"`
+}
+
+// UpdateFlashcardRequest represents the request body for updating a flashcard
+type UpdateFlashcardRequest struct {
+	DeckID      *string  `json:"deck_id,omitempty"`
+	Question    *string  `json:"question,omitempty"`
+	Answer      *string  `json:"answer,omitempty"`
+	Explanation *string  `json:"explanation,omitempty"`
+	Difficulty  *string  `json:"difficulty,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// FlashcardResponse represents the response format for flashcards
+type FlashcardResponse struct {
+	ID            string   `json:"id"`
+	DeckID        string   `json:"deck_id"`
+	UserID        string   `json:"user_id"`
+	Question      string   `json:"question"`
+	Answer        string   `json:"answer"`
+	Explanation   string   `json:"explanation,omitempty"`
+	Difficulty    string   `json:"difficulty"`
+	Tags          []string `json:"tags"`
+	SourceNoteIDs []string `json:"source_note_ids"`
+	CreatedAt     string   `json:"created_at"`
+	UpdatedAt     string   `json:"updated_at"`
+}
+
+// CreateFlashcard handles POST /api/flashcards
+func (h *FlashcardHandler) CreateFlashcard(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req CreateFlashcardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var deckUUID, userUUID pgtype.UUID
+	if err := deckUUID.Scan(req.DeckID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	// Check if user owns the deck
+	if _, err := h.queries.GetDeck(c.Request.Context(), db_sqlc.GetDeckParams{
+		ID:     deckUUID,
+		UserID: userUUID,
+	}); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return
+	}
+
+	sourceNoteIDs := []pgtype.UUID{}
+	for _, noteIDStr := range req.SourceNoteIDs {
+		var noteUUID pgtype.UUID
+		if err := noteUUID.Scan(noteIDStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID format: " + noteIDStr})
+			return
+		}
+		sourceNoteIDs = append(sourceNoteIDs, noteUUID)
+	}
+
+	// Cards may only cite the user's own notes
+	if len(sourceNoteIDs) > 0 {
+		owned, err := h.queries.ListOwnedNoteIDs(c.Request.Context(), db_sqlc.ListOwnedNoteIDsParams{
+			UserID: userUUID,
+			Ids:    sourceNoteIDs,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check source notes"})
+			return
+		}
+		for i, noteUUID := range sourceNoteIDs {
+			if !slices.Contains(owned, noteUUID) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Note not found: " + req.SourceNoteIDs[i]})
+				return
+			}
+		}
+	}
+
+	params := db_sqlc.CreateFlashcardParams{
+		DeckID:        deckUUID,
+		UserID:        userUUID,
+		Question:      req.Question,
+		Answer:        req.Answer,
+		Explanation:   req.Explanation,
+		Difficulty:    req.Difficulty,
+		Tags:          req.Tags,
+		SourceNoteIds: sourceNoteIDs,
+	}
+	if params.Difficulty == "" {
+		params.Difficulty = "Medium"
+	}
+	if params.Tags == nil {
+		params.Tags = []string{}
+	}
+
+	flashcard, err := h.queries.CreateFlashcard(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create flashcard"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, convertFlashcardToResponse(flashcard))
+}
+
+// GetFlashcard handles GET /api/flashcards/:id
+func (h *FlashcardHandler) GetFlashcard(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var flashcardUUID, userUUID pgtype.UUID
+	if err := flashcardUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flashcard ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	flashcard, err := h.queries.GetFlashcard(c.Request.Context(), db_sqlc.GetFlashcardParams{
+		ID:     flashcardUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertFlashcardToResponse(flashcard))
+}
+
+// UpdateFlashcard handles PUT /api/flashcards/:id
+func (h *FlashcardHandler) UpdateFlashcard(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req UpdateFlashcardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var flashcardUUID, userUUID pgtype.UUID
+	if err := flashcardUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flashcard ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	params := db_sqlc.UpdateFlashcardParams{
+		ID:     flashcardUUID,
+		UserID: userUUID,
+		Tags:   req.Tags,
+	}
+
+	// Moving a card requires owning the destination deck
+	if req.DeckID != nil {
+		if err := params.DeckID.Scan(*req.DeckID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID format"})
+			return
+		}
+		if _, err := h.queries.GetDeck(c.Request.Context(), db_sqlc.GetDeckParams{
+			ID:     params.DeckID,
+			UserID: userUUID,
+		}); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+			return
+		}
+	}
+	if req.Question != nil {
+		params.Question.Scan(*req.Question)
+	}
+	if req.Answer != nil {
+		params.Answer.Scan(*req.Answer)
+	}
+	if req.Explanation != nil {
+		params.Explanation.Scan(*req.Explanation)
+	}
+	if req.Difficulty != nil {
+		params.Difficulty.Scan(*req.Difficulty)
+	}
+
+	flashcard, err := h.queries.UpdateFlashcard(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertFlashcardToResponse(flashcard))
+}
+
+// DeleteFlashcard handles DELETE /api/flashcards/:id
+func (h *FlashcardHandler) DeleteFlashcard(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var flashcardUUID, userUUID pgtype.UUID
+	if err := flashcardUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flashcard ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	rows, err := h.queries.DeleteFlashcard(c.Request.Context(), db_sqlc.DeleteFlashcardParams{
+		ID:     flashcardUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete flashcard"})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// convertFlashcardToResponse converts a database Flashcard to API response format
+func convertFlashcardToResponse(flashcard db_sqlc.Flashcard) FlashcardResponse {
+	sourceNoteIDs := make([]string, 0, len(flashcard.SourceNoteIds))
+	for _, noteID := range flashcard.SourceNoteIds {
+		sourceNoteIDs = append(sourceNoteIDs, noteID.String())
+	}
+
+	return FlashcardResponse{
+		ID:            flashcard.ID.String(),
+		DeckID:        flashcard.DeckID.String(),
+		UserID:        flashcard.UserID.String(),
+		Question:      flashcard.Question,
+		Answer:        flashcard.Answer,
+		Explanation:   flashcard.Explanation,
+		Difficulty:    flashcard.Difficulty,
+		Tags:          flashcard.Tags,
+		SourceNoteIDs: sourceNoteIDs,
+		CreatedAt:     flashcard.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:     flashcard.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"go-note/internal/auth"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// GraphHandler handles knowledge graph HTTP requests
+type GraphHandler struct {
+	graphService *services.GraphService
+}
+
+// NewGraphHandler creates a new graph handler
+func NewGraphHandler(db *pgxpool.Pool) *GraphHandler {
+	return &GraphHandler{
+		graphService: services.NewGraphService(db),
+	}
+}
+
+// GetGraph handles GET /api/notes/graph
+// Query parameters: note_id to walk out from a note, depth (default 2, max 4),
+// neighbors per note (default 5, max 20, 0 for none), threshold (default 0.75),
+// tags (default true) and max_nodes (default 100, max 500).
+func (h *GraphHandler) GetGraph(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	opts := services.GraphOptions{
+		Depth:       2,
+		Neighbors:   5,
+		Threshold:   0.75,
+		IncludeTags: true,
+		MaxNodes:    100,
+	}
+
+	if noteID := c.Query("note_id"); noteID != "" {
+		var noteUUID pgtype.UUID
+		if err := noteUUID.Scan(noteID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID format"})
+			return
+		}
+		opts.StartNoteID = noteUUID.String()
+	}
+
+	var err error
+	if opts.Depth, err = strconv.Atoi(c.DefaultQuery("depth", "2")); err != nil || opts.Depth < 1 || opts.Depth > 4 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "depth must be between 1 and 4"})
+		return
+	}
+	if opts.Neighbors, err = strconv.Atoi(c.DefaultQuery("neighbors", "5")); err != nil || opts.Neighbors < 0 || opts.Neighbors > 20 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "neighbors must be between 0 and 20"})
+		return
+	}
+	if opts.Threshold, err = strconv.ParseFloat(c.DefaultQuery("threshold", "0.75"), 64); err != nil || opts.Threshold < 0 || opts.Threshold > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be between 0 and 1"})
+		return
+	}
+	if opts.IncludeTags, err = strconv.ParseBool(c.DefaultQuery("tags", "true")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags must be true or false"})
+		return
+	}
+	if opts.MaxNodes, err = strconv.Atoi(c.DefaultQuery("max_nodes", "100")); err != nil || opts.MaxNodes < 1 || opts.MaxNodes > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_nodes must be between 1 and 500"})
+		return
+	}
+
+	graph, err := h.graphService.Graph(c.Request.Context(), userUUID, opts)
+	if errors.Is(err, services.ErrGraphNoteNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to build note graph: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build note graph"})
+		return
+	}
+
+	c.JSON(http.StatusOK, graph)
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxImportUploadSize caps the size of an uploaded export
+const maxImportUploadSize = 100 << 20
+
+// importUploadTimeout is how long an upload may take, well beyond the server's ReadTimeout
+const importUploadTimeout = 5 * time.Minute
+
+// ImportHandler handles importing notes from other apps
+type ImportHandler struct {
+	queries *db_sqlc.Queries
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(db *pgxpool.Pool) *ImportHandler {
+	return &ImportHandler{
+		queries: db_sqlc.New(db),
+	}
+}
+
+// ImportJobResponse represents an import job and, once it finished, its report
+type ImportJobResponse struct {
+	ID         string          `json:"id"`
+	Format     string          `json:"format"`
+	Filename   string          `json:"filename"`
+	DryRun     bool            `json:"dry_run"`
+	NotebookID *string         `json:"notebook_id"`
+	Status     string          `json:"status"` // pending, processing, completed or failed
+	Total      int32           `json:"total"`  // notes to create, known once the export was read
+	Processed  int32           `json:"processed"`
+	Report     json.RawMessage `json:"report,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	CreatedAt  string          `json:"created_at"`
+	StartedAt  string          `json:"started_at,omitempty"`
+	FinishedAt string          `json:"finished_at,omitempty"`
+}
+
+// CreateImport handles POST /api/notes/import
+// Takes a multipart form with the export as "file", its "format" (markdown,
+// enex or notion; defaults to enex for .enex files and markdown otherwise),
+// and optionally dry_run=true to only get the report and notebook_id to import
+// into. The import runs in the background; the response is the queued job.
+func (h *ImportHandler) CreateImport(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	// Large uploads take longer than the server's ReadTimeout allows
+	if err := http.NewResponseController(c.Writer).SetReadDeadline(time.Now().Add(importUploadTimeout)); err != nil {
+		log.Printf("Failed to extend read deadline for import upload: %v", err)
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportUploadSize)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An export is required as file, of at most " + strconv.Itoa(maxImportUploadSize>>20) + " MB"})
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = services.ImportFormatMarkdown
+		if strings.EqualFold(path.Ext(header.Filename), ".enex") {
+			format = services.ImportFormatEnex
+		}
+	}
+	switch format {
+	case services.ImportFormatMarkdown, services.ImportFormatEnex, services.ImportFormatNotion:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be markdown, enex or notion"})
+		return
+	}
+
+	dryRun, err := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	notebookUUID, ok := resolveNotebook(c, h.queries, c.PostForm("notebook_id"), userUUID)
+	if !ok {
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer file.Close()
+
+	payload, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read upload"})
+		return
+	}
+
+	job, err := h.queries.CreateImportJob(c.Request.Context(), db_sqlc.CreateImportJobParams{
+		UserID:     userUUID,
+		Format:     format,
+		Filename:   path.Base(header.Filename),
+		DryRun:     dryRun,
+		NotebookID: notebookUUID,
+		Payload:    payload,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue import"})
+		return
+	}
+
+	c.Header("Location", "/api/notes/imports/"+job.ID.String())
+	c.JSON(http.StatusAccepted, convertImportJobToResponse(db_sqlc.GetImportJobRow(job)))
+}
+
+// ListImports handles GET /api/notes/imports
+func (h *ImportHandler) ListImports(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if offset < 0 {
+		offset = 0
+	}
+
+	jobs, err := h.queries.ListImportJobs(c.Request.Context(), db_sqlc.ListImportJobsParams{
+		UserID: userUUID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list imports"})
+		return
+	}
+
+	responses := make([]ImportJobResponse, len(jobs))
+	for i, job := range jobs {
+		responses[i] = convertImportJobToResponse(db_sqlc.GetImportJobRow(job))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"imports": responses,
+		"count":   len(responses),
+	})
+}
+
+// GetImport handles GET /api/notes/imports/:id
+// Poll it to follow an import's progress; the report is included once it completed.
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID, jobUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+	if err := jobUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID format"})
+		return
+	}
+
+	job, err := h.queries.GetImportJob(c.Request.Context(), db_sqlc.GetImportJobParams{ID: jobUUID, UserID: userUUID})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch import"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertImportJobToResponse(job))
+}
+
+// convertImportJobToResponse converts an import job to API response format
+func convertImportJobToResponse(job db_sqlc.GetImportJobRow) ImportJobResponse {
+	response := ImportJobResponse{
+		ID:         job.ID.String(),
+		Format:     job.Format,
+		Filename:   job.Filename,
+		DryRun:     job.DryRun,
+		NotebookID: optionalUUID(job.NotebookID),
+		Status:     job.Status,
+		Total:      job.Total,
+		Processed:  job.Processed,
+		Report:     job.Report,
+		Error:      job.Error.String,
+		CreatedAt:  job.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if job.StartedAt.Valid {
+		response.StartedAt = job.StartedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+	}
+	if job.FinishedAt.Valid {
+		response.FinishedAt = job.FinishedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
+}
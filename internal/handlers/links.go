@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LinkHandler handles HTTP requests for links between notes
+type LinkHandler struct {
+	queries *db_sqlc.Queries
+}
+
+// NewLinkHandler creates a new link handler
+func NewLinkHandler(db *pgxpool.Pool) *LinkHandler {
+	return &LinkHandler{
+		queries: db_sqlc.New(db),
+	}
+}
+
+// NoteLinkResponse represents an outgoing link of a note
+type NoteLinkResponse struct {
+	TargetNoteID *string `json:"target_note_id"` // nil while the link is unresolved
+	TargetTitle  string  `json:"target_title"`
+	Kind         string  `json:"kind"`
+	Alias        string  `json:"alias,omitempty"`
+	Position     int32   `json:"position"`
+	Resolved     bool    `json:"resolved"`
+}
+
+// BacklinkResponse represents a note linking to another note
+type BacklinkResponse struct {
+	NoteID    string `json:"note_id"`
+	Title     string `json:"title"`
+	Kind      string `json:"kind"`
+	Alias     string `json:"alias,omitempty"`
+	Position  int32  `json:"position"`
+	Context   string `json:"context"` // text around the link in the linking note
+	UpdatedAt string `json:"updated_at"`
+}
+
+// UnresolvedLinkResponse represents a title that notes link to but no note has
+type UnresolvedLinkResponse struct {
+	Title         string   `json:"title"`
+	Count         int64    `json:"count"` // number of notes linking to the title
+	SourceNoteIDs []string `json:"source_note_ids"`
+}
+
+// ListLinks handles GET /api/notes/:id/links
+// Unresolved links are listed with the others and their titles repeated under
+// "unresolved", so the client can offer to create the missing notes.
+func (h *LinkHandler) ListLinks(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+	if !h.requireNote(c, noteUUID, userUUID) {
+		return
+	}
+
+	links, err := h.queries.ListOutgoingLinks(c.Request.Context(), db_sqlc.ListOutgoingLinksParams{
+		SourceNoteID: noteUUID,
+		UserID:       userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch links"})
+		return
+	}
+
+	responses := make([]NoteLinkResponse, 0, len(links))
+	unresolved := []string{}
+	for _, link := range links {
+		response := NoteLinkResponse{
+			TargetTitle: link.TargetTitle,
+			Kind:        link.Kind,
+			Alias:       link.Alias,
+			Position:    link.Position,
+			Resolved:    link.TargetNoteID.Valid,
+		}
+		if link.TargetNoteID.Valid {
+			targetID := link.TargetNoteID.String()
+			response.TargetNoteID = &targetID
+		} else if link.TargetTitle != "" {
+			unresolved = append(unresolved, link.TargetTitle)
+		}
+		responses = append(responses, response)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"note_id":    noteUUID.String(),
+		"links":      responses,
+		"unresolved": unresolved,
+	})
+}
+
+// ListBacklinks handles GET /api/notes/:id/backlinks
+func (h *LinkHandler) ListBacklinks(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+	if !h.requireNote(c, noteUUID, userUUID) {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	backlinks, err := h.queries.ListBacklinks(c.Request.Context(), db_sqlc.ListBacklinksParams{
+		TargetNoteID: noteUUID,
+		UserID:       userUUID,
+		Limit:        int32(limit),
+		Offset:       int32(offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backlinks"})
+		return
+	}
+
+	responses := make([]BacklinkResponse, 0, len(backlinks))
+	for _, backlink := range backlinks {
+		responses = append(responses, BacklinkResponse{
+			NoteID:    backlink.SourceNoteID.String(),
+			Title:     backlink.SourceTitle,
+			Kind:      backlink.Kind,
+			Alias:     backlink.Alias,
+			Position:  backlink.Position,
+			Context:   strings.TrimSpace(backlink.Context),
+			UpdatedAt: backlink.SourceUpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"backlinks": responses,
+		"limit":     limit,
+		"offset":    offset,
+		"count":     len(responses),
+	})
+}
+
+// ListUnresolvedLinks handles GET /api/notes/links/unresolved
+// Lists every title the user's notes link to without a matching note.
+func (h *LinkHandler) ListUnresolvedLinks(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	rows, err := h.queries.ListUnresolvedLinks(c.Request.Context(), db_sqlc.ListUnresolvedLinksParams{
+		UserID: userUUID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch unresolved links"})
+		return
+	}
+
+	responses := make([]UnresolvedLinkResponse, 0, len(rows))
+	for _, row := range rows {
+		sourceIDs := make([]string, 0, len(row.SourceNoteIds))
+		for _, id := range row.SourceNoteIds {
+			sourceIDs = append(sourceIDs, id.String())
+		}
+		responses = append(responses, UnresolvedLinkResponse{
+			Title:         row.TargetTitle,
+			Count:         row.SourceCount,
+			SourceNoteIDs: sourceIDs,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"unresolved": responses,
+		"limit":      limit,
+		"offset":     offset,
+		"count":      len(responses),
+	})
+}
+
+// requireNote checks that the note exists, is not trashed and belongs to the
+// user, writing the error response when it does not
+func (h *LinkHandler) requireNote(c *gin.Context, noteID, userID pgtype.UUID) bool {
+	note, err := h.queries.GetNote(c.Request.Context(), noteID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && note.UserID != userID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch note"})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,464 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NotebookHandler handles notebook-related HTTP requests
+type NotebookHandler struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewNotebookHandler creates a new notebook handler
+func NewNotebookHandler(db *pgxpool.Pool) *NotebookHandler {
+	return &NotebookHandler{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// CreateNotebookRequest represents the request body for creating a notebook
+type CreateNotebookRequest struct {
+	Name     string `json:"name" binding:"required"`
+	ParentID string `json:"parent_id,omitempty"` // Top-level notebook when empty
+}
+
+// UpdateNotebookRequest represents the request body for renaming, reordering or moving a notebook
+type UpdateNotebookRequest struct {
+	Name     *string `json:"name,omitempty"`
+	Position *int32  `json:"position,omitempty"`
+	ParentID *string `json:"parent_id,omitempty"` // "" moves the notebook to the top level
+}
+
+// NotebookResponse represents the response format for notebooks
+type NotebookResponse struct {
+	ID        string  `json:"id"`
+	UserID    string  `json:"user_id"`
+	ParentID  *string `json:"parent_id"`
+	Name      string  `json:"name"`
+	Position  int32   `json:"position"`
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+// ListNotebooks handles GET /api/notebooks
+// Returns the user's notebooks as a tree.
+func (h *NotebookHandler) ListNotebooks(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	notebooks, err := h.queries.ListNotebooks(c.Request.Context(), userUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notebooks"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notebooks": services.BuildNotebookTree(notebooks),
+		"count":     len(notebooks),
+	})
+}
+
+// CreateNotebook handles POST /api/notebooks
+func (h *NotebookHandler) CreateNotebook(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req CreateNotebookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	parentUUID, ok := resolveNotebook(c, h.queries, req.ParentID, userUUID)
+	if !ok {
+		return
+	}
+
+	notebook, err := h.queries.CreateNotebook(c.Request.Context(), db_sqlc.CreateNotebookParams{
+		UserID:   userUUID,
+		ParentID: parentUUID,
+		Name:     req.Name,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create notebook"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, convertNotebookToResponse(notebook))
+}
+
+// GetNotebook handles GET /api/notebooks/:id
+func (h *NotebookHandler) GetNotebook(c *gin.Context) {
+	notebookUUID, userUUID, ok := parseNotebookAndUser(c)
+	if !ok {
+		return
+	}
+
+	notebook, err := h.queries.GetNotebook(c.Request.Context(), db_sqlc.GetNotebookParams{
+		ID:     notebookUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertNotebookToResponse(notebook))
+}
+
+// UpdateNotebook handles PUT /api/notebooks/:id
+func (h *NotebookHandler) UpdateNotebook(c *gin.Context) {
+	notebookUUID, userUUID, ok := parseNotebookAndUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateNotebookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notebook"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.queries.WithTx(tx)
+
+	if req.ParentID != nil {
+		// Serialize moves of the user's notebooks, or moving A under B and B
+		// under A at the same time would both pass the check below
+		if err := qtx.LockNotebooks(ctx, userUUID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notebook"})
+			return
+		}
+
+		parentUUID, ok := resolveNotebook(c, qtx, *req.ParentID, userUUID)
+		if !ok {
+			return
+		}
+
+		// A notebook cannot be moved into itself or one of its descendants
+		subtree, err := qtx.GetNotebookSubtreeIDs(ctx, db_sqlc.GetNotebookSubtreeIDsParams{
+			ID:     notebookUUID,
+			UserID: userUUID,
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notebook"})
+			return
+		}
+		if len(subtree) == 0 {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+			return
+		}
+		if parentUUID.Valid && slices.Contains(subtree, parentUUID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot move a notebook into itself or its descendants"})
+			return
+		}
+
+		if _, err := qtx.MoveNotebook(ctx, db_sqlc.MoveNotebookParams{
+			ParentID: parentUUID,
+			UserID:   userUUID,
+			ID:       notebookUUID,
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move notebook"})
+			return
+		}
+	}
+
+	params := db_sqlc.UpdateNotebookParams{
+		ID:     notebookUUID,
+		UserID: userUUID,
+	}
+	if req.Name != nil {
+		params.Name = pgtype.Text{String: *req.Name, Valid: true}
+	}
+	if req.Position != nil {
+		params.Position = pgtype.Int4{Int32: *req.Position, Valid: true}
+	}
+
+	notebook, err := qtx.UpdateNotebook(ctx, params)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notebook"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notebook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertNotebookToResponse(notebook))
+}
+
+// DeleteNotebook handles DELETE /api/notebooks/:id
+// The notebook and its descendants are deleted and the notes they contain are moved to the trash.
+func (h *NotebookHandler) DeleteNotebook(c *gin.Context) {
+	notebookUUID, userUUID, ok := parseNotebookAndUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notebook"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.queries.WithTx(tx)
+
+	// Wait for moves in progress, or a notebook moved into the subtree after
+	// it was read would be deleted along with it without trashing its notes
+	if err := qtx.LockNotebooks(ctx, userUUID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notebook"})
+		return
+	}
+
+	subtree, err := qtx.GetNotebookSubtreeIDs(ctx, db_sqlc.GetNotebookSubtreeIDsParams{
+		ID:     notebookUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notebook"})
+		return
+	}
+	if len(subtree) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+
+	trashed, err := qtx.TrashNotebookNotes(ctx, db_sqlc.TrashNotebookNotesParams{
+		UserID:      userUUID,
+		NotebookIds: subtree,
+	})
+	if err != nil {
+		log.Printf("Failed to trash notebook notes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notebook"})
+		return
+	}
+
+	if _, err := qtx.DeleteNotebook(ctx, db_sqlc.DeleteNotebookParams{
+		ID:     notebookUUID,
+		UserID: userUUID,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notebook"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete notebook"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted_notebooks": len(subtree),
+		"trashed_notes":     trashed,
+	})
+}
+
+// ListNotebookNotes handles GET /api/notebooks/:id/notes
+// Notes of descendant notebooks are included unless recursive=false.
+func (h *NotebookHandler) ListNotebookNotes(c *gin.Context) {
+	notebookUUID, userUUID, ok := parseNotebookAndUser(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	recursive := c.DefaultQuery("recursive", "true") != "false"
+
+	notebookIDs := []pgtype.UUID{notebookUUID}
+	if recursive {
+		var ok bool
+		notebookIDs, ok = resolveNotebookScope(c, h.queries, notebookUUID.String(), userUUID)
+		if !ok {
+			return
+		}
+	} else if _, err := h.queries.GetNotebook(c.Request.Context(), db_sqlc.GetNotebookParams{
+		ID:     notebookUUID,
+		UserID: userUUID,
+	}); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return
+	}
+
+	notes, err := h.queries.ListNotebookNotes(c.Request.Context(), db_sqlc.ListNotebookNotesParams{
+		UserID:      userUUID,
+		NotebookIds: notebookIDs,
+		Limit:       int32(limit),
+		Offset:      int32(offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notes"})
+		return
+	}
+
+	responses := make([]NoteResponse, 0, len(notes))
+	for _, note := range notes {
+		responses = append(responses, convertListNotebookNotesRowToResponse(note))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notes":     responses,
+		"recursive": recursive,
+		"limit":     limit,
+		"offset":    offset,
+		"count":     len(responses),
+	})
+}
+
+// parseNotebookAndUser reads the notebook ID path parameter and the authenticated user,
+// writing the error response when either is missing or malformed
+func parseNotebookAndUser(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var notebookUUID, userUUID pgtype.UUID
+
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return notebookUUID, userUUID, false
+	}
+
+	if err := notebookUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notebook ID format"})
+		return notebookUUID, userUUID, false
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return notebookUUID, userUUID, false
+	}
+	return notebookUUID, userUUID, true
+}
+
+// resolveNotebook checks that notebookID belongs to the user. An empty ID
+// resolves to a NULL notebook, meaning the top level.
+func resolveNotebook(c *gin.Context, queries *db_sqlc.Queries, notebookID string, userUUID pgtype.UUID) (pgtype.UUID, bool) {
+	var notebookUUID pgtype.UUID
+	if notebookID == "" {
+		return notebookUUID, true
+	}
+
+	if err := notebookUUID.Scan(notebookID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notebook ID format"})
+		return notebookUUID, false
+	}
+	if _, err := queries.GetNotebook(c.Request.Context(), db_sqlc.GetNotebookParams{
+		ID:     notebookUUID,
+		UserID: userUUID,
+	}); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return notebookUUID, false
+	}
+	return notebookUUID, true
+}
+
+// resolveNotebookScope returns the IDs of a notebook and all its descendants,
+// or nil without error when notebookID is empty so searches stay unscoped
+func resolveNotebookScope(c *gin.Context, queries *db_sqlc.Queries, notebookID string, userUUID pgtype.UUID) ([]pgtype.UUID, bool) {
+	if notebookID == "" {
+		return nil, true
+	}
+
+	var notebookUUID pgtype.UUID
+	if err := notebookUUID.Scan(notebookID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notebook ID format"})
+		return nil, false
+	}
+
+	subtree, err := queries.GetNotebookSubtreeIDs(c.Request.Context(), db_sqlc.GetNotebookSubtreeIDsParams{
+		ID:     notebookUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notebook"})
+		return nil, false
+	}
+	if len(subtree) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notebook not found"})
+		return nil, false
+	}
+	return subtree, true
+}
+
+// optionalUUID formats a nullable UUID for a response
+func optionalUUID(id pgtype.UUID) *string {
+	if !id.Valid {
+		return nil
+	}
+	s := id.String()
+	return &s
+}
+
+// convertNotebookToResponse converts a Notebook to API response format
+func convertNotebookToResponse(notebook db_sqlc.Notebook) NotebookResponse {
+	return NotebookResponse{
+		ID:        notebook.ID.String(),
+		UserID:    notebook.UserID.String(),
+		ParentID:  optionalUUID(notebook.ParentID),
+		Name:      notebook.Name,
+		Position:  notebook.Position,
+		CreatedAt: notebook.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: notebook.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// convertListNotebookNotesRowToResponse converts ListNotebookNotesRow to API response format
+func convertListNotebookNotesRowToResponse(note db_sqlc.ListNotebookNotesRow) NoteResponse {
+	return NoteResponse{
+		ID:              note.ID.String(),
+		UserID:          note.UserID.String(),
+		Title:           note.Title,
+		Content:         note.Content,
+		Tags:            note.Tags,
+		EmbeddingStatus: note.EmbeddingStatus,
+		NotebookID:      optionalUUID(note.NotebookID),
+		CreatedAt:       note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -1,27 +1,39 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
 	"go-note/internal/auth"
 	db_sqlc "go-note/internal/db_sqlc"
 	"go-note/internal/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/pgvector/pgvector-go"
 )
 
+// flashcardExportTimeout is how long generating and sending a flashcard export
+// may take, well beyond the server's WriteTimeout
+const flashcardExportTimeout = 5 * time.Minute
+
 // NotesHandler handles note-related HTTP requests
 type NotesHandler struct {
 	queries          *db_sqlc.Queries
 	db               *pgxpool.Pool
 	embeddingService *services.EmbeddingService
 	flashcardService *services.FlashcardService
+	deckService      *services.DeckService
+	searchService    *services.SearchService
 }
 
 // NewNotesHandler creates a new notes handler
@@ -31,32 +43,39 @@ func NewNotesHandler(db *pgxpool.Pool, embeddingService *services.EmbeddingServi
 		db:               db,
 		embeddingService: embeddingService,
 		flashcardService: flashcardService,
+		deckService:      services.NewDeckService(db),
+		searchService:    services.NewSearchService(db, embeddingService),
 	}
 }
 
 // CreateNoteRequest represents the request body for creating a note
 type CreateNoteRequest struct {
-	Title   string   `json:"title" binding:"required"`
-	Content string   `json:"content" binding:"required"`
-	Tags    []string `json:"tags,omitempty"`
+	Title      string   `json:"title" binding:"required"`
+	Content    string   `json:"content" binding:"required"`
+	Tags       []string `json:"tags,omitempty"`
+	NotebookID string   `json:"notebook_id,omitempty"`
 }
 
 // UpdateNoteRequest represents the request body for updating a note
 type UpdateNoteRequest struct {
-	Title   *string  `json:"title,omitempty"`
-	Content *string  `json:"content,omitempty"`
-	Tags    []string `json:"tags,omitempty"`
+	Title      *string  `json:"title,omitempty"`
+	Content    *string  `json:"content,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+	NotebookID *string  `json:"notebook_id,omitempty"` // "" moves the note out of its notebook
 }
 
 // NoteResponse represents the response format for notes
 type NoteResponse struct {
-	ID        string   `json:"id"`
-	UserID    string   `json:"user_id"`
-	Title     string   `json:"title"`
-	Content   string   `json:"content"`
-	Tags      []string `json:"tags"`
-	CreatedAt string   `json:"created_at"`
-	UpdatedAt string   `json:"updated_at"`
+	ID              string   `json:"id"`
+	UserID          string   `json:"user_id"`
+	Title           string   `json:"title"`
+	Content         string   `json:"content"`
+	Tags            []string `json:"tags"`
+	EmbeddingStatus string   `json:"embedding_status"` // pending, ready or failed
+	NotebookID      *string  `json:"notebook_id"`
+	CreatedAt       string   `json:"created_at"`
+	UpdatedAt       string   `json:"updated_at"`
+	DeletedAt       string   `json:"deleted_at,omitempty"` // Only set for notes in the trash
 }
 
 // CreateNote handles POST /api/notes
@@ -79,34 +98,70 @@ func (h *NotesHandler) CreateNote(c *gin.Context) {
 		return
 	}
 
-	// Generate embedding for the note
-	embedding, err := h.embeddingService.GenerateNoteEmbedding(c.Request.Context(), req.Title, req.Content)
+	notebookUUID, ok := resolveNotebook(c, h.queries, req.NotebookID, userUUID)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	// Save the note and queue its embedding atomically; the worker fills in the vector later
+	tx, err := h.db.Begin(ctx)
 	if err != nil {
-		log.Printf("Failed to generate embedding: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate embedding"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	// Convert embedding to pgvector.Vector
-	embeddingVector := pgvector.NewVector(embedding)
+	qtx := h.queries.WithTx(tx)
 
 	// Prepare parameters
 	params := db_sqlc.CreateNoteParams{
-		UserID:    userUUID,
-		Title:     req.Title,
-		Content:   req.Content,
-		Embedding: embeddingVector,
-		Tags:      req.Tags,
+		UserID:        userUUID,
+		Title:         req.Title,
+		Content:       req.Content,
+		Tags:          services.NormalizeTags(req.Tags),
+		SearchTitle:   services.SearchText(req.Title),
+		SearchContent: services.SearchText(req.Content),
+		NotebookID:    notebookUUID,
 	}
 
 	// Create the note
-	note, err := h.queries.CreateNote(c.Request.Context(), params)
+	note, err := qtx.CreateNote(ctx, params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note: " + err.Error()})
 		return
 	}
 
+	revision, err := qtx.CreateNoteRevision(ctx, db_sqlc.CreateNoteRevisionParams{NoteID: note.ID})
+	if err != nil {
+		log.Printf("Failed to record note revision: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
+		return
+	}
+
+	if err := services.IndexNoteLinks(ctx, qtx, note.ID, userUUID, note.Title, note.Content); err != nil {
+		log.Printf("Failed to index note links: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
+		return
+	}
+
+	if err := qtx.EnqueueEmbeddingJob(ctx, db_sqlc.EnqueueEmbeddingJobParams{
+		NoteID: note.ID,
+		UserID: userUUID,
+	}); err != nil {
+		log.Printf("Failed to enqueue embedding job: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create note"})
+		return
+	}
+
 	response := convertCreateNoteRowToResponse(note)
+	c.Header("ETag", noteETag(revision.RevisionNumber))
 	c.JSON(http.StatusCreated, response)
 }
 
@@ -139,29 +194,132 @@ func (h *NotesHandler) GetNote(c *gin.Context) {
 		return
 	}
 
+	revision, err := h.queries.GetNoteRevisionNumber(c.Request.Context(), noteUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch note"})
+		return
+	}
+	if notModified(c, noteETag(revision)) {
+		return
+	}
+
 	response := convertGetNoteRowToResponse(note)
 	c.JSON(http.StatusOK, response)
 }
 
+// RelatedNoteResponse represents a note similar to another note
+type RelatedNoteResponse struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Tags       []string `json:"tags"`
+	NotebookID *string  `json:"notebook_id"`
+	Similarity float64  `json:"similarity"`
+	Linked     bool     `json:"linked"` // one of the two notes links to the other
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+// GetRelatedNotes handles GET /api/notes/:id/related
+// Compares the note's stored embedding with the user's other notes, so no
+// embedding call is made. Query parameters: limit (default 10, max 50),
+// threshold (minimum similarity, default 0) and exclude_linked (default false).
+func (h *NotesHandler) GetRelatedNotes(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 50 {
+		limit = 10
+	}
+	threshold, err := strconv.ParseFloat(c.DefaultQuery("threshold", "0"), 64)
+	if err != nil || threshold < 0 || threshold > 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "threshold must be between 0 and 1"})
+		return
+	}
+	excludeLinked, err := strconv.ParseBool(c.DefaultQuery("exclude_linked", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "exclude_linked must be true or false"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	note, err := h.queries.GetNote(ctx, noteUUID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if note.UserID != userUUID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	related, err := h.queries.ListRelatedNotes(ctx, db_sqlc.ListRelatedNotesParams{
+		NoteID:        noteUUID,
+		UserID:        userUUID,
+		ExcludeLinked: excludeLinked,
+		Threshold:     threshold,
+		Limit:         int32(limit),
+	})
+	if err != nil {
+		log.Printf("Failed to find related notes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to find related notes"})
+		return
+	}
+
+	responses := make([]RelatedNoteResponse, 0, len(related))
+	for _, row := range related {
+		response := RelatedNoteResponse{
+			ID:         row.ID.String(),
+			Title:      row.Title,
+			Tags:       row.Tags,
+			Similarity: row.Similarity,
+			Linked:     row.Linked,
+			UpdatedAt:  row.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if row.NotebookID.Valid {
+			notebookID := row.NotebookID.String()
+			response.NotebookID = &notebookID
+		}
+		responses = append(responses, response)
+	}
+
+	// A note that was never embedded has nothing to compare against yet
+	c.JSON(http.StatusOK, gin.H{
+		"note_id":          noteUUID.String(),
+		"embedding_status": note.EmbeddingStatus,
+		"related":          responses,
+		"count":            len(responses),
+	})
+}
+
 // GetUserNotes handles GET /api/notes
+// Supports keyset pagination with cursor, sort, order, tags (comma-separated,
+// matched by tag_mode any or all) and created/updated date bounds, see parsePageRequest.
 func (h *NotesHandler) GetUserNotes(c *gin.Context) {
 	userID, exists := auth.RequireAuth(c)
 	if !exists {
 		return
 	}
 
-	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 || limit > 100 {
-		limit = 10
+	page, ok := parsePageRequest(c, "title")
+	if !ok {
+		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	var tags []string
+	if raw := c.Query("tags"); raw != "" {
+		tags = services.NormalizeTags(strings.Split(raw, ","))
+	}
+	var tagsAny, tagsAll []string
+	switch c.DefaultQuery("tag_mode", "any") {
+	case "any":
+		tagsAny = tags
+	case "all":
+		tagsAll = tags
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag_mode must be any or all"})
+		return
 	}
 
 	// Parse user UUID
@@ -171,27 +329,69 @@ func (h *NotesHandler) GetUserNotes(c *gin.Context) {
 		return
 	}
 
-	// Get user's notes
-	notes, err := h.queries.GetUserNotes(c.Request.Context(), db_sqlc.GetUserNotesParams{
-		UserID: userUUID,
-		Limit:  int32(limit),
-		Offset: int32(offset),
+	ctx := c.Request.Context()
+
+	// Fetch one extra row to learn whether another page follows
+	notes, err := h.queries.GetUserNotes(ctx, db_sqlc.GetUserNotesParams{
+		UserID:        userUUID,
+		TagsAny:       tagsAny,
+		TagsAll:       tagsAll,
+		CreatedAfter:  page.CreatedAfter,
+		CreatedBefore: page.CreatedBefore,
+		UpdatedAfter:  page.UpdatedAfter,
+		UpdatedBefore: page.UpdatedBefore,
+		CursorID:      page.cursorID(),
+		Sort:          page.Sort,
+		Descending:    page.Descending,
+		CursorTitle:   page.cursorText(),
+		CursorTime:    page.cursorTime(),
+		Limit:         int32(page.Limit + 1),
+		Offset:        int32(page.Offset),
 	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notes"})
 		return
 	}
 
-	var responses []NoteResponse
+	total, err := h.queries.CountUserNotes(ctx, db_sqlc.CountUserNotesParams{
+		UserID:        userUUID,
+		TagsAny:       tagsAny,
+		TagsAll:       tagsAll,
+		CreatedAfter:  page.CreatedAfter,
+		CreatedBefore: page.CreatedBefore,
+		UpdatedAfter:  page.UpdatedAfter,
+		UpdatedBefore: page.UpdatedBefore,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count notes"})
+		return
+	}
+
+	hasMore := len(notes) > page.Limit
+	if hasMore {
+		notes = notes[:page.Limit]
+	}
+
+	responses := make([]NoteResponse, 0, len(notes))
 	for _, note := range notes {
 		responses = append(responses, convertGetUserNotesRowToResponse(note))
 	}
 
+	var nextCursor *string
+	if len(notes) > 0 {
+		last := notes[len(notes)-1]
+		nextCursor = page.nextCursor(hasMore, last.ID, last.CreatedAt, last.UpdatedAt, last.Title)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"notes":  responses,
-		"limit":  limit,
-		"offset": offset,
-		"count":  len(responses),
+		"notes":       responses,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"count":       len(responses),
+		"total":       total,
+		"sort":        page.Sort,
+		"order":       page.order(),
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -225,67 +425,117 @@ func (h *NotesHandler) UpdateNote(c *gin.Context) {
 		return
 	}
 
-	// First get the current note to check if title/content changed
-	currentNote, err := h.queries.GetNote(c.Request.Context(), noteUUID)
+	var notebookUUID pgtype.UUID
+	if req.NotebookID != nil {
+		var ok bool
+		notebookUUID, ok = resolveNotebook(c, h.queries, *req.NotebookID, userUUID)
+		if !ok {
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	// Check if user owns the note
-	if currentNote.UserID.String() != userID {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+	qtx := h.queries.WithTx(tx)
+
+	// Lock the note so no other edit lands between the If-Match check and this
+	// one, and fill in the fields that were not sent from its current version
+	currentNote, currentRevision, err := services.LockNote(ctx, qtx, noteUUID, userUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
+		return
+	}
+	if notePreconditionFailed(c, currentRevision) {
 		return
 	}
 
-	// Prepare parameters
+	// Prepare parameters, keeping current values for fields that were not sent
 	params := db_sqlc.UpdateNoteParams{
-		ID:     noteUUID,
-		UserID: userUUID,
+		ID:      noteUUID,
+		UserID:  userUUID,
+		Title:   currentNote.Title,
+		Content: currentNote.Content,
+		Tags:    services.NormalizeTags(req.Tags),
 	}
-
-	// Check if we need to regenerate embedding
-	needsEmbeddingUpdate := false
-	newTitle := currentNote.Title
-	newContent := currentNote.Content
-
 	if req.Title != nil {
 		params.Title = *req.Title
-		newTitle = *req.Title
-		needsEmbeddingUpdate = true
 	}
 	if req.Content != nil {
 		params.Content = *req.Content
-		newContent = *req.Content
-		needsEmbeddingUpdate = true
 	}
-	if req.Tags != nil {
-		params.Tags = req.Tags
+	params.SearchTitle = services.SearchText(params.Title)
+	params.SearchContent = services.SearchText(params.Content)
+
+	// The embedding only needs refreshing when the embedded text changed
+	needsEmbeddingUpdate := params.Title != currentNote.Title || params.Content != currentNote.Content
+
+	// Update the note
+	note, err := qtx.UpdateNote(ctx, params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note: " + err.Error()})
+		return
 	}
 
-	// Generate new embedding if title or content changed
-	if needsEmbeddingUpdate {
-		embedding, err := h.embeddingService.GenerateNoteEmbedding(c.Request.Context(), newTitle, newContent)
-		if err != nil {
-			log.Printf("Failed to generate embedding: %v", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate embedding"})
+	if req.NotebookID != nil {
+		if _, err := qtx.SetNoteNotebook(ctx, db_sqlc.SetNoteNotebookParams{
+			ID:         noteUUID,
+			UserID:     userUUID,
+			NotebookID: notebookUUID,
+		}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move note"})
 			return
 		}
-		params.Embedding = pgvector.NewVector(embedding)
+		note.NotebookID = notebookUUID
 	}
 
-	// Update the note
-	note, err := h.queries.UpdateNote(c.Request.Context(), params)
+	// Keep the new state in the note's history so the edit can be undone
+	revision, err := qtx.CreateNoteRevision(ctx, db_sqlc.CreateNoteRevisionParams{NoteID: noteUUID})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note: " + err.Error()})
+		log.Printf("Failed to record note revision: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
+		return
+	}
+
+	if needsEmbeddingUpdate {
+		if err := qtx.EnqueueEmbeddingJob(ctx, db_sqlc.EnqueueEmbeddingJobParams{
+			NoteID: noteUUID,
+			UserID: userUUID,
+		}); err != nil {
+			log.Printf("Failed to enqueue embedding job: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
+			return
+		}
+
+		// Links are parsed from the content and resolved by title, so they change with the same edits
+		if err := services.IndexNoteLinks(ctx, qtx, noteUUID, userUUID, note.Title, note.Content); err != nil {
+			log.Printf("Failed to index note links: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update note"})
 		return
 	}
 
 	response := convertUpdateNoteRowToResponse(note)
+	c.Header("ETag", noteETag(revision.RevisionNumber))
 	c.JSON(http.StatusOK, response)
 }
 
 // DeleteNote handles DELETE /api/notes/:id
+// The note is moved to the trash, see TrashHandler
 func (h *NotesHandler) DeleteNote(c *gin.Context) {
 	userID, exists := auth.RequireAuth(c)
 	if !exists {
@@ -309,8 +559,33 @@ func (h *NotesHandler) DeleteNote(c *gin.Context) {
 		return
 	}
 
-	// Delete the note
-	err := h.queries.DeleteNote(c.Request.Context(), db_sqlc.DeleteNoteParams{
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.queries.WithTx(tx)
+
+	if c.GetHeader("If-Match") != "" {
+		_, revision, err := services.LockNote(ctx, qtx, noteUUID, userUUID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
+			return
+		}
+		if notePreconditionFailed(c, revision) {
+			return
+		}
+	}
+
+	// Move the note to the trash
+	rows, err := qtx.DeleteNote(ctx, db_sqlc.DeleteNoteParams{
 		ID:     noteUUID,
 		UserID: userUUID,
 	})
@@ -318,6 +593,15 @@ func (h *NotesHandler) DeleteNote(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
 		return
 	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
+		return
+	}
 
 	c.JSON(http.StatusNoContent, nil)
 }
@@ -325,70 +609,119 @@ func (h *NotesHandler) DeleteNote(c *gin.Context) {
 // convertCreateNoteRowToResponse converts CreateNoteRow to API response format
 func convertCreateNoteRowToResponse(note db_sqlc.CreateNoteRow) NoteResponse {
 	return NoteResponse{
-		ID:        note.ID.String(),
-		UserID:    note.UserID.String(),
-		Title:     note.Title,
-		Content:   note.Content,
-		Tags:      note.Tags,
-		CreatedAt: note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		ID:              note.ID.String(),
+		UserID:          note.UserID.String(),
+		Title:           note.Title,
+		Content:         note.Content,
+		Tags:            note.Tags,
+		EmbeddingStatus: note.EmbeddingStatus,
+		NotebookID:      optionalUUID(note.NotebookID),
+		CreatedAt:       note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
 
 // convertGetNoteRowToResponse converts GetNoteRow to API response format
 func convertGetNoteRowToResponse(note db_sqlc.GetNoteRow) NoteResponse {
 	return NoteResponse{
-		ID:        note.ID.String(),
-		UserID:    note.UserID.String(),
-		Title:     note.Title,
-		Content:   note.Content,
-		Tags:      note.Tags,
-		CreatedAt: note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		ID:              note.ID.String(),
+		UserID:          note.UserID.String(),
+		Title:           note.Title,
+		Content:         note.Content,
+		Tags:            note.Tags,
+		EmbeddingStatus: note.EmbeddingStatus,
+		NotebookID:      optionalUUID(note.NotebookID),
+		CreatedAt:       note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
 
 // convertGetUserNotesRowToResponse converts GetUserNotesRow to API response format
 func convertGetUserNotesRowToResponse(note db_sqlc.GetUserNotesRow) NoteResponse {
 	return NoteResponse{
-		ID:        note.ID.String(),
-		UserID:    note.UserID.String(),
-		Title:     note.Title,
-		Content:   note.Content,
-		Tags:      note.Tags,
-		CreatedAt: note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		ID:              note.ID.String(),
+		UserID:          note.UserID.String(),
+		Title:           note.Title,
+		Content:         note.Content,
+		Tags:            note.Tags,
+		EmbeddingStatus: note.EmbeddingStatus,
+		NotebookID:      optionalUUID(note.NotebookID),
+		CreatedAt:       note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
 
 // convertUpdateNoteRowToResponse converts UpdateNoteRow to API response format
 func convertUpdateNoteRowToResponse(note db_sqlc.UpdateNoteRow) NoteResponse {
 	return NoteResponse{
-		ID:        note.ID.String(),
-		UserID:    note.UserID.String(),
-		Title:     note.Title,
-		Content:   note.Content,
-		Tags:      note.Tags,
-		CreatedAt: note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-		UpdatedAt: note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		ID:              note.ID.String(),
+		UserID:          note.UserID.String(),
+		Title:           note.Title,
+		Content:         note.Content,
+		Tags:            note.Tags,
+		EmbeddingStatus: note.EmbeddingStatus,
+		NotebookID:      optionalUUID(note.NotebookID),
+		CreatedAt:       note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
 	}
 }
 
 // SearchNotesRequest represents the request for searching notes by query
 type SearchNotesRequest struct {
 	Query     string  `json:"query" binding:"required"`
+	Mode      string  `json:"mode,omitempty"` // semantic, keyword or hybrid (default)
 	Threshold float64 `json:"threshold,omitempty"`
 	Limit     int     `json:"limit,omitempty"`
+	Passages  int     `json:"passages,omitempty"` // Matching passages returned per note
+
+	Tags           []string   `json:"tags,omitempty"`
+	TagMode        string     `json:"tag_mode,omitempty"` // any (default) or all of tags
+	CreatedAfter   *time.Time `json:"created_after,omitempty"`
+	CreatedBefore  *time.Time `json:"created_before,omitempty"`
+	UpdatedAfter   *time.Time `json:"updated_after,omitempty"`
+	UpdatedBefore  *time.Time `json:"updated_before,omitempty"`
+	NotebookID     string     `json:"notebook_id,omitempty"`     // Only search this notebook and its descendants
+	IncludeContent bool       `json:"include_content,omitempty"` // Return the full note content besides the snippet
+}
+
+// SearchResultResponse represents a note found by search with the score of every ranker
+type SearchResultResponse struct {
+	ID         string                   `json:"id"`
+	UserID     string                   `json:"user_id"`
+	Title      string                   `json:"title"`
+	Content    string                   `json:"content,omitempty"`
+	Snippet    services.Snippet         `json:"snippet"`
+	Tags       []string                 `json:"tags"`
+	CreatedAt  string                   `json:"created_at"`
+	UpdatedAt  string                   `json:"updated_at"`
+	Score      float64                  `json:"score"`
+	Similarity *float64                 `json:"similarity,omitempty"`
+	Semantic   *services.RankerScore    `json:"semantic"`
+	Keyword    *services.RankerScore    `json:"keyword"`
+	Passages   []services.SearchPassage `json:"passages,omitempty"`
 }
 
 // GenerateFlashcardFromQueryRequest represents the request for generating flashcard from query
 type GenerateFlashcardFromQueryRequest struct {
-	Query string `json:"query" binding:"required"`
+	Query      string `json:"query" binding:"required"`
+	DeckID     string `json:"deck_id,omitempty"`     // Save the generated cards into this deck when set
+	NotebookID string `json:"notebook_id,omitempty"` // Only use notes of this notebook and its descendants
 }
 
 // GenerateFlashcardFromNotesRequest represents the request for generating flashcard from selected notes
 type GenerateFlashcardFromNotesRequest struct {
 	NoteIDs []string `json:"note_ids" binding:"required,min=1"`
+	DeckID  string   `json:"deck_id,omitempty"` // Save the generated cards into this deck when set
+}
+
+// ExportFlashcardsRequest represents the request for exporting generated flashcards
+type ExportFlashcardsRequest struct {
+	NoteIDs    []string `json:"note_ids,omitempty"`    // Generate from these notes...
+	Query      string   `json:"query,omitempty"`       // ...or from the notes most related to this query
+	NotebookID string   `json:"notebook_id,omitempty"` // Only use notes of this notebook and its descendants for a query
+	Format     string   `json:"format,omitempty"`      // apkg (default), csv or tsv
+	DeckName   string   `json:"deck_name,omitempty"`   // Anki deck and file name, "go-note" by default
+	Basic      bool     `json:"basic,omitempty"`       // CSV/TSV with only question and answer, for Quizlet
 }
 
 // SearchNotesByQuery handles POST /api/notes/search
@@ -405,19 +738,40 @@ func (h *NotesHandler) SearchNotesByQuery(c *gin.Context) {
 		return
 	}
 
-	// Set default values
+	mode, err := services.ParseSearchMode(req.Mode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set default values; hybrid search also relies on keywords, so it accepts looser semantic matches
 	if req.Threshold == 0 {
 		req.Threshold = 0.7
+		if mode == services.SearchModeHybrid {
+			req.Threshold = 0.5
+		}
 	}
 	if req.Limit == 0 {
 		req.Limit = 10
 	}
+	if req.Passages <= 0 || req.Passages > 10 {
+		req.Passages = 3
+	}
 
-	// Generate embedding for the query
-	queryEmbedding, err := h.embeddingService.GenerateQueryEmbedding(c.Request.Context(), req.Query)
-	if err != nil {
-		log.Printf("Failed to generate query embedding: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process query"})
+	filters := services.SearchFilters{
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		UpdatedAfter:  req.UpdatedAfter,
+		UpdatedBefore: req.UpdatedBefore,
+	}
+	tags := services.NormalizeTags(req.Tags)
+	switch req.TagMode {
+	case "", "any":
+		filters.TagsAny = tags
+	case "all":
+		filters.TagsAll = tags
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag_mode must be any or all"})
 		return
 	}
 
@@ -428,12 +782,19 @@ func (h *NotesHandler) SearchNotesByQuery(c *gin.Context) {
 		return
 	}
 
-	// Search for similar notes
-	notes, err := h.queries.SearchNotesBySimilarity(c.Request.Context(), db_sqlc.SearchNotesBySimilarityParams{
-		Column1: pgvector.NewVector(queryEmbedding), // Query embedding
-		UserID:  userUUID,
-		Column3: req.Threshold, // Similarity threshold
-		Limit:   int32(req.Limit),
+	notebookIDs, ok := resolveNotebookScope(c, h.queries, req.NotebookID, userUUID)
+	if !ok {
+		return
+	}
+	filters.NotebookIDs = notebookIDs
+
+	search, err := h.searchService.Search(c.Request.Context(), userUUID, services.SearchOptions{
+		Query:           req.Query,
+		Mode:            mode,
+		Threshold:       req.Threshold,
+		Limit:           req.Limit,
+		PassagesPerNote: req.Passages,
+		Filters:         filters,
 	})
 	if err != nil {
 		log.Printf("Failed to search notes: %v", err)
@@ -442,28 +803,45 @@ func (h *NotesHandler) SearchNotesByQuery(c *gin.Context) {
 	}
 
 	// Convert to response format
-	var responses []map[string]interface{}
-	for _, note := range notes {
-		responses = append(responses, map[string]interface{}{
-			"id":         note.ID.String(),
-			"user_id":    note.UserID.String(),
-			"title":      note.Title,
-			"content":    note.Content,
-			"tags":       note.Tags,
-			"created_at": note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-			"updated_at": note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
-			"similarity": note.Similarity,
-		})
+	responses := make([]SearchResultResponse, 0, len(search.Results))
+	for _, result := range search.Results {
+		responses = append(responses, convertSearchResultToResponse(result, req.IncludeContent))
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"query":   req.Query,
-		"notes":   responses,
-		"count":   len(responses),
-		"results": len(responses),
+		"query":  req.Query,
+		"mode":   mode,
+		"notes":  responses,
+		"count":  len(responses),
+		"facets": gin.H{"tags": search.TagFacets},
 	})
 }
 
+// convertSearchResultToResponse converts a search result to API response format.
+// The full content is only included on request; the snippet shows where the query matched.
+func convertSearchResultToResponse(result services.SearchResult, includeContent bool) SearchResultResponse {
+	response := SearchResultResponse{
+		ID:        result.ID.String(),
+		UserID:    result.UserID.String(),
+		Title:     result.Title,
+		Snippet:   result.Snippet,
+		Tags:      result.Tags,
+		CreatedAt: result.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: result.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		Score:     result.Score,
+		Semantic:  result.Semantic,
+		Keyword:   result.Keyword,
+		Passages:  result.Passages,
+	}
+	if includeContent {
+		response.Content = result.Content
+	}
+	if result.Semantic != nil {
+		response.Similarity = &result.Semantic.Score
+	}
+	return response
+}
+
 // StreamFlashcardFromQuery handles POST /api/notes/flashcard/query
 func (h *NotesHandler) StreamFlashcardFromQuery(c *gin.Context) {
 	userID, exists := auth.RequireAuth(c)
@@ -477,14 +855,6 @@ func (h *NotesHandler) StreamFlashcardFromQuery(c *gin.Context) {
 		return
 	}
 
-	// Generate embedding for the query
-	queryEmbedding, err := h.embeddingService.GenerateQueryEmbedding(c.Request.Context(), req.Query)
-	if err != nil {
-		log.Printf("Failed to generate query embedding: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process query"})
-		return
-	}
-
 	// Parse user UUID
 	var userUUID pgtype.UUID
 	if err := userUUID.Scan(userID); err != nil {
@@ -492,35 +862,16 @@ func (h *NotesHandler) StreamFlashcardFromQuery(c *gin.Context) {
 		return
 	}
 
-	// Search for similar notes
-	notes, err := h.queries.SearchNotesBySimilarity(c.Request.Context(), db_sqlc.SearchNotesBySimilarityParams{
-		Column1: pgvector.NewVector(queryEmbedding),
-		UserID:  userUUID,
-		Column3: 0.6,
-		Limit:   5,
-	})
-	if err != nil {
-		log.Printf("Failed to search notes: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search notes"})
+	deckUUID, ok := h.resolveTargetDeck(c, req.DeckID, userUUID)
+	if !ok {
 		return
 	}
 
-	if len(notes) == 0 {
-		c.JSON(http.StatusNotFound, gin.H{"error": "No relevant notes found for the query"})
+	serviceNotes, ok := h.flashcardNotesForQuery(c, req.Query, req.NotebookID, userUUID)
+	if !ok {
 		return
 	}
 
-	// Convert to service format
-	var serviceNotes []services.Note
-	for _, note := range notes {
-		serviceNotes = append(serviceNotes, services.Note{
-			ID:      note.ID.String(),
-			Title:   note.Title,
-			Content: note.Content,
-			Tags:    note.Tags,
-		})
-	}
-
 	// Set SSE headers
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
@@ -528,9 +879,15 @@ func (h *NotesHandler) StreamFlashcardFromQuery(c *gin.Context) {
 	c.Header("X-Accel-Buffering", "no")
 
 	responseChan := make(chan string, 100)
+	ctx := c.Request.Context()
 
 	go func() {
-		_ = h.flashcardService.StreamFlashcardFromQuery(c.Request.Context(), req.Query, serviceNotes, responseChan)
+		defer close(responseChan)
+		flashcards, err := h.flashcardService.StreamFlashcardFromQuery(ctx, req.Query, serviceNotes, responseChan)
+		if err != nil || deckUUID == nil {
+			return
+		}
+		h.saveGeneratedFlashcards(ctx, userUUID, *deckUUID, flashcards, serviceNotes, responseChan)
 	}()
 
 	c.Stream(func(w io.Writer) bool {
@@ -570,34 +927,13 @@ func (h *NotesHandler) StreamFlashcardFromNotes(c *gin.Context) {
 		return
 	}
 
-	// Fetch the selected notes
-	var serviceNotes []services.Note
-	for _, noteIDStr := range req.NoteIDs {
-		var noteUUID pgtype.UUID
-		if err := noteUUID.Scan(noteIDStr); err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID format: " + noteIDStr})
-			return
-		}
-
-		note, err := h.queries.GetNoteForFlashcard(c.Request.Context(), db_sqlc.GetNoteForFlashcardParams{
-			ID:     noteUUID,
-			UserID: userUUID,
-		})
-		if err != nil {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Note not found or access denied: " + noteIDStr})
-			return
-		}
-
-		serviceNotes = append(serviceNotes, services.Note{
-			ID:      note.ID.String(),
-			Title:   note.Title,
-			Content: note.Content,
-			Tags:    note.Tags,
-		})
+	deckUUID, ok := h.resolveTargetDeck(c, req.DeckID, userUUID)
+	if !ok {
+		return
 	}
 
-	if len(serviceNotes) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid notes found"})
+	serviceNotes, ok := h.flashcardNotesByID(c, req.NoteIDs, userUUID)
+	if !ok {
 		return
 	}
 
@@ -608,9 +944,15 @@ func (h *NotesHandler) StreamFlashcardFromNotes(c *gin.Context) {
 	c.Header("X-Accel-Buffering", "no")
 
 	responseChan := make(chan string, 100)
+	ctx := c.Request.Context()
 
 	go func() {
-		_ = h.flashcardService.StreamFlashcardFromNotes(c.Request.Context(), serviceNotes, responseChan)
+		defer close(responseChan)
+		flashcards, err := h.flashcardService.StreamFlashcardFromNotes(ctx, serviceNotes, responseChan)
+		if err != nil || deckUUID == nil {
+			return
+		}
+		h.saveGeneratedFlashcards(ctx, userUUID, *deckUUID, flashcards, serviceNotes, responseChan)
 	}()
 
 	c.Stream(func(w io.Writer) bool {
@@ -629,3 +971,215 @@ func (h *NotesHandler) StreamFlashcardFromNotes(c *gin.Context) {
 		}
 	})
 }
+
+// ExportFlashcards handles POST /api/notes/flashcard/export
+// Generates flashcards from note_ids or a query like the streaming endpoints,
+// and returns them as a file to download instead of streaming them.
+func (h *NotesHandler) ExportFlashcards(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req ExportFlashcardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	if (len(req.NoteIDs) == 0) == (strings.TrimSpace(req.Query) == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either note_ids or query is required"})
+		return
+	}
+	if req.Format == "" {
+		req.Format = services.FlashcardExportApkg
+	}
+	switch req.Format {
+	case services.FlashcardExportApkg, services.FlashcardExportCSV, services.FlashcardExportTSV:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be apkg, csv or tsv"})
+		return
+	}
+	req.DeckName = strings.TrimSpace(req.DeckName)
+	if req.DeckName == "" {
+		req.DeckName = "go-note"
+	}
+
+	// Parse user UUID
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var serviceNotes []services.Note
+	var ok bool
+	if len(req.NoteIDs) > 0 {
+		serviceNotes, ok = h.flashcardNotesByID(c, req.NoteIDs, userUUID)
+	} else {
+		serviceNotes, ok = h.flashcardNotesForQuery(c, req.Query, req.NotebookID, userUUID)
+	}
+	if !ok {
+		return
+	}
+
+	// Generating takes longer than the server's WriteTimeout allows
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(flashcardExportTimeout)); err != nil {
+		log.Printf("Failed to extend write deadline for flashcard export: %v", err)
+	}
+
+	var flashcards []services.Flashcard
+	var err error
+	if len(req.NoteIDs) > 0 {
+		flashcards, err = h.flashcardService.GenerateFlashcardFromNotes(c.Request.Context(), serviceNotes)
+	} else {
+		flashcards, err = h.flashcardService.GenerateFlashcardFromQuery(c.Request.Context(), req.Query, serviceNotes)
+	}
+	if err != nil {
+		log.Printf("Failed to generate flashcards for export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate flashcards"})
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "application/octet-stream"
+	switch req.Format {
+	case services.FlashcardExportApkg:
+		err = services.WriteAnkiPackage(&buf, req.DeckName, flashcards, serviceNotes, time.Now())
+	case services.FlashcardExportCSV:
+		contentType = "text/csv; charset=utf-8"
+		err = services.WriteFlashcardsText(&buf, flashcards, serviceNotes, services.FlashcardTextOptions{Separator: ',', Basic: req.Basic})
+	case services.FlashcardExportTSV:
+		contentType = "text/tab-separated-values; charset=utf-8"
+		err = services.WriteFlashcardsText(&buf, flashcards, serviceNotes, services.FlashcardTextOptions{Separator: '\t', Basic: req.Basic})
+	}
+	if err != nil {
+		log.Printf("Failed to write flashcard export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export flashcards"})
+		return
+	}
+
+	filename := services.FlashcardExportFileName(req.DeckName, req.Format)
+	c.Header("Content-Disposition", "attachment; filename*=UTF-8''"+url.PathEscape(filename))
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// flashcardNotesForQuery finds the notes most related to a query, within a
+// notebook when notebookID is set, to generate flashcards from. It writes an
+// error response when the notebook is invalid or nothing relevant is found.
+func (h *NotesHandler) flashcardNotesForQuery(c *gin.Context, query, notebookID string, userUUID pgtype.UUID) ([]services.Note, bool) {
+	notebookIDs, ok := resolveNotebookScope(c, h.queries, notebookID, userUUID)
+	if !ok {
+		return nil, false
+	}
+
+	// Search for similar notes
+	search, err := h.searchService.Search(c.Request.Context(), userUUID, services.SearchOptions{
+		Query:           query,
+		Mode:            services.SearchModeSemantic,
+		Threshold:       0.6,
+		Limit:           5,
+		PassagesPerNote: 1,
+		Filters:         services.SearchFilters{NotebookIDs: notebookIDs},
+	})
+	if err != nil {
+		log.Printf("Failed to search notes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search notes"})
+		return nil, false
+	}
+
+	if len(search.Results) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No relevant notes found for the query"})
+		return nil, false
+	}
+
+	// Convert to service format
+	var serviceNotes []services.Note
+	for _, note := range search.Results {
+		serviceNotes = append(serviceNotes, services.Note{
+			ID:      note.ID.String(),
+			Title:   note.Title,
+			Content: note.Content,
+			Tags:    note.Tags,
+		})
+	}
+	return serviceNotes, true
+}
+
+// flashcardNotesByID fetches the selected notes to generate flashcards from,
+// writing an error response when an ID is invalid or a note is not the user's
+func (h *NotesHandler) flashcardNotesByID(c *gin.Context, noteIDs []string, userUUID pgtype.UUID) ([]services.Note, bool) {
+	var serviceNotes []services.Note
+	for _, noteIDStr := range noteIDs {
+		var noteUUID pgtype.UUID
+		if err := noteUUID.Scan(noteIDStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID format: " + noteIDStr})
+			return nil, false
+		}
+
+		note, err := h.queries.GetNoteForFlashcard(c.Request.Context(), db_sqlc.GetNoteForFlashcardParams{
+			ID:     noteUUID,
+			UserID: userUUID,
+		})
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Note not found or access denied: " + noteIDStr})
+			return nil, false
+		}
+
+		serviceNotes = append(serviceNotes, services.Note{
+			ID:      note.ID.String(),
+			Title:   note.Title,
+			Content: note.Content,
+			Tags:    note.Tags,
+		})
+	}
+
+	if len(serviceNotes) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid notes found"})
+		return nil, false
+	}
+	return serviceNotes, true
+}
+
+// resolveTargetDeck parses the optional deck ID of a flashcard request and checks that the deck belongs to the user.
+// It returns nil when no deck was requested and writes an error response when the deck is invalid.
+func (h *NotesHandler) resolveTargetDeck(c *gin.Context, deckIDStr string, userUUID pgtype.UUID) (*pgtype.UUID, bool) {
+	if deckIDStr == "" {
+		return nil, true
+	}
+
+	var deckUUID pgtype.UUID
+	if err := deckUUID.Scan(deckIDStr); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID format"})
+		return nil, false
+	}
+
+	if _, err := h.queries.GetDeck(c.Request.Context(), db_sqlc.GetDeckParams{
+		ID:     deckUUID,
+		UserID: userUUID,
+	}); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deck not found"})
+		return nil, false
+	}
+
+	return &deckUUID, true
+}
+
+// saveGeneratedFlashcards stores the generated flashcards in the deck and reports the outcome over SSE
+func (h *NotesHandler) saveGeneratedFlashcards(ctx context.Context, userUUID, deckUUID pgtype.UUID, flashcards []services.Flashcard, notes []services.Note, responseChan chan<- string) {
+	var sourceNoteIDs []pgtype.UUID
+	for _, note := range notes {
+		var noteUUID pgtype.UUID
+		if err := noteUUID.Scan(note.ID); err == nil {
+			sourceNoteIDs = append(sourceNoteIDs, noteUUID)
+		}
+	}
+
+	saved, err := h.deckService.SaveFlashcards(ctx, userUUID, deckUUID, flashcards, sourceNoteIDs)
+	if err != nil {
+		log.Printf("Failed to save flashcards: %v", err)
+		h.flashcardService.SendError(responseChan, "儲存閃卡失敗")
+		return
+	}
+
+	h.flashcardService.SendSaved(responseChan, saved)
+}
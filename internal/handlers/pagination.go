@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// pageRequest holds the paging, sorting and date filters of a listing request
+type pageRequest struct {
+	Limit      int
+	Offset     int // only used without a cursor, for clients paging the old way
+	Sort       string
+	Descending bool
+	Cursor     *services.PageCursor
+
+	CreatedAfter  pgtype.Timestamptz
+	CreatedBefore pgtype.Timestamptz
+	UpdatedAfter  pgtype.Timestamptz
+	UpdatedBefore pgtype.Timestamptz
+}
+
+// parsePageRequest reads limit, offset, cursor, sort (created_at, updated_at or
+// textSort), order and the RFC 3339 created_after, created_before,
+// updated_after and updated_before query parameters. Listings default to
+// created_at descending; sorting by textSort defaults to ascending. Writes the
+// error response when a parameter is invalid.
+func parsePageRequest(c *gin.Context, textSort string) (pageRequest, bool) {
+	page := pageRequest{Sort: c.DefaultQuery("sort", "created_at")}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+	page.Limit = limit
+
+	switch page.Sort {
+	case "created_at", "updated_at", textSort:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be created_at, updated_at or " + textSort})
+		return page, false
+	}
+
+	order := "desc"
+	if page.Sort == textSort {
+		order = "asc"
+	}
+	switch c.DefaultQuery("order", order) {
+	case "asc":
+	case "desc":
+		page.Descending = true
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "order must be asc or desc"})
+		return page, false
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		cursor, err := services.DecodeCursor(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return page, false
+		}
+		if cursor.Sort != page.Sort || cursor.Descending != page.Descending {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "cursor was issued for a different sort order"})
+			return page, false
+		}
+		page.Cursor = &cursor
+	} else {
+		offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+		if err != nil || offset < 0 {
+			offset = 0
+		}
+		page.Offset = offset
+	}
+
+	bounds := []struct {
+		name  string
+		value *pgtype.Timestamptz
+	}{
+		{"created_after", &page.CreatedAfter},
+		{"created_before", &page.CreatedBefore},
+		{"updated_after", &page.UpdatedAfter},
+		{"updated_before", &page.UpdatedBefore},
+	}
+	for _, bound := range bounds {
+		raw := c.Query(bound.name)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": bound.name + " must be an RFC 3339 timestamp"})
+			return page, false
+		}
+		*bound.value = pgtype.Timestamptz{Time: t, Valid: true}
+	}
+
+	return page, true
+}
+
+// cursorID returns the ID recorded in the cursor, or NULL on the first page
+func (p pageRequest) cursorID() pgtype.UUID {
+	var id pgtype.UUID
+	if p.Cursor != nil {
+		if err := id.Scan(p.Cursor.ID); err != nil {
+			return pgtype.UUID{}
+		}
+	}
+	return id
+}
+
+// cursorTime returns the timestamp sort value recorded in the cursor
+func (p pageRequest) cursorTime() pgtype.Timestamptz {
+	if p.Cursor == nil || p.Cursor.Time.IsZero() {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: p.Cursor.Time, Valid: true}
+}
+
+// cursorText returns the text sort value recorded in the cursor
+func (p pageRequest) cursorText() pgtype.Text {
+	if p.Cursor == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: p.Cursor.Text, Valid: true}
+}
+
+// nextCursor returns the cursor for the page after the row with the given
+// values, or nil when hasMore is false
+func (p pageRequest) nextCursor(hasMore bool, id pgtype.UUID, createdAt, updatedAt pgtype.Timestamptz, text string) *string {
+	if !hasMore {
+		return nil
+	}
+
+	cursor := services.PageCursor{Sort: p.Sort, Descending: p.Descending, ID: id.String()}
+	switch p.Sort {
+	case "created_at":
+		cursor.Time = createdAt.Time
+	case "updated_at":
+		cursor.Time = updatedAt.Time
+	default:
+		cursor.Text = text
+	}
+
+	token := services.EncodeCursor(cursor)
+	return &token
+}
+
+// order names the sort direction for responses
+func (p pageRequest) order() string {
+	if p.Descending {
+		return "desc"
+	}
+	return "asc"
+}
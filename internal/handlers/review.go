@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ReviewHandler handles spaced-repetition review HTTP requests
+type ReviewHandler struct {
+	queries       *db_sqlc.Queries
+	db            *pgxpool.Pool
+	reviewService *services.ReviewService
+}
+
+// NewReviewHandler creates a new review handler
+func NewReviewHandler(db *pgxpool.Pool) (*ReviewHandler, error) {
+	reviewService, err := services.NewReviewService(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReviewHandler{
+		queries:       db_sqlc.New(db),
+		db:            db,
+		reviewService: reviewService,
+	}, nil
+}
+
+// ReviewCardRequest represents the request body for grading a card.
+// Either a numeric grade (0-5) or a rating (again, hard, good, easy) is required.
+type ReviewCardRequest struct {
+	Grade  *int   `json:"grade,omitempty"`
+	Rating string `json:"rating,omitempty"`
+}
+
+// CardScheduleResponse represents the response format for a card's schedule
+type CardScheduleResponse struct {
+	FlashcardID    string  `json:"flashcard_id"`
+	Algorithm      string  `json:"algorithm"`
+	EaseFactor     float64 `json:"ease_factor"`
+	IntervalDays   int32   `json:"interval_days"`
+	Repetitions    int32   `json:"repetitions"`
+	Lapses         int32   `json:"lapses"`
+	DueAt          string  `json:"due_at"`
+	LastReviewedAt string  `json:"last_reviewed_at,omitempty"`
+}
+
+// DueCardResponse represents a flashcard that is due for review
+type DueCardResponse struct {
+	ID            string   `json:"id"`
+	DeckID        string   `json:"deck_id"`
+	Question      string   `json:"question"`
+	Answer        string   `json:"answer"`
+	Explanation   string   `json:"explanation,omitempty"`
+	Difficulty    string   `json:"difficulty"`
+	Tags          []string `json:"tags"`
+	SourceNoteIDs []string `json:"source_note_ids"`
+	EaseFactor    *float64 `json:"ease_factor,omitempty"`
+	IntervalDays  *int32   `json:"interval_days,omitempty"`
+	Repetitions   *int32   `json:"repetitions,omitempty"`
+	DueAt         string   `json:"due_at,omitempty"`
+	IsNew         bool     `json:"is_new"`
+}
+
+// ReviewLogResponse represents a single past review
+type ReviewLogResponse struct {
+	ID                   string  `json:"id"`
+	Algorithm            string  `json:"algorithm"`
+	Grade                int16   `json:"grade"`
+	EaseFactor           float64 `json:"ease_factor"`
+	PreviousIntervalDays int32   `json:"previous_interval_days"`
+	IntervalDays         int32   `json:"interval_days"`
+	DueAt                string  `json:"due_at"`
+	ReviewedAt           string  `json:"reviewed_at"`
+}
+
+// GetDueCards handles GET /api/review/due
+func (h *ReviewHandler) GetDueCards(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	params := db_sqlc.ListDueFlashcardsParams{
+		UserID: userUUID,
+		Now:    pgtype.Timestamptz{Time: time.Now(), Valid: true},
+		Limit:  int32(limit),
+	}
+	if deckIDStr := c.Query("deck_id"); deckIDStr != "" {
+		if err := params.DeckID.Scan(deckIDStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deck ID format"})
+			return
+		}
+	}
+
+	cards, err := h.queries.ListDueFlashcards(c.Request.Context(), params)
+	if err != nil {
+		log.Printf("Failed to list due cards: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch due cards"})
+		return
+	}
+
+	var responses []DueCardResponse
+	for _, card := range cards {
+		responses = append(responses, convertDueFlashcardRowToResponse(card))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"cards":     responses,
+		"count":     len(responses),
+		"algorithm": h.reviewService.Algorithm(),
+	})
+}
+
+// ReviewCard handles POST /api/review/:card_id
+func (h *ReviewHandler) ReviewCard(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var req ReviewCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	var grade services.Grade
+	switch {
+	case req.Grade != nil:
+		grade = services.Grade(*req.Grade)
+		if !grade.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Grade must be between 0 and 5"})
+			return
+		}
+	case req.Rating != "":
+		parsed, err := services.ParseRating(req.Rating)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		grade = parsed
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Either grade or rating is required"})
+		return
+	}
+
+	var cardUUID, userUUID pgtype.UUID
+	if err := cardUUID.Scan(c.Param("card_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flashcard ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	schedule, err := h.reviewService.ReviewCard(c.Request.Context(), userUUID, cardUUID, grade, time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrFlashcardNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Flashcard not found"})
+			return
+		}
+		log.Printf("Failed to review card: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record review"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertCardScheduleToResponse(*schedule))
+}
+
+// GetReviewHistory handles GET /api/review/:card_id/history
+func (h *ReviewHandler) GetReviewHistory(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 1 || limit > 200 {
+		limit = 50
+	}
+
+	var cardUUID, userUUID pgtype.UUID
+	if err := cardUUID.Scan(c.Param("card_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flashcard ID format"})
+		return
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	logs, err := h.queries.ListCardReviewLogs(c.Request.Context(), db_sqlc.ListCardReviewLogsParams{
+		FlashcardID: cardUUID,
+		UserID:      userUUID,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch review history"})
+		return
+	}
+
+	var responses []ReviewLogResponse
+	for _, entry := range logs {
+		responses = append(responses, ReviewLogResponse{
+			ID:                   entry.ID.String(),
+			Algorithm:            entry.Algorithm,
+			Grade:                entry.Grade,
+			EaseFactor:           entry.EaseFactor,
+			PreviousIntervalDays: entry.PreviousIntervalDays,
+			IntervalDays:         entry.IntervalDays,
+			DueAt:                entry.DueAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			ReviewedAt:           entry.ReviewedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"flashcard_id": cardUUID.String(),
+		"reviews":      responses,
+		"count":        len(responses),
+	})
+}
+
+// convertCardScheduleToResponse converts a database CardSchedule to API response format
+func convertCardScheduleToResponse(schedule db_sqlc.CardSchedule) CardScheduleResponse {
+	response := CardScheduleResponse{
+		FlashcardID:  schedule.FlashcardID.String(),
+		Algorithm:    schedule.Algorithm,
+		EaseFactor:   schedule.EaseFactor,
+		IntervalDays: schedule.IntervalDays,
+		Repetitions:  schedule.Repetitions,
+		Lapses:       schedule.Lapses,
+		DueAt:        schedule.DueAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if schedule.LastReviewedAt.Valid {
+		response.LastReviewedAt = schedule.LastReviewedAt.Time.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
+}
+
+// convertDueFlashcardRowToResponse converts ListDueFlashcardsRow to API response format
+func convertDueFlashcardRowToResponse(card db_sqlc.ListDueFlashcardsRow) DueCardResponse {
+	sourceNoteIDs := make([]string, 0, len(card.SourceNoteIds))
+	for _, noteID := range card.SourceNoteIds {
+		sourceNoteIDs = append(sourceNoteIDs, noteID.String())
+	}
+
+	response := DueCardResponse{
+		ID:            card.ID.String(),
+		DeckID:        card.DeckID.String(),
+		Question:      card.Question,
+		Answer:        card.Answer,
+		Explanation:   card.Explanation,
+		Difficulty:    card.Difficulty,
+		Tags:          card.Tags,
+		SourceNoteIDs: sourceNoteIDs,
+		IsNew:         !card.DueAt.Valid,
+	}
+	if card.EaseFactor.Valid {
+		response.EaseFactor = &card.EaseFactor.Float64
+	}
+	if card.IntervalDays.Valid {
+		response.IntervalDays = &card.IntervalDays.Int32
+	}
+	if card.Repetitions.Valid {
+		response.Repetitions = &card.Repetitions.Int32
+	}
+	if card.DueAt.Valid {
+		response.DueAt = card.DueAt.Time.Format("2006-01-02T15:04:05Z07:00")
+	}
+	return response
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// RevisionHandler handles note history HTTP requests
+type RevisionHandler struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewRevisionHandler creates a new revision handler
+func NewRevisionHandler(db *pgxpool.Pool) *RevisionHandler {
+	return &RevisionHandler{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// NoteRevisionResponse represents the response format for note revisions
+type NoteRevisionResponse struct {
+	ID           string   `json:"id"`
+	NoteID       string   `json:"note_id"`
+	Revision     int32    `json:"revision"`
+	Title        string   `json:"title"`
+	Content      *string  `json:"content,omitempty"` // Omitted when listing revisions
+	Tags         []string `json:"tags"`
+	RestoredFrom *int32   `json:"restored_from,omitempty"`
+	CreatedAt    string   `json:"created_at"`
+}
+
+// NoteRevisionDiffResponse represents the changes between two revisions
+type NoteRevisionDiffResponse struct {
+	NoteID      string            `json:"note_id"`
+	From        int32             `json:"from"`
+	To          int32             `json:"to"`
+	Granularity string            `json:"granularity"`
+	Title       []services.DiffOp `json:"title"`
+	Content     []services.DiffOp `json:"content"`
+	TagsAdded   []string          `json:"tags_added"`
+	TagsRemoved []string          `json:"tags_removed"`
+}
+
+// ListRevisions handles GET /api/notes/:id/revisions
+func (h *RevisionHandler) ListRevisions(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	revisions, err := h.queries.ListNoteRevisions(c.Request.Context(), db_sqlc.ListNoteRevisionsParams{
+		NoteID: noteUUID,
+		UserID: userUUID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	responses := make([]NoteRevisionResponse, 0, len(revisions))
+	for _, revision := range revisions {
+		responses = append(responses, convertNoteRevisionToResponse(db_sqlc.NoteRevision{
+			ID:             revision.ID,
+			NoteID:         revision.NoteID,
+			RevisionNumber: revision.RevisionNumber,
+			Title:          revision.Title,
+			Tags:           revision.Tags,
+			RestoredFrom:   revision.RestoredFrom,
+			CreatedAt:      revision.CreatedAt,
+		}, false))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": responses,
+		"limit":     limit,
+		"offset":    offset,
+		"count":     len(responses),
+	})
+}
+
+// GetRevision handles GET /api/notes/:id/revisions/:revision
+func (h *RevisionHandler) GetRevision(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	revision, ok := h.getRevision(c, noteUUID, userUUID, int32(number))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, convertNoteRevisionToResponse(revision, true))
+}
+
+// DiffRevisions handles GET /api/notes/:id/diff?from=1&to=2&granularity=line
+func (h *RevisionHandler) DiffRevisions(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+
+	from, err := strconv.Atoi(c.Query("from"))
+	if err != nil || from < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a revision number"})
+		return
+	}
+	to, err := strconv.Atoi(c.Query("to"))
+	if err != nil || to < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a revision number"})
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "line")
+	diffContent := services.DiffLines
+	switch granularity {
+	case "line":
+	case "word":
+		diffContent = services.DiffWords
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "granularity must be line or word"})
+		return
+	}
+
+	before, ok := h.getRevision(c, noteUUID, userUUID, int32(from))
+	if !ok {
+		return
+	}
+	after, ok := h.getRevision(c, noteUUID, userUUID, int32(to))
+	if !ok {
+		return
+	}
+
+	response := NoteRevisionDiffResponse{
+		NoteID:      noteUUID.String(),
+		From:        before.RevisionNumber,
+		To:          after.RevisionNumber,
+		Granularity: granularity,
+		Title:       services.DiffWords(before.Title, after.Title),
+		Content:     diffContent(before.Content, after.Content),
+		TagsAdded:   []string{},
+		TagsRemoved: []string{},
+	}
+	for _, tag := range after.Tags {
+		if !slices.Contains(before.Tags, tag) {
+			response.TagsAdded = append(response.TagsAdded, tag)
+		}
+	}
+	for _, tag := range before.Tags {
+		if !slices.Contains(after.Tags, tag) {
+			response.TagsRemoved = append(response.TagsRemoved, tag)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RestoreRevision handles POST /api/notes/:id/revisions/:revision/restore.
+// The note gets the revision's title, content and tags, which is recorded as
+// a new revision; nothing in the history is discarded. Like PUT, it honours
+// If-Match and returns the note's new ETag.
+func (h *RevisionHandler) RestoreRevision(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+
+	number, err := strconv.Atoi(c.Param("revision"))
+	if err != nil || number < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	revision, ok := h.getRevision(c, noteUUID, userUUID, int32(number))
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.queries.WithTx(tx)
+
+	// Lock the note so a client holding a stale ETag cannot restore over edits it never saw
+	currentNote, currentRevision, err := services.LockNote(ctx, qtx, noteUUID, userUUID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+	if notePreconditionFailed(c, currentRevision) {
+		return
+	}
+
+	note, err := qtx.UpdateNote(ctx, db_sqlc.UpdateNoteParams{
+		ID:            noteUUID,
+		UserID:        userUUID,
+		Title:         revision.Title,
+		Content:       revision.Content,
+		Tags:          services.NormalizeTags(revision.Tags),
+		SearchTitle:   services.SearchText(revision.Title),
+		SearchContent: services.SearchText(revision.Content),
+	})
+	if err != nil {
+		log.Printf("Failed to restore revision: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	restored, err := qtx.CreateNoteRevision(ctx, db_sqlc.CreateNoteRevisionParams{
+		NoteID:       noteUUID,
+		RestoredFrom: pgtype.Int4{Int32: revision.RevisionNumber, Valid: true},
+	})
+	if err != nil {
+		log.Printf("Failed to record note revision: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	if revision.Title != currentNote.Title || revision.Content != currentNote.Content {
+		if err := qtx.EnqueueEmbeddingJob(ctx, db_sqlc.EnqueueEmbeddingJobParams{
+			NoteID: noteUUID,
+			UserID: userUUID,
+		}); err != nil {
+			log.Printf("Failed to enqueue embedding job: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+			return
+		}
+
+		if err := services.IndexNoteLinks(ctx, qtx, noteUUID, userUUID, note.Title, note.Content); err != nil {
+			log.Printf("Failed to index note links: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore revision"})
+		return
+	}
+
+	c.Header("ETag", noteETag(restored.RevisionNumber))
+	c.JSON(http.StatusOK, gin.H{
+		"note":     convertUpdateNoteRowToResponse(note),
+		"revision": convertNoteRevisionToResponse(restored, false),
+	})
+}
+
+// getRevision loads a revision of the user's note, writing the error response when it fails
+func (h *RevisionHandler) getRevision(c *gin.Context, noteID, userID pgtype.UUID, number int32) (db_sqlc.NoteRevision, bool) {
+	revision, err := h.queries.GetNoteRevision(c.Request.Context(), db_sqlc.GetNoteRevisionParams{
+		NoteID:         noteID,
+		UserID:         userID,
+		RevisionNumber: number,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision " + strconv.Itoa(int(number)) + " not found"})
+		return revision, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revision"})
+		return revision, false
+	}
+	return revision, true
+}
+
+// parseNoteAndUser reads the note ID path parameter and the authenticated user,
+// writing the error response when either is missing or malformed
+func parseNoteAndUser(c *gin.Context) (pgtype.UUID, pgtype.UUID, bool) {
+	var noteUUID, userUUID pgtype.UUID
+
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return noteUUID, userUUID, false
+	}
+
+	if err := noteUUID.Scan(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid note ID format"})
+		return noteUUID, userUUID, false
+	}
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return noteUUID, userUUID, false
+	}
+	return noteUUID, userUUID, true
+}
+
+// convertNoteRevisionToResponse converts a NoteRevision to API response format
+func convertNoteRevisionToResponse(revision db_sqlc.NoteRevision, includeContent bool) NoteRevisionResponse {
+	response := NoteRevisionResponse{
+		ID:        revision.ID.String(),
+		NoteID:    revision.NoteID.String(),
+		Revision:  revision.RevisionNumber,
+		Title:     revision.Title,
+		Tags:      revision.Tags,
+		CreatedAt: revision.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if includeContent {
+		response.Content = &revision.Content
+	}
+	if revision.RestoredFrom.Valid {
+		response.RestoredFrom = &revision.RestoredFrom.Int32
+	}
+	return response
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TagHandler handles HTTP requests for managing a user's tags
+type TagHandler struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewTagHandler creates a new tag handler
+func NewTagHandler(db *pgxpool.Pool) *TagHandler {
+	return &TagHandler{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// RenameTagRequest represents the request body for renaming a tag
+type RenameTagRequest struct {
+	From            string `json:"from" binding:"required"`
+	To              string `json:"to" binding:"required"`
+	IncludeChildren *bool  `json:"include_children,omitempty"` // Rename descendants like from/x too, defaults to true
+}
+
+// MergeTagsRequest represents the request body for merging tags into one
+type MergeTagsRequest struct {
+	Sources         []string `json:"sources" binding:"required,min=1"`
+	Target          string   `json:"target" binding:"required"`
+	IncludeChildren *bool    `json:"include_children,omitempty"` // Move descendants of the sources under target, defaults to true
+}
+
+// ListTags handles GET /api/tags
+// Lists the tags of the user's notes with the number of notes using each,
+// optionally limited to prefix and its descendants. With tree=true the tags
+// are nested by their levels (lang/go under lang) instead.
+func (h *TagHandler) ListTags(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	var prefix pgtype.Text
+	if raw, ok := c.GetQuery("prefix"); ok {
+		if prefix.String = services.NormalizeTag(raw); prefix.String != "" {
+			prefix.Valid = true
+		}
+	}
+
+	rows, err := h.queries.ListTagCounts(c.Request.Context(), db_sqlc.ListTagCountsParams{
+		UserID: userUUID,
+		Prefix: prefix,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+
+	counts := make([]services.TagCount, len(rows))
+	for i, row := range rows {
+		counts[i] = services.TagCount{Tag: row.Tag, Count: row.NoteCount}
+	}
+
+	if c.Query("tree") == "true" {
+		c.JSON(http.StatusOK, gin.H{"tags": services.BuildTagTree(counts), "count": len(counts)})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"tags": counts, "count": len(counts)})
+}
+
+// RenameTag handles POST /api/tags/rename
+// Renaming onto a tag that already exists merges the two.
+func (h *TagHandler) RenameTag(c *gin.Context) {
+	var req RenameTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	from, to := services.NormalizeTag(req.From), services.NormalizeTag(req.To)
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to must not be empty"})
+		return
+	}
+	if from == to {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from and to are the same tag"})
+		return
+	}
+
+	h.retag(c, []string{from}, pgtype.Text{String: to, Valid: true}, req.IncludeChildren == nil || *req.IncludeChildren)
+}
+
+// MergeTags handles POST /api/tags/merge
+// Every source tag is replaced by target on the notes carrying it.
+func (h *TagHandler) MergeTags(c *gin.Context) {
+	var req MergeTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+
+	target := services.NormalizeTag(req.Target)
+	if target == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target must not be empty"})
+		return
+	}
+	var sources []string
+	for _, source := range services.NormalizeTags(req.Sources) {
+		if source != target {
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sources must name tags other than target"})
+		return
+	}
+
+	h.retag(c, sources, pgtype.Text{String: target, Valid: true}, req.IncludeChildren == nil || *req.IncludeChildren)
+}
+
+// DeleteTag handles DELETE /api/tags?tag=...
+// Removes the tag from all notes, and its descendants too unless
+// include_children=false. The notes themselves are kept.
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	tag := services.NormalizeTag(c.Query("tag"))
+	if tag == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tag is required"})
+		return
+	}
+
+	h.retag(c, []string{tag}, pgtype.Text{}, c.DefaultQuery("include_children", "true") != "false")
+}
+
+// retag replaces sources by target (or removes them when target is NULL) on
+// all of the user's notes and records a revision for every note it changed
+func (h *TagHandler) retag(c *gin.Context, sources []string, target pgtype.Text, includeChildren bool) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.queries.WithTx(tx)
+	noteIDs, err := qtx.RetagNotes(ctx, db_sqlc.RetagNotesParams{
+		Sources:         sources,
+		Target:          target,
+		IncludeChildren: includeChildren,
+		UserID:          userUUID,
+	})
+	if err != nil {
+		log.Printf("Failed to retag notes: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+		return
+	}
+
+	if len(noteIDs) > 0 {
+		if err := qtx.CreateNoteRevisions(ctx, noteIDs); err != nil {
+			log.Printf("Failed to record note revisions: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+			return
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"updated_notes": len(noteIDs)})
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"go-note/internal/auth"
+	db_sqlc "go-note/internal/db_sqlc"
+	"go-note/internal/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// TrashHandler handles HTTP requests for deleted notes
+type TrashHandler struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewTrashHandler creates a new trash handler
+func NewTrashHandler(db *pgxpool.Pool) *TrashHandler {
+	return &TrashHandler{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// ListTrash handles GET /api/notes/trash
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = 10
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	notes, err := h.queries.ListTrashedNotes(c.Request.Context(), db_sqlc.ListTrashedNotesParams{
+		UserID: userUUID,
+		Limit:  int32(limit),
+		Offset: int32(offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+
+	responses := make([]NoteResponse, 0, len(notes))
+	for _, note := range notes {
+		responses = append(responses, convertListTrashedNotesRowToResponse(note))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notes":  responses,
+		"limit":  limit,
+		"offset": offset,
+		"count":  len(responses),
+	})
+}
+
+// RestoreNote handles POST /api/notes/trash/:id/restore
+func (h *TrashHandler) RestoreNote(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore note"})
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.queries.WithTx(tx)
+
+	note, err := qtx.RestoreNote(ctx, db_sqlc.RestoreNoteParams{
+		ID:     noteUUID,
+		UserID: userUUID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found in trash"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore note"})
+		return
+	}
+
+	// The worker skips trashed notes, so finish any embedding that was interrupted
+	if note.EmbeddingStatus != services.EmbeddingStatusReady {
+		if err := qtx.EnqueueEmbeddingJob(ctx, db_sqlc.EnqueueEmbeddingJobParams{
+			NoteID: note.ID,
+			UserID: note.UserID,
+		}); err != nil {
+			log.Printf("Failed to enqueue embedding job: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore note"})
+			return
+		}
+	}
+
+	// Links waiting for this title while the note was in the trash now find it
+	if err := services.IndexNoteLinks(ctx, qtx, note.ID, note.UserID, note.Title, note.Content); err != nil {
+		log.Printf("Failed to index note links: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore note"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore note"})
+		return
+	}
+
+	c.JSON(http.StatusOK, convertRestoreNoteRowToResponse(note))
+}
+
+// PurgeNote handles DELETE /api/notes/trash/:id
+// Only notes already in the trash can be deleted permanently.
+func (h *TrashHandler) PurgeNote(c *gin.Context) {
+	noteUUID, userUUID, ok := parseNoteAndUser(c)
+	if !ok {
+		return
+	}
+
+	rows, err := h.queries.PurgeNote(c.Request.Context(), db_sqlc.PurgeNoteParams{
+		ID:     noteUUID,
+		UserID: userUUID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete note"})
+		return
+	}
+	if rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Note not found in trash"})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// EmptyTrash handles DELETE /api/notes/trash
+func (h *TrashHandler) EmptyTrash(c *gin.Context) {
+	userID, exists := auth.RequireAuth(c)
+	if !exists {
+		return
+	}
+
+	var userUUID pgtype.UUID
+	if err := userUUID.Scan(userID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+
+	rows, err := h.queries.EmptyTrash(c.Request.Context(), userUUID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to empty trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"deleted": rows})
+}
+
+// convertListTrashedNotesRowToResponse converts ListTrashedNotesRow to API response format
+func convertListTrashedNotesRowToResponse(note db_sqlc.ListTrashedNotesRow) NoteResponse {
+	return NoteResponse{
+		ID:              note.ID.String(),
+		UserID:          note.UserID.String(),
+		Title:           note.Title,
+		Content:         note.Content,
+		Tags:            note.Tags,
+		EmbeddingStatus: note.EmbeddingStatus,
+		NotebookID:      optionalUUID(note.NotebookID),
+		CreatedAt:       note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		DeletedAt:       note.DeletedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
+
+// convertRestoreNoteRowToResponse converts RestoreNoteRow to API response format
+func convertRestoreNoteRowToResponse(note db_sqlc.RestoreNoteRow) NoteResponse {
+	return NoteResponse{
+		ID:              note.ID.String(),
+		UserID:          note.UserID.String(),
+		Title:           note.Title,
+		Content:         note.Content,
+		Tags:            note.Tags,
+		EmbeddingStatus: note.EmbeddingStatus,
+		NotebookID:      optionalUUID(note.NotebookID),
+		CreatedAt:       note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt:       note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+}
@@ -3,7 +3,6 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
-	"strconv"
 
 	"go-note/internal/auth"
 	db_sqlc "go-note/internal/db_sqlc"
@@ -74,6 +73,10 @@ func (h *UserHandler) GetUserProfile(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, profileETag(profile.UpdatedAt)) {
+		return
+	}
+
 	response := convertUserProfileToResponse(profile)
 	c.JSON(http.StatusOK, response)
 }
@@ -95,6 +98,10 @@ func (h *UserHandler) GetUserProfileByUsername(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, profileETag(profile.UpdatedAt)) {
+		return
+	}
+
 	response := convertUserProfileToResponse(profile)
 	c.JSON(http.StatusOK, response)
 }
@@ -165,6 +172,7 @@ func (h *UserHandler) CreateUserProfile(c *gin.Context) {
 	}
 
 	response := convertUserProfileToResponse(profile)
+	c.Header("ETag", profileETag(profile.UpdatedAt))
 	c.JSON(http.StatusCreated, response)
 }
 
@@ -233,13 +241,41 @@ func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
 		params.Preferences = preferencesJSON
 	}
 
-	profile, err := h.queries.UpdateUserProfile(c.Request.Context(), params)
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user profile"})
 		return
 	}
+	defer tx.Rollback(ctx)
+
+	qtx := h.queries.WithTx(tx)
+
+	// Lock the profile so no other update lands between the If-Match check and this one
+	if c.GetHeader("If-Match") != "" {
+		updatedAt, err := qtx.LockUserProfile(ctx, uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User profile not found"})
+			return
+		}
+		if profilePreconditionFailed(c, updatedAt) {
+			return
+		}
+	}
+
+	profile, err := qtx.UpdateUserProfile(ctx, params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user profile"})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user profile"})
+		return
+	}
 
 	response := convertUserProfileToResponse(profile)
+	c.Header("ETag", profileETag(profile.UpdatedAt))
 	c.JSON(http.StatusOK, response)
 }
 
@@ -257,52 +293,106 @@ func (h *UserHandler) DeleteUserProfile(c *gin.Context) {
 		return
 	}
 
-	err := h.queries.DeleteUserProfile(c.Request.Context(), uuid)
+	ctx := c.Request.Context()
+	tx, err := h.db.Begin(ctx)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user profile"})
 		return
 	}
+	defer tx.Rollback(ctx)
 
-	c.JSON(http.StatusNoContent, nil)
-}
+	qtx := h.queries.WithTx(tx)
 
-// ListUserProfiles handles GET /api/users
-func (h *UserHandler) ListUserProfiles(c *gin.Context) {
-	// Parse query parameters
-	limitStr := c.DefaultQuery("limit", "10")
-	offsetStr := c.DefaultQuery("offset", "0")
+	if c.GetHeader("If-Match") != "" {
+		updatedAt, err := qtx.LockUserProfile(ctx, uuid)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User profile not found"})
+			return
+		}
+		if profilePreconditionFailed(c, updatedAt) {
+			return
+		}
+	}
 
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit < 1 || limit > 100 {
-		limit = 10
+	if err := qtx.DeleteUserProfile(ctx, uuid); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user profile"})
+		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
-	if err != nil || offset < 0 {
-		offset = 0
+	if err := tx.Commit(ctx); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user profile"})
+		return
 	}
 
-	params := db_sqlc.ListUserProfilesParams{
-		Limit:  int32(limit),
-		Offset: int32(offset),
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// ListUserProfiles handles GET /api/users
+// Supports keyset pagination with cursor, sort (created_at, updated_at or
+// username), order and created/updated date bounds, see parsePageRequest.
+func (h *UserHandler) ListUserProfiles(c *gin.Context) {
+	page, ok := parsePageRequest(c, "username")
+	if !ok {
+		return
 	}
 
-	profiles, err := h.queries.ListUserProfiles(c.Request.Context(), params)
+	ctx := c.Request.Context()
+
+	// Fetch one extra row to learn whether another page follows
+	profiles, err := h.queries.ListUserProfiles(ctx, db_sqlc.ListUserProfilesParams{
+		CreatedAfter:   page.CreatedAfter,
+		CreatedBefore:  page.CreatedBefore,
+		UpdatedAfter:   page.UpdatedAfter,
+		UpdatedBefore:  page.UpdatedBefore,
+		CursorID:       page.cursorID(),
+		Sort:           page.Sort,
+		Descending:     page.Descending,
+		CursorUsername: page.cursorText(),
+		CursorTime:     page.cursorTime(),
+		Limit:          int32(page.Limit + 1),
+		Offset:         int32(page.Offset),
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user profiles"})
 		return
 	}
 
-	var responses []UserProfileResponse
+	total, err := h.queries.CountUserProfiles(ctx, db_sqlc.CountUserProfilesParams{
+		CreatedAfter:  page.CreatedAfter,
+		CreatedBefore: page.CreatedBefore,
+		UpdatedAfter:  page.UpdatedAfter,
+		UpdatedBefore: page.UpdatedBefore,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count user profiles"})
+		return
+	}
+
+	hasMore := len(profiles) > page.Limit
+	if hasMore {
+		profiles = profiles[:page.Limit]
+	}
+
+	responses := make([]UserProfileResponse, 0, len(profiles))
 	for _, profile := range profiles {
 		responses = append(responses, convertUserProfileToResponse(profile))
 	}
 
+	var nextCursor *string
+	if len(profiles) > 0 {
+		last := profiles[len(profiles)-1]
+		nextCursor = page.nextCursor(hasMore, last.ID, last.CreatedAt, last.UpdatedAt, last.Username.String)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"users":  responses,
-		"limit":  limit,
-		"offset": offset,
-		"count":  len(responses),
+		"users":       responses,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"count":       len(responses),
+		"total":       total,
+		"sort":        page.Sort,
+		"order":       page.order(),
+		"next_cursor": nextCursor,
 	})
 }
 
@@ -19,22 +19,38 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:5173", os.Getenv("FRONTEND_URL")}, // Add your frontend URL
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "If-Match", "If-None-Match"},
+		ExposeHeaders:    []string{"ETag"},
 		AllowCredentials: true, // Enable cookies/auth
 	}))
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(s.db.GetPool())
 
-	// Create notes handler with services (handle nil services gracefully)
-	var notesHandler *handlers.NotesHandler
-	if s.embeddingService != nil && s.flashcardService != nil {
-		notesHandler = handlers.NewNotesHandler(s.db.GetPool(), s.embeddingService, s.flashcardService)
-	} else {
-		log.Printf("Warning: Some services are not available, notes handler will have limited functionality")
-		// For now, we'll create a basic handler without the services
-		// You might want to create a fallback version of NewNotesHandler
-		log.Fatal("Embedding and flashcard services are required for notes handler")
+	// Notes CRUD works without the embedding and LLM services; the routes
+	// that need them are only registered when they are available
+	notesHandler := handlers.NewNotesHandler(s.db.GetPool(), s.embeddingService, s.flashcardService)
+	if s.embeddingService == nil {
+		log.Printf("Warning: Embedding service is not available, search, flashcard and chat routes are disabled")
+	} else if s.flashcardService == nil {
+		log.Printf("Warning: Flashcard service is not available, flashcard and chat routes are disabled")
+	}
+
+	deckHandler := handlers.NewDeckHandler(s.db.GetPool())
+	flashcardHandler := handlers.NewFlashcardHandler(s.db.GetPool())
+	revisionHandler := handlers.NewRevisionHandler(s.db.GetPool())
+	trashHandler := handlers.NewTrashHandler(s.db.GetPool())
+	notebookHandler := handlers.NewNotebookHandler(s.db.GetPool())
+	linkHandler := handlers.NewLinkHandler(s.db.GetPool())
+	graphHandler := handlers.NewGraphHandler(s.db.GetPool())
+	batchHandler := handlers.NewBatchHandler(s.db.GetPool())
+	tagHandler := handlers.NewTagHandler(s.db.GetPool())
+	importHandler := handlers.NewImportHandler(s.db.GetPool())
+	exportHandler := handlers.NewExportHandler(s.db.GetPool())
+
+	reviewHandler, err := handlers.NewReviewHandler(s.db.GetPool())
+	if err != nil {
+		log.Fatal("Failed to create review handler:", err)
 	}
 
 	oauthHandler, err := handlers.NewOAuthHandler(s.db.GetPool())
@@ -85,17 +101,102 @@ func (s *Server) RegisterRoutes() http.Handler {
 			notes.GET("/:id", notesHandler.GetNote)
 			notes.PUT("/:id", notesHandler.UpdateNote)
 			notes.DELETE("/:id", notesHandler.DeleteNote)
+			notes.POST("/batch", batchHandler.ApplyBatch)
+			notes.POST("/import", importHandler.CreateImport)
+			notes.GET("/imports", importHandler.ListImports)
+			notes.GET("/imports/:id", importHandler.GetImport)
+
+			// Trash endpoints
+			notes.GET("/trash", trashHandler.ListTrash)
+			notes.DELETE("/trash", trashHandler.EmptyTrash)
+			notes.POST("/trash/:id/restore", trashHandler.RestoreNote)
+			notes.DELETE("/trash/:id", trashHandler.PurgeNote)
+
+			// Version history endpoints
+			notes.GET("/:id/revisions", revisionHandler.ListRevisions)
+			notes.GET("/:id/revisions/:revision", revisionHandler.GetRevision)
+			notes.POST("/:id/revisions/:revision/restore", revisionHandler.RestoreRevision)
+			notes.GET("/:id/diff", revisionHandler.DiffRevisions)
+
+			// Links between notes
+			notes.GET("/:id/links", linkHandler.ListLinks)
+			notes.GET("/:id/backlinks", linkHandler.ListBacklinks)
+			notes.GET("/links/unresolved", linkHandler.ListUnresolvedLinks)
+			notes.GET("/graph", graphHandler.GetGraph)
+			notes.GET("/:id/related", notesHandler.GetRelatedNotes)
+
+			if s.embeddingService != nil {
+				// Semantic search endpoint
+				notes.POST("/search", notesHandler.SearchNotesByQuery)
+			}
 
-			// Semantic search endpoint
-			notes.POST("/search", notesHandler.SearchNotesByQuery)
-
-			// Flashcard generation endpoints
-			flashcard := notes.Group("/flashcard")
-			{
-				flashcard.POST("/query", notesHandler.StreamFlashcardFromQuery)
-				flashcard.POST("/notes", notesHandler.StreamFlashcardFromNotes)
+			if s.embeddingService != nil && s.flashcardService != nil {
+				// Flashcard generation endpoints
+				flashcard := notes.Group("/flashcard")
+				{
+					flashcard.POST("/query", notesHandler.StreamFlashcardFromQuery)
+					flashcard.POST("/notes", notesHandler.StreamFlashcardFromNotes)
+					flashcard.POST("/export", notesHandler.ExportFlashcards)
+				}
 			}
 		}
+
+		// Notebook routes (all protected, auth required)
+		notebooks := api.Group("/notebooks", auth.AuthMiddleware())
+		{
+			notebooks.GET("", notebookHandler.ListNotebooks)
+			notebooks.POST("", notebookHandler.CreateNotebook)
+			notebooks.GET("/:id", notebookHandler.GetNotebook)
+			notebooks.PUT("/:id", notebookHandler.UpdateNotebook)
+			notebooks.DELETE("/:id", notebookHandler.DeleteNotebook)
+			notebooks.GET("/:id/notes", notebookHandler.ListNotebookNotes)
+		}
+
+		// Tag routes (all protected, auth required)
+		tags := api.Group("/tags", auth.AuthMiddleware())
+		{
+			tags.GET("", tagHandler.ListTags)
+			tags.DELETE("", tagHandler.DeleteTag)
+			tags.POST("/rename", tagHandler.RenameTag)
+			tags.POST("/merge", tagHandler.MergeTags)
+		}
+
+		// Account export (protected, auth required)
+		api.GET("/export", auth.AuthMiddleware(), exportHandler.ExportAccount)
+
+		// Question answering over the user's notes (protected, auth required)
+		if s.embeddingService != nil && s.flashcardService != nil {
+			chatHandler := handlers.NewChatHandler(s.db.GetPool(), s.embeddingService, s.flashcardService)
+			api.POST("/chat", auth.AuthMiddleware(), chatHandler.Chat)
+		}
+
+		// Deck routes (all protected, auth required)
+		decks := api.Group("/decks", auth.AuthMiddleware())
+		{
+			decks.GET("", deckHandler.ListDecks)
+			decks.POST("", deckHandler.CreateDeck)
+			decks.GET("/:id", deckHandler.GetDeck)
+			decks.PUT("/:id", deckHandler.UpdateDeck)
+			decks.DELETE("/:id", deckHandler.DeleteDeck)
+			decks.GET("/:id/flashcards", deckHandler.ListDeckFlashcards)
+		}
+
+		// Saved flashcard routes (all protected, auth required)
+		flashcards := api.Group("/flashcards", auth.AuthMiddleware())
+		{
+			flashcards.POST("", flashcardHandler.CreateFlashcard)
+			flashcards.GET("/:id", flashcardHandler.GetFlashcard)
+			flashcards.PUT("/:id", flashcardHandler.UpdateFlashcard)
+			flashcards.DELETE("/:id", flashcardHandler.DeleteFlashcard)
+		}
+
+		// Spaced-repetition review routes (all protected, auth required)
+		review := api.Group("/review", auth.AuthMiddleware())
+		{
+			review.GET("/due", reviewHandler.GetDueCards)
+			review.POST("/:card_id", reviewHandler.ReviewCard)
+			review.GET("/:card_id/history", reviewHandler.GetReviewHistory)
+		}
 	}
 
 	return r
@@ -39,9 +39,32 @@ func NewServer() *http.Server {
 		// Continue without flashcard service for now
 	}
 
+	db := database.New()
+
+	// Refuse to start if the embedder and the notes.embedding column disagree on vector size
+	if embeddingService != nil {
+		if err := embeddingService.ValidateSchema(ctx, db.GetQueries()); err != nil {
+			log.Fatalf("Embedding schema check failed: %v", err)
+		}
+	}
+
+	// Segment notes saved before CJK-aware keyword search in the background
+	go func() {
+		if err := services.NewSearchService(db.GetPool(), embeddingService).BackfillSearchText(ctx); err != nil {
+			log.Printf("Warning: Failed to backfill search text: %v", err)
+		}
+	}()
+
+	// Index links of notes saved before links between notes were tracked
+	go func() {
+		if err := services.NewLinkService(db.GetPool()).BackfillLinks(ctx); err != nil {
+			log.Printf("Warning: Failed to backfill note links: %v", err)
+		}
+	}()
+
 	NewServer := &Server{
 		port:             port,
-		db:               database.New(),
+		db:               db,
 		embeddingService: embeddingService,
 		flashcardService: flashcardService,
 	}
@@ -55,5 +78,31 @@ func NewServer() *http.Server {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// Fill in note embeddings in the background until the server shuts down
+	if embeddingService != nil {
+		embeddingWorker, err := services.NewEmbeddingWorker(db.GetPool(), embeddingService)
+		if err != nil {
+			log.Fatalf("Failed to create embedding worker: %v", err)
+		}
+		workerCtx, stopWorker := context.WithCancel(ctx)
+		go embeddingWorker.Run(workerCtx)
+		server.RegisterOnShutdown(stopWorker)
+	}
+
+	// Run queued imports in the background, outside the request timeouts
+	importWorker := services.NewImportWorker(db.GetPool())
+	importCtx, stopImports := context.WithCancel(ctx)
+	go importWorker.Run(importCtx)
+	server.RegisterOnShutdown(stopImports)
+
+	// Permanently delete notes that outlived the trash retention period
+	trashPurger, err := services.NewTrashPurger(db.GetPool())
+	if err != nil {
+		log.Fatalf("Failed to create trash purger: %v", err)
+	}
+	purgerCtx, stopPurger := context.WithCancel(ctx)
+	go trashPurger.Run(purgerCtx)
+	server.RegisterOnShutdown(stopPurger)
+
 	return server
 }
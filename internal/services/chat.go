@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// chatNoAnswer is what the LLM must reply when the sources do not answer the question
+const chatNoAnswer = "NO_ANSWER"
+
+// chatRefusal is the answer sent instead when nothing relevant is found
+const chatRefusal = "我在你的筆記中找不到足夠相關的內容，無法回答這個問題。"
+
+// maxChatHistory is how many earlier messages of the conversation are sent to the LLM
+const maxChatHistory = 6
+
+// maxChatExcerptRunes is the length above which a paragraph of a passage is split into sentences
+const maxChatExcerptRunes = 400
+
+// chatCitationPattern matches citation markers such as [2] or [1, 3] in an answer
+var chatCitationPattern = regexp.MustCompile(`\[(\d+(?:\s*[,，]\s*\d+)*)\]`)
+
+// ChatService answers questions from the passages of a user's notes
+type ChatService struct {
+	llm llms.Model
+}
+
+// NewChatService creates a chat service backed by the given model
+func NewChatService(llm llms.Model) *ChatService {
+	return &ChatService{llm: llm}
+}
+
+// ChatMessage is an earlier message of the conversation
+type ChatMessage struct {
+	Role    string `json:"role"` // user or assistant
+	Content string `json:"content"`
+}
+
+// ChatSource is a numbered excerpt of a note the LLM may cite. Offsets are
+// character (rune) positions into the note content.
+type ChatSource struct {
+	Index       int    `json:"index"`
+	NoteID      string `json:"note_id"`
+	Title       string `json:"title"`
+	Quote       string `json:"quote"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+}
+
+// ChatClaim is a sentence of the answer with the sources it cites. Claims
+// without citations are not backed by the user's notes.
+type ChatClaim struct {
+	Text      string       `json:"text"`
+	Citations []ChatSource `json:"citations"`
+}
+
+// ChatAnswer is the complete answer to a question
+type ChatAnswer struct {
+	Answer  string      `json:"answer"`
+	Claims  []ChatClaim `json:"claims"`
+	Refused bool        `json:"refused"`
+}
+
+// BuildChatSources numbers the passages of search results as excerpts to cite.
+// Paragraphs become separate excerpts and long ones are split between
+// sentences, so a citation quotes only what supports a claim.
+func BuildChatSources(results []SearchResult) []ChatSource {
+	type span struct {
+		noteID string
+		start  int
+	}
+	seen := make(map[span]bool)
+
+	var sources []ChatSource
+	for _, result := range results {
+		noteID := result.ID.String()
+		for _, passage := range result.Passages {
+			for _, excerpt := range splitExcerpts(passage.Content) {
+				start := passage.StartOffset + excerpt[0]
+				if seen[span{noteID, start}] {
+					continue
+				}
+				seen[span{noteID, start}] = true
+
+				runes := []rune(passage.Content)
+				sources = append(sources, ChatSource{
+					Index:       len(sources) + 1,
+					NoteID:      noteID,
+					Title:       result.Title,
+					Quote:       string(runes[excerpt[0]:excerpt[1]]),
+					StartOffset: start,
+					EndOffset:   passage.StartOffset + excerpt[1],
+				})
+			}
+		}
+	}
+	return sources
+}
+
+// splitExcerpts returns the rune ranges of the paragraphs of text, with
+// surrounding whitespace trimmed. Paragraphs longer than
+// maxChatExcerptRunes are cut after the sentence end closest to the limit.
+func splitExcerpts(text string) [][2]int {
+	runes := []rune(text)
+
+	var excerpts [][2]int
+	add := func(start, end int) {
+		for start < end && unicode.IsSpace(runes[start]) {
+			start++
+		}
+		for end > start && unicode.IsSpace(runes[end-1]) {
+			end--
+		}
+		for end-start > maxChatExcerptRunes {
+			cut := start + maxChatExcerptRunes
+			for i := cut - 1; i > start; i-- {
+				if isSentenceEnd(runes[i]) {
+					cut = i + 1
+					break
+				}
+			}
+			excerpts = append(excerpts, [2]int{start, cut})
+			start = cut
+			for start < end && unicode.IsSpace(runes[start]) {
+				start++
+			}
+		}
+		if start < end {
+			excerpts = append(excerpts, [2]int{start, end})
+		}
+	}
+
+	start := 0
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '\n' {
+			continue
+		}
+		// A blank line ends the paragraph
+		j := i + 1
+		for j < len(runes) && runes[j] != '\n' && unicode.IsSpace(runes[j]) {
+			j++
+		}
+		if j < len(runes) && runes[j] == '\n' {
+			add(start, i)
+			start = j
+			i = j
+		}
+	}
+	add(start, len(runes))
+	return excerpts
+}
+
+// isSentenceEnd reports whether r ends a sentence
+func isSentenceEnd(r rune) bool {
+	switch r {
+	case '.', '!', '?', '。', '！', '？':
+		return true
+	}
+	return false
+}
+
+// sendEvent sends a message of the answer stream via SSE
+func (s *ChatService) sendEvent(responseChan chan<- string, response FlashcardStreamResponse) {
+	if jsonData, err := json.Marshal(response); err == nil {
+		responseChan <- fmt.Sprintf("data: %s\n\n", string(jsonData))
+	}
+}
+
+// StreamAnswer answers question from sources with SSE streaming. The answer
+// is streamed as chunk events and ends with a complete event carrying the
+// ChatAnswer with the claims and their citations. Without sources, or when
+// the LLM finds no answer in them, the complete event is a refusal.
+// The caller owns responseChan and is responsible for closing it once this returns.
+func (s *ChatService) StreamAnswer(ctx context.Context, question string, history []ChatMessage, sources []ChatSource, responseChan chan<- string) (*ChatAnswer, error) {
+	if strings.TrimSpace(question) == "" {
+		s.sendEvent(responseChan, FlashcardStreamResponse{Type: "error", Error: "問題不能為空"})
+		return nil, fmt.Errorf("question cannot be empty")
+	}
+	if len(sources) == 0 {
+		answer := &ChatAnswer{Answer: chatRefusal, Claims: []ChatClaim{}, Refused: true}
+		s.sendEvent(responseChan, FlashcardStreamResponse{Type: "complete", Data: answer})
+		return answer, nil
+	}
+
+	s.sendEvent(responseChan, FlashcardStreamResponse{Type: "sources", Data: sources})
+	s.sendEvent(responseChan, FlashcardStreamResponse{Type: "status", Data: StreamStatus{Stage: "generating", Description: "正在根據筆記回答...", Progress: 50}})
+
+	// Hold the output back while it could still be the no-answer reply, so
+	// that the marker never reaches the client
+	var full strings.Builder
+	sent := 0
+	_, err := s.llm.GenerateContent(ctx, buildChatMessages(question, history, sources), llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		full.WriteString(string(chunk))
+		text := full.String()
+		if strings.HasPrefix(chatNoAnswer, strings.TrimSpace(text)) {
+			return nil
+		}
+		s.sendEvent(responseChan, FlashcardStreamResponse{Type: "chunk", Message: text[sent:]})
+		sent = len(text)
+		return nil
+	}))
+	if err != nil {
+		s.sendEvent(responseChan, FlashcardStreamResponse{Type: "error", Error: fmt.Sprintf("回答失敗: %v", err)})
+		return nil, fmt.Errorf("failed to generate answer: %w", err)
+	}
+
+	text := strings.TrimSpace(full.String())
+	if text == "" || strings.HasPrefix(text, chatNoAnswer) {
+		answer := &ChatAnswer{Answer: chatRefusal, Claims: []ChatClaim{}, Refused: true}
+		s.sendEvent(responseChan, FlashcardStreamResponse{Type: "complete", Data: answer})
+		return answer, nil
+	}
+
+	// The model may not have streamed, or the whole answer was held back
+	if sent < len(full.String()) {
+		s.sendEvent(responseChan, FlashcardStreamResponse{Type: "chunk", Message: full.String()[sent:]})
+	}
+
+	answer := &ChatAnswer{Answer: text, Claims: parseChatClaims(text, sources)}
+	s.sendEvent(responseChan, FlashcardStreamResponse{Type: "complete", Data: answer})
+	return answer, nil
+}
+
+// buildChatMessages builds the conversation sent to the LLM: the
+// instructions with the numbered sources, the recent history and the question
+func buildChatMessages(question string, history []ChatMessage, sources []ChatSource) []llms.MessageContent {
+	var sourceText strings.Builder
+	for _, source := range sources {
+		sourceText.WriteString(fmt.Sprintf("[%d] 筆記「%s」（ID: %s）\n%s\n\n", source.Index, source.Title, source.NoteID, source.Quote))
+	}
+
+	system := fmt.Sprintf(`你是用戶的筆記助理，只能根據以下編號的筆記摘錄回答問題，不可使用其他知識。
+每一句陳述之後都要用方括號標註它所依據的摘錄編號，例如 [1] 或 [1, 3]。
+如果摘錄不足以回答問題，只回覆 %s，不要包含任何其他文字。
+用與問題相同的語言回答，簡潔且不要使用 markdown 標題。
+
+筆記摘錄：
+%s`, chatNoAnswer, sourceText.String())
+
+	messages := []llms.MessageContent{llms.TextParts(llms.ChatMessageTypeSystem, system)}
+	if len(history) > maxChatHistory {
+		history = history[len(history)-maxChatHistory:]
+	}
+	for _, message := range history {
+		role := llms.ChatMessageTypeHuman
+		if message.Role == "assistant" {
+			role = llms.ChatMessageTypeAI
+		}
+		messages = append(messages, llms.TextParts(role, message.Content))
+	}
+	return append(messages, llms.TextParts(llms.ChatMessageTypeHuman, question))
+}
+
+// parseChatClaims splits an answer into sentences and resolves the citation
+// markers of each. Markers right after a sentence end belong to that
+// sentence; markers of unknown sources are dropped.
+func parseChatClaims(answer string, sources []ChatSource) []ChatClaim {
+	byIndex := make(map[int]ChatSource, len(sources))
+	for _, source := range sources {
+		byIndex[source.Index] = source
+	}
+	markers := chatCitationPattern.FindAllStringSubmatchIndex(answer, -1)
+
+	claims := []ChatClaim{}
+	var text string
+	var cited []int
+	flush := func() {
+		claim := ChatClaim{Text: strings.TrimSpace(text), Citations: []ChatSource{}}
+		for _, index := range cited {
+			claim.Citations = append(claim.Citations, byIndex[index])
+		}
+		switch {
+		case claim.Text != "":
+			claims = append(claims, claim)
+		case len(claims) > 0:
+			// Markers on a line of their own back the previous sentence
+			last := &claims[len(claims)-1]
+			for _, citation := range claim.Citations {
+				if !slices.ContainsFunc(last.Citations, func(c ChatSource) bool { return c.Index == citation.Index }) {
+					last.Citations = append(last.Citations, citation)
+				}
+			}
+		}
+		text, cited = "", nil
+	}
+
+	ended := false
+	for i := 0; i < len(answer); {
+		if len(markers) > 0 && markers[0][0] == i {
+			for _, number := range strings.FieldsFunc(answer[markers[0][2]:markers[0][3]], func(r rune) bool {
+				return r == ',' || r == '，' || unicode.IsSpace(r)
+			}) {
+				index, err := strconv.Atoi(number)
+				if _, ok := byIndex[index]; err == nil && ok && !slices.Contains(cited, index) {
+					cited = append(cited, index)
+				}
+			}
+			text = strings.TrimRight(text, " ")
+			i = markers[0][1]
+			markers = markers[1:]
+			continue
+		}
+
+		r, size := utf8.DecodeRuneInString(answer[i:])
+		i += size
+		if r == '\n' {
+			flush()
+			ended = false
+			continue
+		}
+		if ended && !unicode.IsSpace(r) {
+			flush()
+			ended = false
+		}
+		text += string(r)
+		// A period only ends a sentence before a space, a marker or the end, not in 3.5
+		if isSentenceEnd(r) && (r != '.' || i == len(answer) || answer[i] == ' ' || answer[i] == '[' || answer[i] == '\n') {
+			ended = true
+		}
+	}
+	flush()
+	return claims
+}
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// scriptedLLM streams a fixed reply in small chunks
+type scriptedLLM struct {
+	reply string
+}
+
+func (m *scriptedLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+	if opts.StreamingFunc != nil {
+		for start := 0; start < len(m.reply); start += 3 {
+			if err := opts.StreamingFunc(ctx, []byte(m.reply[start:min(start+3, len(m.reply))])); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{Content: m.reply}}}, nil
+}
+
+func (m *scriptedLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+// collectChatEvents runs StreamAnswer and decodes the SSE messages it sent
+func collectChatEvents(t *testing.T, llm llms.Model, sources []ChatSource) (*ChatAnswer, []FlashcardStreamResponse) {
+	t.Helper()
+	responseChan := make(chan string, 1000)
+	answer, err := NewChatService(llm).StreamAnswer(context.Background(), "How do goroutines work?", nil, sources, responseChan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	close(responseChan)
+
+	var events []FlashcardStreamResponse
+	for message := range responseChan {
+		var event FlashcardStreamResponse
+		if err := json.Unmarshal([]byte(strings.TrimSuffix(strings.TrimPrefix(message, "data: "), "\n\n")), &event); err != nil {
+			t.Fatalf("invalid SSE message %q: %v", message, err)
+		}
+		events = append(events, event)
+	}
+	return answer, events
+}
+
+func streamedText(events []FlashcardStreamResponse) string {
+	var text strings.Builder
+	for _, event := range events {
+		if event.Type == "chunk" {
+			text.WriteString(event.Message)
+		}
+	}
+	return text.String()
+}
+
+func TestBuildChatSources(t *testing.T) {
+	results := []SearchResult{{
+		ID:    pgtype.UUID{Bytes: [16]byte{1}, Valid: true},
+		Title: "Go",
+		Passages: []SearchPassage{
+			{Content: "Goroutines are cheap.\n\n  Channels connect them.  ", StartOffset: 10},
+			{Content: "Channels connect them.", StartOffset: 35},
+		},
+	}}
+
+	got := BuildChatSources(results)
+	noteID := results[0].ID.String()
+	want := []ChatSource{
+		{Index: 1, NoteID: noteID, Title: "Go", Quote: "Goroutines are cheap.", StartOffset: 10, EndOffset: 31},
+		{Index: 2, NoteID: noteID, Title: "Go", Quote: "Channels connect them.", StartOffset: 35, EndOffset: 57},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected sources %+v, want %+v", got, want)
+	}
+}
+
+func TestSplitExcerptsCutsLongParagraphsAtSentences(t *testing.T) {
+	sentence := strings.Repeat("字", 149) + "。"
+	text := strings.Repeat(sentence, 3)
+
+	got := splitExcerpts(text)
+	want := [][2]int{{0, 300}, {300, 450}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected excerpts %v, want %v", got, want)
+	}
+}
+
+func TestParseChatClaims(t *testing.T) {
+	sources := []ChatSource{{Index: 1, NoteID: "a"}, {Index: 2, NoteID: "b"}}
+	answer := "Goroutines are cheap [1]. They cost about 2.5 KB.[1, 2] Nobody knows why [7].\n" +
+		"Channels connect them。[2]\n[1]"
+
+	got := parseChatClaims(answer, sources)
+	want := []ChatClaim{
+		{Text: "Goroutines are cheap.", Citations: []ChatSource{sources[0]}},
+		{Text: "They cost about 2.5 KB.", Citations: []ChatSource{sources[0], sources[1]}},
+		{Text: "Nobody knows why.", Citations: []ChatSource{}},
+		{Text: "Channels connect them。", Citations: []ChatSource{sources[1], sources[0]}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected claims %+v, want %+v", got, want)
+	}
+}
+
+func TestStreamAnswerCitesSources(t *testing.T) {
+	sources := []ChatSource{{Index: 1, NoteID: "note-1", Title: "Go", Quote: "Goroutines are cheap."}}
+
+	answer, events := collectChatEvents(t, NewFakeLLM(), sources)
+	if answer.Refused {
+		t.Fatalf("expected an answer, got a refusal")
+	}
+	if len(answer.Claims) != 1 || !reflect.DeepEqual(answer.Claims[0].Citations, sources) {
+		t.Errorf("expected one claim citing the source, got %+v", answer.Claims)
+	}
+	if got := streamedText(events); got != answer.Answer {
+		t.Errorf("streamed %q, answer is %q", got, answer.Answer)
+	}
+	if events[0].Type != "sources" || events[len(events)-1].Type != "complete" {
+		t.Errorf("expected sources first and complete last, got %s and %s", events[0].Type, events[len(events)-1].Type)
+	}
+}
+
+func TestStreamAnswerRefuses(t *testing.T) {
+	sources := []ChatSource{{Index: 1, NoteID: "note-1", Title: "Go", Quote: "Goroutines are cheap."}}
+
+	tests := map[string]struct {
+		llm     llms.Model
+		sources []ChatSource
+	}{
+		"no sources":   {NewFakeLLM(), nil},
+		"no answer":    {&scriptedLLM{reply: " NO_ANSWER"}, sources},
+		"empty answer": {&scriptedLLM{reply: ""}, sources},
+	}
+	for name, tt := range tests {
+		answer, events := collectChatEvents(t, tt.llm, tt.sources)
+		if !answer.Refused || answer.Answer != chatRefusal {
+			t.Errorf("%s: expected a refusal, got %+v", name, answer)
+		}
+		if text := streamedText(events); text != "" {
+			t.Errorf("%s: expected nothing streamed, got %q", name, text)
+		}
+	}
+}
+
+func TestStreamAnswerStreamsAnswerStartingLikeRefusal(t *testing.T) {
+	sources := []ChatSource{{Index: 1, NoteID: "note-1"}}
+
+	answer, events := collectChatEvents(t, &scriptedLLM{reply: "NO means no [1]."}, sources)
+	if answer.Refused {
+		t.Fatalf("expected an answer, got a refusal")
+	}
+	if got := streamedText(events); got != "NO means no [1]." {
+		t.Errorf("unexpected streamed text %q", got)
+	}
+}
+
+func TestBuildChatMessagesKeepsRecentHistory(t *testing.T) {
+	var history []ChatMessage
+	for i := range 10 {
+		role := "user"
+		if i%2 == 1 {
+			role = "assistant"
+		}
+		history = append(history, ChatMessage{Role: role, Content: string(rune('a' + i))})
+	}
+
+	messages := buildChatMessages("question", history, []ChatSource{{Index: 1, NoteID: "n", Title: "T", Quote: "q"}})
+	if len(messages) != maxChatHistory+2 {
+		t.Fatalf("expected %d messages, got %d", maxChatHistory+2, len(messages))
+	}
+	if messages[0].Role != llms.ChatMessageTypeSystem || !strings.Contains(messages[0].Parts[0].(llms.TextContent).Text, "[1] 筆記「T」（ID: n）\nq") {
+		t.Errorf("expected the sources in the system message, got %+v", messages[0])
+	}
+	if first := messages[1]; first.Role != llms.ChatMessageTypeHuman || first.Parts[0].(llms.TextContent).Text != "e" {
+		t.Errorf("expected history to start at the fifth message, got %+v", first)
+	}
+	if last := messages[len(messages)-1]; last.Parts[0].(llms.TextContent).Text != "question" {
+		t.Errorf("expected the question last, got %+v", last)
+	}
+}
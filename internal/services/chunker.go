@@ -0,0 +1,199 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Chunk is a passage of a note that gets its own embedding.
+// Offsets are character (rune) positions into the note content.
+type Chunk struct {
+	Index       int    `json:"chunk_index"`
+	Heading     string `json:"heading"`
+	Content     string `json:"content"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+}
+
+// MarkdownSplitter cuts notes into chunks along markdown structure.
+// Headings always start a new chunk; within a section paragraphs are packed
+// up to MaxTokens, and sections that are still too long are cut into
+// windows that share OverlapTokens with the previous chunk.
+type MarkdownSplitter struct {
+	MaxTokens     int
+	OverlapTokens int
+}
+
+// NewMarkdownSplitter creates a splitter with the given token budget and overlap
+func NewMarkdownSplitter(maxTokens, overlapTokens int) *MarkdownSplitter {
+	if maxTokens <= 0 {
+		maxTokens = 256
+	}
+	if overlapTokens < 0 || overlapTokens >= maxTokens {
+		overlapTokens = maxTokens / 8
+	}
+	return &MarkdownSplitter{MaxTokens: maxTokens, OverlapTokens: overlapTokens}
+}
+
+// markdownSection is the body of one heading, as rune offsets into the note
+type markdownSection struct {
+	heading string
+	start   int
+	end     int
+}
+
+// tokenSpan is the rune range of one estimated token
+type tokenSpan struct {
+	start int
+	end   int
+	// paragraphEnd marks the last token before a blank line, a preferred cut point
+	paragraphEnd bool
+}
+
+// Split returns the chunks of content. A note always yields at least one
+// chunk so that notes with an empty body can still be found by title.
+func (s *MarkdownSplitter) Split(content string) []Chunk {
+	runes := []rune(content)
+
+	var chunks []Chunk
+	for _, section := range splitMarkdownSections(runes) {
+		spans := tokenSpans(runes, section.start, section.end)
+		for _, window := range s.windows(spans) {
+			start, end := spans[window[0]].start, spans[window[1]-1].end
+			chunks = append(chunks, Chunk{
+				Index:       len(chunks),
+				Heading:     section.heading,
+				Content:     string(runes[start:end]),
+				StartOffset: start,
+				EndOffset:   end,
+			})
+		}
+	}
+
+	if len(chunks) == 0 {
+		chunks = append(chunks, Chunk{Content: strings.TrimSpace(content), EndOffset: len(runes)})
+	}
+	return chunks
+}
+
+// windows packs token spans into [start, end) index ranges within the budget
+func (s *MarkdownSplitter) windows(spans []tokenSpan) [][2]int {
+	var windows [][2]int
+	start := 0
+	for start < len(spans) {
+		end := start + s.MaxTokens
+		if end >= len(spans) {
+			windows = append(windows, [2]int{start, len(spans)})
+			break
+		}
+
+		// Prefer to stop at the last paragraph break that fits the budget
+		for i := end - 1; i > start; i-- {
+			if spans[i].paragraphEnd {
+				end = i + 1
+				break
+			}
+		}
+		windows = append(windows, [2]int{start, end})
+
+		next := end - s.OverlapTokens
+		if next <= start {
+			next = start + 1
+		}
+		start = next
+	}
+	return windows
+}
+
+// splitMarkdownSections splits runes at ATX headings outside fenced code blocks.
+// Each section's heading is the path of enclosing headings, e.g. "Setup > Docker".
+func splitMarkdownSections(runes []rune) []markdownSection {
+	var sections []markdownSection
+	var headingPath []string
+	var headingLevels []int
+
+	current := markdownSection{}
+	inFence := false
+
+	lineStart := 0
+	for lineStart <= len(runes) {
+		lineEnd := lineStart
+		for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+			lineEnd++
+		}
+		line := strings.TrimSpace(string(runes[lineStart:lineEnd]))
+
+		if strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~") {
+			inFence = !inFence
+		} else if level, title := parseHeading(line); !inFence && level > 0 {
+			current.end = lineStart
+			sections = append(sections, current)
+
+			for len(headingLevels) > 0 && headingLevels[len(headingLevels)-1] >= level {
+				headingLevels = headingLevels[:len(headingLevels)-1]
+				headingPath = headingPath[:len(headingPath)-1]
+			}
+			headingLevels = append(headingLevels, level)
+			headingPath = append(headingPath, title)
+
+			current = markdownSection{heading: strings.Join(headingPath, " > "), start: lineEnd}
+		}
+
+		lineStart = lineEnd + 1
+	}
+	current.end = len(runes)
+	sections = append(sections, current)
+
+	return sections
+}
+
+// parseHeading returns the level and text of an ATX heading line, or 0
+func parseHeading(line string) (int, string) {
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || (level < len(line) && line[level] != ' ') {
+		return 0, ""
+	}
+	return level, strings.TrimSpace(strings.TrimRight(line[level:], "#"))
+}
+
+// tokenSpans estimates tokens in runes[start:end]: every run of letters or
+// digits is one token, and every CJK character is a token of its own
+func tokenSpans(runes []rune, start, end int) []tokenSpan {
+	var spans []tokenSpan
+	newlines := 0
+	for i := start; i < end; {
+		r := runes[i]
+		switch {
+		case isCJK(r):
+			spans = append(spans, tokenSpan{start: i, end: i + 1})
+			i++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			j := i
+			for j < end && !isCJK(runes[j]) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j]) || runes[j] == '\'' || runes[j] == '_') {
+				j++
+			}
+			spans = append(spans, tokenSpan{start: i, end: j})
+			i = j
+		default:
+			if r == '\n' {
+				newlines++
+				if newlines == 2 && len(spans) > 0 {
+					spans[len(spans)-1].paragraphEnd = true
+				}
+			} else if !unicode.IsSpace(r) {
+				newlines = 0
+				if len(spans) > 0 {
+					// Keep trailing punctuation inside the passage
+					spans[len(spans)-1].end = i + 1
+				}
+			}
+			i++
+			continue
+		}
+		newlines = 0
+	}
+	return spans
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdownSplitterSections(t *testing.T) {
+	content := "Intro paragraph.\n\n# Setup\nInstall Go.\n\n## Docker\nRun `docker compose up`.\n\n```\n# not a heading\n```\n\n# Usage\n使用方法說明。"
+	chunks := NewMarkdownSplitter(100, 10).Split(content)
+
+	wantHeadings := []string{"", "Setup", "Setup > Docker", "Usage"}
+	if len(chunks) != len(wantHeadings) {
+		t.Fatalf("expected %d chunks, got %d: %+v", len(wantHeadings), len(chunks), chunks)
+	}
+
+	runes := []rune(content)
+	for i, chunk := range chunks {
+		if chunk.Heading != wantHeadings[i] {
+			t.Errorf("chunk %d: expected heading %q, got %q", i, wantHeadings[i], chunk.Heading)
+		}
+		if chunk.Index != i {
+			t.Errorf("chunk %d: unexpected index %d", i, chunk.Index)
+		}
+		if got := string(runes[chunk.StartOffset:chunk.EndOffset]); got != chunk.Content {
+			t.Errorf("chunk %d: offsets point at %q, content is %q", i, got, chunk.Content)
+		}
+	}
+
+	if !strings.Contains(chunks[2].Content, "# not a heading") {
+		t.Errorf("expected fenced code to stay in the Docker chunk, got %q", chunks[2].Content)
+	}
+	if chunks[3].Content != "使用方法說明。" {
+		t.Errorf("unexpected CJK chunk %q", chunks[3].Content)
+	}
+}
+
+func TestMarkdownSplitterBudgetAndOverlap(t *testing.T) {
+	words := make([]string, 50)
+	for i := range words {
+		words[i] = "word"
+	}
+	content := strings.Join(words, " ")
+
+	chunks := NewMarkdownSplitter(20, 5).Split(content)
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %d", len(chunks))
+	}
+	for i, chunk := range chunks {
+		if n := len(strings.Fields(chunk.Content)); n > 20 {
+			t.Errorf("chunk %d has %d tokens, over budget", i, n)
+		}
+	}
+	if chunks[1].StartOffset >= chunks[0].EndOffset {
+		t.Error("expected consecutive chunks to overlap")
+	}
+}
+
+func TestMarkdownSplitterPrefersParagraphBreaks(t *testing.T) {
+	content := "one two three four five six\n\nseven eight nine ten"
+	chunks := NewMarkdownSplitter(8, 0).Split(content)
+
+	if len(chunks) != 2 || chunks[0].Content != "one two three four five six" {
+		t.Fatalf("expected a cut at the paragraph break, got %+v", chunks)
+	}
+}
+
+func TestTokenSpansParagraphEnds(t *testing.T) {
+	tests := map[string]bool{
+		"a\n\nb":  true,
+		"a\n \nb": true,
+		"a.\n\nb": true,
+		"a\nb":    false,
+		"a\n-\nb": false,
+	}
+	for content, want := range tests {
+		runes := []rune(content)
+		spans := tokenSpans(runes, 0, len(runes))
+		if len(spans) == 0 || spans[0].paragraphEnd != want {
+			t.Errorf("tokenSpans(%q): expected paragraph end after the first token to be %v, got %+v", content, want, spans)
+		}
+	}
+}
+
+func TestMarkdownSplitterEmptyContent(t *testing.T) {
+	chunks := NewMarkdownSplitter(100, 10).Split("")
+	if len(chunks) != 1 || chunks[0].Content != "" {
+		t.Fatalf("expected a single empty chunk, got %+v", chunks)
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DeckService handles persisting generated flashcards into decks
+type DeckService struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewDeckService creates a new deck service
+func NewDeckService(db *pgxpool.Pool) *DeckService {
+	return &DeckService{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// SavedFlashcards describes the cards stored after a generation run
+type SavedFlashcards struct {
+	DeckID       string   `json:"deck_id"`
+	FlashcardIDs []string `json:"flashcard_ids"`
+}
+
+// SaveFlashcards stores generated flashcards in the given deck within a single transaction.
+// Each card references its own source note; cards without one fall back to sourceNoteIDs.
+func (s *DeckService) SaveFlashcards(ctx context.Context, userID, deckID pgtype.UUID, flashcards []Flashcard, sourceNoteIDs []pgtype.UUID) (*SavedFlashcards, error) {
+	if len(flashcards) == 0 {
+		return nil, fmt.Errorf("no flashcards to save")
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	// Make sure the deck exists and belongs to the user
+	if _, err := qtx.GetDeck(ctx, db_sqlc.GetDeckParams{ID: deckID, UserID: userID}); err != nil {
+		return nil, fmt.Errorf("deck not found: %w", err)
+	}
+
+	saved := &SavedFlashcards{
+		DeckID:       deckID.String(),
+		FlashcardIDs: make([]string, 0, len(flashcards)),
+	}
+	for _, flashcard := range flashcards {
+		tags := flashcard.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+
+		cardSources := sourceNoteIDs
+		var sourceUUID pgtype.UUID
+		if err := sourceUUID.Scan(flashcard.SourceNoteID); err == nil {
+			cardSources = []pgtype.UUID{sourceUUID}
+		}
+
+		card, err := qtx.CreateFlashcard(ctx, db_sqlc.CreateFlashcardParams{
+			DeckID:        deckID,
+			UserID:        userID,
+			Question:      flashcard.Question,
+			Answer:        flashcard.Answer,
+			Explanation:   flashcard.Explanation,
+			Difficulty:    flashcard.Difficulty,
+			Tags:          tags,
+			SourceNoteIds: cardSources,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to save flashcard: %w", err)
+		}
+		saved.FlashcardIDs = append(saved.FlashcardIDs, card.ID.String())
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit flashcards: %w", err)
+	}
+
+	return saved, nil
+}
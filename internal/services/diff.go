@@ -0,0 +1,180 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Diff operations
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffOp is a run of text that is kept, inserted or deleted between two versions
+type DiffOp struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// DiffLines compares two texts line by line
+func DiffLines(a, b string) []DiffOp {
+	return diffTokens(splitLines(a), splitLines(b))
+}
+
+// DiffWords compares two texts word by word. Whitespace runs are tokens of
+// their own and every CJK character is a word, since CJK text has no spaces.
+func DiffWords(a, b string) []DiffOp {
+	return diffTokens(splitWords(a), splitWords(b))
+}
+
+// splitLines splits text into lines that keep their trailing newline
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// splitWords splits text into words, whitespace runs, CJK characters and punctuation
+func splitWords(text string) []string {
+	var tokens []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		j := i + 1
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			for j < len(runes) && unicode.IsSpace(runes[j]) {
+				j++
+			}
+		case isWordRune(r):
+			for j < len(runes) && isWordRune(runes[j]) {
+				j++
+			}
+		}
+		tokens = append(tokens, string(runes[i:j]))
+		i = j
+	}
+	return tokens
+}
+
+// diffTokens finds a shortest edit script between a and b with Myers' algorithm
+// and merges adjacent tokens with the same operation
+func diffTokens(a, b []string) []DiffOp {
+	// Common prefixes and suffixes are cheap to strip and keep the search small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []DiffOp
+	ops = appendDiffOp(ops, DiffEqual, a[:prefix])
+	ops = append(ops, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	ops = appendDiffOp(ops, DiffEqual, a[len(a)-suffix:])
+	return mergeDiffOps(ops)
+}
+
+// maxDiffEdits bounds the work of myersDiff; texts that differ in more tokens
+// are reported as a wholesale replacement
+const maxDiffEdits = 1000
+
+// myersDiff returns the edit script of a and b, one op per token
+func myersDiff(a, b []string) []DiffOp {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	// trace[d] holds diagonals -d-1..d+1 of v as they were before round d
+	var trace [][]int
+
+search:
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return appendDiffOp(appendDiffOp(nil, DiffDelete, a), DiffInsert, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // step down: insert from b
+			} else {
+				x = v[offset+k-1] + 1 // step right: delete from a
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards from the end to recover the path
+	var reversed []DiffOp
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		base := d + 1 // position of diagonal 0 in v
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && v[base+k-1] < v[base+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[base+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffOp{Op: DiffEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, DiffOp{Op: DiffInsert, Text: b[y-1]})
+			} else {
+				reversed = append(reversed, DiffOp{Op: DiffDelete, Text: a[x-1]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	ops := make([]DiffOp, len(reversed))
+	for i, op := range reversed {
+		ops[len(reversed)-1-i] = op
+	}
+	return ops
+}
+
+// appendDiffOp appends one op per token
+func appendDiffOp(ops []DiffOp, op string, tokens []string) []DiffOp {
+	for _, token := range tokens {
+		ops = append(ops, DiffOp{Op: op, Text: token})
+	}
+	return ops
+}
+
+// mergeDiffOps joins consecutive ops of the same kind into one
+func mergeDiffOps(ops []DiffOp) []DiffOp {
+	merged := make([]DiffOp, 0, len(ops))
+	for _, op := range ops {
+		if n := len(merged); n > 0 && merged[n-1].Op == op.Op {
+			merged[n-1].Text += op.Text
+			continue
+		}
+		merged = append(merged, op)
+	}
+	return merged
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+// applyDiff rebuilds both versions from a diff
+func applyDiff(ops []DiffOp) (string, string) {
+	var before, after strings.Builder
+	for _, op := range ops {
+		if op.Op != DiffInsert {
+			before.WriteString(op.Text)
+		}
+		if op.Op != DiffDelete {
+			after.WriteString(op.Text)
+		}
+	}
+	return before.String(), after.String()
+}
+
+func TestDiffLines(t *testing.T) {
+	a := "# Title\nfirst line\nsecond line\nthird line\n"
+	b := "# Title\nfirst line\nchanged line\nthird line\nfourth line\n"
+
+	ops := DiffLines(a, b)
+	want := []DiffOp{
+		{Op: DiffEqual, Text: "# Title\nfirst line\n"},
+		{Op: DiffDelete, Text: "second line\n"},
+		{Op: DiffInsert, Text: "changed line\n"},
+		{Op: DiffEqual, Text: "third line\n"},
+		{Op: DiffInsert, Text: "fourth line\n"},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("expected %d ops, got %+v", len(want), ops)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("op %d = %+v, want %+v", i, ops[i], want[i])
+		}
+	}
+}
+
+func TestDiffWordsRoundTrip(t *testing.T) {
+	tests := [][2]string{
+		{"the quick brown fox", "the slow brown dog jumps"},
+		{"", "new note"},
+		{"old note", ""},
+		{"機器學習筆記", "深度學習筆記"},
+		{"same", "same"},
+	}
+	for _, tt := range tests {
+		before, after := applyDiff(DiffWords(tt[0], tt[1]))
+		if before != tt[0] || after != tt[1] {
+			t.Errorf("diff of %q -> %q rebuilt %q -> %q", tt[0], tt[1], before, after)
+		}
+	}
+}
+
+func TestDiffWordsGranularity(t *testing.T) {
+	ops := DiffWords("the quick brown fox", "the slow brown fox")
+	want := []DiffOp{
+		{Op: DiffEqual, Text: "the "},
+		{Op: DiffDelete, Text: "quick"},
+		{Op: DiffInsert, Text: "slow"},
+		{Op: DiffEqual, Text: " brown fox"},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("expected %d ops, got %+v", len(want), ops)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("op %d = %+v, want %+v", i, ops[i], want[i])
+		}
+	}
+}
+
+func TestDiffFallsBackOnLargeRewrites(t *testing.T) {
+	var a, b strings.Builder
+	for i := 0; i < maxDiffEdits; i++ {
+		a.WriteString("old\n")
+		b.WriteString("new\n")
+	}
+
+	ops := DiffLines(a.String(), b.String())
+	if len(ops) != 2 || ops[0].Op != DiffDelete || ops[1].Op != DiffInsert {
+		t.Fatalf("expected a wholesale replacement, got %d ops", len(ops))
+	}
+	if before, after := applyDiff(ops); before != a.String() || after != b.String() {
+		t.Error("expected the replacement to rebuild both versions")
+	}
+}
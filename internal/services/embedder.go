@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/google/generative-ai-go/genai"
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+	"google.golang.org/api/option"
+)
+
+// Embedder turns texts into fixed-length vectors
+type Embedder interface {
+	Name() string
+	Dimension() int
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbedderConfig selects and configures the embedding backend
+type EmbedderConfig struct {
+	Provider  string // google, openai, ollama or local
+	Model     string // provider-specific model name
+	BaseURL   string // endpoint for OpenAI-compatible and Ollama servers
+	APIKey    string // credentials for hosted providers
+	Dimension int    // expected vector length, 0 lets the provider report it
+}
+
+// googleEmbeddingDimension is the vector length of Gemini's text embedding models
+const googleEmbeddingDimension = 768
+
+// embedderProvider builds an embedder from config and supplies its defaults
+type embedderProvider struct {
+	defaultModel     string
+	defaultDimension int
+	apiKeyEnv        string
+	build            func(ctx context.Context, cfg EmbedderConfig) (Embedder, error)
+}
+
+// embedderProviders holds the available embedding backends by name
+var embedderProviders = map[string]embedderProvider{
+	"google": {
+		defaultModel:     "text-embedding-004",
+		defaultDimension: googleEmbeddingDimension,
+		apiKeyEnv:        "GOOGLE_API_KEY",
+		build: func(ctx context.Context, cfg EmbedderConfig) (Embedder, error) {
+			if cfg.APIKey == "" {
+				return nil, fmt.Errorf("GOOGLE_API_KEY environment variable is required")
+			}
+			// The Gemini client cannot request another output size
+			if cfg.Dimension != googleEmbeddingDimension {
+				return nil, fmt.Errorf("the google embedder only produces %d-dimensional vectors, got EMBEDDING_DIMENSION %d", googleEmbeddingDimension, cfg.Dimension)
+			}
+			client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.APIKey))
+			if err != nil {
+				return nil, err
+			}
+			return &googleEmbedder{client: client, model: cfg.Model, dimension: cfg.Dimension}, nil
+		},
+	},
+	"openai": {
+		defaultModel: "text-embedding-3-small",
+		apiKeyEnv:    "OPENAI_API_KEY",
+		build: func(ctx context.Context, cfg EmbedderConfig) (Embedder, error) {
+			// Local OpenAI-compatible servers often do not require a key, but the client does
+			apiKey := cfg.APIKey
+			if apiKey == "" {
+				apiKey = "not-needed"
+			}
+			opts := []openai.Option{openai.WithToken(apiKey), openai.WithEmbeddingModel(cfg.Model)}
+			if cfg.BaseURL != "" {
+				opts = append(opts, openai.WithBaseURL(cfg.BaseURL))
+			}
+			client, err := openai.New(opts...)
+			if err != nil {
+				return nil, err
+			}
+			return newClientEmbedder(ctx, "openai", client, cfg.Dimension)
+		},
+	},
+	"ollama": {
+		defaultModel: "nomic-embed-text",
+		build: func(ctx context.Context, cfg EmbedderConfig) (Embedder, error) {
+			opts := []ollama.Option{ollama.WithModel(cfg.Model)}
+			if cfg.BaseURL != "" {
+				opts = append(opts, ollama.WithServerURL(cfg.BaseURL))
+			}
+			client, err := ollama.New(opts...)
+			if err != nil {
+				return nil, err
+			}
+			return newClientEmbedder(ctx, "ollama", client, cfg.Dimension)
+		},
+	},
+	"local": {
+		defaultModel:     "hashing",
+		defaultDimension: 768,
+		build: func(ctx context.Context, cfg EmbedderConfig) (Embedder, error) {
+			return NewLocalEmbedder(cfg.Dimension), nil
+		},
+	},
+}
+
+// LoadEmbedderConfig reads the embedding configuration from the environment.
+// EMBEDDING_PROVIDER defaults to google so existing deployments keep working.
+func LoadEmbedderConfig() (EmbedderConfig, error) {
+	cfg := EmbedderConfig{
+		Provider: strings.ToLower(os.Getenv("EMBEDDING_PROVIDER")),
+		Model:    os.Getenv("EMBEDDING_MODEL"),
+		BaseURL:  os.Getenv("EMBEDDING_BASE_URL"),
+		APIKey:   os.Getenv("EMBEDDING_API_KEY"),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = "google"
+	}
+
+	provider, ok := embedderProviders[cfg.Provider]
+	if !ok {
+		return cfg, fmt.Errorf("unknown embedding provider %q, expected one of: %s", cfg.Provider, strings.Join(embedderProviderNames(), ", "))
+	}
+
+	if cfg.Model == "" {
+		cfg.Model = provider.defaultModel
+	}
+	if cfg.APIKey == "" && provider.apiKeyEnv != "" {
+		cfg.APIKey = os.Getenv(provider.apiKeyEnv)
+	}
+
+	cfg.Dimension = provider.defaultDimension
+	if dimension := os.Getenv("EMBEDDING_DIMENSION"); dimension != "" {
+		parsed, err := strconv.Atoi(dimension)
+		if err != nil || parsed <= 0 {
+			return cfg, fmt.Errorf("invalid EMBEDDING_DIMENSION %q", dimension)
+		}
+		cfg.Dimension = parsed
+	}
+
+	return cfg, nil
+}
+
+// NewEmbedder creates the embedder described by cfg
+func NewEmbedder(ctx context.Context, cfg EmbedderConfig) (Embedder, error) {
+	provider, ok := embedderProviders[cfg.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown embedding provider %q", cfg.Provider)
+	}
+
+	embedder, err := provider.build(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s embedder: %w", cfg.Provider, err)
+	}
+	return embedder, nil
+}
+
+// embedderProviderNames returns the registered provider names in a stable order
+func embedderProviderNames() []string {
+	names := make([]string, 0, len(embedderProviders))
+	for name := range embedderProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// googleEmbedder embeds texts with the Gemini embedding API
+type googleEmbedder struct {
+	client    *genai.Client
+	model     string
+	dimension int
+}
+
+func (e *googleEmbedder) Name() string   { return "google" }
+func (e *googleEmbedder) Dimension() int { return e.dimension }
+
+// Close releases the underlying Gemini client
+func (e *googleEmbedder) Close() error {
+	return e.client.Close()
+}
+
+// Embed generates embeddings for all texts in a single batch request
+func (e *googleEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	em := e.client.EmbeddingModel(e.model)
+	batch := em.NewBatch()
+	for _, text := range texts {
+		batch.AddContent(genai.Text(text))
+	}
+
+	res, err := em.BatchEmbedContents(ctx, batch)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings := make([][]float32, 0, len(res.Embeddings))
+	for _, embedding := range res.Embeddings {
+		embeddings = append(embeddings, embedding.Values)
+	}
+	return embeddings, nil
+}
+
+// embeddingClient is implemented by the langchaingo OpenAI and Ollama clients
+type embeddingClient interface {
+	CreateEmbedding(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// clientEmbedder adapts a langchaingo client to the Embedder interface
+type clientEmbedder struct {
+	name      string
+	client    embeddingClient
+	dimension int
+}
+
+// newClientEmbedder wraps client and, when no dimension is configured,
+// asks the provider for one embedding to learn its vector length
+func newClientEmbedder(ctx context.Context, name string, client embeddingClient, dimension int) (*clientEmbedder, error) {
+	if dimension == 0 {
+		probe, err := client.CreateEmbedding(ctx, []string{"dimension probe"})
+		if err != nil {
+			return nil, fmt.Errorf("failed to probe embedding dimension: %w", err)
+		}
+		if len(probe) == 0 || len(probe[0]) == 0 {
+			return nil, fmt.Errorf("provider returned an empty probe embedding")
+		}
+		dimension = len(probe[0])
+	}
+
+	return &clientEmbedder{name: name, client: client, dimension: dimension}, nil
+}
+
+func (e *clientEmbedder) Name() string   { return e.name }
+func (e *clientEmbedder) Dimension() int { return e.dimension }
+
+// Embed generates embeddings through the wrapped client
+func (e *clientEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	return e.client.CreateEmbedding(ctx, texts)
+}
+
+// LocalEmbedder is a deterministic, offline bag-of-words embedder.
+// Tokens are hashed into signed buckets and the result is L2-normalized, so
+// texts sharing words (or CJK characters) get a positive cosine similarity.
+type LocalEmbedder struct {
+	dimension int
+}
+
+// NewLocalEmbedder creates a hashing embedder producing vectors of the given length
+func NewLocalEmbedder(dimension int) *LocalEmbedder {
+	if dimension <= 0 {
+		dimension = 768
+	}
+	return &LocalEmbedder{dimension: dimension}
+}
+
+func (e *LocalEmbedder) Name() string   { return "local" }
+func (e *LocalEmbedder) Dimension() int { return e.dimension }
+
+// Embed hashes the tokens of every text into a vector
+func (e *LocalEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i, text := range texts {
+		embeddings[i] = e.embed(text)
+	}
+	return embeddings, nil
+}
+
+func (e *LocalEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimension)
+	for _, token := range localEmbedderTokens(text) {
+		h := fnv.New64a()
+		h.Write([]byte(token))
+		sum := h.Sum64()
+
+		bucket := int(sum % uint64(e.dimension))
+		if sum&(1<<63) != 0 {
+			vector[bucket]--
+		} else {
+			vector[bucket]++
+		}
+	}
+
+	var norm float64
+	for _, v := range vector {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		// Give empty or punctuation-only text a valid unit vector
+		vector[0] = 1
+		return vector
+	}
+
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range vector {
+		vector[i] *= scale
+	}
+	return vector
+}
+
+// localEmbedderTokens splits text into lowercased words, plus single
+// characters and bigrams for CJK runs which have no spaces between words
+func localEmbedderTokens(text string) []string {
+	var tokens []string
+	var word []rune
+	var cjk []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushCJK := func() {
+		for i := range cjk {
+			tokens = append(tokens, string(cjk[i]))
+			if i+1 < len(cjk) {
+				tokens = append(tokens, string(cjk[i:i+2]))
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+
+	return tokens
+}
+
+// isCJK reports whether r is a Han, Hiragana, Katakana or Hangul character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+)
+
+func dotProduct(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestLocalEmbedderDeterministicAndNormalized(t *testing.T) {
+	embedder := NewLocalEmbedder(256)
+
+	first, err := embedder.Embed(context.Background(), []string{"Goroutines are lightweight threads", ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := embedder.Embed(context.Background(), []string{"Goroutines are lightweight threads"})
+
+	for i, embedding := range first {
+		if len(embedding) != 256 {
+			t.Fatalf("embedding %d: expected dimension 256, got %d", i, len(embedding))
+		}
+		if norm := math.Sqrt(dotProduct(embedding, embedding)); math.Abs(norm-1) > 1e-5 {
+			t.Errorf("embedding %d: expected unit length, got %f", i, norm)
+		}
+	}
+	for i := range first[0] {
+		if first[0][i] != second[0][i] {
+			t.Fatal("expected identical text to produce identical embeddings")
+		}
+	}
+}
+
+func TestLocalEmbedderSimilarity(t *testing.T) {
+	embedder := NewLocalEmbedder(768)
+	embeddings, _ := embedder.Embed(context.Background(), []string{
+		"go channels and goroutines",
+		"goroutines communicate over channels",
+		"postgres vacuum tuning",
+		"機器學習模型",
+		"深度學習模型訓練",
+	})
+
+	if dotProduct(embeddings[0], embeddings[1]) <= dotProduct(embeddings[0], embeddings[2]) {
+		t.Error("expected texts sharing words to be more similar")
+	}
+	if dotProduct(embeddings[3], embeddings[4]) <= dotProduct(embeddings[3], embeddings[2]) {
+		t.Error("expected CJK texts sharing characters to be more similar")
+	}
+}
+
+func TestLoadEmbedderConfig(t *testing.T) {
+	t.Setenv("EMBEDDING_PROVIDER", "Local")
+	t.Setenv("EMBEDDING_MODEL", "")
+	t.Setenv("EMBEDDING_DIMENSION", "384")
+
+	cfg, err := LoadEmbedderConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	embedder, err := NewEmbedder(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if embedder.Name() != "local" || embedder.Dimension() != 384 {
+		t.Errorf("unexpected embedder %s with dimension %d", embedder.Name(), embedder.Dimension())
+	}
+
+	t.Setenv("EMBEDDING_DIMENSION", "-1")
+	if _, err := LoadEmbedderConfig(); err == nil {
+		t.Error("expected error for invalid dimension")
+	}
+
+	t.Setenv("EMBEDDING_PROVIDER", "mystery")
+	t.Setenv("EMBEDDING_DIMENSION", "")
+	if _, err := LoadEmbedderConfig(); err == nil {
+		t.Error("expected error for unknown provider")
+	}
+}
+
+func TestGoogleEmbedderRejectsOtherDimensions(t *testing.T) {
+	_, err := NewEmbedder(context.Background(), EmbedderConfig{Provider: "google", Model: "text-embedding-004", APIKey: "key", Dimension: 1536})
+	if err == nil {
+		t.Fatal("expected an error for a dimension the google embedder cannot produce")
+	}
+}
+
+func TestEmbeddingServiceRejectsWrongDimension(t *testing.T) {
+	service := NewEmbeddingServiceWithEmbedder(&fixedEmbedder{dimension: 4, vector: []float32{1, 0}})
+	if _, err := service.GenerateEmbedding(context.Background(), "text"); err == nil {
+		t.Fatal("expected dimension mismatch error")
+	}
+}
+
+type fixedEmbedder struct {
+	dimension int
+	vector    []float32
+}
+
+func (e *fixedEmbedder) Name() string   { return "fixed" }
+func (e *fixedEmbedder) Dimension() int { return e.dimension }
+func (e *fixedEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	embeddings := make([][]float32, len(texts))
+	for i := range texts {
+		embeddings[i] = e.vector
+	}
+	return embeddings, nil
+}
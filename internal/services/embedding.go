@@ -3,70 +3,176 @@ package services
 import (
 	"context"
 	"fmt"
-	"os"
+	"io"
+	"log"
+	"math"
+
+	db_sqlc "go-note/internal/db_sqlc"
 
-	"github.com/google/generative-ai-go/genai"
 	_ "github.com/joho/godotenv/autoload"
-	"google.golang.org/api/option"
 )
 
-// EmbeddingService handles text embedding operations using Google AI
+// maxEmbeddingBatch is the most texts sent to the provider in one call
+const maxEmbeddingBatch = 100
+
+// EmbeddingService handles text embedding operations through a pluggable Embedder
 type EmbeddingService struct {
-	client *genai.Client
+	embedder Embedder
 }
 
-// NewEmbeddingService creates a new embedding service
+// NewEmbeddingService creates a new embedding service using the provider configured in the environment
 func NewEmbeddingService(ctx context.Context) (*EmbeddingService, error) {
-	apiKey := os.Getenv("GOOGLE_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GOOGLE_API_KEY environment variable is required")
+	cfg, err := LoadEmbedderConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
+	embedder, err := NewEmbedder(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create genai client: %w", err)
+		return nil, err
 	}
 
+	log.Printf("Embedding service using %s embedder (dimension: %d)", embedder.Name(), embedder.Dimension())
+	return NewEmbeddingServiceWithEmbedder(embedder), nil
+}
+
+// NewEmbeddingServiceWithEmbedder creates an embedding service backed by the given embedder
+func NewEmbeddingServiceWithEmbedder(embedder Embedder) *EmbeddingService {
 	return &EmbeddingService{
-		client: client,
-	}, nil
+		embedder: embedder,
+	}
 }
 
 // Close closes the embedding service client
 func (s *EmbeddingService) Close() error {
-	return s.client.Close()
+	if closer, ok := s.embedder.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Dimension returns the length of the vectors produced by the embedder
+func (s *EmbeddingService) Dimension() int {
+	return s.embedder.Dimension()
+}
+
+// ValidateSchema checks that the embedder's dimension matches the vector
+// columns it fills, notes.embedding and note_chunks.embedding
+func (s *EmbeddingService) ValidateSchema(ctx context.Context, queries *db_sqlc.Queries) error {
+	columns := []struct {
+		name      string
+		dimension func(context.Context) (int32, error)
+	}{
+		{"notes.embedding", queries.GetNoteEmbeddingDimension},
+		{"note_chunks.embedding", queries.GetNoteChunkEmbeddingDimension},
+	}
+	for _, column := range columns {
+		columnDimension, err := column.dimension(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read %s dimension: %w", column.name, err)
+		}
+
+		if int(columnDimension) != s.embedder.Dimension() {
+			return fmt.Errorf("%s embedder produces %d-dimensional vectors but %s is VECTOR(%d); adjust EMBEDDING_DIMENSION or migrate the column",
+				s.embedder.Name(), s.embedder.Dimension(), column.name, columnDimension)
+		}
+	}
+	return nil
 }
 
 // GenerateEmbedding generates an embedding vector for the given text
-// Uses the text-embedding-004 model which produces 768-dimensional vectors
 func (s *EmbeddingService) GenerateEmbedding(ctx context.Context, text string) ([]float32, error) {
 	if text == "" {
 		return nil, fmt.Errorf("text cannot be empty")
 	}
 
-	// Use the text-embedding-004 model for consistent 768-dimensional embeddings
-	em := s.client.EmbeddingModel("text-embedding-004")
-
-	res, err := em.EmbedContent(ctx, genai.Text(text))
+	embeddings, err := s.GenerateEmbeddings(ctx, []string{text})
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate embedding: %w", err)
+		return nil, err
+	}
+
+	return embeddings[0], nil
+}
+
+// GenerateEmbeddings generates embeddings for several texts, batching provider calls
+func (s *EmbeddingService) GenerateEmbeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
 	}
 
-	if res.Embedding == nil || len(res.Embedding.Values) == 0 {
-		return nil, fmt.Errorf("empty embedding response")
+	embeddings := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbeddingBatch {
+		end := min(start+maxEmbeddingBatch, len(texts))
+		batch, err := s.embedder.Embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate embedding: %w", err)
+		}
+		embeddings = append(embeddings, batch...)
 	}
 
-	return res.Embedding.Values, nil
+	if len(embeddings) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(embeddings))
+	}
+	for _, embedding := range embeddings {
+		if len(embedding) == 0 {
+			return nil, fmt.Errorf("empty embedding response")
+		}
+		if len(embedding) != s.embedder.Dimension() {
+			return nil, fmt.Errorf("expected %d-dimensional embedding, got %d", s.embedder.Dimension(), len(embedding))
+		}
+	}
+
+	return embeddings, nil
+}
+
+// NoteEmbeddingText formats a note for embedding by combining title and content
+func NoteEmbeddingText(title, content string) string {
+	return fmt.Sprintf("Title: %s\n\nContent: %s", title, content)
+}
+
+// ChunkEmbeddingText formats a note chunk for embedding, keeping the note title
+// and section heading so short passages retain their context
+func ChunkEmbeddingText(title string, chunk Chunk) string {
+	if chunk.Heading == "" {
+		return NoteEmbeddingText(title, chunk.Content)
+	}
+	return fmt.Sprintf("Title: %s\n\nSection: %s\n\nContent: %s", title, chunk.Heading, chunk.Content)
 }
 
 // GenerateNoteEmbedding generates an embedding for a note by combining title and content
 func (s *EmbeddingService) GenerateNoteEmbedding(ctx context.Context, title, content string) ([]float32, error) {
-	// Combine title and content with proper formatting for better embeddings
-	combinedText := fmt.Sprintf("Title: %s\n\nContent: %s", title, content)
-	return s.GenerateEmbedding(ctx, combinedText)
+	return s.GenerateEmbedding(ctx, NoteEmbeddingText(title, content))
 }
 
 // GenerateQueryEmbedding generates an embedding for a search query
 func (s *EmbeddingService) GenerateQueryEmbedding(ctx context.Context, query string) ([]float32, error) {
 	return s.GenerateEmbedding(ctx, query)
 }
+
+// MeanEmbedding averages embeddings into a single unit-length vector
+func MeanEmbedding(embeddings [][]float32) []float32 {
+	if len(embeddings) == 0 {
+		return nil
+	}
+
+	mean := make([]float32, len(embeddings[0]))
+	for _, embedding := range embeddings {
+		for i, v := range embedding {
+			mean[i] += v
+		}
+	}
+
+	var norm float64
+	for _, v := range mean {
+		norm += float64(v) * float64(v)
+	}
+	if norm == 0 {
+		return mean
+	}
+
+	scale := float32(1 / math.Sqrt(norm))
+	for i := range mean {
+		mean[i] *= scale
+	}
+	return mean
+}
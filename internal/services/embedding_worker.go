@@ -0,0 +1,384 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/pgvector/pgvector-go"
+)
+
+// embeddingJobsPerWorker is the most jobs one worker takes from a claim
+const embeddingJobsPerWorker = 10
+
+// Note embedding states stored in notes.embedding_status
+const (
+	EmbeddingStatusPending = "pending"
+	EmbeddingStatusReady   = "ready"
+	EmbeddingStatusFailed  = "failed"
+)
+
+// EmbeddingWorker fills in note embeddings from the embedding_jobs outbox.
+// Each note is split into chunks that are embedded individually; the note's
+// own embedding is the mean of its chunk embeddings. Workers claim several
+// jobs at once and embed their chunks together, up to maxEmbeddingBatch texts
+// per provider call. Failed jobs are retried with exponential backoff and
+// dead-lettered after maxAttempts, which marks the note's embedding as failed.
+type EmbeddingWorker struct {
+	queries          *db_sqlc.Queries
+	db               *pgxpool.Pool
+	embeddingService *EmbeddingService
+	splitter         *MarkdownSplitter
+	concurrency      int
+	maxAttempts      int
+	pollInterval     time.Duration
+	baseBackoff      time.Duration
+	maxBackoff       time.Duration
+	staleAfter       time.Duration
+	jobTimeout       time.Duration
+}
+
+// NewEmbeddingWorker creates a worker pool sized by EMBEDDING_WORKERS (default 2)
+// that gives up on a note after EMBEDDING_MAX_ATTEMPTS (default 5) failures.
+// Chunks hold up to EMBEDDING_CHUNK_TOKENS (default 256) tokens and overlap by
+// EMBEDDING_CHUNK_OVERLAP (default 32).
+func NewEmbeddingWorker(db *pgxpool.Pool, embeddingService *EmbeddingService) (*EmbeddingWorker, error) {
+	concurrency, err := positiveIntEnv("EMBEDDING_WORKERS", 2)
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts, err := positiveIntEnv("EMBEDDING_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+	chunkTokens, err := positiveIntEnv("EMBEDDING_CHUNK_TOKENS", 256)
+	if err != nil {
+		return nil, err
+	}
+	chunkOverlap, err := nonNegativeIntEnv("EMBEDDING_CHUNK_OVERLAP", 32)
+	if err != nil {
+		return nil, err
+	}
+	if chunkOverlap >= chunkTokens {
+		return nil, fmt.Errorf("EMBEDDING_CHUNK_OVERLAP must be smaller than EMBEDDING_CHUNK_TOKENS")
+	}
+
+	return &EmbeddingWorker{
+		queries:          db_sqlc.New(db),
+		db:               db,
+		embeddingService: embeddingService,
+		splitter:         NewMarkdownSplitter(chunkTokens, chunkOverlap),
+		concurrency:      concurrency,
+		maxAttempts:      maxAttempts,
+		pollInterval:     2 * time.Second,
+		baseBackoff:      10 * time.Second,
+		maxBackoff:       30 * time.Minute,
+		staleAfter:       5 * time.Minute,
+		jobTimeout:       time.Minute,
+	}, nil
+}
+
+// positiveIntEnv reads a positive integer from the environment
+func positiveIntEnv(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed <= 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return parsed, nil
+}
+
+// nonNegativeIntEnv reads a non-negative integer from the environment
+func nonNegativeIntEnv(key string, fallback int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < 0 {
+		return 0, fmt.Errorf("invalid %s %q", key, value)
+	}
+	return parsed, nil
+}
+
+// Run claims and processes jobs until ctx is cancelled.
+// Jobs claimed but not finished at shutdown are reclaimed once they go stale.
+func (w *EmbeddingWorker) Run(ctx context.Context) {
+	log.Printf("Embedding worker started with %d workers", w.concurrency)
+
+	jobs := make(chan []db_sqlc.ClaimEmbeddingJobsRow)
+	var wg sync.WaitGroup
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for group := range jobs {
+				w.process(ctx, group)
+			}
+		}()
+	}
+
+	defer func() {
+		close(jobs)
+		wg.Wait()
+		log.Println("Embedding worker stopped")
+	}()
+
+	batchSize := w.concurrency * embeddingJobsPerWorker
+	for {
+		claimed, err := w.queries.ClaimEmbeddingJobs(ctx, db_sqlc.ClaimEmbeddingJobsParams{
+			StaleBefore: pgtype.Timestamptz{Time: time.Now().Add(-w.staleAfter), Valid: true},
+			BatchSize:   int32(batchSize),
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Failed to claim embedding jobs: %v", err)
+		}
+
+		for start := 0; start < len(claimed); start += embeddingJobsPerWorker {
+			end := min(start+embeddingJobsPerWorker, len(claimed))
+			select {
+			case jobs <- claimed[start:end]:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		// Keep draining while there is a backlog, otherwise wait for new work
+		if len(claimed) == batchSize {
+			continue
+		}
+		select {
+		case <-time.After(w.pollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// pendingEmbedding is a claimed job whose note has been split into chunks
+type pendingEmbedding struct {
+	job    db_sqlc.ClaimEmbeddingJobsRow
+	chunks []Chunk
+	texts  []string
+}
+
+// process generates and stores the chunk embeddings for a group of jobs,
+// sending the chunks of several notes to the provider together
+func (w *EmbeddingWorker) process(ctx context.Context, jobs []db_sqlc.ClaimEmbeddingJobsRow) {
+	var pending []pendingEmbedding
+	for _, job := range jobs {
+		if p, ok := w.prepare(ctx, job); ok {
+			pending = append(pending, p)
+		}
+	}
+
+	for _, batch := range packEmbeddingBatches(pending, maxEmbeddingBatch) {
+		w.embed(ctx, batch)
+	}
+}
+
+// prepare loads and splits the note of a job. Jobs whose note is gone are
+// completed and failed jobs rescheduled, neither is returned.
+func (w *EmbeddingWorker) prepare(ctx context.Context, job db_sqlc.ClaimEmbeddingJobsRow) (pendingEmbedding, bool) {
+	ctx, cancel := context.WithTimeout(ctx, w.jobTimeout)
+	defer cancel()
+
+	note, err := w.queries.GetNote(ctx, job.NoteID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The note is gone, nothing left to embed
+		if _, err := w.queries.CompleteEmbeddingJob(ctx, db_sqlc.CompleteEmbeddingJobParams{ID: job.ID, Generation: job.Generation}); err != nil {
+			log.Printf("Failed to complete embedding job %s: %v", job.ID.String(), err)
+		}
+		return pendingEmbedding{}, false
+	}
+	if err != nil {
+		w.fail(ctx, job, err)
+		return pendingEmbedding{}, false
+	}
+
+	chunks := w.splitter.Split(note.Content)
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = ChunkEmbeddingText(note.Title, chunk)
+	}
+	return pendingEmbedding{job: job, chunks: chunks, texts: texts}, true
+}
+
+// packEmbeddingBatches groups jobs in order so that each group holds at most
+// limit texts. A note with more chunks than that is embedded on its own.
+func packEmbeddingBatches(pending []pendingEmbedding, limit int) [][]pendingEmbedding {
+	var batches [][]pendingEmbedding
+	texts := 0
+	for _, p := range pending {
+		if len(batches) == 0 || texts+len(p.texts) > limit {
+			batches = append(batches, nil)
+			texts = 0
+		}
+		batches[len(batches)-1] = append(batches[len(batches)-1], p)
+		texts += len(p.texts)
+	}
+	return batches
+}
+
+// embed embeds the chunks of a group of jobs in one provider call and stores
+// them per note. If the call fails every job of the group is retried.
+func (w *EmbeddingWorker) embed(ctx context.Context, batch []pendingEmbedding) {
+	ctx, cancel := context.WithTimeout(ctx, w.jobTimeout)
+	defer cancel()
+
+	var texts []string
+	for _, p := range batch {
+		texts = append(texts, p.texts...)
+	}
+
+	embeddings, err := w.embeddingService.GenerateEmbeddings(ctx, texts)
+	if err != nil {
+		for _, p := range batch {
+			w.fail(ctx, p.job, err)
+		}
+		return
+	}
+
+	for _, p := range batch {
+		noteEmbeddings := embeddings[:len(p.texts)]
+		embeddings = embeddings[len(p.texts):]
+		if err := w.complete(ctx, p.job, p.chunks, noteEmbeddings); err != nil {
+			w.fail(ctx, p.job, err)
+		}
+	}
+}
+
+// complete removes the job and replaces the note's chunks, unless the note was
+// re-enqueued meanwhile in which case the newer job will overwrite them anyway
+func (w *EmbeddingWorker) complete(ctx context.Context, job db_sqlc.ClaimEmbeddingJobsRow, chunks []Chunk, embeddings [][]float32) error {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := w.queries.WithTx(tx)
+
+	rows, err := qtx.CompleteEmbeddingJob(ctx, db_sqlc.CompleteEmbeddingJobParams{ID: job.ID, Generation: job.Generation})
+	if err != nil {
+		return fmt.Errorf("failed to complete embedding job: %w", err)
+	}
+	if rows == 0 {
+		return nil
+	}
+
+	if err := qtx.DeleteNoteChunks(ctx, job.NoteID); err != nil {
+		return fmt.Errorf("failed to delete old note chunks: %w", err)
+	}
+	for i, chunk := range chunks {
+		if err := qtx.CreateNoteChunk(ctx, db_sqlc.CreateNoteChunkParams{
+			NoteID:      job.NoteID,
+			UserID:      job.UserID,
+			ChunkIndex:  int32(chunk.Index),
+			Heading:     chunk.Heading,
+			Content:     chunk.Content,
+			StartOffset: int32(chunk.StartOffset),
+			EndOffset:   int32(chunk.EndOffset),
+			Embedding:   pgvector.NewVector(embeddings[i]),
+		}); err != nil {
+			return fmt.Errorf("failed to store note chunk: %w", err)
+		}
+	}
+
+	if err := qtx.UpdateNoteEmbedding(ctx, db_sqlc.UpdateNoteEmbeddingParams{
+		ID:        job.NoteID,
+		Embedding: pgvector.NewVector(MeanEmbedding(embeddings)),
+	}); err != nil {
+		return fmt.Errorf("failed to store note embedding: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// fail schedules a retry or dead-letters the job once it ran out of attempts
+func (w *EmbeddingWorker) fail(ctx context.Context, job db_sqlc.ClaimEmbeddingJobsRow, jobErr error) {
+	// The job context may be what timed out, so record the failure on a fresh deadline
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+	defer cancel()
+
+	lastError := pgtype.Text{String: jobErr.Error(), Valid: true}
+
+	if int(job.Attempts) < w.maxAttempts {
+		delay := w.retryDelay(int(job.Attempts))
+		log.Printf("Embedding job for note %s failed (attempt %d/%d), retrying in %v: %v",
+			job.NoteID.String(), job.Attempts, w.maxAttempts, delay, jobErr)
+
+		if err := w.queries.RetryEmbeddingJob(ctx, db_sqlc.RetryEmbeddingJobParams{
+			ID:         job.ID,
+			Generation: job.Generation,
+			RunAt:      pgtype.Timestamptz{Time: time.Now().Add(delay), Valid: true},
+			LastError:  lastError,
+		}); err != nil {
+			log.Printf("Failed to reschedule embedding job %s: %v", job.ID.String(), err)
+		}
+		return
+	}
+
+	log.Printf("Embedding job for note %s failed %d times, moving to dead letter: %v", job.NoteID.String(), job.Attempts, jobErr)
+	if err := w.deadLetter(ctx, job, lastError); err != nil {
+		log.Printf("Failed to dead-letter embedding job %s: %v", job.ID.String(), err)
+	}
+}
+
+// deadLetter parks the job and marks the note's embedding as failed
+func (w *EmbeddingWorker) deadLetter(ctx context.Context, job db_sqlc.ClaimEmbeddingJobsRow, lastError pgtype.Text) error {
+	tx, err := w.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := w.queries.WithTx(tx)
+
+	rows, err := qtx.DeadLetterEmbeddingJob(ctx, db_sqlc.DeadLetterEmbeddingJobParams{
+		ID:         job.ID,
+		Generation: job.Generation,
+		LastError:  lastError,
+	})
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return nil
+	}
+
+	if err := qtx.SetNoteEmbeddingStatus(ctx, db_sqlc.SetNoteEmbeddingStatusParams{
+		ID:              job.NoteID,
+		EmbeddingStatus: EmbeddingStatusFailed,
+	}); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// retryDelay doubles the backoff with every attempt, capped at maxBackoff
+func (w *EmbeddingWorker) retryDelay(attempts int) time.Duration {
+	delay := w.baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= w.maxBackoff {
+			return w.maxBackoff
+		}
+	}
+	return delay
+}
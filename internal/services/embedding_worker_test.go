@@ -0,0 +1,86 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEmbeddingWorkerRetryDelay(t *testing.T) {
+	w := &EmbeddingWorker{baseBackoff: 10 * time.Second, maxBackoff: time.Minute}
+
+	tests := map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 40 * time.Second,
+		4: time.Minute,
+		9: time.Minute,
+	}
+	for attempts, want := range tests {
+		if got := w.retryDelay(attempts); got != want {
+			t.Errorf("retryDelay(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestPositiveIntEnv(t *testing.T) {
+	t.Setenv("EMBEDDING_WORKERS", "")
+	if got, err := positiveIntEnv("EMBEDDING_WORKERS", 2); err != nil || got != 2 {
+		t.Fatalf("expected fallback 2, got %d (%v)", got, err)
+	}
+
+	t.Setenv("EMBEDDING_WORKERS", "0")
+	if _, err := positiveIntEnv("EMBEDDING_WORKERS", 2); err == nil {
+		t.Fatal("expected error for non-positive value")
+	}
+}
+
+func TestNonNegativeIntEnv(t *testing.T) {
+	t.Setenv("EMBEDDING_CHUNK_OVERLAP", "0")
+	if got, err := nonNegativeIntEnv("EMBEDDING_CHUNK_OVERLAP", 32); err != nil || got != 0 {
+		t.Fatalf("expected 0, got %d (%v)", got, err)
+	}
+
+	t.Setenv("EMBEDDING_CHUNK_OVERLAP", "-1")
+	if _, err := nonNegativeIntEnv("EMBEDDING_CHUNK_OVERLAP", 32); err == nil {
+		t.Fatal("expected error for negative value")
+	}
+}
+
+func TestPackEmbeddingBatches(t *testing.T) {
+	pending := func(texts int) pendingEmbedding {
+		return pendingEmbedding{texts: make([]string, texts)}
+	}
+
+	tests := []struct {
+		name  string
+		texts []int
+		want  [][]int
+	}{
+		{"none", nil, nil},
+		{"fits one call", []int{3, 4, 3}, [][]int{{3, 4, 3}}},
+		{"splits at the limit", []int{6, 4, 1, 9}, [][]int{{6, 4}, {1, 9}}},
+		{"large note on its own", []int{2, 25, 3}, [][]int{{2}, {25}, {3}}},
+		{"note without chunks", []int{10, 0, 5}, [][]int{{10, 0}, {5}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var in []pendingEmbedding
+			for _, n := range tt.texts {
+				in = append(in, pending(n))
+			}
+
+			var got [][]int
+			for _, batch := range packEmbeddingBatches(in, 10) {
+				var sizes []int
+				for _, p := range batch {
+					sizes = append(sizes, len(p.texts))
+				}
+				got = append(got, sizes)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("packEmbeddingBatches(%v) = %v, want %v", tt.texts, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,447 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gopkg.in/yaml.v3"
+)
+
+// exportPageSize is how many notes are read from the database at a time
+const exportPageSize = 200
+
+// maxExportNameLength caps the length of file and folder names, in runes
+const maxExportNameLength = 100
+
+// ExportFormatVersion is bumped when the layout of the JSON dump changes
+const ExportFormatVersion = 1
+
+// Paths inside the export archive
+const (
+	exportNotesDir = "notes"
+	exportJSONFile = "go-note.json"
+	exportSiteDir  = "site"
+)
+
+// ExportOptions controls what an export contains besides the markdown files and the JSON dump
+type ExportOptions struct {
+	HTML bool // add a static HTML site under site/
+}
+
+// ExportedProfile is the user profile in the JSON dump
+type ExportedProfile struct {
+	ID          string          `json:"id"`
+	Username    *string         `json:"username"`
+	DisplayName *string         `json:"display_name"`
+	AvatarURL   *string         `json:"avatar_url"`
+	Preferences json.RawMessage `json:"preferences,omitempty"`
+	CreatedAt   string          `json:"created_at"`
+	UpdatedAt   string          `json:"updated_at"`
+}
+
+// ExportedNotebook is a notebook in the JSON dump
+type ExportedNotebook struct {
+	ID        string  `json:"id"`
+	ParentID  *string `json:"parent_id"`
+	Name      string  `json:"name"`
+	Position  int32   `json:"position"`
+	Folder    string  `json:"folder"` // folder of its notes in the archive
+	CreatedAt string  `json:"created_at"`
+	UpdatedAt string  `json:"updated_at"`
+}
+
+// ExportedNote is a note in the JSON dump
+type ExportedNote struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Content    string   `json:"content"`
+	Tags       []string `json:"tags"`
+	NotebookID *string  `json:"notebook_id"`
+	File       string   `json:"file"` // markdown file of the note in the archive
+	CreatedAt  string   `json:"created_at"`
+	UpdatedAt  string   `json:"updated_at"`
+}
+
+// exportFrontMatter is the YAML front matter of an exported markdown file,
+// using the keys the markdown importer reads
+type exportFrontMatter struct {
+	ID       string   `yaml:"id"`
+	Title    string   `yaml:"title"`
+	Tags     []string `yaml:"tags,flow,omitempty"`
+	Notebook string   `yaml:"notebook,omitempty"`
+	Created  string   `yaml:"created"`
+	Updated  string   `yaml:"updated"`
+}
+
+// exportedFile is what later parts of an export need to know about a note
+// written in the first pass, without keeping its content in memory
+type exportedFile struct {
+	ID         string
+	Title      string
+	Tags       []string
+	NotebookID string
+	File       string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// ExportService writes a user's data out as a zip archive
+type ExportService struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewExportService creates a new export service
+func NewExportService(db *pgxpool.Pool) *ExportService {
+	return &ExportService{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// WriteExport streams a zip archive of the user's account to w: every live
+// note as a markdown file with YAML front matter under notes/, in folders
+// following its notebooks, and go-note.json holding the profile, notebooks
+// and notes. With opts.HTML the archive also gets a self-contained static
+// site under site/. Notes are read in pages inside one read-only snapshot,
+// so the parts of the archive agree with each other and memory use does not
+// grow with the size of the notes.
+func (s *ExportService) WriteExport(ctx context.Context, w io.Writer, userID pgtype.UUID, opts ExportOptions) error {
+	tx, err := s.db.BeginTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	var profile *ExportedProfile
+	row, err := qtx.GetUserProfile(ctx, userID)
+	switch {
+	case err == nil:
+		profile = exportedProfile(row)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("failed to fetch profile: %w", err)
+	}
+
+	notebookRows, err := qtx.ListNotebooks(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch notebooks: %w", err)
+	}
+	folders := notebookFolders(notebookRows)
+
+	exportedAt := time.Now().UTC()
+	zw := zip.NewWriter(w)
+
+	// The markdown files come first, naming every note's file on the way
+	names := make(exportNames)
+	var files []exportedFile
+	err = eachExportNote(ctx, qtx, userID, func(note db_sqlc.ListNotesForExportRow) error {
+		var notebookID, folder string
+		if note.NotebookID.Valid {
+			notebookID = note.NotebookID.String()
+			folder = folders[notebookID]
+		}
+		file := exportedFile{
+			ID:         note.ID.String(),
+			Title:      note.Title,
+			Tags:       note.Tags,
+			NotebookID: notebookID,
+			File:       names.add(path.Join(exportNotesDir, folder), note.Title, ".md"),
+			CreatedAt:  note.CreatedAt.Time,
+			UpdatedAt:  note.UpdatedAt.Time,
+		}
+
+		data, err := exportMarkdown(file, note.Content, folder)
+		if err != nil {
+			return err
+		}
+		if err := writeExportFile(zw, file.File, file.UpdatedAt, data); err != nil {
+			return err
+		}
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := writeExportJSON(ctx, zw, qtx, userID, exportedAt, profile, notebookRows, folders, files); err != nil {
+		return err
+	}
+
+	if opts.HTML {
+		if err := writeExportSite(ctx, zw, qtx, userID, exportedAt, folders, files); err != nil {
+			return err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
+
+// eachExportNote calls fn with each of the user's live notes, in ID order
+func eachExportNote(ctx context.Context, queries *db_sqlc.Queries, userID pgtype.UUID, fn func(db_sqlc.ListNotesForExportRow) error) error {
+	var afterID pgtype.UUID
+	for {
+		notes, err := queries.ListNotesForExport(ctx, db_sqlc.ListNotesForExportParams{
+			UserID:  userID,
+			AfterID: afterID,
+			Limit:   exportPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to fetch notes: %w", err)
+		}
+		for _, note := range notes {
+			if err := fn(note); err != nil {
+				return err
+			}
+		}
+		if len(notes) < exportPageSize {
+			return nil
+		}
+		afterID = notes[len(notes)-1].ID
+	}
+}
+
+// exportMarkdown renders a note as a markdown file whose front matter carries
+// its ID, title, tags, notebook folder and timestamps. Importing the file
+// again restores the title, tags and timestamps.
+func exportMarkdown(file exportedFile, content, notebook string) ([]byte, error) {
+	meta, err := yaml.Marshal(exportFrontMatter{
+		ID:       file.ID,
+		Title:    file.Title,
+		Tags:     file.Tags,
+		Notebook: notebook,
+		Created:  file.CreatedAt.UTC().Format(time.RFC3339),
+		Updated:  file.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write front matter of %s: %w", file.File, err)
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	b.Write(meta)
+	b.WriteString("---\n\n")
+	b.WriteString(content)
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\n")
+	}
+	return []byte(b.String()), nil
+}
+
+// writeExportJSON writes go-note.json, streaming the notes in as they are read again
+func writeExportJSON(
+	ctx context.Context,
+	zw *zip.Writer,
+	queries *db_sqlc.Queries,
+	userID pgtype.UUID,
+	exportedAt time.Time,
+	profile *ExportedProfile,
+	notebookRows []db_sqlc.ListNotebooksRow,
+	folders map[string]string,
+	files []exportedFile,
+) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: exportJSONFile, Method: zip.Deflate, Modified: exportedAt})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", exportJSONFile, err)
+	}
+
+	notebooks := make([]ExportedNotebook, len(notebookRows))
+	for i, row := range notebookRows {
+		notebooks[i] = ExportedNotebook{
+			ID:        row.ID.String(),
+			Name:      row.Name,
+			Position:  row.Position,
+			Folder:    path.Join(exportNotesDir, folders[row.ID.String()]),
+			CreatedAt: row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			UpdatedAt: row.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if row.ParentID.Valid {
+			parentID := row.ParentID.String()
+			notebooks[i].ParentID = &parentID
+		}
+	}
+
+	header, err := json.Marshal(struct {
+		Format     string             `json:"format"`
+		Version    int                `json:"version"`
+		ExportedAt string             `json:"exported_at"`
+		Profile    *ExportedProfile   `json:"profile"`
+		Notebooks  []ExportedNotebook `json:"notebooks"`
+	}{"go-note", ExportFormatVersion, exportedAt.Format("2006-01-02T15:04:05Z07:00"), profile, notebooks})
+	if err != nil {
+		return err
+	}
+
+	// Splice the notes array into the object without holding every note at once
+	if _, err := w.Write(append(header[:len(header)-1], `,"notes":[`...)); err != nil {
+		return err
+	}
+	i := 0
+	err = eachExportNote(ctx, queries, userID, func(note db_sqlc.ListNotesForExportRow) error {
+		exported := ExportedNote{
+			ID:        note.ID.String(),
+			Title:     note.Title,
+			Content:   note.Content,
+			Tags:      note.Tags,
+			CreatedAt: note.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			UpdatedAt: note.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		}
+		if exported.Tags == nil {
+			exported.Tags = []string{}
+		}
+		if note.NotebookID.Valid {
+			notebookID := note.NotebookID.String()
+			exported.NotebookID = &notebookID
+		}
+		// Both passes read the same snapshot in the same order
+		if i < len(files) && files[i].ID == exported.ID {
+			exported.File = files[i].File
+		}
+
+		data, err := json.Marshal(exported)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			data = append([]byte{','}, data...)
+		}
+		i++
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write([]byte("]}\n"))
+	return err
+}
+
+// writeExportFile adds a compressed file to the archive
+func writeExportFile(zw *zip.Writer, name string, modified time.Time, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// exportedProfile converts a profile row for the JSON dump
+func exportedProfile(row db_sqlc.UserProfile) *ExportedProfile {
+	profile := &ExportedProfile{
+		ID:        row.ID.String(),
+		CreatedAt: row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+		UpdatedAt: row.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+	}
+	if row.Username.Valid {
+		profile.Username = &row.Username.String
+	}
+	if row.DisplayName.Valid {
+		profile.DisplayName = &row.DisplayName.String
+	}
+	if row.AvatarUrl.Valid {
+		profile.AvatarURL = &row.AvatarUrl.String
+	}
+	if json.Valid(row.Preferences) {
+		profile.Preferences = row.Preferences
+	}
+	return profile
+}
+
+// notebookFolders maps notebook IDs to folder paths made of the names of the
+// notebook and its ancestors. Sibling notebooks whose names clash get a
+// numbered suffix, so every notebook has a folder of its own.
+func notebookFolders(rows []db_sqlc.ListNotebooksRow) map[string]string {
+	byID := make(map[string]db_sqlc.ListNotebooksRow, len(rows))
+	for _, row := range rows {
+		byID[row.ID.String()] = row
+	}
+
+	names := make(exportNames)
+	folders := make(map[string]string, len(rows))
+	var resolve func(row db_sqlc.ListNotebooksRow, depth int) string
+	resolve = func(row db_sqlc.ListNotebooksRow, depth int) string {
+		id := row.ID.String()
+		if folder, ok := folders[id]; ok {
+			return folder
+		}
+		parent := ""
+		// depth guards against a parent cycle, which the schema does not rule out
+		if p, ok := byID[row.ParentID.String()]; ok && row.ParentID.Valid && depth < len(rows) {
+			parent = resolve(p, depth+1)
+		}
+		folders[id] = names.add(parent, row.Name, "")
+		return folders[id]
+	}
+	for _, row := range rows {
+		resolve(row, 0)
+	}
+	return folders
+}
+
+// exportNames hands out unique file names, per folder and ignoring case
+type exportNames map[string]bool
+
+// add returns dir/name+ext with name made safe for file systems, numbered
+// "name (2)" and up when the folder already has a file of that name
+func (n exportNames) add(dir, name, ext string) string {
+	base := safeFileName(name)
+	for i := 1; ; i++ {
+		candidate := base
+		if i > 1 {
+			candidate += " (" + strconv.Itoa(i) + ")"
+		}
+		full := path.Join(dir, candidate+ext)
+		if key := strings.ToLower(full); !n[key] {
+			n[key] = true
+			return full
+		}
+	}
+}
+
+// safeFileName turns a title into a name that is valid on common file
+// systems: path separators, reserved and control characters become "-",
+// leading and trailing dots and spaces go, and long titles are shortened.
+func safeFileName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsSpace(r):
+			b.WriteRune(' ')
+		case strings.ContainsRune(`/\:*?"<>|`, r), unicode.IsControl(r):
+			b.WriteRune('-')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	safe := strings.Join(strings.Fields(b.String()), " ")
+	if runes := []rune(safe); len(runes) > maxExportNameLength {
+		safe = string(runes[:maxExportNameLength])
+	}
+	safe = strings.Trim(safe, ". ")
+	if safe == "" {
+		return "Untitled"
+	}
+	return safe
+}
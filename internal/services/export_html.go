@@ -0,0 +1,632 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	// markdownListItemPattern matches a list item line, capturing its indent, marker and text
+	markdownListItemPattern = regexp.MustCompile(`^(\s*)([-*+]|\d{1,9}[.)])\s+(.*)$`)
+	// markdownRulePattern matches a thematic break such as --- or * * *
+	markdownRulePattern = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	// markdownTableSeparatorPattern matches the line under a table's header, e.g. | --- | :-: |
+	markdownTableSeparatorPattern = regexp.MustCompile(`^\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?$`)
+)
+
+// siteTime is how timestamps are shown on the static site
+const siteTime = "2006-01-02 15:04 UTC"
+
+// exportSiteCSS is inlined into every page, so the site works from the file system
+const exportSiteCSS = `
+body { font: 16px/1.6 -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; color: #222; max-width: 48rem; margin: 0 auto; padding: 1rem 1.5rem 3rem; }
+nav { font-size: .9rem; margin-bottom: 2rem; }
+a { color: #2563eb; }
+.meta { color: #666; font-size: .9rem; }
+.tag { display: inline-block; background: #eef2ff; border-radius: .75rem; padding: 0 .6rem; margin: 0 .25rem .25rem 0; text-decoration: none; font-size: .85rem; }
+.missing-link { color: #b91c1c; border-bottom: 1px dashed #b91c1c; }
+pre { background: #f6f8fa; padding: .75rem 1rem; overflow-x: auto; border-radius: .25rem; }
+code { font-family: SFMono-Regular, Menlo, Consolas, monospace; font-size: .9em; }
+blockquote { border-left: 3px solid #ddd; margin-left: 0; padding-left: 1rem; color: #555; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: .25rem .6rem; }
+ul.notes { padding-left: 1.2rem; }
+img { max-width: 100%; }
+`
+
+// exportSiteTemplates renders the pages of the static site
+var exportSiteTemplates = template.Must(template.New("site").Parse(`
+{{define "header"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}} · go-note</title>
+<style>` + exportSiteCSS + `</style>
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">All notes</a> · exported {{.ExportedAt}}</nav>
+<main>
+{{end}}
+
+{{define "footer"}}</main>
+</body>
+</html>
+{{end}}
+
+{{define "notes"}}<ul class="notes">
+{{range .}}<li><a href="{{.Href}}">{{.Title}}</a> <span class="meta">{{if .Notebook}}{{.Notebook}} · {{end}}{{.Updated}}</span></li>
+{{end}}</ul>
+{{end}}
+
+{{define "tags"}}{{range .}}<a class="tag" href="{{.Href}}">#{{.Tag}}{{if .Count}} ({{.Count}}){{end}}</a>{{end}}{{end}}
+
+{{define "index"}}{{template "header" .}}<h1>Notes</h1>
+{{if .Tags}}<p>{{template "tags" .Tags}}</p>
+{{end}}{{if .Notes}}{{template "notes" .Notes}}{{else}}<p>No notes.</p>
+{{end}}{{template "footer" .}}{{end}}
+
+{{define "note"}}{{template "header" .}}<article>
+<h1>{{.Title}}</h1>
+<p class="meta">{{if .Notebook}}{{.Notebook}} · {{end}}created {{.Created}} · updated {{.Updated}}</p>
+{{if .Tags}}<p>{{template "tags" .Tags}}</p>
+{{end}}{{.Content}}</article>
+{{template "footer" .}}{{end}}
+
+{{define "tag"}}{{template "header" .}}<h1>#{{.Title}}</h1>
+{{if .Parent}}<p class="meta">in {{template "tags" .Parent}}</p>
+{{end}}{{if .Children}}<p>{{template "tags" .Children}}</p>
+{{end}}{{if .Notes}}{{template "notes" .Notes}}{{end}}{{template "footer" .}}{{end}}
+`))
+
+// sitePage holds what every page of the static site shows
+type sitePage struct {
+	Title      string
+	Root       string // relative path from the page to the site's root
+	ExportedAt string
+}
+
+// siteNoteLink is an entry in a list of notes
+type siteNoteLink struct {
+	Title    string
+	Href     string
+	Notebook string
+	Updated  string
+}
+
+// siteTagLink links to a tag's page
+type siteTagLink struct {
+	Tag   string
+	Href  string
+	Count int64
+}
+
+// linkResolver returns the href of the note a link points at, if it is part of the export
+type linkResolver func(link NoteLink) (string, bool)
+
+// writeExportSite adds the static HTML site: an index of all notes and tags,
+// a page per note and a page per tag listing its notes and subtags
+func writeExportSite(
+	ctx context.Context,
+	zw *zip.Writer,
+	queries *db_sqlc.Queries,
+	userID pgtype.UUID,
+	exportedAt time.Time,
+	folders map[string]string,
+	files []exportedFile,
+) error {
+	byID := make(map[string]exportedFile, len(files))
+	byTitle := make(map[string]string, len(files))
+	tagNotes := make(map[string][]exportedFile)
+	for _, file := range files {
+		byID[file.ID] = file
+		if key := strings.ToLower(file.Title); byTitle[key] == "" {
+			byTitle[key] = file.ID
+		}
+		for _, tag := range file.Tags {
+			tagNotes[tag] = append(tagNotes[tag], file)
+		}
+	}
+
+	sorted := append([]exportedFile(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return strings.ToLower(sorted[i].Title) < strings.ToLower(sorted[j].Title)
+	})
+	noteLinks := func(root string, notes []exportedFile) []siteNoteLink {
+		links := make([]siteNoteLink, len(notes))
+		for i, note := range notes {
+			links[i] = siteNoteLink{
+				Title:    note.Title,
+				Href:     root + "notes/" + note.ID + ".html",
+				Notebook: folders[note.NotebookID],
+				Updated:  note.UpdatedAt.UTC().Format(siteTime),
+			}
+		}
+		return links
+	}
+	tagLinks := func(root string, tags []string) []siteTagLink {
+		links := make([]siteTagLink, len(tags))
+		for i, tag := range tags {
+			links[i] = siteTagLink{Tag: tag, Href: root + tagPageHref(tag)}
+		}
+		return links
+	}
+
+	counts := make([]TagCount, 0, len(tagNotes))
+	for tag, notes := range tagNotes {
+		counts = append(counts, TagCount{Tag: tag, Count: int64(len(notes))})
+	}
+	tree := BuildTagTree(counts)
+
+	page := func(title, root string) sitePage {
+		return sitePage{Title: title, Root: root, ExportedAt: exportedAt.Format(siteTime)}
+	}
+	render := func(name, file string, data any) error {
+		var buf bytes.Buffer
+		if err := exportSiteTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", file, err)
+		}
+		return writeExportFile(zw, path.Join(exportSiteDir, file), exportedAt, buf.Bytes())
+	}
+
+	topTags := make([]siteTagLink, len(tree))
+	for i, node := range tree {
+		topTags[i] = siteTagLink{Tag: node.Tag, Href: tagPageHref(node.Tag), Count: node.Total}
+	}
+	err := render("index", "index.html", struct {
+		sitePage
+		Tags  []siteTagLink
+		Notes []siteNoteLink
+	}{page("All notes", ""), topTags, noteLinks("", sorted)})
+	if err != nil {
+		return err
+	}
+
+	var renderTag func(node *TagNode, parent string) error
+	renderTag = func(node *TagNode, parent string) error {
+		root := strings.Repeat("../", strings.Count(node.Tag, TagSeparator)+1)
+		children := make([]siteTagLink, len(node.Children))
+		for i, child := range node.Children {
+			children[i] = siteTagLink{Tag: child.Tag, Href: root + tagPageHref(child.Tag), Count: child.Total}
+		}
+		var parentLinks []siteTagLink
+		if parent != "" {
+			parentLinks = tagLinks(root, []string{parent})
+		}
+		notes := append([]exportedFile(nil), tagNotes[node.Tag]...)
+		sort.SliceStable(notes, func(i, j int) bool {
+			return strings.ToLower(notes[i].Title) < strings.ToLower(notes[j].Title)
+		})
+
+		err := render("tag", tagPagePath(node.Tag), struct {
+			sitePage
+			Parent   []siteTagLink
+			Children []siteTagLink
+			Notes    []siteNoteLink
+		}{page(node.Tag, root), parentLinks, children, noteLinks(root, notes)})
+		if err != nil {
+			return err
+		}
+		for _, child := range node.Children {
+			if err := renderTag(child, node.Tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, node := range tree {
+		if err := renderTag(node, ""); err != nil {
+			return err
+		}
+	}
+
+	// Note pages live next to each other, so links between notes are just the file name
+	resolve := func(link NoteLink) (string, bool) {
+		id := link.TargetID
+		if id == "" {
+			id = byTitle[strings.ToLower(link.Target)]
+		}
+		if _, ok := byID[id]; !ok {
+			return "", false
+		}
+		return id + ".html", true
+	}
+	return eachExportNote(ctx, queries, userID, func(note db_sqlc.ListNotesForExportRow) error {
+		file := byID[note.ID.String()]
+		return render("note", "notes/"+file.ID+".html", struct {
+			sitePage
+			Notebook string
+			Created  string
+			Updated  string
+			Tags     []siteTagLink
+			Content  template.HTML
+		}{
+			page(note.Title, "../"),
+			folders[file.NotebookID],
+			note.CreatedAt.Time.UTC().Format(siteTime),
+			note.UpdatedAt.Time.UTC().Format(siteTime),
+			tagLinks("../", note.Tags),
+			template.HTML(renderMarkdown(note.Content, resolve)),
+		})
+	})
+}
+
+// tagPagePath is the path of a tag's page relative to the site's root; each
+// level of a hierarchical tag is a folder, e.g. tags/lang/go.html
+func tagPagePath(tag string) string {
+	levels := strings.Split(tag, TagSeparator)
+	for i, level := range levels {
+		levels[i] = safeFileName(level)
+	}
+	return "tags/" + strings.Join(levels, "/") + ".html"
+}
+
+// tagPageHref is tagPagePath escaped for use in a link
+func tagPageHref(tag string) string {
+	levels := strings.Split(strings.TrimSuffix(tagPagePath(tag), ".html"), "/")
+	for i, level := range levels {
+		levels[i] = url.PathEscape(level)
+	}
+	return strings.Join(levels, "/") + ".html"
+}
+
+// renderMarkdown converts note content to HTML. It covers the markdown notes
+// are written in (headings, paragraphs, lists and task lists, block quotes,
+// fenced code, tables, rules, emphasis, code spans, links and images) rather
+// than all of CommonMark. Wiki links and markdown links to notes go through
+// resolve; links it cannot resolve are marked as missing. All text is
+// escaped, so content cannot inject markup or scripts.
+func renderMarkdown(content string, resolve linkResolver) string {
+	var b strings.Builder
+	renderMarkdownBlocks(&b, strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n"), resolve)
+	return b.String()
+}
+
+// renderMarkdownBlocks renders lines as a sequence of block elements
+func renderMarkdownBlocks(b *strings.Builder, lines []string, resolve linkResolver) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + renderMarkdownInline(strings.Join(paragraph, "\n"), resolve) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			flush()
+
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence := trimmed[:3]
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			b.WriteString("<pre><code")
+			if info := strings.Fields(strings.TrimLeft(trimmed, fence[:1])); len(info) > 0 {
+				b.WriteString(` class="language-` + html.EscapeString(info[0]) + `"`)
+			}
+			b.WriteString(">" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+
+		case markdownHeadingPattern.MatchString(trimmed):
+			flush()
+			level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+			text := markdownHeadingPattern.FindStringSubmatch(trimmed)[1]
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, renderMarkdownInline(text, resolve), level)
+
+		case markdownRulePattern.MatchString(line):
+			flush()
+			b.WriteString("<hr>\n")
+
+		case strings.HasPrefix(trimmed, ">"):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				quoted = append(quoted, strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(lines[i]), ">"), " "))
+			}
+			i--
+			b.WriteString("<blockquote>\n")
+			renderMarkdownBlocks(b, quoted, resolve)
+			b.WriteString("</blockquote>\n")
+
+		case markdownListItemPattern.MatchString(line):
+			flush()
+			end := i + 1
+			for end < len(lines) {
+				next := lines[end]
+				if strings.TrimSpace(next) == "" {
+					// A blank line only continues the list when more of it follows
+					if end+1 < len(lines) && (markdownListItemPattern.MatchString(lines[end+1]) || startsIndented(lines[end+1])) {
+						end++
+						continue
+					}
+					break
+				}
+				if !markdownListItemPattern.MatchString(next) && !startsIndented(next) {
+					break
+				}
+				end++
+			}
+			renderMarkdownList(b, lines[i:end], resolve)
+			i = end - 1
+
+		case strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && markdownTableSeparatorPattern.MatchString(strings.TrimSpace(lines[i+1])):
+			flush()
+			b.WriteString("<table>\n<thead><tr>")
+			for _, cell := range splitTableRow(trimmed) {
+				b.WriteString("<th>" + renderMarkdownInline(cell, resolve) + "</th>")
+			}
+			b.WriteString("</tr></thead>\n<tbody>\n")
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				b.WriteString("<tr>")
+				for _, cell := range splitTableRow(strings.TrimSpace(lines[i])) {
+					b.WriteString("<td>" + renderMarkdownInline(cell, resolve) + "</td>")
+				}
+				b.WriteString("</tr>\n")
+			}
+			i--
+			b.WriteString("</tbody>\n</table>\n")
+
+		default:
+			paragraph = append(paragraph, trimmed)
+		}
+	}
+	flush()
+}
+
+// renderMarkdownList renders the lines of a list; lines indented deeper than
+// its items belong to the item above them and are rendered as nested blocks
+func renderMarkdownList(b *strings.Builder, lines []string, resolve linkResolver) {
+	first := markdownListItemPattern.FindStringSubmatch(lines[0])
+	indent := len(first[1])
+	tag := "ul"
+	if unicode.IsDigit(rune(first[2][0])) {
+		tag = "ol"
+	}
+
+	b.WriteString("<" + tag + ">\n")
+	var item []string
+	flushItem := func() {
+		if item == nil {
+			return
+		}
+		text := item[0]
+		b.WriteString("<li>")
+		for _, box := range []struct{ prefix, input string }{
+			{"[ ] ", `<input type="checkbox" disabled> `},
+			{"[x] ", `<input type="checkbox" checked disabled> `},
+			{"[X] ", `<input type="checkbox" checked disabled> `},
+		} {
+			if strings.HasPrefix(text, box.prefix) {
+				b.WriteString(box.input)
+				text = text[len(box.prefix):]
+				break
+			}
+		}
+		b.WriteString(renderMarkdownInline(text, resolve))
+		if children := dedentLines(item[1:]); len(children) > 0 {
+			b.WriteString("\n")
+			renderMarkdownBlocks(b, children, resolve)
+		}
+		b.WriteString("</li>\n")
+		item = nil
+	}
+	for _, line := range lines {
+		if m := markdownListItemPattern.FindStringSubmatch(line); m != nil && len(m[1]) <= indent {
+			flushItem()
+			item = []string{m[3]}
+			continue
+		}
+		item = append(item, line)
+	}
+	flushItem()
+	b.WriteString("</" + tag + ">\n")
+}
+
+// renderMarkdownInline renders the inline markup of a block's text
+func renderMarkdownInline(text string, resolve linkResolver) string {
+	var b strings.Builder
+	runes := []rune(text)
+	n := len(runes)
+	for i := 0; i < n; i++ {
+		r := runes[i]
+		switch {
+		case r == '\\' && i+1 < n && strings.ContainsRune("\\`*_{}[]()#+-.!|~<>", runes[i+1]):
+			i++
+			b.WriteString(html.EscapeString(string(runes[i])))
+			continue
+
+		case r == '\n':
+			b.WriteString("<br>\n")
+			continue
+
+		case r == '`':
+			if end := indexRune(runes, '`', i+1, n); end > i+1 {
+				b.WriteString("<code>" + html.EscapeString(string(runes[i+1:end])) + "</code>")
+				i = end
+				continue
+			}
+
+		case r == '[' && i+1 < n && runes[i+1] == '[':
+			closing := indexString(runes, "]]", i+2, n)
+			if closing < 0 {
+				break
+			}
+			inner := string(runes[i+2 : closing])
+			link, ok := parseWikiLink(inner)
+			if !ok {
+				break
+			}
+			shown := link.Alias
+			if shown == "" {
+				shown, _, _ = strings.Cut(inner, "|")
+				shown = strings.TrimSpace(shown)
+			}
+			if href, ok := resolve(link); ok {
+				b.WriteString(`<a href="` + html.EscapeString(href) + `">` + html.EscapeString(shown) + "</a>")
+			} else {
+				b.WriteString(`<span class="missing-link">` + html.EscapeString(shown) + "</span>")
+			}
+			i = closing + 1
+			continue
+
+		case r == '[' || (r == '!' && i+1 < n && runes[i+1] == '['):
+			start := i
+			if r == '!' {
+				start++
+			}
+			textEnd := indexRune(runes, ']', start+1, n)
+			if textEnd < 0 || textEnd+1 >= n || runes[textEnd+1] != '(' {
+				break
+			}
+			urlEnd := indexRune(runes, ')', textEnd+2, n)
+			if urlEnd < 0 {
+				break
+			}
+			label, destination := string(runes[start+1:textEnd]), string(runes[textEnd+2:urlEnd])
+			if r == '!' {
+				b.WriteString(`<img src="` + html.EscapeString(safeLinkURL(destination)) + `" alt="` + html.EscapeString(label) + `">`)
+			} else {
+				href := safeLinkURL(destination)
+				if link, ok := parseMarkdownLink(label, destination); ok {
+					if resolved, ok := resolve(link); ok {
+						href = resolved
+					}
+				}
+				b.WriteString(`<a href="` + html.EscapeString(href) + `">` + renderMarkdownInline(label, resolve) + "</a>")
+			}
+			i = urlEnd
+			continue
+
+		case r == '*' || r == '_' || r == '~':
+			delim := []rune{r}
+			if i+1 < n && runes[i+1] == r {
+				delim = append(delim, r)
+			}
+			if (r == '~' && len(delim) == 1) || (r == '_' && i > 0 && isWordRune(runes[i-1])) {
+				break
+			}
+			open := i + len(delim)
+			end := indexString(runes, string(delim), open, n)
+			if end <= open || unicode.IsSpace(runes[open]) || unicode.IsSpace(runes[end-1]) {
+				break
+			}
+			element := "em"
+			if r == '~' {
+				element = "del"
+			} else if len(delim) == 2 {
+				element = "strong"
+			}
+			b.WriteString("<" + element + ">" + renderMarkdownInline(string(runes[open:end]), resolve) + "</" + element + ">")
+			i = end + len(delim) - 1
+			continue
+
+		case r == 'h' && (i == 0 || !isWordRune(runes[i-1])) && (hasPrefixAt(runes, i, "https://") || hasPrefixAt(runes, i, "http://")):
+			end := i
+			for end < n && !unicode.IsSpace(runes[end]) && runes[end] != '<' {
+				end++
+			}
+			for end > i && strings.ContainsRune(`.,:;!?)"'`, runes[end-1]) {
+				end--
+			}
+			link := html.EscapeString(string(runes[i:end]))
+			b.WriteString(`<a href="` + link + `">` + link + "</a>")
+			i = end - 1
+			continue
+		}
+		b.WriteString(html.EscapeString(string(r)))
+	}
+	return b.String()
+}
+
+// safeLinkURL drops a link's optional title and only lets through web,
+// mailto and relative URLs, so content cannot link to javascript: and such
+func safeLinkURL(destination string) string {
+	destination = strings.TrimSpace(destination)
+	if i := strings.IndexAny(destination, " \t"); i >= 0 {
+		destination = destination[:i]
+	}
+	destination = strings.TrimSuffix(strings.TrimPrefix(destination, "<"), ">")
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "#"
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+		return destination
+	}
+	return "#"
+}
+
+// splitTableRow splits a table row into its trimmed cells, honoring escaped pipes
+func splitTableRow(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(row); i++ {
+		switch {
+		case row[i] == '\\' && i+1 < len(row) && row[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case row[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(row[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// dedentLines removes the indentation shared by lines, returning nil when all are blank
+func dedentLines(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if n := len(line) - len(strings.TrimLeft(line, " \t")); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent < 0 {
+		return nil
+	}
+	dedented := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent {
+			dedented[i] = line[indent:]
+		}
+	}
+	return dedented
+}
+
+// startsIndented reports whether a non-blank line starts with whitespace
+func startsIndented(line string) bool {
+	return strings.TrimSpace(line) != "" && (line[0] == ' ' || line[0] == '\t')
+}
+
+// hasPrefixAt reports whether prefix occurs in runes at position i
+func hasPrefixAt(runes []rune, i int, prefix string) bool {
+	needle := []rune(prefix)
+	return i+len(needle) <= len(runes) && hasRunesAt(runes, needle, i)
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	resolve := func(link NoteLink) (string, bool) {
+		if strings.EqualFold(link.Target, "Roadmap") {
+			return "roadmap-id.html", true
+		}
+		return "", false
+	}
+	content := strings.Join([]string{
+		"## Plan *now*",
+		"",
+		"Read **chapter 3** and `x < y`",
+		"see [[roadmap|the roadmap]], [[Nowhere]] and https://example.com.",
+		"",
+		"- [x] done",
+		"- open",
+		"  1. nested",
+		"",
+		"> quoted _text_",
+		"",
+		"```go",
+		"if a < b {}",
+		"```",
+		"",
+		"| A | B |",
+		"| --- | --- |",
+		"| 1 | a\\|b |",
+		"",
+		"---",
+		"[Site](https://go.dev \"Go\") and ![logo](img/logo.png) and [Roadmap](Roadmap.md)",
+	}, "\n")
+
+	want := strings.Join([]string{
+		"<h2>Plan <em>now</em></h2>",
+		"<p>Read <strong>chapter 3</strong> and <code>x &lt; y</code><br>",
+		`see <a href="roadmap-id.html">the roadmap</a>, <span class="missing-link">Nowhere</span> and <a href="https://example.com">https://example.com</a>.</p>`,
+		"<ul>",
+		`<li><input type="checkbox" checked disabled> done</li>`,
+		"<li>open",
+		"<ol>",
+		"<li>nested</li>",
+		"</ol>",
+		"</li>",
+		"</ul>",
+		"<blockquote>",
+		"<p>quoted <em>text</em></p>",
+		"</blockquote>",
+		`<pre><code class="language-go">if a &lt; b {}</code></pre>`,
+		"<table>",
+		"<thead><tr><th>A</th><th>B</th></tr></thead>",
+		"<tbody>",
+		"<tr><td>1</td><td>a|b</td></tr>",
+		"</tbody>",
+		"</table>",
+		"<hr>",
+		`<p><a href="https://go.dev">Site</a> and <img src="img/logo.png" alt="logo"> and <a href="roadmap-id.html">Roadmap</a></p>`,
+		"",
+	}, "\n")
+
+	if got := renderMarkdown(content, resolve); got != want {
+		t.Errorf("unexpected HTML:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestRenderMarkdownEscapesMarkup(t *testing.T) {
+	noLinks := func(NoteLink) (string, bool) { return "", false }
+	got := renderMarkdown("<script>alert(1)</script> [click](javascript:alert(1)) snake_case_name", noLinks)
+	want := `<p>&lt;script&gt;alert(1)&lt;/script&gt; <a href="#">click</a>) snake_case_name</p>` + "\n"
+	if got != want {
+		t.Errorf("unexpected HTML:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTagPageHref(t *testing.T) {
+	if got := tagPagePath("lang/c#"); got != "tags/lang/c#.html" {
+		t.Errorf("unexpected tag page path %q", got)
+	}
+	if got := tagPageHref("lang/c#"); got != "tags/lang/c%23.html" {
+		t.Errorf("unexpected tag page href %q", got)
+	}
+}
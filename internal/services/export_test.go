@@ -0,0 +1,105 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestSafeFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Meeting notes", "Meeting notes"},
+		{"Q3: plans / goals?", "Q3- plans - goals-"},
+		{"  tabs\tand\nnewlines  ", "tabs and newlines"},
+		{"...", "Untitled"},
+		{"", "Untitled"},
+		{"../../etc/passwd", "-..-etc-passwd"},
+		{strings.Repeat("ü", 150), strings.Repeat("ü", maxExportNameLength)},
+	}
+	for _, tt := range tests {
+		if got := safeFileName(tt.name); got != tt.want {
+			t.Errorf("safeFileName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestExportNamesNumberClashes(t *testing.T) {
+	names := make(exportNames)
+	got := []string{
+		names.add("notes", "Ideas", ".md"),
+		names.add("notes", "ideas", ".md"),
+		names.add("notes", "Ideas?", ".md"),
+		names.add("notes/Work", "Ideas", ".md"),
+		names.add("notes", "Ideas", ".md"),
+	}
+	want := []string{
+		"notes/Ideas.md",
+		"notes/ideas (2).md",
+		"notes/Ideas-.md",
+		"notes/Work/Ideas.md",
+		"notes/Ideas (3).md",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected names %v, want %v", got, want)
+	}
+}
+
+func TestNotebookFolders(t *testing.T) {
+	id := func(b byte) pgtype.UUID { return pgtype.UUID{Bytes: [16]byte{b}, Valid: true} }
+	rows := []db_sqlc.ListNotebooksRow{
+		{ID: id(1), Name: "Work"},
+		{ID: id(2), ParentID: id(1), Name: "Projects"},
+		{ID: id(3), Name: "work"},
+		{ID: id(4), ParentID: id(9), Name: "Orphan"},
+	}
+
+	folders := notebookFolders(rows)
+	want := map[string]string{
+		id(1).String(): "Work",
+		id(2).String(): "Work/Projects",
+		id(3).String(): "work (2)",
+		id(4).String(): "Orphan",
+	}
+	if !reflect.DeepEqual(folders, want) {
+		t.Errorf("unexpected folders %v, want %v", folders, want)
+	}
+}
+
+func TestExportMarkdownRoundTrips(t *testing.T) {
+	file := exportedFile{
+		ID:        "0b6e1c1e-2f5a-4c59-9c43-3f1d2a0f6c11",
+		Title:     "Release: v2 #plan",
+		Tags:      []string{"work", "lang/go"},
+		File:      "notes/Work/Release- v2 #plan.md",
+		CreatedAt: time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC),
+		UpdatedAt: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	content := "# Steps\n\n- [ ] tag the release\n- see [[Changelog]]"
+
+	data, err := exportMarkdown(file, content, "Work")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(data), "id: "+file.ID+"\n") || !strings.Contains(string(data), "notebook: Work\n") {
+		t.Errorf("expected the ID and notebook in the front matter, got:\n%s", data)
+	}
+
+	note, err := ParseMarkdownNote(file.File, data, time.Now())
+	if err != nil {
+		t.Fatalf("exported markdown does not import: %v", err)
+	}
+	if note.Title != file.Title || note.Content != content || !reflect.DeepEqual(note.Tags, file.Tags) {
+		t.Errorf("round trip changed the note: %+v", note)
+	}
+	if !note.CreatedAt.Equal(file.CreatedAt) || !note.UpdatedAt.Equal(file.UpdatedAt) {
+		t.Errorf("round trip changed the dates: %v %v", note.CreatedAt, note.UpdatedAt)
+	}
+}
@@ -4,12 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
+	"log"
 	"strings"
 
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/tmc/langchaingo/llms"
-	"github.com/tmc/langchaingo/llms/googleai"
 )
 
 // FlashcardService handles flashcard generation using LangChain
@@ -17,25 +16,32 @@ type FlashcardService struct {
 	llm llms.Model
 }
 
-// NewFlashcardService creates a new flashcard service
+// NewFlashcardService creates a new flashcard service using the LLM provider configured in the environment
 func NewFlashcardService(ctx context.Context) (*FlashcardService, error) {
-	apiKey := os.Getenv("GOOGLE_API_KEY")
-	if apiKey == "" {
-		return nil, fmt.Errorf("GOOGLE_API_KEY environment variable is required")
+	cfg, err := LoadLLMConfig()
+	if err != nil {
+		return nil, err
 	}
 
-	llm, err := googleai.New(
-		ctx,
-		googleai.WithAPIKey(apiKey),
-		googleai.WithDefaultModel("gemini-1.5-flash"),
-	)
+	llm, err := NewLLM(ctx, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create LangChain GoogleAI client: %w", err)
+		return nil, err
 	}
 
+	log.Printf("Flashcard service using %s LLM (model: %s)", cfg.Provider, cfg.Model)
+	return NewFlashcardServiceWithModel(llm), nil
+}
+
+// NewFlashcardServiceWithModel creates a flashcard service backed by the given model
+func NewFlashcardServiceWithModel(llm llms.Model) *FlashcardService {
 	return &FlashcardService{
 		llm: llm,
-	}, nil
+	}
+}
+
+// Model returns the LLM the service generates with, for other generative features to share
+func (s *FlashcardService) Model() llms.Model {
+	return s.llm
 }
 
 // Close closes the flashcard service client (no-op for langchain)
@@ -53,11 +59,12 @@ type Note struct {
 
 // Flashcard represents a generated flashcard
 type Flashcard struct {
-	Question    string   `json:"question"`
-	Answer      string   `json:"answer"`
-	Explanation string   `json:"explanation,omitempty"`
-	Difficulty  string   `json:"difficulty,omitempty"`
-	Tags        []string `json:"tags,omitempty"`
+	Question     string   `json:"question"`
+	Answer       string   `json:"answer"`
+	Explanation  string   `json:"explanation,omitempty"`
+	Difficulty   string   `json:"difficulty,omitempty"`
+	SourceNoteID string   `json:"source_note_id,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
 }
 
 // FlashcardStreamResponse represents different types of SSE messages
@@ -75,6 +82,20 @@ type StreamStatus struct {
 	Progress    int    `json:"progress"` // 0-100
 }
 
+// maxRepairAttempts is how many times an invalid LLM response is sent back for repair
+const maxRepairAttempts = 2
+
+// flashcardSchema describes the JSON output required from the LLM
+const flashcardSchema = `[
+  {
+    "question": "問題（字串，必填）",
+    "answer": "簡短解答（字串，必填）",
+    "explanation": "補充說明（字串，可為空）",
+    "difficulty": "Easy | Medium | Hard",
+    "source_note_id": "問題所依據的筆記 ID（必須是上面列出的 ID 之一）"
+  }
+]`
+
 // sendStatus sends a status update via SSE
 func (s *FlashcardService) sendStatus(responseChan chan<- string, stage, description string, progress int) {
 	status := StreamStatus{
@@ -102,8 +123,8 @@ func (s *FlashcardService) sendChunk(responseChan chan<- string, chunk string) {
 	}
 }
 
-// sendError sends an error via SSE
-func (s *FlashcardService) sendError(responseChan chan<- string, errorMsg string) {
+// SendError sends an error via SSE
+func (s *FlashcardService) SendError(responseChan chan<- string, errorMsg string) {
 	response := FlashcardStreamResponse{
 		Type:  "error",
 		Error: errorMsg,
@@ -113,10 +134,10 @@ func (s *FlashcardService) sendError(responseChan chan<- string, errorMsg string
 	}
 }
 
-// sendComplete sends completion with final flashcard via SSE
-func (s *FlashcardService) sendComplete(responseChan chan<- string, flashcard *Flashcard) {
+// sendCard sends a single parsed flashcard via SSE
+func (s *FlashcardService) sendCard(responseChan chan<- string, flashcard Flashcard) {
 	response := FlashcardStreamResponse{
-		Type: "complete",
+		Type: "card",
 		Data: flashcard,
 	}
 	if jsonData, err := json.Marshal(response); err == nil {
@@ -124,129 +145,72 @@ func (s *FlashcardService) sendComplete(responseChan chan<- string, flashcard *F
 	}
 }
 
-// StreamFlashcardFromNotes generates a flashcard from multiple notes with SSE streaming
-func (s *FlashcardService) StreamFlashcardFromNotes(ctx context.Context, notes []Note, responseChan chan<- string) error {
-	defer close(responseChan)
-
-	if len(notes) == 0 {
-		s.sendError(responseChan, "至少需要一個筆記")
-		return fmt.Errorf("at least one note is required")
+// sendComplete sends completion with all parsed flashcards via SSE
+func (s *FlashcardService) sendComplete(responseChan chan<- string, flashcards []Flashcard) {
+	response := FlashcardStreamResponse{
+		Type: "complete",
+		Data: flashcards,
 	}
-
-	// Send initial status
-	s.sendStatus(responseChan, "preparing", "準備處理筆記...", 10)
-
-	// Combine notes into a single context
-	var notesContent strings.Builder
-	var allTags []string
-	tagSet := make(map[string]bool)
-
-	for i, note := range notes {
-		notesContent.WriteString(fmt.Sprintf("筆記 %d - %s:\n%s\n\n", i+1, note.Title, note.Content))
-
-		// Collect unique tags
-		for _, tag := range note.Tags {
-			if !tagSet[tag] {
-				allTags = append(allTags, tag)
-				tagSet[tag] = true
-			}
-		}
+	if jsonData, err := json.Marshal(response); err == nil {
+		responseChan <- fmt.Sprintf("data: %s\n\n", string(jsonData))
 	}
+}
 
-	s.sendStatus(responseChan, "generating", "正在生成閃卡...", 50)
-
-	prompt := fmt.Sprintf(`基於以下筆記請幫用戶想三個問題，這三個問題來幫助他學習，切記只需要有問題以及剪短解答即可，務必要用條列式的方式說明。筆記:%s
-專注於這些筆記中最重要的概念或關係。讓問題足夠具體，對學習有用，每個問題之間希望你能空兩格。你的回覆只需要是 markdown 即可。`, notesContent.String())
-
-	// Collect all content first, then parse
-	var fullContent strings.Builder
-
-	// Use streaming generation
-	_, err := s.llm.GenerateContent(ctx, []llms.MessageContent{
-		{
-			Role: llms.ChatMessageTypeHuman,
-			Parts: []llms.ContentPart{
-				llms.TextPart(prompt),
-			},
-		},
-	}, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
-		chunkStr := string(chunk)
-		fullContent.WriteString(chunkStr)
-		// Send each chunk as it arrives
-		s.sendChunk(responseChan, chunkStr)
-		return nil
-	}))
-
-	if err != nil {
-		s.sendError(responseChan, fmt.Sprintf("生成閃卡失敗: %v", err))
-		return fmt.Errorf("failed to generate flashcard: %w", err)
+// SendSaved notifies the client via SSE that the generated flashcards were stored in a deck
+func (s *FlashcardService) SendSaved(responseChan chan<- string, saved *SavedFlashcards) {
+	response := FlashcardStreamResponse{
+		Type: "saved",
+		Data: saved,
 	}
+	if jsonData, err := json.Marshal(response); err == nil {
+		responseChan <- fmt.Sprintf("data: %s\n\n", string(jsonData))
+	}
+}
 
-	s.sendStatus(responseChan, "parsing", "解析閃卡內容...", 90)
-
-	// Parse the complete response
-	flashcard, err := s.parseJSONFlashcardResponse(fullContent.String())
-	if err != nil {
-		s.sendError(responseChan, fmt.Sprintf("解析閃卡失敗: %v", err))
-		return fmt.Errorf("failed to parse flashcard: %w", err)
+// StreamFlashcardFromNotes generates flashcards from multiple notes with SSE streaming.
+// The caller owns responseChan and is responsible for closing it once this returns.
+func (s *FlashcardService) StreamFlashcardFromNotes(ctx context.Context, notes []Note, responseChan chan<- string) ([]Flashcard, error) {
+	if len(notes) == 0 {
+		s.SendError(responseChan, "至少需要一個筆記")
+		return nil, fmt.Errorf("at least one note is required")
 	}
 
-	flashcard.Tags = allTags
-	s.sendComplete(responseChan, flashcard)
-	s.sendStatus(responseChan, "completed", "閃卡生成完成！", 100)
+	// Send initial status
+	s.sendStatus(responseChan, "preparing", "準備處理筆記...", 10)
 
-	return nil
+	prompt := buildFlashcardPrompt("", notes)
+	return s.streamFlashcards(ctx, prompt, notes, responseChan)
 }
 
-// StreamFlashcardFromQuery generates a flashcard based on a user query and related notes with SSE streaming
-func (s *FlashcardService) StreamFlashcardFromQuery(ctx context.Context, query string, relatedNotes []Note, responseChan chan<- string) error {
-	defer close(responseChan)
-
+// StreamFlashcardFromQuery generates flashcards based on a user query and related notes with SSE streaming.
+// The caller owns responseChan and is responsible for closing it once this returns.
+func (s *FlashcardService) StreamFlashcardFromQuery(ctx context.Context, query string, relatedNotes []Note, responseChan chan<- string) ([]Flashcard, error) {
 	if query == "" {
-		s.sendError(responseChan, "查詢不能為空")
-		return fmt.Errorf("query cannot be empty")
+		s.SendError(responseChan, "查詢不能為空")
+		return nil, fmt.Errorf("query cannot be empty")
 	}
 	if len(relatedNotes) == 0 {
-		s.sendError(responseChan, "沒有找到相關筆記")
-		return fmt.Errorf("no related notes found")
+		s.SendError(responseChan, "沒有找到相關筆記")
+		return nil, fmt.Errorf("no related notes found")
 	}
 
 	s.sendStatus(responseChan, "preparing", "準備處理查詢和相關筆記...", 10)
 
-	// Combine notes into context
-	var notesContent strings.Builder
-	var allTags []string
-	tagSet := make(map[string]bool)
-
-	for i, note := range relatedNotes {
-		notesContent.WriteString(fmt.Sprintf("筆記 %d - %s:\n%s\n\n", i+1, note.Title, note.Content))
-
-		// Collect unique tags
-		for _, tag := range note.Tags {
-			if !tagSet[tag] {
-				allTags = append(allTags, tag)
-				tagSet[tag] = true
-			}
-		}
-	}
+	prompt := buildFlashcardPrompt(query, relatedNotes)
+	return s.streamFlashcards(ctx, prompt, relatedNotes, responseChan)
+}
 
+// streamFlashcards streams the LLM output, repairs it if needed and emits one card event per flashcard
+func (s *FlashcardService) streamFlashcards(ctx context.Context, prompt string, notes []Note, responseChan chan<- string) ([]Flashcard, error) {
 	s.sendStatus(responseChan, "generating", "正在生成閃卡...", 50)
 
-	prompt := fmt.Sprintf(`用戶詢問："%s" 基於以下筆記請幫用戶想三個問題，這三個問題來幫助他學習，切記只需要有問題以及剪短解答即可，務必要用條列式的方式說明。筆記:%s
-專注於這些筆記中最重要的概念或關係。讓問題足夠具體，對學習有用，每個問題之間希望你能空兩格。你的回覆只需要是 markdown 即可。`, query, notesContent.String())
-
 	// Collect all content first, then parse
 	var fullContent strings.Builder
 
 	// Use streaming generation
 	_, err := s.llm.GenerateContent(ctx, []llms.MessageContent{
-		{
-			Role: llms.ChatMessageTypeHuman,
-			Parts: []llms.ContentPart{
-				llms.TextPart(prompt),
-			},
-		},
-	}, llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt),
+	}, llms.WithJSONMode(), llms.WithStreamingFunc(func(ctx context.Context, chunk []byte) error {
 		chunkStr := string(chunk)
 		fullContent.WriteString(chunkStr)
 		// Send each chunk as it arrives
@@ -255,191 +219,214 @@ func (s *FlashcardService) StreamFlashcardFromQuery(ctx context.Context, query s
 	}))
 
 	if err != nil {
-		s.sendError(responseChan, fmt.Sprintf("生成閃卡失敗: %v", err))
-		return fmt.Errorf("failed to generate flashcard: %w", err)
+		s.SendError(responseChan, fmt.Sprintf("生成閃卡失敗: %v", err))
+		return nil, fmt.Errorf("failed to generate flashcard: %w", err)
 	}
 
 	s.sendStatus(responseChan, "parsing", "解析閃卡內容...", 90)
 
-	// Parse the complete response
-	flashcard, err := s.parseJSONFlashcardResponse(fullContent.String())
+	flashcards, err := s.parseWithRepair(ctx, fullContent.String(), notes, func(attempt int) {
+		s.sendStatus(responseChan, "repairing", fmt.Sprintf("閃卡格式錯誤，正在修正（第 %d 次）...", attempt), 90)
+	})
 	if err != nil {
-		s.sendError(responseChan, fmt.Sprintf("解析閃卡失敗: %v", err))
-		return fmt.Errorf("failed to parse flashcard: %w", err)
+		s.SendError(responseChan, fmt.Sprintf("解析閃卡失敗: %v", err))
+		return nil, fmt.Errorf("failed to parse flashcard: %w", err)
 	}
 
-	flashcard.Tags = allTags
-	s.sendComplete(responseChan, flashcard)
+	for _, flashcard := range flashcards {
+		s.sendCard(responseChan, flashcard)
+	}
+	s.sendComplete(responseChan, flashcards)
 	s.sendStatus(responseChan, "completed", "閃卡生成完成！", 100)
 
-	return nil
+	return flashcards, nil
 }
 
-// GenerateFlashcardFromNotes generates a flashcard from multiple notes (non-streaming version)
-func (s *FlashcardService) GenerateFlashcardFromNotes(ctx context.Context, notes []Note) (*Flashcard, error) {
+// GenerateFlashcardFromNotes generates flashcards from multiple notes (non-streaming version)
+func (s *FlashcardService) GenerateFlashcardFromNotes(ctx context.Context, notes []Note) ([]Flashcard, error) {
 	if len(notes) == 0 {
 		return nil, fmt.Errorf("at least one note is required")
 	}
 
-	// Combine notes into a single context
-	var notesContent strings.Builder
-	var allTags []string
-	tagSet := make(map[string]bool)
+	return s.generateFlashcards(ctx, buildFlashcardPrompt("", notes), notes)
+}
 
-	for i, note := range notes {
-		notesContent.WriteString(fmt.Sprintf("筆記 %d - %s:\n%s\n\n", i+1, note.Title, note.Content))
-
-		// Collect unique tags
-		for _, tag := range note.Tags {
-			if !tagSet[tag] {
-				allTags = append(allTags, tag)
-				tagSet[tag] = true
-			}
-		}
+// GenerateFlashcardFromQuery generates flashcards based on a user query and related notes (non-streaming version)
+func (s *FlashcardService) GenerateFlashcardFromQuery(ctx context.Context, query string, relatedNotes []Note) ([]Flashcard, error) {
+	if query == "" {
+		return nil, fmt.Errorf("query cannot be empty")
+	}
+	if len(relatedNotes) == 0 {
+		return nil, fmt.Errorf("no related notes found")
 	}
 
-	prompt := fmt.Sprintf(`基於以下筆記請幫用戶想三個問題，這三個問題來幫助他學習，切記只需要有問題以及剪短解答即可，務必要用條列式的方式說明。筆記:%s
-專注於這些筆記中最重要的概念或關係。讓問題足夠具體，對學習有用，每個問題之間希望你能空兩格。你的回覆只需要是 markdown 即可。`, notesContent.String())
+	return s.generateFlashcards(ctx, buildFlashcardPrompt(query, relatedNotes), relatedNotes)
+}
 
+// generateFlashcards runs the prompt without streaming and parses the result
+func (s *FlashcardService) generateFlashcards(ctx context.Context, prompt string, notes []Note) ([]Flashcard, error) {
 	resp, err := s.llm.GenerateContent(ctx, []llms.MessageContent{
-		{
-			Role: llms.ChatMessageTypeHuman,
-			Parts: []llms.ContentPart{
-				llms.TextPart(prompt),
-			},
-		},
-	})
+		llms.TextParts(llms.ChatMessageTypeHuman, prompt),
+	}, llms.WithJSONMode())
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate flashcard: %w", err)
 	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("empty response from LLM")
+	}
 
-	// Parse JSON response
-	flashcard, err := s.parseJSONFlashcardResponse(resp.Choices[0].Content)
+	flashcards, err := s.parseWithRepair(ctx, resp.Choices[0].Content, notes, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse flashcard response: %w", err)
 	}
 
-	flashcard.Tags = allTags
-	return flashcard, nil
+	return flashcards, nil
 }
 
-// GenerateFlashcardFromQuery generates a flashcard based on a user query and related notes (non-streaming version)
-func (s *FlashcardService) GenerateFlashcardFromQuery(ctx context.Context, query string, relatedNotes []Note) (*Flashcard, error) {
-	if query == "" {
-		return nil, fmt.Errorf("query cannot be empty")
+// parseWithRepair parses the LLM output and asks the LLM to fix it when it does not match the schema
+func (s *FlashcardService) parseWithRepair(ctx context.Context, content string, notes []Note, onRepair func(attempt int)) ([]Flashcard, error) {
+	flashcards, err := parseFlashcards(content, notes)
+	for attempt := 1; err != nil && attempt <= maxRepairAttempts; attempt++ {
+		if onRepair != nil {
+			onRepair(attempt)
+		}
+
+		resp, genErr := s.llm.GenerateContent(ctx, []llms.MessageContent{
+			llms.TextParts(llms.ChatMessageTypeHuman, buildRepairPrompt(content, err, notes)),
+		}, llms.WithJSONMode())
+		if genErr != nil {
+			return nil, fmt.Errorf("failed to repair flashcards: %w", genErr)
+		}
+		if len(resp.Choices) == 0 {
+			return nil, fmt.Errorf("empty repair response from LLM")
+		}
+
+		content = resp.Choices[0].Content
+		flashcards, err = parseFlashcards(content, notes)
 	}
-	if len(relatedNotes) == 0 {
-		return nil, fmt.Errorf("no related notes found")
+	if err != nil {
+		return nil, err
 	}
 
-	// Combine notes into context
+	return flashcards, nil
+}
+
+// buildNotesContext formats notes for the prompt, labelling each note with its ID
+func buildNotesContext(notes []Note) string {
 	var notesContent strings.Builder
-	var allTags []string
-	tagSet := make(map[string]bool)
-
-	for i, note := range relatedNotes {
-		notesContent.WriteString(fmt.Sprintf("筆記 %d - %s:\n%s\n\n", i+1, note.Title, note.Content))
-
-		// Collect unique tags
-		for _, tag := range note.Tags {
-			if !tagSet[tag] {
-				allTags = append(allTags, tag)
-				tagSet[tag] = true
-			}
-		}
+	for i, note := range notes {
+		notesContent.WriteString(fmt.Sprintf("筆記 %d（ID: %s）- %s:\n%s\n\n", i+1, note.ID, note.Title, note.Content))
 	}
+	return notesContent.String()
+}
 
-	prompt := fmt.Sprintf(`用戶詢問："%s"基於以下筆記請幫用戶想三個問題，這三個問題來幫助他學習，切記只需要有問題以及剪短解答即可，務必要用條列式的方式說明。筆記:%s
-專注於這些筆記中最重要的概念或關係。讓問題足夠具體，對學習有用，每個問題之間希望你能空兩格。你的回覆只需要是 markdown 即可。`, query, notesContent.String())
-
-	resp, err := s.llm.GenerateContent(ctx, []llms.MessageContent{
-		{
-			Role: llms.ChatMessageTypeHuman,
-			Parts: []llms.ContentPart{
-				llms.TextPart(prompt),
-			},
-		},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate flashcard: %w", err)
+// buildFlashcardPrompt builds the generation prompt, optionally focused on a user query
+func buildFlashcardPrompt(query string, notes []Note) string {
+	var focus string
+	if query != "" {
+		focus = fmt.Sprintf("用戶詢問：\"%s\"\n", query)
 	}
 
-	// Parse JSON response
-	flashcard, err := s.parseJSONFlashcardResponse(resp.Choices[0].Content)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse flashcard response: %w", err)
+	return fmt.Sprintf(`%s基於以下筆記請幫用戶想三個問題，這三個問題來幫助他學習，每個問題只需要簡短解答。
+筆記:
+%s
+專注於這些筆記中最重要的概念或關係。讓問題足夠具體，對學習有用。
+你的回覆必須是符合以下格式的 JSON 陣列，不要包含 markdown 或任何其他文字：
+%s`, focus, buildNotesContext(notes), flashcardSchema)
+}
+
+// buildRepairPrompt asks the LLM to rewrite an invalid response so it matches the schema
+func buildRepairPrompt(invalid string, parseErr error, notes []Note) string {
+	noteIDs := make([]string, 0, len(notes))
+	for _, note := range notes {
+		noteIDs = append(noteIDs, note.ID)
 	}
 
-	flashcard.Tags = allTags
-	return flashcard, nil
+	return fmt.Sprintf(`以下的回覆不符合要求的 JSON 格式，錯誤：%v
+請修正並只回傳符合格式的 JSON 陣列，不要包含 markdown 或任何其他文字。
+可用的 source_note_id：%s
+格式：
+%s
+原始回覆：
+%s`, parseErr, strings.Join(noteIDs, ", "), flashcardSchema, invalid)
 }
 
-// parseJSONFlashcardResponse parses the JSON response from LLM into a Flashcard struct
-func (s *FlashcardService) parseJSONFlashcardResponse(content string) (*Flashcard, error) {
+// parseFlashcards parses and validates the JSON array returned by the LLM
+func parseFlashcards(content string, notes []Note) ([]Flashcard, error) {
 	// Clean the content - remove markdown code blocks if present
 	content = strings.TrimSpace(content)
-	if strings.TrimPrefix(content, "```json") != "" {
-		content = strings.TrimPrefix(content, "```json")
-	}
-	if strings.TrimPrefix(content, "```") != "" {
-		content = strings.TrimPrefix(content, "```")
-	}
-	if strings.TrimSuffix(content, "```") != "" {
-		content = strings.TrimSuffix(content, "```")
-	}
+	content = strings.TrimPrefix(content, "```json")
+	content = strings.TrimPrefix(content, "```")
+	content = strings.TrimSuffix(content, "```")
 	content = strings.TrimSpace(content)
 
-	var flashcard Flashcard
-	if err := json.Unmarshal([]byte(content), &flashcard); err != nil {
-		// If JSON parsing fails, try to extract from text format
-		return s.parseTextFlashcardResponse(content), nil
+	var flashcards []Flashcard
+	if err := json.Unmarshal([]byte(content), &flashcards); err != nil {
+		// Some models wrap the array in an object
+		var wrapped struct {
+			Flashcards []Flashcard `json:"flashcards"`
+		}
+		if wrapErr := json.Unmarshal([]byte(content), &wrapped); wrapErr != nil || wrapped.Flashcards == nil {
+			return nil, fmt.Errorf("response is not a JSON array of flashcards: %w", err)
+		}
+		flashcards = wrapped.Flashcards
 	}
 
-	// Set default values if empty
-	if flashcard.Question == "" {
-		flashcard.Question = "學習問題"
-	}
-	if flashcard.Answer == "" {
-		flashcard.Answer = content // Use entire content as fallback
+	if len(flashcards) == 0 {
+		return nil, fmt.Errorf("response contains no flashcards")
 	}
-	if flashcard.Difficulty == "" {
-		flashcard.Difficulty = "Medium"
+
+	notesByID := make(map[string]Note, len(notes))
+	for _, note := range notes {
+		notesByID[note.ID] = note
 	}
 
-	return &flashcard, nil
-}
+	for i := range flashcards {
+		card := &flashcards[i]
+		card.Question = strings.TrimSpace(card.Question)
+		card.Answer = strings.TrimSpace(card.Answer)
+		card.Explanation = strings.TrimSpace(card.Explanation)
+		card.SourceNoteID = strings.TrimSpace(card.SourceNoteID)
 
-// parseTextFlashcardResponse parses text format response as fallback
-func (s *FlashcardService) parseTextFlashcardResponse(content string) *Flashcard {
-	flashcard := &Flashcard{
-		Difficulty: "Medium",
-	}
+		if card.Question == "" {
+			return nil, fmt.Errorf("flashcard %d is missing a question", i+1)
+		}
+		if card.Answer == "" {
+			return nil, fmt.Errorf("flashcard %d is missing an answer", i+1)
+		}
 
-	lines := strings.Split(content, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
+		difficulty, err := normalizeDifficulty(card.Difficulty)
+		if err != nil {
+			return nil, fmt.Errorf("flashcard %d: %w", i+1, err)
 		}
+		card.Difficulty = difficulty
 
-		if strings.HasPrefix(line, "Question:") || strings.HasPrefix(line, "問題:") {
-			flashcard.Question = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "Question:"), "問題:"))
-		} else if strings.HasPrefix(line, "Answer:") || strings.HasPrefix(line, "答案:") {
-			flashcard.Answer = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "Answer:"), "答案:"))
-		} else if strings.HasPrefix(line, "Explanation:") || strings.HasPrefix(line, "解釋:") {
-			flashcard.Explanation = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "Explanation:"), "解釋:"))
-		} else if strings.HasPrefix(line, "Difficulty:") || strings.HasPrefix(line, "難度:") {
-			flashcard.Difficulty = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(line, "Difficulty:"), "難度:"))
+		// A single source note is unambiguous even if the model omitted it
+		if card.SourceNoteID == "" && len(notes) == 1 {
+			card.SourceNoteID = notes[0].ID
 		}
+		note, ok := notesByID[card.SourceNoteID]
+		if !ok {
+			return nil, fmt.Errorf("flashcard %d has unknown source_note_id %q", i+1, card.SourceNoteID)
+		}
+		card.Tags = note.Tags
 	}
 
-	// Fallback values
-	if flashcard.Question == "" {
-		flashcard.Question = "學習問題"
-	}
-	if flashcard.Answer == "" {
-		flashcard.Answer = content
-	}
+	return flashcards, nil
+}
 
-	return flashcard
+// normalizeDifficulty maps the difficulty to Easy, Medium or Hard, defaulting to Medium
+func normalizeDifficulty(difficulty string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(difficulty)) {
+	case "":
+		return "Medium", nil
+	case "easy", "簡單":
+		return "Easy", nil
+	case "medium", "中等":
+		return "Medium", nil
+	case "hard", "困難":
+		return "Hard", nil
+	default:
+		return "", fmt.Errorf("invalid difficulty %q", difficulty)
+	}
 }
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Flashcard export formats
+const (
+	FlashcardExportApkg = "apkg"
+	FlashcardExportCSV  = "csv"
+	FlashcardExportTSV  = "tsv"
+)
+
+// FlashcardExportFileName is the file name to download an export of the deck as
+func FlashcardExportFileName(deckName, format string) string {
+	return safeFileName(deckName) + "." + format
+}
+
+// ankiModelID identifies the go-note note type. It stays the same across
+// exports, so importing another export reuses the note type in Anki.
+const ankiModelID int64 = 1700000000001
+
+// ankiModelName is the name of the go-note note type in Anki
+const ankiModelName = "go-note Flashcard"
+
+// ankiFields are the fields of the go-note note type, in order
+var ankiFields = []string{"Question", "Answer", "Explanation", "Source"}
+
+// ankiCSS styles the cards of the go-note note type
+const ankiCSS = `.card { font-family: arial; font-size: 20px; text-align: center; color: black; background-color: white; }
+.explanation { margin-top: 1em; font-size: 16px; color: #555; text-align: left; }
+.source { margin-top: 1em; font-size: 12px; color: #888; }`
+
+// Card templates of the go-note note type
+const (
+	ankiQuestionFormat = `{{Question}}`
+	ankiAnswerFormat   = `{{FrontSide}}
+
+<hr id=answer>
+
+{{Answer}}
+{{#Explanation}}<div class="explanation">{{Explanation}}</div>{{/Explanation}}
+{{#Source}}<div class="source">{{Source}}</div>{{/Source}}`
+)
+
+// ankiSchema is the schema of an Anki collection (version 11), which every
+// Anki release can import
+var ankiSchema = []struct{ name, table, sql string }{
+	{"col", "", "CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null)"},
+	{"notes", "", "CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null)"},
+	{"cards", "", "CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null)"},
+	{"revlog", "", "CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null)"},
+	{"graves", "", "CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null)"},
+	{"ix_notes_usn", "notes", "CREATE INDEX ix_notes_usn on notes (usn)"},
+	{"ix_cards_usn", "cards", "CREATE INDEX ix_cards_usn on cards (usn)"},
+	{"ix_revlog_usn", "revlog", "CREATE INDEX ix_revlog_usn on revlog (usn)"},
+	{"ix_cards_nid", "cards", "CREATE INDEX ix_cards_nid on cards (nid)"},
+	{"ix_cards_sched", "cards", "CREATE INDEX ix_cards_sched on cards (did, queue, due)"},
+	{"ix_revlog_cid", "revlog", "CREATE INDEX ix_revlog_cid on revlog (cid)"},
+	{"ix_notes_csum", "notes", "CREATE INDEX ix_notes_csum on notes (csum)"},
+}
+
+// WriteAnkiPackage writes flashcards as an Anki package (.apkg): a zip with
+// the collection as a SQLite database and an empty media manifest. The cards
+// go into a deck named deckName and use the go-note note type, whose fields
+// are the question, answer, explanation and the title of the source note;
+// the source note's tags become Anki tags, with / turned into Anki's ::
+// hierarchy. notes are the notes the flashcards were generated from.
+func WriteAnkiPackage(w io.Writer, deckName string, flashcards []Flashcard, notes []Note, now time.Time) error {
+	collection, err := ankiCollection(deckName, flashcards, notes, now)
+	if err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"collection.anki2", collection},
+		{"media", []byte("{}")},
+	} {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", file.name, err)
+		}
+		if _, err := fw.Write(file.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to finish package: %w", err)
+	}
+	return nil
+}
+
+// ankiCollection builds the SQLite database of an Anki package
+func ankiCollection(deckName string, flashcards []Flashcard, notes []Note, now time.Time) ([]byte, error) {
+	titles := make(map[string]string, len(notes))
+	for _, note := range notes {
+		titles[note.ID] = note.Title
+	}
+
+	// Anki uses millisecond timestamps as IDs; consecutive ones keep the cards in order
+	deckID := now.UnixMilli()
+	mod := now.Unix()
+	noteRows := make([]sqliteRow, len(flashcards))
+	cardRows := make([]sqliteRow, len(flashcards))
+	var noteKeys, csumKeys, cardKeys, nidKeys, schedKeys [][]any
+	for i, card := range flashcards {
+		id := deckID + int64(i)
+		fields := []string{
+			ankiField(card.Question),
+			ankiField(card.Answer),
+			ankiField(card.Explanation),
+			ankiField(titles[card.SourceNoteID]),
+		}
+		guid := sha256.Sum256([]byte(card.SourceNoteID + "\x1f" + card.Question))
+		csum := sha1.Sum([]byte(card.Question))
+		checksum := int64(binary.BigEndian.Uint32(csum[:4]))
+
+		noteRows[i] = sqliteRow{RowID: id, Values: []any{
+			nil,
+			hex.EncodeToString(guid[:8]),
+			ankiModelID,
+			mod,
+			int64(-1),
+			ankiTags(card.Tags),
+			strings.Join(fields, "\x1f"),
+			card.Question,
+			checksum,
+			int64(0),
+			"",
+		}}
+		// New cards: type and queue 0, due is the position in the new queue
+		cardRows[i] = sqliteRow{RowID: id, Values: []any{
+			nil, id, deckID, int64(0), mod, int64(-1),
+			int64(0), int64(0), int64(i + 1), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0), int64(0),
+			"",
+		}}
+		noteKeys = append(noteKeys, []any{int64(-1), id})
+		csumKeys = append(csumKeys, []any{checksum, id})
+		cardKeys = append(cardKeys, []any{int64(-1), id})
+		nidKeys = append(nidKeys, []any{id, id})
+		schedKeys = append(schedKeys, []any{deckID, int64(0), int64(i + 1), id})
+	}
+
+	colRow, err := ankiColRow(deckName, deckID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	db := newSQLiteDatabase()
+	tables := map[string][]sqliteRow{
+		"col":   {colRow},
+		"notes": noteRows,
+		"cards": cardRows,
+	}
+	indexKeys := map[string][][]any{
+		"ix_notes_usn":   noteKeys,
+		"ix_cards_usn":   cardKeys,
+		"ix_cards_nid":   nidKeys,
+		"ix_cards_sched": schedKeys,
+		"ix_notes_csum":  csumKeys,
+	}
+	for _, entry := range ankiSchema {
+		var err error
+		if entry.table == "" {
+			err = db.addTable(entry.name, entry.sql, tables[entry.name])
+		} else {
+			err = db.addIndex(entry.name, entry.table, entry.sql, indexKeys[entry.name])
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return db.bytes()
+}
+
+// ankiColRow builds the single row of the col table, which holds the
+// collection's configuration, note types and decks as JSON
+func ankiColRow(deckName string, deckID int64, now time.Time) (sqliteRow, error) {
+	mod := now.Unix()
+	deck := func(id int64, name string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "mod": mod, "usn": -1, "desc": "", "dyn": 0, "conf": 1,
+			"collapsed": false, "browserCollapsed": false, "extendNew": 0, "extendRev": 0,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+
+	fields := make([]map[string]any, len(ankiFields))
+	for i, name := range ankiFields {
+		fields[i] = map[string]any{
+			"name": name, "ord": i, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{},
+		}
+	}
+	model := map[string]any{
+		"id":    ankiModelID,
+		"name":  ankiModelName,
+		"type":  0,
+		"mod":   mod,
+		"usn":   -1,
+		"sortf": 0,
+		"did":   deckID,
+		"tmpls": []map[string]any{{
+			"name": "Card 1", "ord": 0, "qfmt": ankiQuestionFormat, "afmt": ankiAnswerFormat,
+			"bqfmt": "", "bafmt": "", "did": nil,
+		}},
+		"flds":      fields,
+		"css":       ankiCSS,
+		"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+		"latexPost": "\\end{document}",
+		"latexsvg":  false,
+		"req":       []any{[]any{0, "any", []int{0}}},
+		"tags":      []string{},
+		"vers":      []string{},
+	}
+
+	conf := map[string]any{
+		"activeDecks": []int64{deckID}, "curDeck": deckID, "curModel": strconv.FormatInt(ankiModelID, 10),
+		"addToCur": true, "collapseTime": 1200, "dueCounts": true, "estTimes": true, "newSpread": 0,
+		"nextPos": 1, "sortBackwards": false, "sortType": "noteFld", "timeLim": 0,
+	}
+	decks := map[string]any{
+		"1":                           deck(1, "Default"),
+		strconv.FormatInt(deckID, 10): deck(deckID, deckName),
+	}
+	dconf := map[string]any{"1": map[string]any{
+		"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+		"new":   map[string]any{"bury": false, "delays": []float64{1, 10}, "initialFactor": 2500, "ints": []int{1, 4, 0}, "order": 1, "perDay": 20},
+		"lapse": map[string]any{"delays": []float64{10}, "leechAction": 1, "leechFails": 8, "minInt": 1, "mult": 0},
+		"rev":   map[string]any{"bury": false, "ease4": 1.3, "ivlFct": 1, "maxIvl": 36500, "perDay": 200, "hardFactor": 1.2},
+	}}
+
+	values := []any{nil}
+	// crt is when the collection was created, at the start of the day
+	year, month, day := now.UTC().Date()
+	values = append(values, time.Date(year, month, day, 0, 0, 0, 0, time.UTC).Unix(), now.UnixMilli(), now.UnixMilli(), int64(11), int64(0), int64(0), int64(0))
+	for _, v := range []any{conf, map[string]any{strconv.FormatInt(ankiModelID, 10): model}, decks, dconf, map[string]any{}} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return sqliteRow{}, fmt.Errorf("failed to encode collection: %w", err)
+		}
+		values = append(values, string(data))
+	}
+	return sqliteRow{RowID: 1, Values: values}, nil
+}
+
+// ankiField turns plain text into the HTML of an Anki field
+func ankiField(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
+
+// ankiTags formats tags as Anki stores them: space separated with a space
+// on either side, hierarchical levels joined by ::
+func ankiTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	converted := make([]string, len(tags))
+	for i, tag := range tags {
+		converted[i] = strings.ReplaceAll(tag, TagSeparator, "::")
+	}
+	return " " + strings.Join(converted, " ") + " "
+}
+
+// FlashcardTextOptions controls a CSV or TSV export of flashcards
+type FlashcardTextOptions struct {
+	Separator rune // ',' or '\t'
+	// Basic writes only the question and answer, one line per card, as
+	// Quizlet imports them. Otherwise the file has the explanation, source
+	// note and tags as well, with header lines telling Anki's text import
+	// which column holds what.
+	Basic bool
+}
+
+// WriteFlashcardsText writes flashcards as CSV or TSV for Anki's text import or Quizlet
+func WriteFlashcardsText(w io.Writer, flashcards []Flashcard, notes []Note, opts FlashcardTextOptions) error {
+	if opts.Basic {
+		// One line per card, quoted where a field holds the separator or a quote
+		flatten := strings.NewReplacer("\r\n", " ", "\n", " ", "\t", " ")
+		cw := csv.NewWriter(w)
+		cw.Comma = opts.Separator
+		for _, card := range flashcards {
+			if err := cw.Write([]string{flatten.Replace(card.Question), flatten.Replace(card.Answer)}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+
+	titles := make(map[string]string, len(notes))
+	for _, note := range notes {
+		titles[note.ID] = note.Title
+	}
+
+	separator := "Comma"
+	if opts.Separator == '\t' {
+		separator = "Tab"
+	}
+	columns := strings.Join(append(ankiFields, "Tags"), string(opts.Separator))
+	header := "#separator:" + separator + "\n#html:true\n#columns:" + columns + "\n#tags column:" + strconv.Itoa(len(ankiFields)+1) + "\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Comma = opts.Separator
+	for _, card := range flashcards {
+		record := []string{
+			ankiField(card.Question),
+			ankiField(card.Answer),
+			ankiField(card.Explanation),
+			ankiField(titles[card.SourceNoteID]),
+			strings.TrimSpace(ankiTags(card.Tags)),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var exportFlashcards = []Flashcard{
+	{Question: "What is a goroutine?", Answer: "A lightweight thread,\nmanaged by the Go runtime", Explanation: "Started with <go>", SourceNoteID: "n1", Tags: []string{"lang/go", "concurrency"}},
+	{Question: "What does \"defer\" do?", Answer: "Runs a call when the function returns", SourceNoteID: "n2"},
+}
+
+var exportNotes = []Note{
+	{ID: "n1", Title: "Go, concurrency"},
+	{ID: "n2", Title: "Go basics"},
+}
+
+func TestWriteFlashcardsText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFlashcardsText(&buf, exportFlashcards, exportNotes, FlashcardTextOptions{Separator: ','}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := strings.Join([]string{
+		"#separator:Comma",
+		"#html:true",
+		"#columns:Question,Answer,Explanation,Source,Tags",
+		"#tags column:5",
+		`What is a goroutine?,"A lightweight thread,<br>managed by the Go runtime",Started with &lt;go&gt;,"Go, concurrency",lang::go concurrency`,
+		`What does &#34;defer&#34; do?,Runs a call when the function returns,,Go basics,`,
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected CSV:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteFlashcardsTextBasic(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFlashcardsText(&buf, exportFlashcards, exportNotes, FlashcardTextOptions{Separator: '\t', Basic: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "What is a goroutine?\tA lightweight thread, managed by the Go runtime\n" +
+		"\"What does \"\"defer\"\" do?\"\tRuns a call when the function returns\n"
+	if got := buf.String(); got != want {
+		t.Errorf("unexpected TSV:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteFlashcardsCSVBasicQuotesFields(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFlashcardsText(&buf, exportFlashcards, exportNotes, FlashcardTextOptions{Separator: ',', Basic: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("basic CSV does not parse: %v", err)
+	}
+	want := [][]string{
+		{"What is a goroutine?", "A lightweight thread, managed by the Go runtime"},
+		{`What does "defer" do?`, "Runs a call when the function returns"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("unexpected records %q, want %q", records, want)
+	}
+}
+
+func TestWriteAnkiPackage(t *testing.T) {
+	var buf bytes.Buffer
+	now := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if err := WriteAnkiPackage(&buf, "Go", exportFlashcards, exportNotes, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("package is not a zip: %v", err)
+	}
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		files[f.Name] = data
+	}
+	if len(files) != 2 {
+		t.Errorf("expected collection.anki2 and media, got %d files", len(files))
+	}
+	if string(files["media"]) != "{}" {
+		t.Errorf("expected an empty media manifest, got %q", files["media"])
+	}
+	collection := files["collection.anki2"]
+	if !bytes.HasPrefix(collection, []byte("SQLite format 3\x00")) {
+		t.Fatalf("collection is not a SQLite database")
+	}
+	checkSQLiteIntegrity(t, collection, 2)
+	for _, want := range []string{"go-note Flashcard", "What is a goroutine?\x1fA lightweight thread,<br>managed by the Go runtime", " lang::go concurrency "} {
+		if !bytes.Contains(collection, []byte(want)) {
+			t.Errorf("expected %q in the collection", want)
+		}
+	}
+}
+
+// checkSQLiteIntegrity opens a database with the sqlite3 command line tool,
+// when it is installed, and checks that it is intact and holds notes notes
+func checkSQLiteIntegrity(t *testing.T, data []byte, notes int) {
+	t.Helper()
+	sqlite, err := exec.LookPath("sqlite3")
+	if err != nil {
+		t.Log("sqlite3 not installed, not checking the collection with SQLite")
+		return
+	}
+
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("failed to write collection: %v", err)
+	}
+	out, err := exec.Command(sqlite, path, "PRAGMA integrity_check;", "SELECT count(*) FROM notes;", "SELECT count(*) FROM cards;").CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3 failed: %v\n%s", err, out)
+	}
+	want := fmt.Sprintf("ok\n%d\n%d\n", notes, notes)
+	if string(out) != want {
+		t.Errorf("unexpected sqlite3 output %q, want %q", out, want)
+	}
+}
+
+func TestAnkiTags(t *testing.T) {
+	if got := ankiTags([]string{"lang/go", "db"}); got != " lang::go db " {
+		t.Errorf("unexpected tags %q", got)
+	}
+	if got := ankiTags(nil); got != "" {
+		t.Errorf("expected no tags, got %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseFlashcards(t *testing.T) {
+	notes := []Note{
+		{ID: "note-1", Title: "Go", Tags: []string{"go"}},
+		{ID: "note-2", Title: "Postgres", Tags: []string{"db"}},
+	}
+
+	content := "```json\n" + `[
+		{"question": "What is a goroutine?", "answer": "A lightweight thread", "difficulty": "easy", "source_note_id": "note-1"},
+		{"question": "What is MVCC?", "answer": "Multi-version concurrency control", "source_note_id": "note-2"}
+	]` + "\n```"
+
+	flashcards, err := parseFlashcards(content, notes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(flashcards) != 2 {
+		t.Fatalf("expected 2 flashcards, got %d", len(flashcards))
+	}
+	if flashcards[0].Difficulty != "Easy" {
+		t.Errorf("expected difficulty to be normalized to Easy, got %q", flashcards[0].Difficulty)
+	}
+	if flashcards[1].Difficulty != "Medium" {
+		t.Errorf("expected default difficulty Medium, got %q", flashcards[1].Difficulty)
+	}
+	if len(flashcards[1].Tags) != 1 || flashcards[1].Tags[0] != "db" {
+		t.Errorf("expected tags of the source note, got %v", flashcards[1].Tags)
+	}
+}
+
+func TestParseFlashcardsWrappedObject(t *testing.T) {
+	notes := []Note{{ID: "note-1"}}
+	content := `{"flashcards": [{"question": "Q", "answer": "A"}]}`
+
+	flashcards, err := parseFlashcards(content, notes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if flashcards[0].SourceNoteID != "note-1" {
+		t.Errorf("expected single note to be used as source, got %q", flashcards[0].SourceNoteID)
+	}
+}
+
+func TestParseFlashcardsInvalid(t *testing.T) {
+	notes := []Note{{ID: "note-1"}, {ID: "note-2"}}
+
+	tests := map[string]string{
+		"markdown":       "1. What is Go?\n   - A language",
+		"empty array":    `[]`,
+		"missing answer": `[{"question": "Q", "source_note_id": "note-1"}]`,
+		"unknown source": `[{"question": "Q", "answer": "A", "source_note_id": "note-9"}]`,
+		"bad difficulty": `[{"question": "Q", "answer": "A", "difficulty": "extreme", "source_note_id": "note-1"}]`,
+	}
+	for name, content := range tests {
+		if _, err := parseFlashcards(content, notes); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+func TestBuildRepairPromptIncludesNoteIDs(t *testing.T) {
+	prompt := buildRepairPrompt("not json", errors.New("invalid"), []Note{{ID: "note-1"}, {ID: "note-2"}})
+	if !strings.Contains(prompt, "note-1, note-2") {
+		t.Errorf("expected repair prompt to list note IDs, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "not json") {
+		t.Errorf("expected repair prompt to include the invalid output")
+	}
+}
@@ -0,0 +1,439 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Graph node and edge types
+const (
+	GraphNodeNote = "note"
+	GraphNodeTag  = "tag"
+
+	GraphEdgeLink     = "link"     // a note references another in its content
+	GraphEdgeTag      = "tag"      // a note carries a tag
+	GraphEdgeSemantic = "semantic" // two notes have similar embeddings
+)
+
+// ErrGraphNoteNotFound is returned when the start note does not exist
+var ErrGraphNoteNotFound = errors.New("note not found")
+
+// GraphNode is a note or a tag in the knowledge graph
+type GraphNode struct {
+	ID         string   `json:"id"` // note ID, or "tag:" followed by the tag
+	Type       string   `json:"type"`
+	Label      string   `json:"label"`
+	Tags       []string `json:"tags,omitempty"`
+	NotebookID *string  `json:"notebook_id,omitempty"`
+	Depth      int      `json:"depth"` // hops from the start note; tags take their closest note's depth
+}
+
+// GraphEdge connects two nodes of the knowledge graph
+type GraphEdge struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Type   string  `json:"type"`
+	Weight float64 `json:"weight"` // similarity for semantic edges, 1 otherwise
+}
+
+// Graph is the part of a user's knowledge graph returned to the client
+type Graph struct {
+	Nodes     []GraphNode `json:"nodes"`
+	Edges     []GraphEdge `json:"edges"`
+	Truncated bool        `json:"truncated"` // more notes were reachable than MaxNodes
+}
+
+// GraphOptions selects the part of the graph to build
+type GraphOptions struct {
+	StartNoteID string  // walk out from this note; empty for the most recent notes
+	Depth       int     // hops to walk from the start note
+	Neighbors   int     // semantic neighbors per note, 0 leaves semantic edges out
+	Threshold   float64 // minimum similarity of a semantic edge
+	IncludeTags bool
+	MaxNodes    int // most note nodes to return
+}
+
+// GraphNote is a note as seen by the graph builder
+type GraphNote struct {
+	ID         string
+	Title      string
+	Tags       []string
+	NotebookID *string
+}
+
+// SemanticEdge pairs a note with one of its nearest notes by embedding
+type SemanticEdge struct {
+	Source     string
+	Target     string
+	Similarity float64
+}
+
+// GraphSource looks up the parts of a user's notes the graph builder walks
+type GraphSource interface {
+	// RecentNotes returns up to limit of the most recently updated notes
+	RecentNotes(limit int) ([]GraphNote, error)
+	// Notes returns the notes with the given IDs, leaving out unknown ones
+	Notes(ids []string) ([]GraphNote, error)
+	// Links returns the links from or to any of the given notes
+	Links(ids []string) ([][2]string, error)
+	// TaggedNotes returns up to limit IDs of notes carrying any of the tags,
+	// except the excluded notes
+	TaggedNotes(tags []string, exclude []string, limit int) ([]string, error)
+	// Neighbors returns the semantic neighbors of the given notes
+	Neighbors(ids []string) ([]SemanticEdge, error)
+}
+
+// BuildGraph assembles a knowledge graph from source. With a start note it
+// walks breadth-first along links (in either direction), shared tags and
+// semantic neighbors up to opts.Depth hops, looking up each hop by the IDs of
+// the notes reached; without one it takes the opts.MaxNodes most recent
+// notes. Tags only become nodes when at least two notes in the graph carry them.
+func BuildGraph(source GraphSource, opts GraphOptions) (*Graph, error) {
+	graph := &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	byID := make(map[string]GraphNote)
+	depth := make(map[string]int)
+	var selected []string
+	var semantic []SemanticEdge
+
+	if opts.StartNoteID == "" {
+		notes, err := source.RecentNotes(opts.MaxNodes + 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(notes) > opts.MaxNodes {
+			notes = notes[:opts.MaxNodes]
+			graph.Truncated = true
+		}
+		for _, note := range notes {
+			byID[note.ID] = note
+			depth[note.ID] = 0
+			selected = append(selected, note.ID)
+		}
+		if opts.Neighbors > 0 && len(selected) > 0 {
+			edges, err := source.Neighbors(selected)
+			if err != nil {
+				return nil, err
+			}
+			semantic = edges
+		}
+	} else {
+		start, err := source.Notes([]string{opts.StartNoteID})
+		if err != nil {
+			return nil, err
+		}
+		if len(start) == 0 {
+			return nil, ErrGraphNoteNotFound
+		}
+		byID[opts.StartNoteID] = start[0]
+		depth[opts.StartNoteID] = 0
+		selected = append(selected, opts.StartNoteID)
+
+		frontier := []string{opts.StartNoteID}
+		for d := 1; d <= opts.Depth && len(frontier) > 0 && !graph.Truncated; d++ {
+			var candidates []string
+			links, err := source.Links(frontier)
+			if err != nil {
+				return nil, err
+			}
+			for _, link := range links {
+				candidates = append(candidates, link[0], link[1])
+			}
+			if opts.IncludeTags {
+				var tags []string
+				for _, id := range frontier {
+					tags = append(tags, byID[id].Tags...)
+				}
+				if len(tags) > 0 {
+					// One more than still fits tells whether the graph is cut short
+					tagged, err := source.TaggedNotes(tags, selected, opts.MaxNodes-len(selected)+1)
+					if err != nil {
+						return nil, err
+					}
+					candidates = append(candidates, tagged...)
+				}
+			}
+			if opts.Neighbors > 0 {
+				edges, err := source.Neighbors(frontier)
+				if err != nil {
+					return nil, err
+				}
+				for _, edge := range edges {
+					candidates = append(candidates, edge.Target)
+				}
+				semantic = append(semantic, edges...)
+			}
+
+			var unseen []string
+			queued := make(map[string]bool)
+			for _, id := range candidates {
+				if _, seen := depth[id]; !seen && !queued[id] {
+					queued[id] = true
+					unseen = append(unseen, id)
+				}
+			}
+			if len(unseen) == 0 {
+				break
+			}
+			notes, err := source.Notes(unseen)
+			if err != nil {
+				return nil, err
+			}
+			for _, note := range notes {
+				byID[note.ID] = note
+			}
+
+			var next []string
+			for _, id := range unseen {
+				if _, ok := byID[id]; !ok {
+					continue
+				}
+				if len(selected) >= opts.MaxNodes {
+					graph.Truncated = true
+					break
+				}
+				depth[id] = d
+				selected = append(selected, id)
+				next = append(next, id)
+			}
+			frontier = next
+		}
+	}
+
+	order := make(map[string]int, len(selected))
+	for i, id := range selected {
+		order[id] = i
+		note := byID[id]
+		graph.Nodes = append(graph.Nodes, GraphNode{
+			ID:         note.ID,
+			Type:       GraphNodeNote,
+			Label:      note.Title,
+			Tags:       note.Tags,
+			NotebookID: note.NotebookID,
+			Depth:      depth[id],
+		})
+	}
+
+	if len(selected) > 0 {
+		links, err := source.Links(selected)
+		if err != nil {
+			return nil, err
+		}
+		seenLinks := make(map[[2]string]bool)
+		for _, link := range links {
+			_, sourceIn := depth[link[0]]
+			_, targetIn := depth[link[1]]
+			if !sourceIn || !targetIn || link[0] == link[1] || seenLinks[link] {
+				continue
+			}
+			seenLinks[link] = true
+			graph.Edges = append(graph.Edges, GraphEdge{Source: link[0], Target: link[1], Type: GraphEdgeLink, Weight: 1})
+		}
+	}
+
+	if opts.IncludeTags {
+		graph.addTags(selected, byID, depth)
+	}
+
+	seenPairs := make(map[[2]string]bool)
+	for _, edge := range semantic {
+		_, sourceIn := depth[edge.Source]
+		_, targetIn := depth[edge.Target]
+		if !sourceIn || !targetIn || edge.Source == edge.Target || edge.Similarity < opts.Threshold {
+			continue
+		}
+		// Similarity is symmetric, so A→B and B→A are the same edge
+		pair := [2]string{edge.Source, edge.Target}
+		if order[pair[0]] > order[pair[1]] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+		if seenPairs[pair] {
+			continue
+		}
+		seenPairs[pair] = true
+		graph.Edges = append(graph.Edges, GraphEdge{Source: pair[0], Target: pair[1], Type: GraphEdgeSemantic, Weight: edge.Similarity})
+	}
+
+	return graph, nil
+}
+
+// addTags adds a node for each tag shared by at least two selected notes,
+// with an edge from every selected note carrying it
+func (g *Graph) addTags(selected []string, byID map[string]GraphNote, depth map[string]int) {
+	counts := make(map[string]int)
+	for _, id := range selected {
+		for _, tag := range byID[id].Tags {
+			counts[tag]++
+		}
+	}
+
+	tagIndex := make(map[string]int)
+	for _, id := range selected {
+		for _, tag := range byID[id].Tags {
+			if counts[tag] < 2 {
+				continue
+			}
+			tagID := "tag:" + tag
+			if i, ok := tagIndex[tag]; ok {
+				g.Nodes[i].Depth = min(g.Nodes[i].Depth, depth[id])
+			} else {
+				tagIndex[tag] = len(g.Nodes)
+				g.Nodes = append(g.Nodes, GraphNode{ID: tagID, Type: GraphNodeTag, Label: tag, Depth: depth[id]})
+			}
+			g.Edges = append(g.Edges, GraphEdge{Source: id, Target: tagID, Type: GraphEdgeTag, Weight: 1})
+		}
+	}
+}
+
+// GraphService builds knowledge graphs from the database
+type GraphService struct {
+	queries *db_sqlc.Queries
+}
+
+// NewGraphService creates a new graph service
+func NewGraphService(db *pgxpool.Pool) *GraphService {
+	return &GraphService{
+		queries: db_sqlc.New(db),
+	}
+}
+
+// Graph builds the user's knowledge graph, see BuildGraph
+func (s *GraphService) Graph(ctx context.Context, userID pgtype.UUID, opts GraphOptions) (*Graph, error) {
+	return BuildGraph(&dbGraphSource{ctx: ctx, queries: s.queries, userID: userID, opts: opts}, opts)
+}
+
+// dbGraphSource looks up a user's graph with one query per lookup
+type dbGraphSource struct {
+	ctx     context.Context
+	queries *db_sqlc.Queries
+	userID  pgtype.UUID
+	opts    GraphOptions
+}
+
+// graphNote converts a note row of the graph queries
+func graphNote(id pgtype.UUID, title string, tags []string, notebook pgtype.UUID) GraphNote {
+	note := GraphNote{ID: id.String(), Title: title, Tags: tags}
+	if notebook.Valid {
+		notebookID := notebook.String()
+		note.NotebookID = &notebookID
+	}
+	return note
+}
+
+// graphNoteUUIDs parses the note IDs handed to the source
+func graphNoteUUIDs(ids []string) ([]pgtype.UUID, error) {
+	uuids := make([]pgtype.UUID, 0, len(ids))
+	for _, id := range ids {
+		var noteUUID pgtype.UUID
+		if err := noteUUID.Scan(id); err != nil {
+			return nil, err
+		}
+		uuids = append(uuids, noteUUID)
+	}
+	return uuids, nil
+}
+
+func (g *dbGraphSource) RecentNotes(limit int) ([]GraphNote, error) {
+	rows, err := g.queries.ListGraphNotes(g.ctx, db_sqlc.ListGraphNotesParams{
+		UserID: g.userID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notes: %w", err)
+	}
+	notes := make([]GraphNote, 0, len(rows))
+	for _, row := range rows {
+		notes = append(notes, graphNote(row.ID, row.Title, row.Tags, row.NotebookID))
+	}
+	return notes, nil
+}
+
+func (g *dbGraphSource) Notes(ids []string) ([]GraphNote, error) {
+	uuids, err := graphNoteUUIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := g.queries.ListGraphNotesByID(g.ctx, db_sqlc.ListGraphNotesByIDParams{
+		UserID:  g.userID,
+		NoteIds: uuids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notes: %w", err)
+	}
+	notes := make([]GraphNote, 0, len(rows))
+	for _, row := range rows {
+		notes = append(notes, graphNote(row.ID, row.Title, row.Tags, row.NotebookID))
+	}
+	return notes, nil
+}
+
+func (g *dbGraphSource) Links(ids []string) ([][2]string, error) {
+	uuids, err := graphNoteUUIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := g.queries.ListLinkEdges(g.ctx, db_sqlc.ListLinkEdgesParams{
+		UserID:  g.userID,
+		NoteIds: uuids,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list links: %w", err)
+	}
+	links := make([][2]string, 0, len(rows))
+	for _, row := range rows {
+		links = append(links, [2]string{row.SourceNoteID.String(), row.TargetNoteID.String()})
+	}
+	return links, nil
+}
+
+func (g *dbGraphSource) TaggedNotes(tags []string, exclude []string, limit int) ([]string, error) {
+	excludeUUIDs, err := graphNoteUUIDs(exclude)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := g.queries.ListTaggedGraphNoteIDs(g.ctx, db_sqlc.ListTaggedGraphNoteIDsParams{
+		UserID:  g.userID,
+		Tags:    tags,
+		Exclude: excludeUUIDs,
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tagged notes: %w", err)
+	}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.String())
+	}
+	return ids, nil
+}
+
+func (g *dbGraphSource) Neighbors(ids []string) ([]SemanticEdge, error) {
+	uuids, err := graphNoteUUIDs(ids)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := g.queries.ListSemanticNeighbors(g.ctx, db_sqlc.ListSemanticNeighborsParams{
+		K:         int32(g.opts.Neighbors),
+		UserID:    g.userID,
+		NoteIds:   uuids,
+		Threshold: g.opts.Threshold,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find semantic neighbors: %w", err)
+	}
+
+	edges := make([]SemanticEdge, 0, len(rows))
+	for _, row := range rows {
+		edges = append(edges, SemanticEdge{
+			Source:     row.NoteID.String(),
+			Target:     row.NeighborID.String(),
+			Similarity: row.Similarity,
+		})
+	}
+	return edges, nil
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// memoryGraph is a GraphSource over notes held in memory, most recent first
+type memoryGraph struct {
+	notes     []GraphNote
+	links     [][2]string
+	neighbors func(ids []string) ([]SemanticEdge, error)
+}
+
+func (m *memoryGraph) RecentNotes(limit int) ([]GraphNote, error) {
+	return m.notes[:min(limit, len(m.notes))], nil
+}
+
+func (m *memoryGraph) Notes(ids []string) ([]GraphNote, error) {
+	var notes []GraphNote
+	for _, note := range m.notes {
+		if slices.Contains(ids, note.ID) {
+			notes = append(notes, note)
+		}
+	}
+	return notes, nil
+}
+
+func (m *memoryGraph) Links(ids []string) ([][2]string, error) {
+	var links [][2]string
+	for _, link := range m.links {
+		if slices.Contains(ids, link[0]) || slices.Contains(ids, link[1]) {
+			links = append(links, link)
+		}
+	}
+	return links, nil
+}
+
+func (m *memoryGraph) TaggedNotes(tags []string, exclude []string, limit int) ([]string, error) {
+	var ids []string
+	for _, note := range m.notes {
+		if len(ids) < limit && !slices.Contains(exclude, note.ID) && slices.ContainsFunc(note.Tags, func(tag string) bool {
+			return slices.Contains(tags, tag)
+		}) {
+			ids = append(ids, note.ID)
+		}
+	}
+	return ids, nil
+}
+
+func (m *memoryGraph) Neighbors(ids []string) ([]SemanticEdge, error) {
+	return m.neighbors(ids)
+}
+
+func graphNodeIDs(graph *Graph) map[string]int {
+	ids := make(map[string]int)
+	for _, node := range graph.Nodes {
+		ids[node.ID] = node.Depth
+	}
+	return ids
+}
+
+func countEdges(graph *Graph, edgeType string) int {
+	n := 0
+	for _, edge := range graph.Edges {
+		if edge.Type == edgeType {
+			n++
+		}
+	}
+	return n
+}
+
+func TestBuildGraphWalksLinksWithinDepth(t *testing.T) {
+	notes := []GraphNote{{ID: "a"}, {ID: "b"}, {ID: "c"}, {ID: "d"}}
+	links := [][2]string{{"a", "b"}, {"c", "b"}, {"c", "d"}}
+	graph, err := BuildGraph(&memoryGraph{notes: notes, links: links}, GraphOptions{StartNoteID: "a", Depth: 2, MaxNodes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := graphNodeIDs(graph)
+	if len(ids) != 3 || ids["a"] != 0 || ids["b"] != 1 || ids["c"] != 2 {
+		t.Fatalf("expected a, b and c within two hops, got %v", ids)
+	}
+	if countEdges(graph, GraphEdgeLink) != 2 {
+		t.Errorf("expected the two links between reached notes, got %+v", graph.Edges)
+	}
+}
+
+func TestBuildGraphSharedTags(t *testing.T) {
+	notes := []GraphNote{
+		{ID: "a", Tags: []string{"sql", "db"}},
+		{ID: "b", Tags: []string{"db"}},
+		{ID: "c", Tags: []string{"go"}},
+	}
+	graph, err := BuildGraph(&memoryGraph{notes: notes}, GraphOptions{StartNoteID: "a", Depth: 1, IncludeTags: true, MaxNodes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := graphNodeIDs(graph)
+	if _, ok := ids["b"]; !ok {
+		t.Fatalf("expected b to be reached through the shared tag, got %v", ids)
+	}
+	if _, ok := ids["tag:db"]; !ok {
+		t.Errorf("expected a node for the shared tag, got %v", ids)
+	}
+	if _, ok := ids["tag:sql"]; ok {
+		t.Error("expected tags carried by a single note to be left out")
+	}
+	if countEdges(graph, GraphEdgeTag) != 2 {
+		t.Errorf("expected an edge from each note to the shared tag, got %+v", graph.Edges)
+	}
+}
+
+func TestBuildGraphSemanticNeighbors(t *testing.T) {
+	notes := []GraphNote{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	var asked [][]string
+	neighbors := func(ids []string) ([]SemanticEdge, error) {
+		asked = append(asked, ids)
+		return []SemanticEdge{
+			{Source: "a", Target: "b", Similarity: 0.9},
+			{Source: "b", Target: "a", Similarity: 0.9},
+			{Source: "a", Target: "c", Similarity: 0.5},
+		}, nil
+	}
+
+	graph, err := BuildGraph(&memoryGraph{notes: notes, neighbors: neighbors}, GraphOptions{Neighbors: 5, Threshold: 0.8, MaxNodes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(asked) != 1 || len(asked[0]) != 3 {
+		t.Fatalf("expected one lookup for all notes, got %v", asked)
+	}
+	if countEdges(graph, GraphEdgeSemantic) != 1 {
+		t.Fatalf("expected one semantic edge above the threshold, got %+v", graph.Edges)
+	}
+	if edge := graph.Edges[0]; edge.Source != "a" || edge.Target != "b" || edge.Weight != 0.9 {
+		t.Errorf("unexpected semantic edge %+v", edge)
+	}
+}
+
+func TestBuildGraphTruncatesAtMaxNodes(t *testing.T) {
+	notes := []GraphNote{{ID: "a"}, {ID: "b"}, {ID: "c"}}
+	links := [][2]string{{"a", "b"}, {"a", "c"}}
+
+	graph, err := BuildGraph(&memoryGraph{notes: notes, links: links}, GraphOptions{StartNoteID: "a", Depth: 1, MaxNodes: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Nodes) != 2 || !graph.Truncated {
+		t.Errorf("expected 2 nodes and a truncated graph, got %+v", graph)
+	}
+
+	graph, err = BuildGraph(&memoryGraph{notes: notes}, GraphOptions{MaxNodes: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph.Nodes) != 2 || graph.Nodes[0].ID != "a" || !graph.Truncated {
+		t.Errorf("expected the first 2 notes, got %+v", graph)
+	}
+}
+
+func TestBuildGraphUnknownStartNote(t *testing.T) {
+	_, err := BuildGraph(&memoryGraph{}, GraphOptions{StartNoteID: "missing", Depth: 1, MaxNodes: 10})
+	if !errors.Is(err, ErrGraphNoteNotFound) {
+		t.Errorf("expected ErrGraphNoteNotFound, got %v", err)
+	}
+}
+
+func TestBuildGraphReachesNotesOnlyByID(t *testing.T) {
+	// The walk looks notes up by ID, so the oldest notes are reached even when
+	// fewer than all of the user's notes would fit in the graph
+	notes := []GraphNote{{ID: "new"}, {ID: "newer"}, {ID: "old"}, {ID: "oldest"}}
+	links := [][2]string{{"oldest", "old"}}
+
+	graph, err := BuildGraph(&memoryGraph{notes: notes, links: links}, GraphOptions{StartNoteID: "oldest", Depth: 1, MaxNodes: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := graphNodeIDs(graph)
+	if len(ids) != 2 || ids["oldest"] != 0 || ids["old"] != 1 || graph.Truncated {
+		t.Errorf("expected oldest and old without truncation, got %v (truncated %v)", ids, graph.Truncated)
+	}
+	if countEdges(graph, GraphEdgeLink) != 1 {
+		t.Errorf("expected the link between them, got %+v", graph.Edges)
+	}
+}
@@ -0,0 +1,415 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// enexTimeLayout is the format of timestamps in Evernote exports
+const enexTimeLayout = "20060102T150405Z"
+
+// enexNote is a <note> element of an Evernote export
+type enexNote struct {
+	Title     string         `xml:"title"`
+	Content   string         `xml:"content"`
+	Created   string         `xml:"created"`
+	Updated   string         `xml:"updated"`
+	Tags      []string       `xml:"tag"`
+	Resources []enexResource `xml:"resource"`
+}
+
+// enexResource is an attachment of an Evernote note
+type enexResource struct {
+	Data     string `xml:"data"` // base64
+	Mime     string `xml:"mime"`
+	FileName string `xml:"resource-attributes>file-name"`
+}
+
+// ReadEnex reads the notes of an Evernote .enex export, converting their ENML
+// content to markdown. Attachments cannot be imported and are replaced by a
+// placeholder naming them. Notes are decoded one at a time, so large exports
+// with many attachments are not held in memory at once.
+func ReadEnex(name string, r io.Reader) (*ImportBatch, error) {
+	batch := &ImportBatch{}
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+
+	index := 0
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid ENEX file: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "note" {
+			continue
+		}
+
+		index++
+		notePath := name + "#" + strconv.Itoa(index)
+		var raw enexNote
+		if err := decoder.DecodeElement(&raw, &start); err != nil {
+			return nil, fmt.Errorf("invalid ENEX file: %w", err)
+		}
+
+		content, err := ENMLToMarkdown(raw.Content, enexAttachmentNames(raw.Resources))
+		if err != nil {
+			batch.skip(notePath, err.Error())
+			continue
+		}
+
+		note := ImportedNote{
+			Path:    notePath,
+			Title:   strings.TrimSpace(raw.Title),
+			Content: content,
+			Tags:    NormalizeTags(raw.Tags),
+		}
+		if note.Title == "" {
+			note.Title = "Untitled"
+		}
+		if created, err := time.Parse(enexTimeLayout, strings.TrimSpace(raw.Created)); err == nil {
+			note.CreatedAt = created
+		}
+		if updated, err := time.Parse(enexTimeLayout, strings.TrimSpace(raw.Updated)); err == nil {
+			note.UpdatedAt = updated
+		}
+		note.Hash = ContentHash(note.Content)
+		batch.add(note)
+	}
+	return batch, nil
+}
+
+// enexAttachmentNames maps the MD5 hash of each attachment, by which ENML
+// refers to it, to a name to show for it
+func enexAttachmentNames(resources []enexResource) map[string]string {
+	names := make(map[string]string, len(resources))
+	for _, resource := range resources {
+		data, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(resource.Data), ""))
+		if err != nil {
+			continue
+		}
+		sum := md5.Sum(data)
+		name := resource.FileName
+		if name == "" {
+			name = resource.Mime
+		}
+		names[hex.EncodeToString(sum[:])] = name
+	}
+	return names
+}
+
+// enmlNode is an element or, when name is empty, a text node of an ENML document
+type enmlNode struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*enmlNode
+}
+
+// ENML elements that start a block of their own in markdown
+var enmlBlockElements = map[string]bool{
+	"en-note": true, "div": true, "p": true, "blockquote": true, "pre": true, "hr": true,
+	"ul": true, "ol": true, "li": true, "table": true, "h1": true, "h2": true,
+	"h3": true, "h4": true, "h5": true, "h6": true, "center": true, "section": true,
+}
+
+var (
+	enmlSpacePattern   = regexp.MustCompile(`\s+`)
+	enmlBlankPattern   = regexp.MustCompile(`\n{3,}`)
+	enmlTodoPattern    = regexp.MustCompile(`^\[[ x]\] `)
+	enmlLineEndPattern = regexp.MustCompile(` *\n *`)
+	enmlHeadingPattern = regexp.MustCompile(`^h([1-6])$`)
+)
+
+// ENMLToMarkdown converts the ENML body of an Evernote note to markdown.
+// attachments maps the hashes of the note's attachments to their names.
+func ENMLToMarkdown(enml string, attachments map[string]string) (string, error) {
+	decoder := xml.NewDecoder(strings.NewReader(enml))
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	root := &enmlNode{name: "en-note"}
+	stack := []*enmlNode{root}
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("invalid note content: %w", err)
+		}
+
+		parent := stack[len(stack)-1]
+		switch t := token.(type) {
+		case xml.StartElement:
+			node := &enmlNode{name: strings.ToLower(t.Name.Local), attrs: make(map[string]string)}
+			for _, attr := range t.Attr {
+				node.attrs[strings.ToLower(attr.Name.Local)] = attr.Value
+			}
+			if node.name == "en-note" && len(stack) == 1 {
+				continue // the document element is the root itself
+			}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.children = append(parent.children, &enmlNode{text: string(t)})
+		}
+	}
+
+	converter := enmlConverter{attachments: attachments}
+	markdown := strings.Join(converter.blocks(root.children), "\n\n")
+	return strings.TrimSpace(enmlBlankPattern.ReplaceAllString(markdown, "\n\n")), nil
+}
+
+// enmlConverter renders an ENML tree as markdown
+type enmlConverter struct {
+	attachments map[string]string
+}
+
+// blocks renders nodes as markdown blocks, grouping runs of inline nodes into paragraphs
+func (c enmlConverter) blocks(nodes []*enmlNode) []string {
+	var blocks []string
+	var inline strings.Builder
+	flush := func() {
+		text := strings.TrimSpace(enmlLineEndPattern.ReplaceAllString(inline.String(), "\n"))
+		inline.Reset()
+		if text == "" {
+			return
+		}
+		// A line starting with a checkbox becomes a task list item
+		if enmlTodoPattern.MatchString(text) {
+			text = "- " + text
+		}
+		blocks = append(blocks, text)
+	}
+
+	for _, node := range nodes {
+		if node.name == "" || !enmlBlockElements[node.name] {
+			inline.WriteString(c.inline(node))
+			continue
+		}
+		flush()
+		blocks = append(blocks, c.block(node)...)
+	}
+	flush()
+	return blocks
+}
+
+// block renders a block element
+func (c enmlConverter) block(node *enmlNode) []string {
+	if match := enmlHeadingPattern.FindStringSubmatch(node.name); match != nil {
+		level, _ := strconv.Atoi(match[1])
+		text := strings.TrimSpace(c.inlineChildren(node))
+		if text == "" {
+			return nil
+		}
+		return []string{strings.Repeat("#", level) + " " + text}
+	}
+
+	switch node.name {
+	case "hr":
+		return []string{"---"}
+
+	case "pre":
+		return []string{"```\n" + strings.Trim(enmlText(node), "\n") + "\n```"}
+
+	case "ul", "ol":
+		return []string{c.list(node)}
+
+	case "blockquote":
+		var lines []string
+		for _, line := range strings.Split(strings.Join(c.blocks(node.children), "\n\n"), "\n") {
+			lines = append(lines, strings.TrimRight("> "+line, " "))
+		}
+		return []string{strings.Join(lines, "\n")}
+
+	case "table":
+		return []string{c.table(node)}
+
+	case "div":
+		// Evernote marks code blocks with a style on a div
+		if strings.Contains(node.attrs["style"], "-en-codeblock") {
+			return []string{"```\n" + strings.Trim(enmlText(node), "\n") + "\n```"}
+		}
+	}
+	return c.blocks(node.children)
+}
+
+// list renders a ul or ol, indenting nested lists under their items
+func (c enmlConverter) list(node *enmlNode) string {
+	var lines []string
+	number := 0
+	for _, item := range node.children {
+		if item.name != "li" {
+			continue
+		}
+		number++
+		marker := "- "
+		if node.name == "ol" {
+			marker = strconv.Itoa(number) + ". "
+		}
+
+		content := strings.Join(c.blocks(item.children), "\n")
+		for i, line := range strings.Split(content, "\n") {
+			if i == 0 {
+				// A checkbox item from a paragraph is already marked as a list item
+				lines = append(lines, marker+strings.TrimPrefix(line, "- "))
+			} else if line != "" {
+				lines = append(lines, strings.Repeat(" ", len(marker))+line)
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// table renders a table as a markdown table, using its first row as header
+func (c enmlConverter) table(node *enmlNode) string {
+	var rows [][]string
+	var collect func(n *enmlNode)
+	collect = func(n *enmlNode) {
+		for _, child := range n.children {
+			if child.name == "tr" {
+				var cells []string
+				for _, cell := range child.children {
+					if cell.name == "td" || cell.name == "th" {
+						text := strings.TrimSpace(strings.ReplaceAll(c.inlineChildren(cell), "\n", " "))
+						cells = append(cells, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, cells)
+			} else if child.name != "" {
+				collect(child) // thead, tbody
+			}
+		}
+	}
+	collect(node)
+
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	if width == 0 {
+		return ""
+	}
+
+	var lines []string
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// inline renders an inline element or text
+func (c enmlConverter) inline(node *enmlNode) string {
+	if node.name == "" {
+		// Evernote pads text with non-breaking spaces, which markdown has no use for
+		return enmlSpacePattern.ReplaceAllString(strings.ReplaceAll(node.text, "\u00a0", " "), " ")
+	}
+
+	switch node.name {
+	case "br":
+		return "\n"
+	case "b", "strong":
+		return wrapInline(c.inlineChildren(node), "**")
+	case "i", "em":
+		return wrapInline(c.inlineChildren(node), "*")
+	case "s", "strike", "del":
+		return wrapInline(c.inlineChildren(node), "~~")
+	case "code":
+		return wrapInline(enmlText(node), "`")
+	case "a":
+		text := strings.TrimSpace(c.inlineChildren(node))
+		href := node.attrs["href"]
+		if href == "" {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return "[" + text + "](" + href + ")"
+	case "img":
+		return "![" + node.attrs["alt"] + "](" + node.attrs["src"] + ")"
+	case "en-todo":
+		if node.attrs["checked"] == "true" {
+			return "[x] "
+		}
+		return "[ ] "
+	case "en-media":
+		name := c.attachments[node.attrs["hash"]]
+		if name == "" {
+			name = node.attrs["type"]
+		}
+		return "[attachment: " + name + "]"
+	case "en-crypt":
+		return "[encrypted content]"
+	}
+	return c.inlineChildren(node)
+}
+
+// inlineChildren renders the children of an element as inline text, with
+// nested blocks on lines of their own
+func (c enmlConverter) inlineChildren(node *enmlNode) string {
+	var text strings.Builder
+	for _, child := range node.children {
+		if child.name != "" && enmlBlockElements[child.name] {
+			text.WriteString("\n" + strings.Join(c.block(child), "\n") + "\n")
+			continue
+		}
+		text.WriteString(c.inline(child))
+	}
+	return text.String()
+}
+
+// wrapInline surrounds text with a markdown marker, keeping surrounding spaces
+// outside of it as markdown requires
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	start := strings.Index(text, trimmed)
+	return text[:start] + marker + trimmed + marker + text[start+len(trimmed):]
+}
+
+// enmlText returns the raw text of an element, with line breaks for br and
+// for block elements such as the divs of a code block
+func enmlText(node *enmlNode) string {
+	if node.name == "" {
+		return node.text
+	}
+	if node.name == "br" {
+		return "\n"
+	}
+	var text strings.Builder
+	for _, child := range node.children {
+		text.WriteString(enmlText(child))
+	}
+	if enmlBlockElements[node.name] && !strings.HasSuffix(text.String(), "\n") {
+		text.WriteString("\n")
+	}
+	return text.String()
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestENMLToMarkdown(t *testing.T) {
+	enml := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-note SYSTEM "http://xml.evernote.com/pub/enml2.dtd">
+<en-note><h2>Plan</h2>
+<div>Read <b>chapter 3</b> and <a href="https://example.com">the&nbsp;notes</a><br/>then rest</div>
+<div><br/></div>
+<ul><li>one</li><li>two<ol><li>nested</li></ol></li></ul>
+<div><en-todo checked="true"/>done</div>
+<div><en-todo/>open</div>
+<div style="-en-codeblock:true"><div>x := 1</div><div>y := 2</div></div>
+<table><tr><th>A</th><th>B</th></tr><tr><td>1</td><td>a|b</td></tr></table>
+<div><en-media hash="abc" type="image/png"/></div>
+</en-note>`
+
+	got, err := ENMLToMarkdown(enml, map[string]string{"abc": "photo.png"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"## Plan",
+		"Read **chapter 3** and [the notes](https://example.com)\nthen rest",
+		"- one\n- two\n  1. nested",
+		"- [x] done",
+		"- [ ] open",
+		"```\nx := 1\ny := 2\n```",
+		"| A | B |\n| --- | --- |\n| 1 | a\\|b |",
+		"[attachment: photo.png]",
+	}, "\n\n")
+	if got != want {
+		t.Errorf("unexpected markdown:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestReadEnex(t *testing.T) {
+	attachment := []byte("fake image bytes")
+	sum := md5.Sum(attachment)
+	enex := `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE en-export SYSTEM "http://xml.evernote.com/pub/evernote-export3.dtd">
+<en-export export-date="20240101T000000Z" application="Evernote">
+  <note>
+    <title>Trip ideas</title>
+    <content><![CDATA[<?xml version="1.0" encoding="UTF-8"?><en-note><div>Kyoto</div><en-media hash="` + hex.EncodeToString(sum[:]) + `" type="image/jpeg"/></en-note>]]></content>
+    <created>20200115T083000Z</created>
+    <updated>20210220T101500Z</updated>
+    <tag>Travel</tag>
+    <tag>Japan Trip</tag>
+    <resource>
+      <data encoding="base64">` + base64.StdEncoding.EncodeToString(attachment) + `</data>
+      <mime>image/jpeg</mime>
+      <resource-attributes><file-name>temple.jpg</file-name></resource-attributes>
+    </resource>
+  </note>
+  <note>
+    <title>Blank</title>
+    <content><![CDATA[<en-note><div><br/></div></en-note>]]></content>
+  </note>
+</en-export>`
+
+	batch, err := ReadEnex("export.enex", strings.NewReader(enex))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batch.Notes) != 1 {
+		t.Fatalf("expected 1 note, got %+v", batch.Notes)
+	}
+
+	note := batch.Notes[0]
+	if note.Path != "export.enex#1" || note.Title != "Trip ideas" {
+		t.Errorf("unexpected note %+v", note)
+	}
+	if note.Content != "Kyoto\n\n[attachment: temple.jpg]" {
+		t.Errorf("unexpected content %q", note.Content)
+	}
+	if !reflect.DeepEqual(note.Tags, []string{"travel", "japan-trip"}) {
+		t.Errorf("unexpected tags %v", note.Tags)
+	}
+	if !note.CreatedAt.Equal(time.Date(2020, 1, 15, 8, 30, 0, 0, time.UTC)) || !note.UpdatedAt.Equal(time.Date(2021, 2, 20, 10, 15, 0, 0, time.UTC)) {
+		t.Errorf("unexpected dates %v %v", note.CreatedAt, note.UpdatedAt)
+	}
+
+	if len(batch.Skipped) != 1 || batch.Skipped[0].Path != "export.enex#2" {
+		t.Errorf("expected the blank note to be skipped, got %+v", batch.Skipped)
+	}
+}
+
+func TestReadImportRejectsMismatchedFormat(t *testing.T) {
+	if _, err := ReadImport(ImportFormatNotion, "export.enex", []byte("<en-export/>")); !errors.Is(err, ErrUnsupportedImport) {
+		t.Errorf("expected ErrUnsupportedImport for a Notion import that is not a zip, got %v", err)
+	}
+	if _, err := ReadImport("onenote", "x.zip", nil); !errors.Is(err, ErrUnsupportedImport) {
+		t.Errorf("expected ErrUnsupportedImport for an unknown format, got %v", err)
+	}
+}
@@ -0,0 +1,231 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// maxImportFileSize caps the size of a single file read from an export
+const maxImportFileSize = 10 << 20
+
+// markdownHeadingPattern matches an ATX heading line, e.g. "## Title"
+var markdownHeadingPattern = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*\s*$`)
+
+// Front matter keys holding a note's timestamps, in order of preference
+var (
+	createdKeys = []string{"created", "created_at", "date", "creation_date"}
+	updatedKeys = []string{"updated", "updated_at", "modified", "last_modified"}
+)
+
+// Layouts tried for front matter dates that YAML does not parse itself
+var frontMatterDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseMarkdownNote turns a markdown file into a note. YAML front matter may
+// set the title, tags and created/updated dates; otherwise the title is taken
+// from the first heading or the file name, and the dates from modTime. The
+// front matter itself is not part of the note's content.
+func ParseMarkdownNote(name string, data []byte, modTime time.Time) (ImportedNote, error) {
+	if !utf8.Valid(data) {
+		return ImportedNote{}, fmt.Errorf("not valid UTF-8")
+	}
+	text := strings.ReplaceAll(string(bytes.TrimPrefix(data, []byte("\ufeff"))), "\r\n", "\n")
+
+	meta, body, err := splitFrontMatter(text)
+	if err != nil {
+		return ImportedNote{}, err
+	}
+
+	note := ImportedNote{
+		Path:      name,
+		Content:   strings.TrimRight(strings.TrimLeft(body, "\n"), " \t\n"),
+		Tags:      NormalizeTags(frontMatterList(meta["tags"], meta["tag"])),
+		CreatedAt: frontMatterTime(meta, createdKeys),
+		UpdatedAt: frontMatterTime(meta, updatedKeys),
+	}
+	if title, ok := meta["title"].(string); ok {
+		note.Title = strings.TrimSpace(title)
+	}
+	if note.Title == "" {
+		note.Title = firstHeading(note.Content)
+	}
+	if note.Title == "" {
+		note.Title = strings.TrimSuffix(path.Base(name), path.Ext(name))
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = modTime
+	}
+	if note.UpdatedAt.IsZero() || note.UpdatedAt.Before(note.CreatedAt) {
+		note.UpdatedAt = note.CreatedAt
+		if modTime.After(note.CreatedAt) {
+			note.UpdatedAt = modTime
+		}
+	}
+	note.Hash = ContentHash(note.Content)
+	return note, nil
+}
+
+// ReadMarkdownVault reads every markdown file of a folder or Obsidian vault.
+// Hidden files and folders, like .obsidian and .trash, are ignored; other
+// files that cannot be imported are reported as skipped.
+func ReadMarkdownVault(fsys fs.FS) (*ImportBatch, error) {
+	batch := &ImportBatch{}
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		base := path.Base(name)
+		if name != "." && (strings.HasPrefix(base, ".") || base == "__MACOSX") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(path.Ext(name)) {
+		case ".md", ".markdown":
+		default:
+			batch.skip(name, "not a markdown file")
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := readImportFile(fsys, name, info)
+		if err != nil {
+			batch.skip(name, err.Error())
+			return nil
+		}
+		note, err := ParseMarkdownNote(name, data, info.ModTime())
+		if err != nil {
+			batch.skip(name, err.Error())
+			return nil
+		}
+		batch.add(note)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault: %w", err)
+	}
+	return batch, nil
+}
+
+// readImportFile reads a file of an export, refusing files over maxImportFileSize
+func readImportFile(fsys fs.FS, name string, info fs.FileInfo) ([]byte, error) {
+	if info.Size() > maxImportFileSize {
+		return nil, fmt.Errorf("file larger than %d MB", maxImportFileSize>>20)
+	}
+	file, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// The size in a zip header can lie, so the limit is enforced while reading too
+	data, err := io.ReadAll(io.LimitReader(file, maxImportFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxImportFileSize {
+		return nil, fmt.Errorf("file larger than %d MB", maxImportFileSize>>20)
+	}
+	return data, nil
+}
+
+// splitFrontMatter separates a leading YAML block between "---" lines from
+// the text. Text without a closing "---" line has no front matter.
+func splitFrontMatter(text string) (map[string]any, string, error) {
+	if !strings.HasPrefix(text, "---\n") {
+		return nil, text, nil
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	offset := len(lines[0])
+	for _, line := range lines[1:] {
+		if strings.TrimRight(line, " \t\n") == "---" {
+			meta := make(map[string]any)
+			if err := yaml.Unmarshal([]byte(text[len(lines[0]):offset]), &meta); err != nil {
+				return nil, "", fmt.Errorf("invalid front matter: %w", err)
+			}
+			return meta, text[offset+len(line):], nil
+		}
+		offset += len(line)
+	}
+	return nil, text, nil
+}
+
+// frontMatterList reads tag-like front matter values, given either as a YAML
+// list or as one string separated by commas or spaces
+func frontMatterList(values ...any) []string {
+	var items []string
+	for _, value := range values {
+		switch v := value.(type) {
+		case string:
+			items = append(items, strings.FieldsFunc(v, func(r rune) bool {
+				return r == ',' || r == ' '
+			})...)
+		case []any:
+			for _, item := range v {
+				if item != nil {
+					items = append(items, fmt.Sprint(item))
+				}
+			}
+		}
+	}
+	return items
+}
+
+// frontMatterTime returns the first of keys that holds a date, or the zero time
+func frontMatterTime(meta map[string]any, keys []string) time.Time {
+	for _, key := range keys {
+		switch v := meta[key].(type) {
+		case time.Time:
+			return v
+		case string:
+			for _, layout := range frontMatterDateLayouts {
+				if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// firstHeading returns the text of the first heading outside code blocks
+func firstHeading(content string) string {
+	inFence := false
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+		if match := markdownHeadingPattern.FindStringSubmatch(line); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestParseMarkdownNoteFrontMatter(t *testing.T) {
+	data := []byte("---\r\ntitle: Query Planning\r\ntags: [Postgres, 'Databases/SQL']\r\ncreated: 2021-03-04 10:30\r\nupdated: 2022-01-02\r\n---\r\n\r\n# Heading\r\nBody text\r\n")
+	modTime := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	note, err := ParseMarkdownNote("db/planning.md", data, modTime)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if note.Title != "Query Planning" {
+		t.Errorf("expected front matter title, got %q", note.Title)
+	}
+	if note.Content != "# Heading\nBody text" {
+		t.Errorf("expected content without front matter, got %q", note.Content)
+	}
+	if !reflect.DeepEqual(note.Tags, []string{"postgres", "databases/sql"}) {
+		t.Errorf("expected normalized tags, got %v", note.Tags)
+	}
+	if !note.CreatedAt.Equal(time.Date(2021, 3, 4, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected created date %v", note.CreatedAt)
+	}
+	if !note.UpdatedAt.Equal(time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected updated date %v", note.UpdatedAt)
+	}
+	if note.Hash != ContentHash(note.Content) {
+		t.Error("expected the hash of the content")
+	}
+}
+
+func TestParseMarkdownNoteTitleFallbacks(t *testing.T) {
+	modTime := time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC)
+
+	note, err := ParseMarkdownNote("a/b.md", []byte("```\n# not a heading\n```\nIntro\n## First Heading ##\n"), modTime)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if note.Title != "First Heading" {
+		t.Errorf("expected title from first heading, got %q", note.Title)
+	}
+	if !note.CreatedAt.Equal(modTime) || !note.UpdatedAt.Equal(modTime) {
+		t.Errorf("expected dates from the file, got %v and %v", note.CreatedAt, note.UpdatedAt)
+	}
+
+	note, err = ParseMarkdownNote("a/Reading List.md", []byte("---\ntags: books, to-read\n---\n- Dune"), modTime)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if note.Title != "Reading List" {
+		t.Errorf("expected title from file name, got %q", note.Title)
+	}
+	if !reflect.DeepEqual(note.Tags, []string{"books", "to-read"}) {
+		t.Errorf("expected tags from a string, got %v", note.Tags)
+	}
+}
+
+func TestParseMarkdownNoteInvalidFrontMatter(t *testing.T) {
+	if _, err := ParseMarkdownNote("x.md", []byte("---\ntags: [unclosed\n---\nBody"), time.Time{}); err == nil {
+		t.Error("expected an error for invalid front matter")
+	}
+
+	// A thematic break without a closing delimiter is content, not front matter
+	note, err := ParseMarkdownNote("x.md", []byte("---\nJust a rule above"), time.Time{})
+	if err != nil || note.Content != "---\nJust a rule above" {
+		t.Errorf("expected content to be kept, got %q (%v)", note.Content, err)
+	}
+}
+
+func TestReadMarkdownVault(t *testing.T) {
+	vault := fstest.MapFS{
+		"Inbox.md":               {Data: []byte("# Inbox\nTodo")},
+		"Projects/Go.markdown":   {Data: []byte("Notes on Go")},
+		"Projects/diagram.png":   {Data: []byte{0x89, 'P', 'N', 'G'}},
+		"Empty.md":               {Data: []byte("---\ntitle: Nothing\n---\n")},
+		".obsidian/app.json":     {Data: []byte("{}")},
+		".trash/Old.md":          {Data: []byte("old")},
+		"Projects/.hidden.md":    {Data: []byte("hidden")},
+		"Broken.md":              {Data: []byte{0xff, 0xfe}},
+		"Projects/Sub/Nested.md": {Data: []byte("nested")},
+	}
+
+	batch, err := ReadMarkdownVault(vault)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var paths []string
+	for _, note := range batch.Notes {
+		paths = append(paths, note.Path)
+	}
+	if !reflect.DeepEqual(paths, []string{"Inbox.md", "Projects/Go.markdown", "Projects/Sub/Nested.md"}) {
+		t.Errorf("unexpected notes %v", paths)
+	}
+
+	skipped := make(map[string]string)
+	for _, skip := range batch.Skipped {
+		skipped[skip.Path] = skip.Reason
+	}
+	if len(skipped) != 3 || skipped["Projects/diagram.png"] == "" || skipped["Empty.md"] != "empty note" || skipped["Broken.md"] == "" {
+		t.Errorf("unexpected skipped files %v", skipped)
+	}
+}
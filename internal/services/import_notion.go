@@ -0,0 +1,271 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var (
+	// notionIDPattern matches the page ID Notion appends to exported file names
+	notionIDPattern = regexp.MustCompile(`(?: |%20)[0-9a-f]{32}`)
+	// notionLinkPattern matches the target of a markdown link
+	notionLinkPattern = regexp.MustCompile(`\]\(([^)\s]+)\)`)
+	// notionPropertyPattern matches a "Name: value" property line under a page title
+	notionPropertyPattern = regexp.MustCompile(`^([^:\n]{1,40}): (.*)$`)
+)
+
+// Notion property names mapped onto note fields, compared in lowercase
+var (
+	notionTagProperties     = map[string]bool{"tags": true, "tag": true, "labels": true, "keywords": true}
+	notionCreatedProperties = map[string]bool{"created": true, "created time": true, "date created": true, "created at": true}
+	notionUpdatedProperties = map[string]bool{"last edited time": true, "last edited": true, "updated": true, "updated at": true}
+)
+
+// Layouts of dates in Notion properties, besides the front matter ones
+var notionDateLayouts = append([]string{
+	"January 2, 2006 3:04 PM",
+	"January 2, 2006",
+	"2006/01/02 15:04",
+	"2006/01/02",
+}, frontMatterDateLayouts...)
+
+// notionPage is a page or database row of a Notion export
+type notionPage struct {
+	title      string
+	properties [][2]string
+	body       string
+}
+
+// ReadNotionExport reads a Notion "Markdown & CSV" export. Pages become
+// notes, with the page IDs Notion appends to file names removed from titles
+// and links, and their Tags and Created/Last edited time properties carried
+// over. Rows of database CSVs become notes listing their properties, unless
+// the export has a page for the row already.
+func ReadNotionExport(fsys fs.FS) (*ImportBatch, error) {
+	batch := &ImportBatch{}
+	pageTitles := make(map[string]bool)
+	var databases []string
+	csvFiles := make(map[string]bool)
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		switch strings.ToLower(path.Ext(name)) {
+		case ".md":
+		case ".csv":
+			databases = append(databases, name)
+			csvFiles[strings.ToLower(name)] = true
+			return nil
+		case ".zip":
+			batch.skip(name, "nested zip archive, extract it and import its contents")
+			return nil
+		default:
+			batch.skip(name, "not a markdown or CSV file")
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := readImportFile(fsys, name, info)
+		if err != nil {
+			batch.skip(name, err.Error())
+			return nil
+		}
+		if !utf8.Valid(data) {
+			batch.skip(name, "not valid UTF-8")
+			return nil
+		}
+
+		page := parseNotionPage(notionTitle(name), string(data))
+		pageTitles[strings.ToLower(page.title)] = true
+		batch.add(page.note(name, info.ModTime()))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Notion export: %w", err)
+	}
+
+	for _, name := range databases {
+		// Notion exports each database twice, the _all file with every column
+		// and the other with the columns of its current view only
+		if csvFiles[notionAllCSV(name)] {
+			continue
+		}
+		if err := readNotionDatabase(fsys, name, pageTitles, batch); err != nil {
+			batch.skip(name, err.Error())
+		}
+	}
+	return batch, nil
+}
+
+// readNotionDatabase adds the rows of a database CSV that have no page of their own
+func readNotionDatabase(fsys fs.FS, name string, pageTitles map[string]bool, batch *ImportBatch) error {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return err
+	}
+	data, err := readImportFile(fsys, name, info)
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return fmt.Errorf("invalid CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return nil
+	}
+
+	header := records[0]
+	for i, record := range records[1:] {
+		if len(record) == 0 || strings.TrimSpace(record[0]) == "" {
+			continue
+		}
+		page := notionPage{title: strings.TrimSpace(record[0])}
+		if pageTitles[strings.ToLower(page.title)] {
+			continue
+		}
+
+		var lines []string
+		for j := 1; j < len(record) && j < len(header); j++ {
+			if value := strings.TrimSpace(record[j]); value != "" {
+				page.properties = append(page.properties, [2]string{header[j], value})
+				lines = append(lines, "- **"+header[j]+":** "+value)
+			}
+		}
+		page.body = strings.Join(lines, "\n")
+		if page.body == "" {
+			// A row with only a title still holds the title
+			page.body = page.title
+		}
+		batch.add(page.note(fmt.Sprintf("%s#%d", name, i+1), info.ModTime()))
+	}
+	return nil
+}
+
+// notionAllCSV returns the lowercased name of the _all export of a database
+// CSV, or "" for an _all file itself
+func notionAllCSV(name string) string {
+	lower := strings.ToLower(name)
+	if strings.HasSuffix(lower, "_all.csv") {
+		return ""
+	}
+	return strings.TrimSuffix(lower, ".csv") + "_all.csv"
+}
+
+// notionTitle turns an exported file name into the page title
+func notionTitle(name string) string {
+	base := strings.TrimSuffix(path.Base(name), path.Ext(name))
+	return strings.TrimSpace(notionIDPattern.ReplaceAllString(base, ""))
+}
+
+// parseNotionPage splits an exported page into its title heading, the
+// property lines under it and the rest of its content
+func parseNotionPage(fallbackTitle, text string) notionPage {
+	page := notionPage{title: fallbackTitle}
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	i := 0
+	for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+		i++
+	}
+	if i < len(lines) && strings.HasPrefix(lines[i], "# ") {
+		page.title = strings.TrimSpace(lines[i][2:])
+		i++
+		for i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			i++
+		}
+
+		// Properties follow the title as a block of "Name: value" lines
+		start := i
+		var properties [][2]string
+		for i < len(lines) && strings.TrimSpace(lines[i]) != "" {
+			match := notionPropertyPattern.FindStringSubmatch(lines[i])
+			if match == nil {
+				properties, i = nil, start
+				break
+			}
+			properties = append(properties, [2]string{match[1], match[2]})
+			i++
+		}
+		page.properties = properties
+	}
+
+	var kept []string
+	for _, property := range page.properties {
+		key := strings.ToLower(property[0])
+		if !notionTagProperties[key] && !notionCreatedProperties[key] && !notionUpdatedProperties[key] {
+			kept = append(kept, property[0]+": "+property[1])
+		}
+	}
+	body := strings.TrimLeft(strings.Join(lines[i:], "\n"), "\n")
+	if len(kept) > 0 {
+		body = strings.Join(kept, "\n") + "\n\n" + body
+	}
+
+	// Point links at page titles instead of ID-suffixed file names
+	page.body = notionLinkPattern.ReplaceAllStringFunc(body, func(link string) string {
+		return notionIDPattern.ReplaceAllString(link, "")
+	})
+	return page
+}
+
+// note converts a page into a note to import
+func (p notionPage) note(notePath string, modTime time.Time) ImportedNote {
+	note := ImportedNote{
+		Path:    notePath,
+		Title:   p.title,
+		Content: strings.TrimSpace(p.body),
+	}
+	var tags []string
+	for _, property := range p.properties {
+		key, value := strings.ToLower(property[0]), property[1]
+		switch {
+		case notionTagProperties[key]:
+			tags = append(tags, strings.Split(value, ",")...)
+		case notionCreatedProperties[key]:
+			note.CreatedAt = parseNotionDate(value)
+		case notionUpdatedProperties[key]:
+			note.UpdatedAt = parseNotionDate(value)
+		}
+	}
+	note.Tags = NormalizeTags(tags)
+	if note.Title == "" {
+		note.Title = "Untitled"
+	}
+	if note.CreatedAt.IsZero() {
+		note.CreatedAt = modTime
+	}
+	if note.UpdatedAt.Before(note.CreatedAt) {
+		note.UpdatedAt = note.CreatedAt
+	}
+	note.Hash = ContentHash(note.Content)
+	return note
+}
+
+// parseNotionDate parses a date property, returning the zero time when it cannot
+func parseNotionDate(value string) time.Time {
+	for _, layout := range notionDateLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
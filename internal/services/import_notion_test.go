@@ -0,0 +1,67 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestReadNotionExport(t *testing.T) {
+	export := fstest.MapFS{
+		"Projects 0123456789abcdef0123456789abcdef.md": {Data: []byte(
+			"# Projects\n\nTags: Work, Planning\nCreated: July 30, 2021 8:52 PM\nStatus: Active\n\n" +
+				"See [Roadmap](Projects%200123456789abcdef0123456789abcdef/Roadmap%20fedcba9876543210fedcba9876543210.md).\n")},
+		"Projects 0123456789abcdef0123456789abcdef/Roadmap fedcba9876543210fedcba9876543210.md": {Data: []byte(
+			"# Roadmap\n\nNote: this line is content, not a property\nQ1 goals\n")},
+		"Projects 0123456789abcdef0123456789abcdef/logo.png": {Data: []byte{0x89, 'P', 'N', 'G'}},
+		"Tasks 00112233445566778899aabbccddeeff.csv": {Data: []byte(
+			"\ufeffName,Tags\nRoadmap,work\nBuy milk,errands\n")},
+		"Tasks 00112233445566778899aabbccddeeff_all.csv": {Data: []byte(
+			"\ufeffName,Tags,Due\nRoadmap,work,2024-01-01\nBuy milk,errands,2024-02-02\nCall mum,,\n")},
+		"Reading 99887766554433221100ffeeddccbbaa.csv": {Data: []byte("Name,Author\nDune,Frank Herbert\n")},
+	}
+
+	batch, err := ReadNotionExport(export)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	notes := make(map[string]ImportedNote)
+	for _, note := range batch.Notes {
+		notes[note.Title] = note
+	}
+	if len(notes) != 5 {
+		t.Fatalf("expected Projects, Roadmap and the Buy milk, Call mum and Dune rows, got %+v", batch.Notes)
+	}
+
+	projects := notes["Projects"]
+	if projects.Content != "Status: Active\n\nSee [Roadmap](Projects/Roadmap.md)." {
+		t.Errorf("unexpected Projects content %q", projects.Content)
+	}
+	if !reflect.DeepEqual(projects.Tags, []string{"work", "planning"}) {
+		t.Errorf("unexpected Projects tags %v", projects.Tags)
+	}
+	if !projects.CreatedAt.Equal(time.Date(2021, 7, 30, 20, 52, 0, 0, time.UTC)) {
+		t.Errorf("unexpected Projects created date %v", projects.CreatedAt)
+	}
+
+	if roadmap := notes["Roadmap"]; roadmap.Content != "Note: this line is content, not a property\nQ1 goals" || len(roadmap.Tags) != 0 {
+		t.Errorf("unexpected Roadmap note %+v", roadmap)
+	}
+
+	milk := notes["Buy milk"]
+	if milk.Content != "- **Tags:** errands\n- **Due:** 2024-02-02" || !reflect.DeepEqual(milk.Tags, []string{"errands"}) {
+		t.Errorf("unexpected database row note %+v", milk)
+	}
+	if mum := notes["Call mum"]; mum.Content != "Call mum" {
+		t.Errorf("expected a title-only row to keep its title, got %+v", mum)
+	}
+	if dune := notes["Dune"]; dune.Content != "- **Author:** Frank Herbert" {
+		t.Errorf("expected a database without _all file to be read, got %+v", dune)
+	}
+
+	if len(batch.Skipped) != 1 || batch.Skipped[0].Path != "Projects 0123456789abcdef0123456789abcdef/logo.png" {
+		t.Errorf("expected only the image to be skipped, got %+v", batch.Skipped)
+	}
+}
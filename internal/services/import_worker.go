@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Import job states stored in import_jobs.status
+const (
+	ImportStatusPending    = "pending"
+	ImportStatusProcessing = "processing"
+	ImportStatusCompleted  = "completed"
+	ImportStatusFailed     = "failed"
+)
+
+// ImportWorker runs queued imports one at a time in the background, so large
+// exports are not bound by the HTTP server's timeouts. Progress is written to
+// the job as notes are saved, which also renews its lock until the import ends.
+type ImportWorker struct {
+	queries          *db_sqlc.Queries
+	importService    *ImportService
+	maxAttempts      int
+	pollInterval     time.Duration
+	progressInterval time.Duration
+	staleAfter       time.Duration
+}
+
+// NewImportWorker creates an import worker
+func NewImportWorker(db *pgxpool.Pool) *ImportWorker {
+	return &ImportWorker{
+		queries:          db_sqlc.New(db),
+		importService:    NewImportService(db),
+		maxAttempts:      3,
+		pollInterval:     2 * time.Second,
+		progressInterval: time.Second,
+		staleAfter:       5 * time.Minute,
+	}
+}
+
+// Run claims and processes import jobs until ctx is cancelled. A job
+// interrupted by a shutdown is reclaimed once it goes stale; its transaction
+// was rolled back, so it starts over.
+func (w *ImportWorker) Run(ctx context.Context) {
+	log.Println("Import worker started")
+	defer log.Println("Import worker stopped")
+
+	for {
+		job, err := w.queries.ClaimImportJob(ctx, pgtype.Timestamptz{Time: time.Now().Add(-w.staleAfter), Valid: true})
+		if err == nil {
+			w.process(ctx, job)
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) && ctx.Err() == nil {
+			log.Printf("Failed to claim import job: %v", err)
+		}
+
+		select {
+		case <-time.After(w.pollInterval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// process reads and saves the export of one job and stores its report
+func (w *ImportWorker) process(ctx context.Context, job db_sqlc.ClaimImportJobRow) {
+	if int(job.Attempts) > w.maxAttempts {
+		w.fail(ctx, job, fmt.Errorf("import did not finish after %d attempts", w.maxAttempts))
+		return
+	}
+
+	batch, err := ReadImport(job.Format, job.Filename, job.Payload)
+	if err != nil {
+		w.fail(ctx, job, err)
+		return
+	}
+
+	var lastUpdate time.Time
+	lastProcessed := -1
+	report, err := w.importService.Import(ctx, job.UserID, batch, ImportOptions{
+		DryRun:     job.DryRun,
+		NotebookID: job.NotebookID,
+		Progress: func(processed, total int) {
+			// Report the last note right away; after that the calls made while
+			// links are indexed only keep renewing the job's lock
+			done := processed == total && lastProcessed != total
+			if !done && time.Since(lastUpdate) < w.progressInterval {
+				return
+			}
+			lastUpdate, lastProcessed = time.Now(), processed
+			if err := w.queries.UpdateImportJobProgress(ctx, db_sqlc.UpdateImportJobProgressParams{
+				ID:        job.ID,
+				Total:     int32(total),
+				Processed: int32(processed),
+			}); err != nil {
+				log.Printf("Failed to update progress of import job %s: %v", job.ID.String(), err)
+			}
+		},
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return // shutting down, the job is picked up again later
+		}
+		w.fail(ctx, job, err)
+		return
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		w.fail(ctx, job, err)
+		return
+	}
+	if err := w.queries.CompleteImportJob(ctx, db_sqlc.CompleteImportJobParams{ID: job.ID, Report: data}); err != nil {
+		log.Printf("Failed to complete import job %s: %v", job.ID.String(), err)
+		return
+	}
+	log.Printf("Import job %s finished: %d notes, %d duplicates, %d skipped",
+		job.ID.String(), len(report.Created), len(report.Duplicates), len(report.Skipped))
+}
+
+// fail marks a job as failed with the error that stopped it
+func (w *ImportWorker) fail(ctx context.Context, job db_sqlc.ClaimImportJobRow, cause error) {
+	log.Printf("Import job %s failed: %v", job.ID.String(), cause)
+	if err := w.queries.FailImportJob(ctx, db_sqlc.FailImportJobParams{
+		ID:    job.ID,
+		Error: pgtype.Text{String: cause.Error(), Valid: true},
+	}); err != nil {
+		log.Printf("Failed to mark import job %s as failed: %v", job.ID.String(), err)
+	}
+}
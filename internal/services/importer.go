@@ -0,0 +1,329 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Export formats that can be imported
+const (
+	ImportFormatMarkdown = "markdown" // zip of markdown files or an Obsidian vault
+	ImportFormatEnex     = "enex"     // Evernote .enex file, or a zip of them
+	ImportFormatNotion   = "notion"   // zip of a Notion "Markdown & CSV" export
+)
+
+// ErrUnsupportedImport is returned for exports that cannot be read in the requested format
+var ErrUnsupportedImport = errors.New("unsupported import")
+
+// importEmbeddingBatchSize is how many imported notes are queued for
+// embedding per statement
+const importEmbeddingBatchSize = 500
+
+// ImportedNote is a note read from another app's export, ready to be saved
+type ImportedNote struct {
+	Path      string // file the note came from, relative to the export root
+	Title     string
+	Content   string
+	Tags      []string
+	CreatedAt time.Time // zero when unknown
+	UpdatedAt time.Time // zero when unknown
+	Hash      string    // ContentHash of Content
+}
+
+// ImportSkip is a file of an export that was not imported, and why
+type ImportSkip struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// ImportBatch holds the notes read from one export
+type ImportBatch struct {
+	Notes   []ImportedNote
+	Skipped []ImportSkip
+}
+
+// add queues a note for import, skipping notes without content
+func (b *ImportBatch) add(note ImportedNote) {
+	if strings.TrimSpace(note.Content) == "" {
+		b.skip(note.Path, "empty note")
+		return
+	}
+	b.Notes = append(b.Notes, note)
+}
+
+// skip records a file that is not imported
+func (b *ImportBatch) skip(path, reason string) {
+	b.Skipped = append(b.Skipped, ImportSkip{Path: path, Reason: reason})
+}
+
+// merge appends the notes and skipped files of another batch
+func (b *ImportBatch) merge(other *ImportBatch) {
+	b.Notes = append(b.Notes, other.Notes...)
+	b.Skipped = append(b.Skipped, other.Skipped...)
+}
+
+// ReadImport reads an uploaded export in the given format. Markdown and
+// Notion exports are zip archives; Evernote exports may be a single .enex
+// file or a zip of them.
+func ReadImport(format, filename string, data []byte) (*ImportBatch, error) {
+	var archive *zip.Reader
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		var err error
+		if archive, err = zip.NewReader(bytes.NewReader(data), int64(len(data))); err != nil {
+			return nil, fmt.Errorf("%w: invalid zip archive: %v", ErrUnsupportedImport, err)
+		}
+	}
+
+	switch format {
+	case ImportFormatMarkdown, ImportFormatNotion:
+		if archive == nil {
+			return nil, fmt.Errorf("%w: %s imports must be zip archives", ErrUnsupportedImport, format)
+		}
+		if format == ImportFormatNotion {
+			return ReadNotionExport(archive)
+		}
+		return ReadMarkdownVault(archive)
+
+	case ImportFormatEnex:
+		if archive == nil {
+			return ReadEnex(filename, bytes.NewReader(data))
+		}
+		batch := &ImportBatch{}
+		err := fs.WalkDir(archive, ".", func(name string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			if strings.ToLower(path.Ext(name)) != ".enex" {
+				batch.skip(name, "not an .enex file")
+				return nil
+			}
+			file, err := archive.Open(name)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			notes, err := ReadEnex(name, file)
+			if err != nil {
+				batch.skip(name, err.Error())
+				return nil
+			}
+			batch.merge(notes)
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		return batch, nil
+	}
+	return nil, fmt.Errorf("%w: unknown format %q", ErrUnsupportedImport, format)
+}
+
+// ContentHash identifies note content for deduplication: the hex SHA-256 of
+// its UTF-8 bytes, which the database computes the same way for stored notes
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// ImportOptions controls how an import is saved
+type ImportOptions struct {
+	DryRun     bool        // report what would be imported without saving anything
+	NotebookID pgtype.UUID // put the imported notes in this notebook when valid
+
+	// Progress, when set, is called as notes are saved with how many of
+	// the notes to create are done, and again for each note whose links are
+	// indexed once all of them are saved
+	Progress func(processed, total int)
+}
+
+// ImportedNoteResult is a note created by an import, or that would be in a dry run
+type ImportedNoteResult struct {
+	Path      string    `json:"path"`
+	NoteID    string    `json:"note_id,omitempty"` // empty in a dry run
+	Title     string    `json:"title"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+// ImportDuplicate is a note left out because its content already exists,
+// either as an existing note or in an earlier file of the same import
+type ImportDuplicate struct {
+	Path            string `json:"path"`
+	Title           string `json:"title"`
+	DuplicateOfID   string `json:"duplicate_of_id,omitempty"`
+	DuplicateOfPath string `json:"duplicate_of_path,omitempty"`
+}
+
+// ImportReport describes the outcome of an import
+type ImportReport struct {
+	DryRun          bool                 `json:"dry_run"`
+	Created         []ImportedNoteResult `json:"created"`
+	Duplicates      []ImportDuplicate    `json:"duplicates"`
+	Skipped         []ImportSkip         `json:"skipped"`
+	EmbeddingQueued int                  `json:"embedding_queued"`
+}
+
+// ImportService saves notes read from other apps' exports
+type ImportService struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewImportService creates a new import service
+func NewImportService(db *pgxpool.Pool) *ImportService {
+	return &ImportService{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// Import saves the notes of batch for the user in one transaction. Notes whose
+// content matches an existing live note, or an earlier note of the batch, are
+// reported as duplicates instead. Links are indexed once all notes exist, so
+// links between imported notes resolve, and the new notes are queued for
+// embedding in batches for the embedding worker.
+func (s *ImportService) Import(ctx context.Context, userID pgtype.UUID, batch *ImportBatch, opts ImportOptions) (*ImportReport, error) {
+	report := &ImportReport{
+		DryRun:     opts.DryRun,
+		Created:    []ImportedNoteResult{},
+		Duplicates: []ImportDuplicate{},
+		Skipped:    batch.Skipped,
+	}
+	if report.Skipped == nil {
+		report.Skipped = []ImportSkip{}
+	}
+
+	firstPath := make(map[string]string)
+	var unique []ImportedNote
+	var hashes []string
+	for _, note := range batch.Notes {
+		if path, ok := firstPath[note.Hash]; ok {
+			report.Duplicates = append(report.Duplicates, ImportDuplicate{Path: note.Path, Title: note.Title, DuplicateOfPath: path})
+			continue
+		}
+		firstPath[note.Hash] = note.Path
+		unique = append(unique, note)
+		hashes = append(hashes, note.Hash)
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	existingID := make(map[string]string)
+	if len(hashes) > 0 {
+		existing, err := qtx.FindNotesByContentHash(ctx, db_sqlc.FindNotesByContentHashParams{UserID: userID, Hashes: hashes})
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up existing notes: %w", err)
+		}
+		for _, note := range existing {
+			existingID[note.ContentHash] = note.ID.String()
+		}
+	}
+
+	var toCreate []ImportedNote
+	for _, note := range unique {
+		if id, ok := existingID[note.Hash]; ok {
+			report.Duplicates = append(report.Duplicates, ImportDuplicate{Path: note.Path, Title: note.Title, DuplicateOfID: id})
+			continue
+		}
+		toCreate = append(toCreate, note)
+	}
+
+	progress := func(processed int) {
+		if opts.Progress != nil {
+			opts.Progress(processed, len(toCreate))
+		}
+	}
+
+	if opts.DryRun {
+		for _, note := range toCreate {
+			report.Created = append(report.Created, importedNoteResult(note, ""))
+		}
+		progress(len(toCreate))
+		return report, nil
+	}
+
+	progress(0)
+	created := make([]db_sqlc.ImportNoteRow, 0, len(toCreate))
+	for _, note := range toCreate {
+		row, err := qtx.ImportNote(ctx, db_sqlc.ImportNoteParams{
+			UserID:        userID,
+			Title:         note.Title,
+			Content:       note.Content,
+			Tags:          note.Tags,
+			SearchTitle:   SearchText(note.Title),
+			SearchContent: SearchText(note.Content),
+			NotebookID:    opts.NotebookID,
+			CreatedAt:     pgtype.Timestamptz{Time: note.CreatedAt, Valid: !note.CreatedAt.IsZero()},
+			UpdatedAt:     pgtype.Timestamptz{Time: note.UpdatedAt, Valid: !note.UpdatedAt.IsZero()},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to import %s: %w", note.Path, err)
+		}
+		if _, err := qtx.CreateNoteRevision(ctx, db_sqlc.CreateNoteRevisionParams{NoteID: row.ID}); err != nil {
+			return nil, fmt.Errorf("failed to record revision of %s: %w", note.Path, err)
+		}
+		created = append(created, row)
+		report.Created = append(report.Created, importedNoteResult(note, row.ID.String()))
+		progress(len(created))
+	}
+
+	noteIDs := make([]pgtype.UUID, len(created))
+	for i, row := range created {
+		if err := IndexNoteLinks(ctx, qtx, row.ID, userID, row.Title, row.Content); err != nil {
+			return nil, err
+		}
+		noteIDs[i] = row.ID
+		progress(len(created))
+	}
+
+	for start := 0; start < len(noteIDs); start += importEmbeddingBatchSize {
+		end := min(start+importEmbeddingBatchSize, len(noteIDs))
+		if err := qtx.EnqueueEmbeddingJobs(ctx, db_sqlc.EnqueueEmbeddingJobsParams{
+			UserID:  userID,
+			NoteIds: noteIDs[start:end],
+		}); err != nil {
+			return nil, fmt.Errorf("failed to enqueue embedding jobs: %w", err)
+		}
+	}
+	report.EmbeddingQueued = len(noteIDs)
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit import: %w", err)
+	}
+	return report, nil
+}
+
+// importedNoteResult reports a note of an import
+func importedNoteResult(note ImportedNote, noteID string) ImportedNoteResult {
+	tags := note.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	return ImportedNoteResult{
+		Path:      note.Path,
+		NoteID:    noteID,
+		Title:     note.Title,
+		Tags:      tags,
+		CreatedAt: note.CreatedAt,
+	}
+}
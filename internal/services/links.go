@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Link kinds stored in note_links.kind
+const (
+	LinkKindWiki     = "wiki"
+	LinkKindMarkdown = "markdown"
+)
+
+// noteURLPattern matches markdown link targets that point at a note by ID, e.g. /notes/<uuid>
+var noteURLPattern = regexp.MustCompile(`(?i)(?:^|/)notes/([0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12})/?$`)
+
+// NoteLink is a reference from a note's content to another note
+type NoteLink struct {
+	Target   string // title of the linked note
+	TargetID string // ID of the linked note, set instead of Target for /notes/<id> links
+	Alias    string // display text, when it differs from the target
+	Kind     string // wiki or markdown
+	Offset   int    // character position of the link in the content
+}
+
+// ExtractLinks finds [[wiki links]] and markdown links to other notes in content.
+// Wiki links may carry an alias ([[Target|shown text]]) or point at a heading
+// ([[Target#Heading]]); markdown links count when they point at a .md file
+// (as in an Obsidian vault) or at /notes/<id>. Links inside code are ignored,
+// and each target is reported once, at its first occurrence.
+func ExtractLinks(content string) []NoteLink {
+	var links []NoteLink
+	seen := make(map[string]bool)
+	add := func(link NoteLink) {
+		key := strings.ToLower(link.Target)
+		if link.TargetID != "" {
+			key = "id:" + strings.ToLower(link.TargetID)
+		}
+		if !seen[key] {
+			seen[key] = true
+			links = append(links, link)
+		}
+	}
+
+	runes := []rune(content)
+	inFence := false
+	lineStart := 0
+	for lineStart <= len(runes) {
+		lineEnd := lineStart
+		for lineEnd < len(runes) && runes[lineEnd] != '\n' {
+			lineEnd++
+		}
+
+		trimmed := strings.TrimSpace(string(runes[lineStart:lineEnd]))
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+		} else if !inFence {
+			for _, link := range lineLinks(runes, lineStart, lineEnd) {
+				add(link)
+			}
+		}
+
+		lineStart = lineEnd + 1
+	}
+	return links
+}
+
+// lineLinks scans runes[start:end], a single line, for links outside inline code
+func lineLinks(runes []rune, start, end int) []NoteLink {
+	var links []NoteLink
+	for i := start; i < end; i++ {
+		switch {
+		case runes[i] == '`':
+			// Skip the inline code span, if it is closed on this line
+			if j := indexRune(runes, '`', i+1, end); j >= 0 {
+				i = j
+			}
+		case runes[i] == '[' && i+1 < end && runes[i+1] == '[':
+			closing := indexString(runes, "]]", i+2, end)
+			if closing < 0 {
+				continue
+			}
+			if link, ok := parseWikiLink(string(runes[i+2 : closing])); ok {
+				link.Offset = i
+				links = append(links, link)
+			}
+			i = closing + 1
+		case runes[i] == '[' && (i == start || runes[i-1] != '!'):
+			textEnd := indexRune(runes, ']', i+1, end)
+			if textEnd < 0 || textEnd+1 >= end || runes[textEnd+1] != '(' {
+				continue
+			}
+			urlEnd := indexRune(runes, ')', textEnd+2, end)
+			if urlEnd < 0 {
+				continue
+			}
+			if link, ok := parseMarkdownLink(string(runes[i+1:textEnd]), string(runes[textEnd+2:urlEnd])); ok {
+				link.Offset = i
+				links = append(links, link)
+			}
+			i = urlEnd
+		}
+	}
+	return links
+}
+
+// parseWikiLink parses the inside of [[...]]
+func parseWikiLink(inner string) (NoteLink, bool) {
+	target, alias, _ := strings.Cut(inner, "|")
+	// Headings and block references point into the same note
+	if i := strings.IndexAny(target, "#^"); i >= 0 {
+		target = target[:i]
+	}
+	target = strings.TrimSpace(target)
+	alias = strings.TrimSpace(alias)
+	if target == "" {
+		return NoteLink{}, false
+	}
+	if alias == target {
+		alias = ""
+	}
+	return NoteLink{Target: target, Alias: alias, Kind: LinkKindWiki}, true
+}
+
+// parseMarkdownLink parses [text](destination), keeping only links to notes
+func parseMarkdownLink(text, destination string) (NoteLink, bool) {
+	destination = strings.TrimSpace(destination)
+	// Drop an optional link title: [text](target "title")
+	if i := strings.IndexAny(destination, " \t"); i >= 0 {
+		destination = destination[:i]
+	}
+	destination = strings.TrimSuffix(strings.TrimPrefix(destination, "<"), ">")
+	if i := strings.IndexByte(destination, '#'); i >= 0 {
+		destination = destination[:i]
+	}
+	text = strings.TrimSpace(text)
+
+	if m := noteURLPattern.FindStringSubmatch(destination); m != nil {
+		return NoteLink{TargetID: strings.ToLower(m[1]), Alias: text, Kind: LinkKindMarkdown}, true
+	}
+
+	if destination == "" || strings.Contains(destination, "://") || strings.HasPrefix(destination, "mailto:") {
+		return NoteLink{}, false
+	}
+	if !strings.EqualFold(path.Ext(destination), ".md") {
+		return NoteLink{}, false
+	}
+	if unescaped, err := url.PathUnescape(destination); err == nil {
+		destination = unescaped
+	}
+	target := strings.TrimSpace(strings.TrimSuffix(path.Base(destination), path.Ext(destination)))
+	if target == "" {
+		return NoteLink{}, false
+	}
+
+	alias := text
+	if alias == target {
+		alias = ""
+	}
+	return NoteLink{Target: target, Alias: alias, Kind: LinkKindMarkdown}, true
+}
+
+// indexRune returns the position of r in runes[from:to], or -1
+func indexRune(runes []rune, r rune, from, to int) int {
+	for i := from; i < to; i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
+
+// indexString returns the position of s in runes[from:to], or -1
+func indexString(runes []rune, s string, from, to int) int {
+	needle := []rune(s)
+	for i := from; i+len(needle) <= to; i++ {
+		if hasRunesAt(runes, needle, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// IndexNoteLinks replaces the stored outgoing links of a note and resolves
+// links elsewhere that were waiting for a note with its title. Pass queries
+// bound to the transaction that saves the note.
+func IndexNoteLinks(ctx context.Context, queries *db_sqlc.Queries, noteID, userID pgtype.UUID, title, content string) error {
+	if err := queries.DeleteNoteLinks(ctx, noteID); err != nil {
+		return fmt.Errorf("failed to delete old note links: %w", err)
+	}
+
+	for _, link := range ExtractLinks(content) {
+		params := db_sqlc.CreateNoteLinkParams{
+			SourceNoteID: noteID,
+			UserID:       userID,
+			TargetTitle:  link.Target,
+			Kind:         link.Kind,
+			Alias:        link.Alias,
+			Position:     int32(link.Offset),
+		}
+		if link.TargetID != "" {
+			if err := params.TargetNoteID.Scan(link.TargetID); err != nil {
+				continue
+			}
+		}
+		if err := queries.CreateNoteLink(ctx, params); err != nil {
+			return fmt.Errorf("failed to store note link: %w", err)
+		}
+	}
+
+	if _, err := queries.ResolveNoteLinks(ctx, db_sqlc.ResolveNoteLinksParams{
+		UserID:       userID,
+		TargetNoteID: noteID,
+		Title:        title,
+	}); err != nil {
+		return fmt.Errorf("failed to resolve links to note: %w", err)
+	}
+
+	if err := queries.MarkNoteLinksIndexed(ctx, noteID); err != nil {
+		return fmt.Errorf("failed to mark note links indexed: %w", err)
+	}
+	return nil
+}
+
+// LinkService maintains the links between notes
+type LinkService struct {
+	queries *db_sqlc.Queries
+	db      *pgxpool.Pool
+}
+
+// NewLinkService creates a new link service
+func NewLinkService(db *pgxpool.Pool) *LinkService {
+	return &LinkService{
+		queries: db_sqlc.New(db),
+		db:      db,
+	}
+}
+
+// BackfillLinks indexes the links of notes written before links were tracked.
+// It works in batches until no unindexed note is left.
+func (s *LinkService) BackfillLinks(ctx context.Context) error {
+	const batchSize = 200
+
+	total := 0
+	for {
+		notes, err := s.queries.ListUnindexedLinkNotes(ctx, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list notes without link index: %w", err)
+		}
+
+		for _, note := range notes {
+			if err := s.indexNote(ctx, note); err != nil {
+				return fmt.Errorf("failed to index links of note %s: %w", note.ID.String(), err)
+			}
+		}
+		total += len(notes)
+
+		if len(notes) < batchSize {
+			if total > 0 {
+				log.Printf("Indexed links of %d notes", total)
+			}
+			return nil
+		}
+	}
+}
+
+// indexNote indexes one note's links in its own transaction
+func (s *LinkService) indexNote(ctx context.Context, note db_sqlc.ListUnindexedLinkNotesRow) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := IndexNoteLinks(ctx, s.queries.WithTx(tx), note.ID, note.UserID, note.Title, note.Content); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
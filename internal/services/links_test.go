@@ -0,0 +1,74 @@
+package services
+
+import (
+	"testing"
+)
+
+func TestExtractLinksWikiLinks(t *testing.T) {
+	content := "See [[Postgres]] and [[Vector Search|semantic search]].\nAlso [[Postgres#Indexes]] and [[ Query Planner ]]."
+	links := ExtractLinks(content)
+
+	if len(links) != 3 {
+		t.Fatalf("expected 3 links, got %+v", links)
+	}
+	if links[0].Target != "Postgres" || links[0].Kind != LinkKindWiki || links[0].Offset != 4 {
+		t.Errorf("unexpected first link %+v", links[0])
+	}
+	if links[1].Target != "Vector Search" || links[1].Alias != "semantic search" {
+		t.Errorf("expected aliased link, got %+v", links[1])
+	}
+	if links[2].Target != "Query Planner" {
+		t.Errorf("expected trimmed target, got %+v", links[2])
+	}
+}
+
+func TestExtractLinksMarkdownLinks(t *testing.T) {
+	content := "[Planner](Query%20Planner.md) [by id](/notes/3F2504E0-4F89-11D3-9A0C-0305E82C3301) " +
+		"[site](https://example.com/page.md) ![diagram](Diagram.md) [anchor](#top) [same](Postgres.md)"
+	links := ExtractLinks(content)
+
+	if len(links) != 3 {
+		t.Fatalf("expected 3 links, got %+v", links)
+	}
+	if links[0].Target != "Query Planner" || links[0].Alias != "Planner" || links[0].Kind != LinkKindMarkdown {
+		t.Errorf("expected link to Query Planner, got %+v", links[0])
+	}
+	if links[1].TargetID != "3f2504e0-4f89-11d3-9a0c-0305e82c3301" || links[1].Target != "" {
+		t.Errorf("expected link by ID, got %+v", links[1])
+	}
+	if links[2].Target != "Postgres" || links[2].Alias != "same" {
+		t.Errorf("expected link to Postgres, got %+v", links[2])
+	}
+}
+
+func TestExtractLinksSkipsCode(t *testing.T) {
+	content := "Use `[[not a link]]` here.\n```\n[[Also not]]\n```\nBut [[Real]] counts."
+	links := ExtractLinks(content)
+
+	if len(links) != 1 || links[0].Target != "Real" {
+		t.Fatalf("expected only the link outside code, got %+v", links)
+	}
+}
+
+func TestExtractLinksDeduplicatesTargets(t *testing.T) {
+	content := "[[Go]] then [[go|the language]] and [Go](Go.md)"
+	links := ExtractLinks(content)
+
+	if len(links) != 1 || links[0].Offset != 0 {
+		t.Fatalf("expected one link at the first occurrence, got %+v", links)
+	}
+}
+
+func TestExtractLinksOffsetsCountCharacters(t *testing.T) {
+	links := ExtractLinks("日本語 [[東京]]")
+
+	if len(links) != 1 || links[0].Offset != 4 {
+		t.Fatalf("expected link at character 4, got %+v", links)
+	}
+}
+
+func TestExtractLinksIgnoresEmptyAndUnclosed(t *testing.T) {
+	if links := ExtractLinks("[[]] [[#Heading]] [[unclosed"); len(links) != 0 {
+		t.Errorf("expected no links, got %+v", links)
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/joho/godotenv/autoload"
+	"github.com/tmc/langchaingo/llms"
+	"github.com/tmc/langchaingo/llms/googleai"
+	"github.com/tmc/langchaingo/llms/ollama"
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// LLMConfig selects and configures the chat model used for generation
+type LLMConfig struct {
+	Provider string        // gemini, openai, ollama or fake
+	Model    string        // provider-specific model name
+	BaseURL  string        // endpoint for OpenAI-compatible and Ollama servers
+	APIKey   string        // credentials for hosted providers
+	Timeout  time.Duration // per-call timeout
+}
+
+// llmProvider builds a model from config and supplies its defaults
+type llmProvider struct {
+	defaultModel   string
+	defaultTimeout time.Duration
+	apiKeyEnv      string
+	build          func(ctx context.Context, cfg LLMConfig) (llms.Model, error)
+}
+
+// llmProviders holds the available LLM backends by name
+var llmProviders = map[string]llmProvider{
+	"gemini": {
+		defaultModel:   "gemini-1.5-flash",
+		defaultTimeout: 60 * time.Second,
+		apiKeyEnv:      "GOOGLE_API_KEY",
+		build: func(ctx context.Context, cfg LLMConfig) (llms.Model, error) {
+			if cfg.APIKey == "" {
+				return nil, fmt.Errorf("GOOGLE_API_KEY environment variable is required")
+			}
+			return googleai.New(
+				ctx,
+				googleai.WithAPIKey(cfg.APIKey),
+				googleai.WithDefaultModel(cfg.Model),
+			)
+		},
+	},
+	"openai": {
+		defaultModel:   "gpt-4o-mini",
+		defaultTimeout: 60 * time.Second,
+		apiKeyEnv:      "OPENAI_API_KEY",
+		build: func(ctx context.Context, cfg LLMConfig) (llms.Model, error) {
+			opts := []openai.Option{openai.WithModel(cfg.Model)}
+			// Local OpenAI-compatible servers often do not require a key, but the client does
+			apiKey := cfg.APIKey
+			if apiKey == "" {
+				apiKey = "not-needed"
+			}
+			opts = append(opts, openai.WithToken(apiKey))
+			if cfg.BaseURL != "" {
+				opts = append(opts, openai.WithBaseURL(cfg.BaseURL))
+			}
+			return openai.New(opts...)
+		},
+	},
+	"ollama": {
+		defaultModel:   "llama3.1",
+		defaultTimeout: 120 * time.Second,
+		build: func(ctx context.Context, cfg LLMConfig) (llms.Model, error) {
+			opts := []ollama.Option{ollama.WithModel(cfg.Model)}
+			if cfg.BaseURL != "" {
+				opts = append(opts, ollama.WithServerURL(cfg.BaseURL))
+			}
+			return ollama.New(opts...)
+		},
+	},
+	"fake": {
+		defaultModel:   "fake",
+		defaultTimeout: 5 * time.Second,
+		build: func(ctx context.Context, cfg LLMConfig) (llms.Model, error) {
+			return NewFakeLLM(), nil
+		},
+	},
+}
+
+// LoadLLMConfig reads the LLM configuration from the environment.
+// LLM_PROVIDER defaults to gemini so existing deployments keep working.
+func LoadLLMConfig() (LLMConfig, error) {
+	cfg := LLMConfig{
+		Provider: strings.ToLower(os.Getenv("LLM_PROVIDER")),
+		Model:    os.Getenv("LLM_MODEL"),
+		BaseURL:  os.Getenv("LLM_BASE_URL"),
+		APIKey:   os.Getenv("LLM_API_KEY"),
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = "gemini"
+	}
+
+	provider, ok := llmProviders[cfg.Provider]
+	if !ok {
+		return cfg, fmt.Errorf("unknown LLM provider %q, expected one of: %s", cfg.Provider, strings.Join(llmProviderNames(), ", "))
+	}
+
+	if cfg.Model == "" {
+		cfg.Model = provider.defaultModel
+	}
+	if cfg.APIKey == "" && provider.apiKeyEnv != "" {
+		cfg.APIKey = os.Getenv(provider.apiKeyEnv)
+	}
+
+	cfg.Timeout = provider.defaultTimeout
+	if timeout := os.Getenv("LLM_TIMEOUT"); timeout != "" {
+		parsed, err := time.ParseDuration(timeout)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid LLM_TIMEOUT: %w", err)
+		}
+		cfg.Timeout = parsed
+	}
+
+	return cfg, nil
+}
+
+// NewLLM creates the chat model described by cfg
+func NewLLM(ctx context.Context, cfg LLMConfig) (llms.Model, error) {
+	provider, ok := llmProviders[cfg.Provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown LLM provider %q", cfg.Provider)
+	}
+
+	llm, err := provider.build(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s LLM client: %w", cfg.Provider, err)
+	}
+
+	if cfg.Timeout <= 0 {
+		return llm, nil
+	}
+	return &timeoutModel{Model: llm, timeout: cfg.Timeout}, nil
+}
+
+// llmProviderNames returns the registered provider names in a stable order
+func llmProviderNames() []string {
+	names := make([]string, 0, len(llmProviders))
+	for name := range llmProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// timeoutModel bounds every call to the wrapped model
+type timeoutModel struct {
+	llms.Model
+	timeout time.Duration
+}
+
+// GenerateContent calls the wrapped model with a deadline
+func (m *timeoutModel) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	return m.Model.GenerateContent(ctx, messages, options...)
+}
+
+// Call calls the wrapped model with a deadline
+func (m *timeoutModel) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
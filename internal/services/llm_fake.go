@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/tmc/langchaingo/llms"
+)
+
+// fakeNotePattern matches the note headers written by buildNotesContext
+var fakeNotePattern = regexp.MustCompile(`筆記 \d+（ID: ([^）]+)）- ([^\n]*):`)
+
+// fakeChatSourcePattern matches the numbered note excerpts written by buildChatMessages
+var fakeChatSourcePattern = regexp.MustCompile(`\[(\d+)\] 筆記「([^」]*)」`)
+
+// FakeLLM is a deterministic in-process model for tests and offline development.
+// In JSON mode it answers with one flashcard per note found in the prompt;
+// questions about note excerpts are answered by citing the first excerpt.
+type FakeLLM struct{}
+
+// NewFakeLLM creates a new fake LLM
+func NewFakeLLM() *FakeLLM {
+	return &FakeLLM{}
+}
+
+// GenerateContent returns a canned response derived from the prompt
+func (f *FakeLLM) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	opts := llms.CallOptions{}
+	for _, opt := range options {
+		opt(&opts)
+	}
+
+	var prompt strings.Builder
+	for _, message := range messages {
+		for _, part := range message.Parts {
+			if text, ok := part.(llms.TextContent); ok {
+				prompt.WriteString(text.Text)
+			}
+		}
+	}
+
+	var content string
+	if opts.JSONMode {
+		content = fakeFlashcardJSON(prompt.String())
+	} else if match := fakeChatSourcePattern.FindStringSubmatch(prompt.String()); match != nil {
+		content = fmt.Sprintf("筆記「%s」提到了這個問題。[%s]", match[2], match[1])
+	} else {
+		content = fmt.Sprintf("這是離線測試模型的回覆（%d 個字元的提示）。", len([]rune(prompt.String())))
+	}
+
+	if opts.StreamingFunc != nil {
+		runes := []rune(content)
+		for start := 0; start < len(runes); start += 32 {
+			end := min(start+32, len(runes))
+			if err := opts.StreamingFunc(ctx, []byte(string(runes[start:end]))); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &llms.ContentResponse{
+		Choices: []*llms.ContentChoice{{Content: content, StopReason: "stop"}},
+	}, nil
+}
+
+// Call returns a canned response for a single prompt
+func (f *FakeLLM) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, f, prompt, options...)
+}
+
+// fakeFlashcardJSON builds a valid flashcard array referencing the notes in the prompt
+func fakeFlashcardJSON(prompt string) string {
+	flashcards := []Flashcard{}
+	for _, match := range fakeNotePattern.FindAllStringSubmatch(prompt, -1) {
+		flashcards = append(flashcards, Flashcard{
+			Question:     fmt.Sprintf("「%s」的重點是什麼？", match[2]),
+			Answer:       fmt.Sprintf("請複習筆記「%s」。", match[2]),
+			Difficulty:   "Medium",
+			SourceNoteID: match[1],
+		})
+	}
+
+	data, _ := json.Marshal(flashcards)
+	return string(data)
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadLLMConfigDefaults(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "ollama")
+	t.Setenv("LLM_MODEL", "")
+	t.Setenv("LLM_TIMEOUT", "")
+
+	cfg, err := LoadLLMConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Model != "llama3.1" {
+		t.Errorf("expected default ollama model, got %q", cfg.Model)
+	}
+	if cfg.Timeout != 120*time.Second {
+		t.Errorf("expected default ollama timeout, got %v", cfg.Timeout)
+	}
+}
+
+func TestLoadLLMConfigOverrides(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "OpenAI")
+	t.Setenv("LLM_MODEL", "qwen2.5")
+	t.Setenv("LLM_BASE_URL", "http://localhost:8000/v1")
+	t.Setenv("LLM_TIMEOUT", "15s")
+
+	cfg, err := LoadLLMConfig()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Provider != "openai" || cfg.Model != "qwen2.5" || cfg.Timeout != 15*time.Second {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+func TestLoadLLMConfigUnknownProvider(t *testing.T) {
+	t.Setenv("LLM_PROVIDER", "mystery")
+
+	if _, err := LoadLLMConfig(); err == nil {
+		t.Fatal("expected error for unknown provider")
+	}
+}
+
+func TestFakeLLMStreamsValidFlashcards(t *testing.T) {
+	llm, err := NewLLM(context.Background(), LLMConfig{Provider: "fake", Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	service := NewFlashcardServiceWithModel(llm)
+
+	notes := []Note{
+		{ID: "note-1", Title: "Goroutines", Content: "..."},
+		{ID: "note-2", Title: "Channels", Content: "..."},
+	}
+
+	responseChan := make(chan string, 100)
+	flashcards, err := service.StreamFlashcardFromNotes(context.Background(), notes, responseChan)
+	close(responseChan)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(flashcards) != 2 {
+		t.Fatalf("expected one flashcard per note, got %d", len(flashcards))
+	}
+
+	var cardEvents int
+	for message := range responseChan {
+		if strings.Contains(message, `"type":"card"`) {
+			cardEvents++
+		}
+	}
+	if cardEvents != 2 {
+		t.Errorf("expected 2 card events, got %d", cardEvents)
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	db_sqlc "go-note/internal/db_sqlc"
+)
+
+// NotebookNode is a notebook with its child notebooks, in display order
+type NotebookNode struct {
+	ID        string          `json:"id"`
+	ParentID  *string         `json:"parent_id"`
+	Name      string          `json:"name"`
+	Position  int32           `json:"position"`
+	NoteCount int64           `json:"note_count"` // notes directly in this notebook
+	CreatedAt string          `json:"created_at"`
+	UpdatedAt string          `json:"updated_at"`
+	Children  []*NotebookNode `json:"children"`
+}
+
+// BuildNotebookTree nests notebooks under their parents. Rows must already be
+// sorted in sibling order; notebooks whose parent is missing become roots.
+func BuildNotebookTree(rows []db_sqlc.ListNotebooksRow) []*NotebookNode {
+	nodes := make(map[string]*NotebookNode, len(rows))
+	for _, row := range rows {
+		node := &NotebookNode{
+			ID:        row.ID.String(),
+			Name:      row.Name,
+			Position:  row.Position,
+			NoteCount: row.NoteCount,
+			CreatedAt: row.CreatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			UpdatedAt: row.UpdatedAt.Time.Format("2006-01-02T15:04:05Z07:00"),
+			Children:  []*NotebookNode{},
+		}
+		if row.ParentID.Valid {
+			parentID := row.ParentID.String()
+			node.ParentID = &parentID
+		}
+		nodes[node.ID] = node
+	}
+
+	roots := []*NotebookNode{}
+	for _, row := range rows {
+		node := nodes[row.ID.String()]
+		if node.ParentID != nil {
+			if parent, ok := nodes[*node.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots
+}
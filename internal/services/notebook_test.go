@@ -0,0 +1,41 @@
+package services
+
+import (
+	"testing"
+
+	db_sqlc "go-note/internal/db_sqlc"
+)
+
+func TestBuildNotebookTree(t *testing.T) {
+	root, child, grandchild, sibling, orphan := testUUID(1), testUUID(2), testUUID(3), testUUID(4), testUUID(5)
+
+	rows := []db_sqlc.ListNotebooksRow{
+		{ID: root, Name: "Computer Science", Position: 0},
+		{ID: child, ParentID: root, Name: "Databases", Position: 0, NoteCount: 2},
+		{ID: sibling, Name: "Languages", Position: 1},
+		{ID: grandchild, ParentID: child, Name: "Postgres", Position: 0},
+		{ID: orphan, ParentID: testUUID(9), Name: "Orphan", Position: 0},
+	}
+
+	tree := BuildNotebookTree(rows)
+	if len(tree) != 3 {
+		t.Fatalf("expected 3 roots, got %d", len(tree))
+	}
+	if tree[0].Name != "Computer Science" || tree[1].Name != "Languages" || tree[2].Name != "Orphan" {
+		t.Errorf("expected roots in row order, got %s, %s, %s", tree[0].Name, tree[1].Name, tree[2].Name)
+	}
+
+	databases := tree[0].Children
+	if len(databases) != 1 || databases[0].Name != "Databases" || databases[0].NoteCount != 2 {
+		t.Fatalf("expected Databases under Computer Science, got %+v", databases)
+	}
+	if len(databases[0].Children) != 1 || databases[0].Children[0].Name != "Postgres" {
+		t.Errorf("expected Postgres under Databases, got %+v", databases[0].Children)
+	}
+	if tree[0].ParentID != nil || *databases[0].ParentID != root.String() {
+		t.Error("expected parent IDs to be set only on child notebooks")
+	}
+	if tree[1].Children == nil {
+		t.Error("expected leaf notebooks to have an empty children list")
+	}
+}
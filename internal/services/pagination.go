@@ -0,0 +1,41 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrInvalidCursor is returned for cursors that were not issued by the API
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// PageCursor marks the last row of a page in a keyset-paginated listing. It
+// records the sort it was issued for, so it cannot be replayed against
+// another ordering.
+type PageCursor struct {
+	Sort       string    `json:"s"`
+	Descending bool      `json:"d,omitempty"`
+	Time       time.Time `json:"t,omitzero"`  // sort value for timestamp sorts
+	Text       string    `json:"x,omitempty"` // sort value for text sorts
+	ID         string    `json:"i"`
+}
+
+// EncodeCursor turns a cursor into an opaque URL-safe token
+func EncodeCursor(cursor PageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a token produced by EncodeCursor
+func DecodeCursor(token string) (PageCursor, error) {
+	var cursor PageCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == "" || cursor.Sort == "" {
+		return PageCursor{}, ErrInvalidCursor
+	}
+	return cursor, nil
+}
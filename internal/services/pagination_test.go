@@ -0,0 +1,37 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 10, 16, 9, 30, 0, 123456000, time.UTC)
+	cursor := PageCursor{Sort: "updated_at", Descending: true, Time: at, ID: "3f2504e0-4f89-11d3-9a0c-0305e82c3301"}
+
+	decoded, err := DecodeCursor(EncodeCursor(cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Time.Equal(at) || decoded.Sort != cursor.Sort || !decoded.Descending || decoded.ID != cursor.ID {
+		t.Errorf("expected %+v, got %+v", cursor, decoded)
+	}
+
+	text := PageCursor{Sort: "title", Text: "Zürich notes", ID: "3f2504e0-4f89-11d3-9a0c-0305e82c3301"}
+	decoded, err = DecodeCursor(EncodeCursor(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Text != text.Text || decoded.Descending || !decoded.Time.IsZero() {
+		t.Errorf("expected %+v, got %+v", text, decoded)
+	}
+}
+
+func TestDecodeCursorRejectsGarbage(t *testing.T) {
+	for _, token := range []string{"", "not base64!", "e30", EncodeCursor(PageCursor{Sort: "title"})} {
+		if _, err := DecodeCursor(token); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("expected ErrInvalidCursor for %q, got %v", token, err)
+		}
+	}
+}
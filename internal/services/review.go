@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// ErrFlashcardNotFound is returned when a flashcard does not exist or belongs to another user
+var ErrFlashcardNotFound = errors.New("flashcard not found")
+
+// ReviewService schedules spaced-repetition reviews of saved flashcards
+type ReviewService struct {
+	queries   *db_sqlc.Queries
+	db        *pgxpool.Pool
+	scheduler Scheduler
+}
+
+// NewReviewService creates a new review service.
+// The algorithm is chosen with REVIEW_ALGORITHM and defaults to SM-2.
+func NewReviewService(db *pgxpool.Pool) (*ReviewService, error) {
+	algorithm := os.Getenv("REVIEW_ALGORITHM")
+	if algorithm == "" {
+		algorithm = "sm2"
+	}
+
+	scheduler, err := NewScheduler(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReviewService{
+		queries:   db_sqlc.New(db),
+		db:        db,
+		scheduler: scheduler,
+	}, nil
+}
+
+// Algorithm returns the name of the active scheduling algorithm
+func (s *ReviewService) Algorithm() string {
+	return s.scheduler.Name()
+}
+
+// ReviewCard records a review of the flashcard and stores its next schedule
+func (s *ReviewService) ReviewCard(ctx context.Context, userID, flashcardID pgtype.UUID, grade Grade, now time.Time) (*db_sqlc.CardSchedule, error) {
+	if !grade.Valid() {
+		return nil, fmt.Errorf("grade must be between 0 and 5")
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	// Lock the card so a concurrent review, even its first, waits and then starts from this one's schedule
+	if _, err := qtx.LockFlashcard(ctx, db_sqlc.LockFlashcardParams{ID: flashcardID, UserID: userID}); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrFlashcardNotFound
+		}
+		return nil, fmt.Errorf("failed to get flashcard: %w", err)
+	}
+
+	// Cards that were never reviewed start from the algorithm's initial state
+	state := s.scheduler.InitialState(now)
+	schedule, err := qtx.GetCardSchedule(ctx, db_sqlc.GetCardScheduleParams{FlashcardID: flashcardID, UserID: userID})
+	switch {
+	case err == nil:
+		state = cardStateFromSchedule(schedule)
+	case !errors.Is(err, pgx.ErrNoRows):
+		return nil, fmt.Errorf("failed to get card schedule: %w", err)
+	}
+
+	next := s.scheduler.Next(state, grade, now)
+
+	updated, err := qtx.UpsertCardSchedule(ctx, db_sqlc.UpsertCardScheduleParams{
+		FlashcardID:    flashcardID,
+		UserID:         userID,
+		Algorithm:      s.scheduler.Name(),
+		EaseFactor:     next.EaseFactor,
+		IntervalDays:   int32(next.IntervalDays),
+		Repetitions:    int32(next.Repetitions),
+		Lapses:         int32(next.Lapses),
+		DueAt:          pgtype.Timestamptz{Time: next.DueAt, Valid: true},
+		LastReviewedAt: pgtype.Timestamptz{Time: next.LastReviewedAt, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save card schedule: %w", err)
+	}
+
+	if _, err := qtx.CreateReviewLog(ctx, db_sqlc.CreateReviewLogParams{
+		FlashcardID:          flashcardID,
+		UserID:               userID,
+		Algorithm:            s.scheduler.Name(),
+		Grade:                int16(grade),
+		EaseFactor:           next.EaseFactor,
+		PreviousIntervalDays: int32(state.IntervalDays),
+		IntervalDays:         int32(next.IntervalDays),
+		DueAt:                pgtype.Timestamptz{Time: next.DueAt, Valid: true},
+		ReviewedAt:           pgtype.Timestamptz{Time: now, Valid: true},
+	}); err != nil {
+		return nil, fmt.Errorf("failed to save review log: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit review: %w", err)
+	}
+
+	return &updated, nil
+}
+
+// cardStateFromSchedule converts a stored schedule into scheduler state
+func cardStateFromSchedule(schedule db_sqlc.CardSchedule) CardState {
+	return CardState{
+		EaseFactor:     schedule.EaseFactor,
+		IntervalDays:   int(schedule.IntervalDays),
+		Repetitions:    int(schedule.Repetitions),
+		Lapses:         int(schedule.Lapses),
+		DueAt:          schedule.DueAt.Time,
+		LastReviewedAt: schedule.LastReviewedAt.Time,
+	}
+}
@@ -0,0 +1,26 @@
+package services
+
+import (
+	"context"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// LockNote locks the user's live note until the transaction of queries ends
+// and returns it with its latest revision number. The revision is read in a
+// statement of its own, after the lock is held, so it includes edits that
+// committed while the lock was awaited. Returns pgx.ErrNoRows when the user
+// has no such note.
+func LockNote(ctx context.Context, queries *db_sqlc.Queries, noteID, userID pgtype.UUID) (db_sqlc.LockNoteRow, int32, error) {
+	note, err := queries.LockNote(ctx, db_sqlc.LockNoteParams{ID: noteID, UserID: userID})
+	if err != nil {
+		return note, 0, err
+	}
+	revision, err := queries.GetNoteRevisionNumber(ctx, noteID)
+	if err != nil {
+		return note, 0, err
+	}
+	return note, revision, nil
+}
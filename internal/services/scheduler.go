@@ -0,0 +1,133 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Grade is the recall quality of a review on the SM-2 scale (0 = blackout, 5 = perfect recall)
+type Grade int
+
+// Named grades for Again/Hard/Good/Easy style review buttons
+const (
+	GradeAgain Grade = 1
+	GradeHard  Grade = 3
+	GradeGood  Grade = 4
+	GradeEasy  Grade = 5
+)
+
+// ParseRating converts an Again/Hard/Good/Easy rating into a Grade
+func ParseRating(rating string) (Grade, error) {
+	switch strings.ToLower(strings.TrimSpace(rating)) {
+	case "again":
+		return GradeAgain, nil
+	case "hard":
+		return GradeHard, nil
+	case "good":
+		return GradeGood, nil
+	case "easy":
+		return GradeEasy, nil
+	default:
+		return 0, fmt.Errorf("unknown rating %q, expected again, hard, good or easy", rating)
+	}
+}
+
+// Valid reports whether the grade is within the 0-5 range
+func (g Grade) Valid() bool {
+	return g >= 0 && g <= 5
+}
+
+// CardState is the scheduling state of a single flashcard
+type CardState struct {
+	EaseFactor     float64
+	IntervalDays   int
+	Repetitions    int
+	Lapses         int
+	DueAt          time.Time
+	LastReviewedAt time.Time // zero for cards that were never reviewed
+}
+
+// Scheduler computes the next review of a card from its current state and a grade.
+// Implementations must be deterministic so reviews can be replayed from the review log.
+type Scheduler interface {
+	// Name identifies the algorithm and is stored alongside each schedule and review
+	Name() string
+	// InitialState returns the state of a card that has never been reviewed
+	InitialState(now time.Time) CardState
+	// Next returns the state after reviewing the card with the given grade at now
+	Next(state CardState, grade Grade, now time.Time) CardState
+}
+
+// schedulers holds the available scheduling algorithms by name
+var schedulers = map[string]func() Scheduler{
+	"sm2": func() Scheduler { return SM2Scheduler{} },
+}
+
+// NewScheduler returns the scheduling algorithm registered under name
+func NewScheduler(name string) (Scheduler, error) {
+	factory, ok := schedulers[strings.ToLower(name)]
+	if !ok {
+		return nil, fmt.Errorf("unknown review algorithm: %s", name)
+	}
+	return factory(), nil
+}
+
+// SM2Scheduler implements the SuperMemo SM-2 algorithm
+type SM2Scheduler struct{}
+
+const (
+	sm2InitialEase = 2.5
+	sm2MinimumEase = 1.3
+)
+
+// Name returns the algorithm identifier
+func (SM2Scheduler) Name() string {
+	return "sm2"
+}
+
+// InitialState returns a new card that is due immediately
+func (SM2Scheduler) InitialState(now time.Time) CardState {
+	return CardState{
+		EaseFactor: sm2InitialEase,
+		DueAt:      now,
+	}
+}
+
+// Next applies one SM-2 review step
+func (SM2Scheduler) Next(state CardState, grade Grade, now time.Time) CardState {
+	next := state
+	if next.EaseFactor == 0 {
+		next.EaseFactor = sm2InitialEase
+	}
+
+	if grade < 3 {
+		// Failed recall: start the card over, keeping its ease factor as classic SM-2 does
+		if state.Repetitions > 0 {
+			next.Lapses++
+		}
+		next.Repetitions = 0
+		next.IntervalDays = 1
+	} else {
+		switch next.Repetitions {
+		case 0:
+			next.IntervalDays = 1
+		case 1:
+			next.IntervalDays = 6
+		default:
+			next.IntervalDays = int(math.Round(float64(next.IntervalDays) * next.EaseFactor))
+		}
+		next.Repetitions++
+
+		q := float64(5 - grade)
+		next.EaseFactor += 0.1 - q*(0.08+q*0.02)
+		if next.EaseFactor < sm2MinimumEase {
+			next.EaseFactor = sm2MinimumEase
+		}
+	}
+
+	next.LastReviewedAt = now
+	next.DueAt = now.AddDate(0, 0, next.IntervalDays)
+	return next
+}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSM2SchedulerIntervals(t *testing.T) {
+	s := SM2Scheduler{}
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	state := s.InitialState(now)
+	wantIntervals := []int{1, 6, 15}
+	for i, want := range wantIntervals {
+		state = s.Next(state, GradeGood, now)
+		if state.IntervalDays != want {
+			t.Fatalf("review %d: expected interval %d, got %d", i+1, want, state.IntervalDays)
+		}
+	}
+
+	if state.Repetitions != 3 {
+		t.Fatalf("expected 3 repetitions, got %d", state.Repetitions)
+	}
+	if !state.DueAt.Equal(now.AddDate(0, 0, 15)) {
+		t.Fatalf("expected due date %v, got %v", now.AddDate(0, 0, 15), state.DueAt)
+	}
+}
+
+func TestSM2SchedulerLapse(t *testing.T) {
+	s := SM2Scheduler{}
+	now := time.Date(2025, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	state := s.Next(s.InitialState(now), GradeEasy, now)
+	state = s.Next(state, GradeEasy, now)
+	easeBefore := state.EaseFactor
+
+	state = s.Next(state, GradeAgain, now)
+	if state.Repetitions != 0 || state.IntervalDays != 1 {
+		t.Fatalf("expected card to restart, got repetitions=%d interval=%d", state.Repetitions, state.IntervalDays)
+	}
+	if state.Lapses != 1 {
+		t.Fatalf("expected 1 lapse, got %d", state.Lapses)
+	}
+	if state.EaseFactor != easeBefore {
+		t.Fatalf("expected ease to stay at %.2f, got %.2f", easeBefore, state.EaseFactor)
+	}
+}
+
+func TestSM2SchedulerMinimumEase(t *testing.T) {
+	s := SM2Scheduler{}
+	now := time.Now()
+
+	state := s.InitialState(now)
+	for i := 0; i < 10; i++ {
+		state = s.Next(state, GradeHard, now)
+	}
+	if state.EaseFactor != sm2MinimumEase {
+		t.Fatalf("expected ease to be clamped to %.1f, got %.2f", sm2MinimumEase, state.EaseFactor)
+	}
+}
+
+func TestSM2SchedulerFailedGradesKeepEase(t *testing.T) {
+	s := SM2Scheduler{}
+	now := time.Now()
+
+	for grade := Grade(0); grade < 3; grade++ {
+		state := s.Next(s.InitialState(now), grade, now)
+		if state.EaseFactor != sm2InitialEase {
+			t.Errorf("grade %d: expected ease %.1f, got %.2f", grade, sm2InitialEase, state.EaseFactor)
+		}
+	}
+}
+
+func TestParseRating(t *testing.T) {
+	tests := map[string]Grade{
+		"again": GradeAgain,
+		"Hard":  GradeHard,
+		" good": GradeGood,
+		"EASY":  GradeEasy,
+	}
+	for rating, want := range tests {
+		got, err := ParseRating(rating)
+		if err != nil {
+			t.Fatalf("ParseRating(%q) returned error: %v", rating, err)
+		}
+		if got != want {
+			t.Fatalf("ParseRating(%q) = %d, want %d", rating, got, want)
+		}
+	}
+
+	if _, err := ParseRating("perfect"); err == nil {
+		t.Fatal("expected error for unknown rating")
+	}
+}
+
+func TestNewScheduler(t *testing.T) {
+	if _, err := NewScheduler("sm2"); err != nil {
+		t.Fatalf("expected sm2 to be registered: %v", err)
+	}
+	if _, err := NewScheduler("unknown"); err == nil {
+		t.Fatal("expected error for unknown algorithm")
+	}
+}
@@ -0,0 +1,376 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pgvector/pgvector-go"
+)
+
+// SearchMode selects which rankers a note search uses
+type SearchMode string
+
+const (
+	SearchModeSemantic SearchMode = "semantic"
+	SearchModeKeyword  SearchMode = "keyword"
+	SearchModeHybrid   SearchMode = "hybrid"
+)
+
+// rrfK dampens the weight of top ranks in reciprocal rank fusion; 60 is the value from the original paper
+const rrfK = 60
+
+// ParseSearchMode converts a request mode to a SearchMode, defaulting to hybrid
+func ParseSearchMode(mode string) (SearchMode, error) {
+	switch SearchMode(strings.ToLower(strings.TrimSpace(mode))) {
+	case "", SearchModeHybrid:
+		return SearchModeHybrid, nil
+	case SearchModeSemantic:
+		return SearchModeSemantic, nil
+	case SearchModeKeyword:
+		return SearchModeKeyword, nil
+	default:
+		return "", fmt.Errorf("unknown search mode %q, expected semantic, keyword or hybrid", mode)
+	}
+}
+
+// SearchOptions describes a note search
+type SearchOptions struct {
+	Query           string
+	Mode            SearchMode
+	Threshold       float64 // minimum cosine similarity of semantic matches
+	Limit           int
+	PassagesPerNote int
+	Filters         SearchFilters
+}
+
+// SearchFilters restricts which notes a search may return; zero values match every note
+type SearchFilters struct {
+	TagsAny       []string // note has at least one of these tags
+	TagsAll       []string // note has every one of these tags
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	UpdatedAfter  *time.Time
+	UpdatedBefore *time.Time
+	NotebookIDs   []pgtype.UUID // note is in one of these notebooks
+}
+
+// TagFacet counts the notes matching a search that carry a tag
+type TagFacet struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// RankerScore is a note's position and raw score in one ranker
+type RankerScore struct {
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+}
+
+// SearchPassage is a matching passage of a note; offsets are character positions in the note content
+type SearchPassage struct {
+	ChunkIndex  int     `json:"chunk_index"`
+	Heading     string  `json:"heading"`
+	Content     string  `json:"content"`
+	StartOffset int     `json:"start_offset"`
+	EndOffset   int     `json:"end_offset"`
+	Similarity  float64 `json:"similarity"`
+}
+
+// SearchResult is one note found by a search
+type SearchResult struct {
+	ID        pgtype.UUID
+	UserID    pgtype.UUID
+	Title     string
+	Content   string
+	Tags      []string
+	CreatedAt pgtype.Timestamptz
+	UpdatedAt pgtype.Timestamptz
+	Score     float64      // fused score in hybrid mode, otherwise the ranker's own score
+	Semantic  *RankerScore // nil when the semantic ranker did not match the note
+	Keyword   *RankerScore // nil when the keyword ranker did not match the note
+	Passages  []SearchPassage
+	Snippet   Snippet
+}
+
+// SearchResults holds the top notes of a search and the tag facets of all its matches
+type SearchResults struct {
+	Results   []SearchResult
+	TagFacets []TagFacet
+}
+
+// SearchService finds notes by meaning, keywords, or both
+type SearchService struct {
+	queries          *db_sqlc.Queries
+	db               *pgxpool.Pool
+	embeddingService *EmbeddingService
+}
+
+// NewSearchService creates a new search service
+func NewSearchService(db *pgxpool.Pool, embeddingService *EmbeddingService) *SearchService {
+	return &SearchService{
+		queries:          db_sqlc.New(db),
+		db:               db,
+		embeddingService: embeddingService,
+	}
+}
+
+// SearchText returns the segmented form of text stored for keyword search
+func SearchText(text string) pgtype.Text {
+	return pgtype.Text{String: SegmentForSearch(text), Valid: true}
+}
+
+// BackfillSearchText segments notes written before CJK-aware search existed.
+// It works in batches until no unsegmented note is left.
+func (s *SearchService) BackfillSearchText(ctx context.Context) error {
+	const batchSize = 200
+
+	total := 0
+	for {
+		notes, err := s.queries.ListUnsegmentedNotes(ctx, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list unsegmented notes: %w", err)
+		}
+
+		for _, note := range notes {
+			if err := s.queries.UpdateNoteSearchText(ctx, db_sqlc.UpdateNoteSearchTextParams{
+				ID:            note.ID,
+				SearchTitle:   SearchText(note.Title),
+				SearchContent: SearchText(note.Content),
+			}); err != nil {
+				return fmt.Errorf("failed to segment note %s: %w", note.ID.String(), err)
+			}
+		}
+		total += len(notes)
+
+		if len(notes) < batchSize {
+			if total > 0 {
+				log.Printf("Segmented %d notes for keyword search", total)
+			}
+			return nil
+		}
+	}
+}
+
+// Search runs the rankers selected by opts.Mode. In hybrid mode their lists
+// are merged with reciprocal rank fusion. Tag facets are counted separately
+// over every note the search matches, not only over the returned page.
+func (s *SearchService) Search(ctx context.Context, userID pgtype.UUID, opts SearchOptions) (*SearchResults, error) {
+	candidates := min(max(opts.Limit*3, 30), 100)
+
+	var queryEmbedding *pgvector.Vector
+	if opts.Mode != SearchModeKeyword {
+		embedding, err := s.embeddingService.GenerateQueryEmbedding(ctx, opts.Query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+		}
+		vector := pgvector.NewVector(embedding)
+		queryEmbedding = &vector
+	}
+	var tsQuery string
+	if opts.Mode != SearchModeSemantic {
+		tsQuery = BuildSearchQuery(opts.Query)
+	}
+
+	var semantic, keyword []SearchResult
+	var err error
+	if queryEmbedding != nil {
+		semantic, err = s.semanticSearch(ctx, userID, opts, *queryEmbedding, candidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if tsQuery != "" {
+		keyword, err = s.keywordSearch(ctx, userID, opts, tsQuery, candidates)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var results []SearchResult
+	switch opts.Mode {
+	case SearchModeSemantic:
+		results = semantic
+	case SearchModeKeyword:
+		results = keyword
+	default:
+		results = fuseResults(semantic, keyword)
+	}
+
+	facets, err := s.tagFacets(ctx, userID, opts, queryEmbedding, tsQuery)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) > opts.Limit {
+		results = results[:opts.Limit]
+	}
+	for i := range results {
+		results[i].Snippet = BuildSnippet(results[i].Content, opts.Query, results[i].Passages)
+	}
+
+	return &SearchResults{Results: results, TagFacets: facets}, nil
+}
+
+// semanticSearch ranks notes by their best matching chunk
+func (s *SearchService) semanticSearch(ctx context.Context, userID pgtype.UUID, opts SearchOptions, queryEmbedding pgvector.Vector, limit int) ([]SearchResult, error) {
+	rows, err := s.queries.SearchNotesBySimilarity(ctx, db_sqlc.SearchNotesBySimilarityParams{
+		QueryEmbedding:  queryEmbedding,
+		UserID:          userID,
+		Threshold:       opts.Threshold,
+		TagsAny:         opts.Filters.TagsAny,
+		TagsAll:         opts.Filters.TagsAll,
+		CreatedAfter:    filterTime(opts.Filters.CreatedAfter),
+		CreatedBefore:   filterTime(opts.Filters.CreatedBefore),
+		UpdatedAfter:    filterTime(opts.Filters.UpdatedAfter),
+		UpdatedBefore:   filterTime(opts.Filters.UpdatedBefore),
+		NotebookIds:     opts.Filters.NotebookIDs,
+		Limit:           int32(limit),
+		PassagesPerNote: int64(opts.PassagesPerNote),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search note chunks: %w", err)
+	}
+
+	// Rows come one per passage, grouped by note in rank order
+	var results []SearchResult
+	for _, row := range rows {
+		if len(results) == 0 || results[len(results)-1].ID != row.ID {
+			results = append(results, SearchResult{
+				ID:        row.ID,
+				UserID:    row.UserID,
+				Title:     row.Title,
+				Content:   row.Content,
+				Tags:      row.Tags,
+				CreatedAt: row.CreatedAt,
+				UpdatedAt: row.UpdatedAt,
+				Score:     row.Similarity,
+				Semantic:  &RankerScore{Rank: len(results) + 1, Score: row.Similarity},
+			})
+		}
+		result := &results[len(results)-1]
+		result.Passages = append(result.Passages, SearchPassage{
+			ChunkIndex:  int(row.ChunkIndex),
+			Heading:     row.Heading,
+			Content:     row.Passage,
+			StartOffset: int(row.StartOffset),
+			EndOffset:   int(row.EndOffset),
+			Similarity:  row.PassageSimilarity,
+		})
+	}
+	return results, nil
+}
+
+// keywordSearch ranks notes with Postgres full-text search over segmented text
+func (s *SearchService) keywordSearch(ctx context.Context, userID pgtype.UUID, opts SearchOptions, tsQuery string, limit int) ([]SearchResult, error) {
+	rows, err := s.queries.SearchNotesByKeyword(ctx, db_sqlc.SearchNotesByKeywordParams{
+		Query:         tsQuery,
+		UserID:        userID,
+		TagsAny:       opts.Filters.TagsAny,
+		TagsAll:       opts.Filters.TagsAll,
+		CreatedAfter:  filterTime(opts.Filters.CreatedAfter),
+		CreatedBefore: filterTime(opts.Filters.CreatedBefore),
+		UpdatedAfter:  filterTime(opts.Filters.UpdatedAfter),
+		UpdatedBefore: filterTime(opts.Filters.UpdatedBefore),
+		NotebookIds:   opts.Filters.NotebookIDs,
+		Limit:         int32(limit),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search notes by keyword: %w", err)
+	}
+
+	results := make([]SearchResult, 0, len(rows))
+	for i, row := range rows {
+		results = append(results, SearchResult{
+			ID:        row.ID,
+			UserID:    row.UserID,
+			Title:     row.Title,
+			Content:   row.Content,
+			Tags:      row.Tags,
+			CreatedAt: row.CreatedAt,
+			UpdatedAt: row.UpdatedAt,
+			Score:     row.Rank,
+			Keyword:   &RankerScore{Rank: i + 1, Score: row.Rank},
+		})
+	}
+	return results, nil
+}
+
+// fuseResults merges ranked lists with reciprocal rank fusion: every list
+// contributes 1/(rrfK + rank) for each note it contains.
+func fuseResults(lists ...[]SearchResult) []SearchResult {
+	var fused []SearchResult
+	index := make(map[pgtype.UUID]int)
+
+	for _, list := range lists {
+		for rank, result := range list {
+			i, ok := index[result.ID]
+			if !ok {
+				i = len(fused)
+				index[result.ID] = i
+				result.Score = 0
+				fused = append(fused, result)
+			}
+
+			merged := &fused[i]
+			merged.Score += 1 / float64(rrfK+rank+1)
+			if result.Semantic != nil {
+				merged.Semantic = result.Semantic
+				merged.Passages = result.Passages
+			}
+			if result.Keyword != nil {
+				merged.Keyword = result.Keyword
+			}
+		}
+	}
+
+	sort.SliceStable(fused, func(a, b int) bool {
+		return fused[a].Score > fused[b].Score
+	})
+	return fused
+}
+
+// filterTime converts an optional filter bound to a nullable timestamp
+func filterTime(t *time.Time) pgtype.Timestamptz {
+	if t == nil {
+		return pgtype.Timestamptz{}
+	}
+	return pgtype.Timestamptz{Time: *t, Valid: true}
+}
+
+// tagFacets counts the tags of all notes matching the query embedding or the
+// keyword query, most common first
+func (s *SearchService) tagFacets(ctx context.Context, userID pgtype.UUID, opts SearchOptions, queryEmbedding *pgvector.Vector, tsQuery string) ([]TagFacet, error) {
+	if queryEmbedding == nil && tsQuery == "" {
+		return []TagFacet{}, nil
+	}
+
+	rows, err := s.queries.CountSearchTags(ctx, db_sqlc.CountSearchTagsParams{
+		UserID:         userID,
+		QueryEmbedding: queryEmbedding,
+		Threshold:      opts.Threshold,
+		Query:          pgtype.Text{String: tsQuery, Valid: tsQuery != ""},
+		TagsAny:        opts.Filters.TagsAny,
+		TagsAll:        opts.Filters.TagsAll,
+		CreatedAfter:   filterTime(opts.Filters.CreatedAfter),
+		CreatedBefore:  filterTime(opts.Filters.CreatedBefore),
+		UpdatedAfter:   filterTime(opts.Filters.UpdatedAfter),
+		UpdatedBefore:  filterTime(opts.Filters.UpdatedBefore),
+		NotebookIds:    opts.Filters.NotebookIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count search tags: %w", err)
+	}
+
+	facets := make([]TagFacet, 0, len(rows))
+	for _, row := range rows {
+		facets = append(facets, TagFacet{Tag: row.Tag, Count: int(row.Count)})
+	}
+	return facets, nil
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testUUID(b byte) pgtype.UUID {
+	return pgtype.UUID{Bytes: [16]byte{b}, Valid: true}
+}
+
+func TestFuseResults(t *testing.T) {
+	a, b, c := testUUID(1), testUUID(2), testUUID(3)
+
+	semantic := []SearchResult{
+		{ID: a, Semantic: &RankerScore{Rank: 1, Score: 0.9}, Passages: []SearchPassage{{Content: "passage"}}},
+		{ID: b, Semantic: &RankerScore{Rank: 2, Score: 0.8}},
+	}
+	keyword := []SearchResult{
+		{ID: b, Keyword: &RankerScore{Rank: 1, Score: 0.4}},
+		{ID: c, Keyword: &RankerScore{Rank: 2, Score: 0.1}},
+	}
+
+	fused := fuseResults(semantic, keyword)
+	if len(fused) != 3 {
+		t.Fatalf("expected 3 fused results, got %d", len(fused))
+	}
+
+	// b is found by both rankers and must win
+	if fused[0].ID != b {
+		t.Fatalf("expected note found by both rankers first, got %v", fused[0].ID)
+	}
+	if fused[0].Semantic == nil || fused[0].Keyword == nil {
+		t.Error("expected scores from both rankers on the fused result")
+	}
+	want := 1/float64(rrfK+2) + 1/float64(rrfK+1)
+	if fused[0].Score != want {
+		t.Errorf("expected fused score %f, got %f", want, fused[0].Score)
+	}
+
+	if fused[1].ID != a || len(fused[1].Passages) != 1 {
+		t.Errorf("expected semantic-only note with its passages second, got %+v", fused[1])
+	}
+	if fused[2].ID != c || fused[2].Semantic != nil {
+		t.Errorf("expected keyword-only note last, got %+v", fused[2])
+	}
+}
+
+func TestParseSearchMode(t *testing.T) {
+	tests := map[string]SearchMode{
+		"":         SearchModeHybrid,
+		"Hybrid":   SearchModeHybrid,
+		"semantic": SearchModeSemantic,
+		"keyword":  SearchModeKeyword,
+	}
+	for input, want := range tests {
+		got, err := ParseSearchMode(input)
+		if err != nil || got != want {
+			t.Errorf("ParseSearchMode(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	if _, err := ParseSearchMode("fuzzy"); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Postgres' text search parser treats a run of Chinese characters as a single
+// word, so "機器學習" would only match the query "機器學習". Before indexing,
+// SegmentForSearch rewrites every CJK run into overlapping bigrams followed by
+// its last character ("機器 器學 學習 習"), and BuildSearchQuery segments the
+// query the same way, turning each CJK word into a phrase of bigrams.
+
+// SegmentForSearch prepares text for the 'simple' tsvector configuration.
+// Non-CJK text is left untouched; CJK runs are separated from surrounding
+// text and expanded into bigrams.
+func SegmentForSearch(text string) string {
+	var b strings.Builder
+	var run []rune
+
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		b.WriteByte(' ')
+		b.WriteString(strings.Join(cjkBigrams(run), " "))
+		b.WriteByte(' ')
+		run = run[:0]
+	}
+
+	for _, r := range text {
+		if isCJK(r) {
+			run = append(run, r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+
+	return b.String()
+}
+
+// cjkBigrams returns the overlapping bigrams of run followed by its last character
+func cjkBigrams(run []rune) []string {
+	if len(run) == 1 {
+		return []string{string(run)}
+	}
+	grams := make([]string, 0, len(run))
+	for i := 0; i+1 < len(run); i++ {
+		grams = append(grams, string(run[i:i+2]))
+	}
+	return append(grams, string(run[len(run)-1]))
+}
+
+// searchTerm is one word or quoted phrase of a search query
+type searchTerm struct {
+	text    string
+	negated bool
+	or      bool // joined to the previous term with OR instead of AND
+}
+
+// BuildSearchQuery converts a web-style query into a to_tsquery expression.
+// Words are ANDed, "quoted phrases" must appear in order, -word excludes
+// notes and "a or b" matches either side. Returns "" when nothing searchable remains.
+func BuildSearchQuery(query string) string {
+	var groups [][]string
+	for _, term := range parseSearchTerms(query) {
+		expr := termQuery(term.text)
+		if expr == "" {
+			continue
+		}
+		if term.negated {
+			expr = "!" + expr
+		}
+		if term.or && len(groups) > 0 {
+			last := len(groups) - 1
+			groups[last] = append(groups[last], expr)
+			continue
+		}
+		groups = append(groups, []string{expr})
+	}
+
+	parts := make([]string, 0, len(groups))
+	for _, group := range groups {
+		if len(group) == 1 {
+			parts = append(parts, group[0])
+			continue
+		}
+		parts = append(parts, "("+strings.Join(group, " | ")+")")
+	}
+	return strings.Join(parts, " & ")
+}
+
+// parseSearchTerms splits a query into words and quoted phrases
+func parseSearchTerms(query string) []searchTerm {
+	var terms []searchTerm
+	runes := []rune(query)
+	pendingOr := false
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		term := searchTerm{or: pendingOr}
+		pendingOr = false
+		if runes[i] == '-' {
+			term.negated = true
+			i++
+		}
+
+		start := i
+		if i < len(runes) && runes[i] == '"' {
+			i++
+			start = i
+			for i < len(runes) && runes[i] != '"' {
+				i++
+			}
+			term.text = string(runes[start:i])
+			i++ // skip the closing quote
+		} else {
+			for i < len(runes) && !unicode.IsSpace(runes[i]) {
+				i++
+			}
+			term.text = string(runes[start:i])
+			if !term.negated && strings.EqualFold(term.text, "or") && len(terms) > 0 {
+				pendingOr = true
+				continue
+			}
+		}
+
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// termQuery turns one word or phrase into a tsquery phrase of its tokens,
+// mirroring SegmentForSearch so positions line up with the indexed document.
+// A lone CJK character becomes a prefix match on the bigrams it starts, and
+// the trailing unigram of a run is dropped when it ends the phrase, since the
+// document may continue the run with more characters.
+func termQuery(text string) string {
+	var operands []string
+	var run []rune
+	var other strings.Builder
+	endsWithUnigram := false
+
+	flushRun := func() {
+		switch {
+		case len(run) == 1:
+			operands = append(operands, quoteLexeme(string(run))+":*")
+			endsWithUnigram = false
+		case len(run) > 1:
+			for _, gram := range cjkBigrams(run) {
+				operands = append(operands, quoteLexeme(gram))
+			}
+			endsWithUnigram = true
+		}
+		run = run[:0]
+	}
+	flushOther := func() {
+		for _, word := range strings.Fields(other.String()) {
+			if containsSearchable(word) {
+				operands = append(operands, quoteLexeme(word))
+				endsWithUnigram = false
+			}
+		}
+		other.Reset()
+	}
+
+	for _, r := range text {
+		if isCJK(r) {
+			flushOther()
+			run = append(run, r)
+			continue
+		}
+		flushRun()
+		other.WriteRune(r)
+	}
+	flushRun()
+	flushOther()
+
+	if endsWithUnigram {
+		operands = operands[:len(operands)-1]
+	}
+
+	switch len(operands) {
+	case 0:
+		return ""
+	case 1:
+		return operands[0]
+	default:
+		return "(" + strings.Join(operands, " <-> ") + ")"
+	}
+}
+
+// containsSearchable reports whether word has a letter, digit or CJK character
+func containsSearchable(word string) bool {
+	for _, r := range word {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteLexeme quotes a word as a tsquery operand
+func quoteLexeme(word string) string {
+	word = strings.ReplaceAll(word, `\`, `\\`)
+	word = strings.ReplaceAll(word, "'", "''")
+	return "'" + word + "'"
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSegmentForSearch(t *testing.T) {
+	tests := map[string]string{
+		"機器學習":               "機器 器學 學習 習",
+		"Go語言很好":             "Go 語言 言很 很好 好",
+		"使用 PostgreSQL 資料庫":  "使用 用 PostgreSQL 資料 料庫 庫",
+		"ERR_CONN_RESET 錯誤。": "ERR_CONN_RESET 錯誤 誤 。",
+		"plain english text": "plain english text",
+	}
+	for input, want := range tests {
+		if got := strings.Join(strings.Fields(SegmentForSearch(input)), " "); got != want {
+			t.Errorf("SegmentForSearch(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	tests := map[string]string{
+		"機器學習":            "('機器' <-> '器學' <-> '學習')",
+		"學":               "'學':*",
+		"Go語言 goroutine":  "('Go' <-> '語言') & 'goroutine'",
+		`"sad cat" -資料庫`:  "('sad' <-> 'cat') & !('資料' <-> '料庫')",
+		"向量 or vector 索引": "('向量' | 'vector') & '索引'",
+		"O'Reilly":        "'O''Reilly'",
+		"!!! ---":         "",
+		"or 開頭":           "'or' & '開頭'",
+	}
+	for input, want := range tests {
+		if got := BuildSearchQuery(input); got != want {
+			t.Errorf("BuildSearchQuery(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+// Every bigram phrase built for a CJK query must appear, in order, in the
+// segmented text of a document containing that query.
+func TestSearchQueryMatchesSegmentedDocument(t *testing.T) {
+	document := strings.Fields(SegmentForSearch("今天學習了深度學習與PyTorch的基礎"))
+	for _, query := range []string{"深度學習", "學習", "基礎", "習與PyTorch"} {
+		operands := strings.Split(strings.Trim(BuildSearchQuery(query), "()"), " <-> ")
+		for i := range operands {
+			operands[i] = strings.Trim(operands[i], "'")
+		}
+		if !containsSequence(document, operands) {
+			t.Errorf("query %q (%v) does not match document tokens %v", query, operands, document)
+		}
+	}
+}
+
+func containsSequence(tokens, sequence []string) bool {
+	for i := 0; i+len(sequence) <= len(tokens); i++ {
+		match := true
+		for j := range sequence {
+			if tokens[i+j] != sequence[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
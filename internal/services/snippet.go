@@ -0,0 +1,196 @@
+package services
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// snippetLength is the size of a search snippet in characters
+	snippetLength = 240
+	// snippetLeadIn is how much text is kept before the first match of a snippet
+	snippetLeadIn = 40
+)
+
+// Snippet is an excerpt of a note around the places a search query matched.
+// Offsets are character positions in the note content; highlight ranges are
+// character positions in Text, so clients can mark them up safely.
+type Snippet struct {
+	Text        string           `json:"text"`
+	StartOffset int              `json:"start_offset"`
+	EndOffset   int              `json:"end_offset"`
+	Highlights  []HighlightRange `json:"highlights"`
+}
+
+// HighlightRange is a [Start, End) character range of a snippet that matched the query
+type HighlightRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// BuildSnippet picks the window of content with the most query matches.
+// Notes found only by meaning fall back to their best passage, or to the
+// start of the note when there is none.
+func BuildSnippet(content, query string, passages []SearchPassage) Snippet {
+	runes := []rune(content)
+	matches := findMatches(runes, highlightTerms(query))
+
+	start := 0
+	switch {
+	case len(matches) > 0:
+		start = densestWindow(matches)
+	case len(passages) > 0:
+		start = passages[0].StartOffset
+	}
+	start = max(min(start, len(runes)-snippetLength), 0)
+	end := min(start+snippetLength, len(runes))
+	start, end = snapToWords(runes, start, end, matches)
+
+	snippet := Snippet{
+		Text:        string(runes[start:end]),
+		StartOffset: start,
+		EndOffset:   end,
+		Highlights:  []HighlightRange{},
+	}
+	for _, match := range matches {
+		if match.Start >= start && match.End <= end {
+			snippet.Highlights = append(snippet.Highlights, HighlightRange{
+				Start: match.Start - start,
+				End:   match.End - start,
+			})
+		}
+	}
+	return snippet
+}
+
+// highlightTerms returns the lowercased words and phrases of a query that should
+// be marked in snippets; excluded terms and the "or" keyword are left out
+func highlightTerms(query string) [][]rune {
+	var terms [][]rune
+	for _, term := range parseSearchTerms(query) {
+		text := strings.TrimSpace(term.text)
+		if term.negated || !containsSearchable(text) {
+			continue
+		}
+		terms = append(terms, lowerRunes([]rune(text)))
+	}
+	return terms
+}
+
+// findMatches returns the merged, sorted ranges where any term occurs in runes.
+// Words only match whole words, while CJK terms match anywhere since CJK text
+// has no spaces between words.
+func findMatches(runes []rune, terms [][]rune) []HighlightRange {
+	lower := lowerRunes(runes)
+
+	var matches []HighlightRange
+	for _, term := range terms {
+		for i := 0; i+len(term) <= len(lower); i++ {
+			if !hasRunesAt(lower, term, i) {
+				continue
+			}
+			end := i + len(term)
+			if isWordRune(term[0]) && i > 0 && isWordRune(lower[i-1]) {
+				continue
+			}
+			if isWordRune(term[len(term)-1]) && end < len(lower) && isWordRune(lower[end]) {
+				continue
+			}
+			matches = append(matches, HighlightRange{Start: i, End: end})
+		}
+	}
+
+	sort.Slice(matches, func(a, b int) bool {
+		return matches[a].Start < matches[b].Start
+	})
+
+	var merged []HighlightRange
+	for _, match := range matches {
+		if n := len(merged); n > 0 && match.Start <= merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, match.End)
+			continue
+		}
+		merged = append(merged, match)
+	}
+	return merged
+}
+
+// densestWindow returns the start of the snippet window holding the most matches
+func densestWindow(matches []HighlightRange) int {
+	best, bestCount := 0, 0
+	for i, match := range matches {
+		start := max(match.Start-snippetLeadIn, 0)
+		count := 0
+		for _, other := range matches[i:] {
+			if other.End > start+snippetLength {
+				break
+			}
+			count++
+		}
+		if count > bestCount {
+			best, bestCount = start, count
+		}
+	}
+	return best
+}
+
+// snapToWords moves the window edges off partial words, as long as no match is cut
+func snapToWords(runes []rune, start, end int, matches []HighlightRange) (int, int) {
+	firstMatch, lastMatch := end, start
+	for _, match := range matches {
+		if match.Start >= start && match.End <= end {
+			firstMatch = min(firstMatch, match.Start)
+			lastMatch = max(lastMatch, match.End)
+		}
+	}
+
+	if start > 0 && isWordRune(runes[start-1]) {
+		for i := start; i < firstMatch && i < end; i++ {
+			if !isWordRune(runes[i]) {
+				start = i
+				break
+			}
+		}
+	}
+	if end < len(runes) && isWordRune(runes[end]) {
+		for i := end; i > lastMatch && i > start; i-- {
+			if !isWordRune(runes[i-1]) {
+				end = i
+				break
+			}
+		}
+	}
+
+	for start < end && unicode.IsSpace(runes[start]) {
+		start++
+	}
+	for end > start && unicode.IsSpace(runes[end-1]) {
+		end--
+	}
+	return start, end
+}
+
+// isWordRune reports whether r belongs to a space-separated word
+func isWordRune(r rune) bool {
+	return !isCJK(r) && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// lowerRunes lowercases rune by rune so positions stay aligned with the input
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+// hasRunesAt reports whether term occurs in runes at position i
+func hasRunesAt(runes, term []rune, i int) bool {
+	for j, r := range term {
+		if runes[i+j] != r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func highlighted(s Snippet) []string {
+	runes := []rune(s.Text)
+	var words []string
+	for _, h := range s.Highlights {
+		words = append(words, string(runes[h.Start:h.End]))
+	}
+	return words
+}
+
+func TestBuildSnippetHighlightsWholeWords(t *testing.T) {
+	content := "Go is great. Google is not Go. The go tool builds Go code."
+	snippet := BuildSnippet(content, "go", nil)
+
+	if snippet.Text != content {
+		t.Fatalf("expected short note to be returned whole, got %q", snippet.Text)
+	}
+	got := highlighted(snippet)
+	if len(got) != 4 {
+		t.Fatalf("expected 4 highlights, got %v", got)
+	}
+	for _, word := range got {
+		if !strings.EqualFold(word, "go") {
+			t.Errorf("unexpected highlight %q", word)
+		}
+	}
+}
+
+func TestBuildSnippetFindsDensestWindow(t *testing.T) {
+	filler := strings.Repeat("lorem ipsum dolor sit amet ", 30)
+	content := filler + "the vector index speeds up vector search " + filler
+	snippet := BuildSnippet(content, "vector -lorem", nil)
+
+	if !strings.Contains(snippet.Text, "vector index speeds up vector search") {
+		t.Fatalf("expected snippet around the matches, got %q", snippet.Text)
+	}
+	if got := highlighted(snippet); len(got) != 2 || got[0] != "vector" {
+		t.Errorf("expected both vector matches highlighted and lorem skipped, got %v", got)
+	}
+	if len([]rune(snippet.Text)) > snippetLength {
+		t.Errorf("snippet longer than %d characters", snippetLength)
+	}
+	if string([]rune(content)[snippet.StartOffset:snippet.EndOffset]) != snippet.Text {
+		t.Error("expected offsets to locate the snippet in the content")
+	}
+	if strings.HasPrefix(snippet.Text, " ") || strings.HasSuffix(snippet.Text, " ") {
+		t.Errorf("expected snippet trimmed to words, got %q", snippet.Text)
+	}
+}
+
+func TestBuildSnippetCJKAndPhrases(t *testing.T) {
+	snippet := BuildSnippet("今天學習機器學習與深度學習", `機器學習 "深度學習"`, nil)
+	got := highlighted(snippet)
+	if len(got) != 2 || got[0] != "機器學習" || got[1] != "深度學習" {
+		t.Errorf("expected CJK terms highlighted, got %v", got)
+	}
+}
+
+func TestBuildSnippetFallsBackToPassage(t *testing.T) {
+	content := strings.Repeat("a", 300) + " semantic passage text"
+	passages := []SearchPassage{{StartOffset: 301, EndOffset: len([]rune(content))}}
+	snippet := BuildSnippet(content, "unrelated", passages)
+
+	if !strings.HasSuffix(snippet.Text, "semantic passage text") || len(snippet.Highlights) != 0 {
+		t.Errorf("expected the best passage without highlights, got %+v", snippet)
+	}
+}
@@ -0,0 +1,482 @@
+package services
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// sqlitePageSize is the page size of the databases sqliteDatabase writes
+const sqlitePageSize = 4096
+
+// B-tree page types of the SQLite file format
+const (
+	sqliteIndexInterior = 0x02
+	sqliteTableInterior = 0x05
+	sqliteIndexLeaf     = 0x0a
+	sqliteTableLeaf     = 0x0d
+)
+
+// sqliteHeaderSize is the size of the database header at the start of page 1
+const sqliteHeaderSize = 100
+
+// Payload sizes above which table and index cells spill into overflow pages,
+// and the part kept on the page when they do, as defined by the file format
+const (
+	sqliteTableMaxLocal = sqlitePageSize - 35
+	sqliteIndexMaxLocal = (sqlitePageSize-12)*64/255 - 23
+	sqliteMinLocal      = (sqlitePageSize-12)*32/255 - 23
+)
+
+// sqliteDatabase writes a SQLite database file from scratch, without a SQLite
+// library: tables and indexes are bulk loaded into B-trees from their full
+// contents. It covers what exporting needs (rowid tables and indexes on
+// integer or text columns), not updating a database.
+type sqliteDatabase struct {
+	pages  [][]byte // page n is pages[n-1]; page 1 holds the schema and is filled in last
+	schema []sqliteRow
+}
+
+// sqliteRow is a table row. Values are nil, int64, float64, string or
+// []byte; the value of an INTEGER PRIMARY KEY column is nil, as it is stored
+// as the rowid.
+type sqliteRow struct {
+	RowID  int64
+	Values []any
+}
+
+// newSQLiteDatabase creates an empty database
+func newSQLiteDatabase() *sqliteDatabase {
+	return &sqliteDatabase{pages: [][]byte{nil}}
+}
+
+// addTable adds a table created by sql with rows, which must be sorted by rowid
+func (db *sqliteDatabase) addTable(name, sql string, rows []sqliteRow) error {
+	for i := 1; i < len(rows); i++ {
+		if rows[i].RowID <= rows[i-1].RowID {
+			return fmt.Errorf("rows of %s are not sorted by unique rowid", name)
+		}
+	}
+	root, err := db.writeTableTree(rows)
+	if err != nil {
+		return fmt.Errorf("failed to write table %s: %w", name, err)
+	}
+	db.addSchema("table", name, name, root, sql)
+	return nil
+}
+
+// addIndex adds an index created by sql, with one key per row of the table:
+// the indexed values followed by the row's rowid
+func (db *sqliteDatabase) addIndex(name, table, sql string, keys [][]any) error {
+	keys = slices.Clone(keys)
+	slices.SortFunc(keys, compareSQLiteKeys)
+	root, err := db.writeIndexTree(keys)
+	if err != nil {
+		return fmt.Errorf("failed to write index %s: %w", name, err)
+	}
+	db.addSchema("index", name, table, root, sql)
+	return nil
+}
+
+// addSchema records a table or index in sqlite_master
+func (db *sqliteDatabase) addSchema(kind, name, table string, root uint32, sql string) {
+	db.schema = append(db.schema, sqliteRow{
+		RowID:  int64(len(db.schema) + 1),
+		Values: []any{kind, name, table, int64(root), sql},
+	})
+}
+
+// bytes returns the database file
+func (db *sqliteDatabase) bytes() ([]byte, error) {
+	cells := make([][]byte, len(db.schema))
+	used := sqliteHeaderSize + 8
+	for i, row := range db.schema {
+		record := sqliteRecord(row.Values)
+		if len(record) > sqliteTableMaxLocal {
+			return nil, fmt.Errorf("schema entry %d is too large", i+1)
+		}
+		cells[i] = appendSQLiteVarint(appendSQLiteVarint(nil, uint64(len(record))), uint64(row.RowID))
+		cells[i] = append(cells[i], record...)
+		used += len(cells[i]) + 2
+	}
+	if used > sqlitePageSize {
+		return nil, fmt.Errorf("schema does not fit on the first page")
+	}
+	db.pages[0] = sqliteBTreePage(sqliteTableLeaf, cells, 0, sqliteHeaderSize)
+
+	header := db.pages[0][:sqliteHeaderSize]
+	copy(header, "SQLite format 3\x00")
+	binary.BigEndian.PutUint16(header[16:], sqlitePageSize)
+	header[18], header[19] = 1, 1 // legacy (rollback journal) read and write versions
+	header[20] = 0                // no reserved space at the end of pages
+	header[21], header[22], header[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(header[24:], 1) // file change counter
+	binary.BigEndian.PutUint32(header[28:], uint32(len(db.pages)))
+	binary.BigEndian.PutUint32(header[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(header[44:], 4) // schema format
+	binary.BigEndian.PutUint32(header[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(header[92:], 1) // version-valid-for, matching the change counter
+	binary.BigEndian.PutUint32(header[96:], 3045000)
+
+	return bytes.Join(db.pages, nil), nil
+}
+
+// addPage appends a page and returns its number
+func (db *sqliteDatabase) addPage(page []byte) uint32 {
+	db.pages = append(db.pages, page)
+	return uint32(len(db.pages))
+}
+
+// writeTableTree writes the B-tree of a table and returns its root page
+func (db *sqliteDatabase) writeTableTree(rows []sqliteRow) (uint32, error) {
+	type child struct {
+		page   uint32
+		maxKey int64
+	}
+
+	var level []child
+	var cells [][]byte
+	used := 8
+	for i, row := range rows {
+		payload := sqliteRecord(row.Values)
+		cell := appendSQLiteVarint(appendSQLiteVarint(nil, uint64(len(payload))), uint64(row.RowID))
+		cell = db.appendPayload(cell, payload, sqliteTableMaxLocal)
+		if len(cells) > 0 && used+len(cell)+2 > sqlitePageSize {
+			level = append(level, child{db.addPage(sqliteBTreePage(sqliteTableLeaf, cells, 0, 0)), rows[i-1].RowID})
+			cells, used = nil, 8
+		}
+		cells = append(cells, cell)
+		used += len(cell) + 2
+	}
+	if len(cells) > 0 || len(level) == 0 {
+		var maxKey int64
+		if len(rows) > 0 {
+			maxKey = rows[len(rows)-1].RowID
+		}
+		level = append(level, child{db.addPage(sqliteBTreePage(sqliteTableLeaf, cells, 0, 0)), maxKey})
+	}
+
+	// Interior cells are a child page number and a rowid varint, at most 13
+	// bytes, so children can be spread evenly over the pages of each level
+	perPage := (sqlitePageSize-12)/(13+2) + 1
+	for len(level) > 1 {
+		pages := (len(level) + perPage - 1) / perPage
+		next := make([]child, 0, pages)
+		for i := range pages {
+			group := level[i*len(level)/pages : (i+1)*len(level)/pages]
+			cells := make([][]byte, len(group)-1)
+			for j, c := range group[:len(group)-1] {
+				cells[j] = appendSQLiteVarint(binary.BigEndian.AppendUint32(nil, c.page), uint64(c.maxKey))
+			}
+			right := group[len(group)-1]
+			next = append(next, child{db.addPage(sqliteBTreePage(sqliteTableInterior, cells, right.page, 0)), right.maxKey})
+		}
+		level = next
+	}
+	return level[0].page, nil
+}
+
+// writeIndexTree writes the B-tree of an index from its sorted keys and
+// returns its root page. Unlike in a table, the keys separating two pages
+// move up into the parent page instead of being copied.
+func (db *sqliteDatabase) writeIndexTree(keys [][]any) (uint32, error) {
+	payloads := make([][]byte, len(keys))
+	for i, key := range keys {
+		payloads[i] = sqliteRecord(key)
+		if len(payloads[i]) > sqliteIndexMaxLocal {
+			return 0, fmt.Errorf("index key %d needs an overflow page", i+1)
+		}
+	}
+
+	// Leaves first, then interior levels until a single page is left
+	type group struct {
+		cells [][]byte
+		right uint32
+	}
+	var groups []group
+	var seps [][]byte
+	var cells [][]byte
+	used := 8
+	for _, payload := range payloads {
+		cell := append(appendSQLiteVarint(nil, uint64(len(payload))), payload...)
+		if len(cells) > 0 && used+len(cell)+2 > sqlitePageSize {
+			groups = append(groups, group{cells: cells})
+			seps = append(seps, payload)
+			cells, used = nil, 8
+			continue
+		}
+		cells = append(cells, cell)
+		used += len(cell) + 2
+	}
+	if len(cells) == 0 && len(seps) > 0 {
+		// The last key became a separator with nothing after it: it gets a
+		// leaf of its own, and the last key of the leaf before separates them
+		last := seps[len(seps)-1]
+		prev := &groups[len(groups)-1]
+		seps[len(seps)-1] = cellPayload(prev.cells[len(prev.cells)-1])
+		prev.cells = prev.cells[:len(prev.cells)-1]
+		cells = [][]byte{append(appendSQLiteVarint(nil, uint64(len(last))), last...)}
+	}
+	groups = append(groups, group{cells: cells})
+
+	children := make([]uint32, len(groups))
+	for i, g := range groups {
+		children[i] = db.addPage(sqliteBTreePage(sqliteIndexLeaf, g.cells, 0, 0))
+	}
+
+	for len(children) > 1 {
+		groups = groups[:0]
+		var nextSeps [][]byte
+		current := group{right: children[0]}
+		used := 12
+		for j, sep := range seps {
+			cell := binary.BigEndian.AppendUint32(nil, current.right)
+			cell = append(appendSQLiteVarint(cell, uint64(len(sep))), sep...)
+			if len(current.cells) > 0 && used+len(cell)+2 > sqlitePageSize {
+				groups = append(groups, current)
+				nextSeps = append(nextSeps, sep)
+				current, used = group{right: children[j+1]}, 12
+				continue
+			}
+			current.cells = append(current.cells, cell)
+			current.right = children[j+1]
+			used += len(cell) + 2
+		}
+		if len(current.cells) == 0 && len(groups) > 0 {
+			// Interior pages need a cell: take the last one of the page before,
+			// whose key goes up in place of the one that went up last
+			prev := &groups[len(groups)-1]
+			last := prev.cells[len(prev.cells)-1]
+			prev.cells = prev.cells[:len(prev.cells)-1]
+			up := nextSeps[len(nextSeps)-1]
+			cell := binary.BigEndian.AppendUint32(nil, prev.right)
+			current.cells = [][]byte{append(appendSQLiteVarint(cell, uint64(len(up))), up...)}
+			prev.right = binary.BigEndian.Uint32(last)
+			nextSeps[len(nextSeps)-1] = cellPayload(last[4:])
+		}
+		groups = append(groups, current)
+
+		children = make([]uint32, len(groups))
+		for i, g := range groups {
+			children[i] = db.addPage(sqliteBTreePage(sqliteIndexInterior, g.cells, g.right, 0))
+		}
+		seps = nextSeps
+	}
+	return children[0], nil
+}
+
+// cellPayload returns the payload of a cell that starts with its size varint
+func cellPayload(cell []byte) []byte {
+	size, n := readSQLiteVarint(cell)
+	return cell[n : n+int(size)]
+}
+
+// appendPayload appends the part of payload kept in the cell, writing the
+// rest to a chain of overflow pages whose first page number ends the cell
+func (db *sqliteDatabase) appendPayload(cell, payload []byte, maxLocal int) []byte {
+	local := len(payload)
+	if local > maxLocal {
+		local = sqliteMinLocal + (len(payload)-sqliteMinLocal)%(sqlitePageSize-4)
+		if local > maxLocal {
+			local = sqliteMinLocal
+		}
+	}
+	cell = append(cell, payload[:local]...)
+	if local == len(payload) {
+		return cell
+	}
+
+	rest := payload[local:]
+	first := uint32(len(db.pages) + 1)
+	for len(rest) > 0 {
+		page := make([]byte, sqlitePageSize)
+		n := copy(page[4:], rest)
+		rest = rest[n:]
+		if len(rest) > 0 {
+			binary.BigEndian.PutUint32(page, uint32(len(db.pages)+2))
+		}
+		db.addPage(page)
+	}
+	return binary.BigEndian.AppendUint32(cell, first)
+}
+
+// sqliteBTreePage lays out a B-tree page with its cells in order. offset is
+// where the page header starts, after the database header on page 1.
+func sqliteBTreePage(kind byte, cells [][]byte, rightChild uint32, offset int) []byte {
+	page := make([]byte, sqlitePageSize)
+	page[offset] = kind
+	binary.BigEndian.PutUint16(page[offset+3:], uint16(len(cells)))
+	pointer := offset + 8
+	if kind == sqliteTableInterior || kind == sqliteIndexInterior {
+		binary.BigEndian.PutUint32(page[offset+8:], rightChild)
+		pointer = offset + 12
+	}
+
+	content := sqlitePageSize
+	for _, cell := range cells {
+		content -= len(cell)
+		copy(page[content:], cell)
+		binary.BigEndian.PutUint16(page[pointer:], uint16(content))
+		pointer += 2
+	}
+	binary.BigEndian.PutUint16(page[offset+5:], uint16(content))
+	return page
+}
+
+// sqliteRecord encodes values in the SQLite record format
+func sqliteRecord(values []any) []byte {
+	var types, body []byte
+	for _, value := range values {
+		switch v := value.(type) {
+		case nil:
+			types = appendSQLiteVarint(types, 0)
+		case int64:
+			serialType, size := sqliteIntType(v)
+			types = appendSQLiteVarint(types, serialType)
+			for i := size - 1; i >= 0; i-- {
+				body = append(body, byte(v>>(8*i)))
+			}
+		case float64:
+			types = appendSQLiteVarint(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			types = appendSQLiteVarint(types, uint64(len(v))*2+13)
+			body = append(body, v...)
+		case []byte:
+			types = appendSQLiteVarint(types, uint64(len(v))*2+12)
+			body = append(body, v...)
+		default:
+			panic(fmt.Sprintf("unsupported SQLite value %T", value))
+		}
+	}
+
+	// The header size counts its own varint
+	headerSize := len(types) + 1
+	for len(appendSQLiteVarint(nil, uint64(headerSize))) != headerSize-len(types) {
+		headerSize++
+	}
+	record := appendSQLiteVarint(make([]byte, 0, headerSize+len(body)), uint64(headerSize))
+	record = append(record, types...)
+	return append(record, body...)
+}
+
+// sqliteIntType returns the smallest serial type holding v and its size in bytes
+func sqliteIntType(v int64) (uint64, int) {
+	switch {
+	case v == 0:
+		return 8, 0
+	case v == 1:
+		return 9, 0
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return 1, 1
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2, 2
+	case v >= -1<<23 && v < 1<<23:
+		return 3, 3
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4, 4
+	case v >= -1<<47 && v < 1<<47:
+		return 5, 6
+	default:
+		return 6, 8
+	}
+}
+
+// appendSQLiteVarint appends v as a SQLite varint: big-endian groups of 7
+// bits, with a ninth byte carrying a full 8 bits for the largest values
+func appendSQLiteVarint(b []byte, v uint64) []byte {
+	if v > 1<<56-1 {
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+
+	var buf [8]byte
+	n := 0
+	for {
+		buf[n] = byte(v & 0x7f)
+		n++
+		v >>= 7
+		if v == 0 {
+			break
+		}
+	}
+	for i := n - 1; i >= 0; i-- {
+		if i > 0 {
+			b = append(b, buf[i]|0x80)
+		} else {
+			b = append(b, buf[i])
+		}
+	}
+	return b
+}
+
+// readSQLiteVarint decodes a varint, returning it and the number of bytes it used
+func readSQLiteVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 8 && i < len(b); i++ {
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	if len(b) < 9 {
+		return v, len(b)
+	}
+	return v<<8 | uint64(b[8]), 9
+}
+
+// compareSQLiteKeys orders index keys the way SQLite does with the BINARY collation
+func compareSQLiteKeys(a, b []any) int {
+	for i := range min(len(a), len(b)) {
+		if c := compareSQLiteValues(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(a), len(b))
+}
+
+// compareSQLiteValues orders NULL before numbers, numbers before text and text before blobs
+func compareSQLiteValues(a, b any) int {
+	rank := func(v any) int {
+		switch v.(type) {
+		case nil:
+			return 0
+		case int64, float64:
+			return 1
+		case string:
+			return 2
+		default:
+			return 3
+		}
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return cmp.Compare(ra, rb)
+	}
+	switch a := a.(type) {
+	case int64:
+		if b, ok := b.(int64); ok {
+			return cmp.Compare(a, b)
+		}
+		return cmp.Compare(float64(a), b.(float64))
+	case float64:
+		if b, ok := b.(int64); ok {
+			return cmp.Compare(a, float64(b))
+		}
+		return cmp.Compare(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case []byte:
+		return bytes.Compare(a, b.([]byte))
+	}
+	return 0
+}
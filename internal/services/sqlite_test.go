@@ -0,0 +1,100 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestSQLiteVarint(t *testing.T) {
+	tests := []struct {
+		value uint64
+		want  []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x81, 0x00}},
+		{16384, []byte{0x81, 0x80, 0x00}},
+		{1<<56 - 1, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f}},
+		{1 << 63, []byte{0xc0, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x00}},
+	}
+	for _, tt := range tests {
+		got := appendSQLiteVarint(nil, tt.value)
+		if !bytes.Equal(got, tt.want) {
+			t.Errorf("appendSQLiteVarint(%d) = %x, want %x", tt.value, got, tt.want)
+		}
+		if v, n := readSQLiteVarint(got); v != tt.value || n != len(got) {
+			t.Errorf("readSQLiteVarint(%x) = %d, %d, want %d, %d", got, v, n, tt.value, len(got))
+		}
+	}
+}
+
+func TestSQLiteRecord(t *testing.T) {
+	got := sqliteRecord([]any{nil, int64(0), int64(1), int64(-2), int64(300), "hi", []byte{0xab}})
+	want := []byte{
+		8,                     // header size
+		0, 8, 9, 1, 2, 17, 14, // serial types
+		0xfe, 0x01, 0x2c, 'h', 'i', 0xab,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("unexpected record %x, want %x", got, want)
+	}
+}
+
+func TestCompareSQLiteKeys(t *testing.T) {
+	ordered := [][]any{
+		{nil},
+		{int64(-5)},
+		{int64(2)},
+		{int64(2), "a"},
+		{"B"},
+		{"a"},
+		{[]byte{0}},
+	}
+	for i := 1; i < len(ordered); i++ {
+		if compareSQLiteKeys(ordered[i-1], ordered[i]) >= 0 {
+			t.Errorf("expected %v before %v", ordered[i-1], ordered[i])
+		}
+	}
+}
+
+func TestSQLiteDatabaseLayout(t *testing.T) {
+	rows := make([]sqliteRow, 500)
+	keys := make([][]any, len(rows))
+	for i := range rows {
+		name := string(rune('a'+i%26)) + "-note"
+		rows[i] = sqliteRow{RowID: int64(i + 1), Values: []any{nil, name}}
+		keys[i] = []any{name, int64(i + 1)}
+	}
+
+	db := newSQLiteDatabase()
+	if err := db.addTable("notes", "CREATE TABLE notes (id integer primary key, name text)", rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.addIndex("ix_notes_name", "notes", "CREATE INDEX ix_notes_name ON notes (name)", keys); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.addTable("notes", "CREATE TABLE unsorted (id integer primary key)", []sqliteRow{{RowID: 2}, {RowID: 1}}); err == nil {
+		t.Error("expected an error for rows out of rowid order")
+	}
+
+	data, err := db.bytes()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("SQLite format 3\x00")) {
+		t.Fatalf("missing SQLite magic")
+	}
+	if len(data)%sqlitePageSize != 0 {
+		t.Fatalf("file size %d is not a multiple of the page size", len(data))
+	}
+	if pages := binary.BigEndian.Uint32(data[28:]); int(pages) != len(data)/sqlitePageSize {
+		t.Errorf("header counts %d pages, file has %d", pages, len(data)/sqlitePageSize)
+	}
+	if data[sqliteHeaderSize] != sqliteTableLeaf {
+		t.Errorf("expected the schema on a table leaf page, got type %#x", data[sqliteHeaderSize])
+	}
+	if cells := binary.BigEndian.Uint16(data[sqliteHeaderSize+3:]); cells != 2 {
+		t.Errorf("expected 2 schema entries, got %d", cells)
+	}
+}
@@ -0,0 +1,100 @@
+package services
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagSeparator splits hierarchical tags such as lang/go into their levels
+const TagSeparator = "/"
+
+// NormalizeTag returns the canonical spelling of a tag: lowercase, without a
+// leading "#", with whitespace runs inside a level replaced by "-" and empty
+// levels of hierarchical tags dropped, so " #Lang / Go  Modules " becomes
+// "lang/go-modules". It returns "" for tags with nothing left.
+func NormalizeTag(tag string) string {
+	tag = strings.TrimLeft(strings.TrimSpace(tag), "#")
+	var levels []string
+	for _, level := range strings.Split(strings.ToLower(tag), TagSeparator) {
+		if level = strings.Join(strings.Fields(level), "-"); level != "" {
+			levels = append(levels, level)
+		}
+	}
+	return strings.Join(levels, TagSeparator)
+}
+
+// NormalizeTags normalizes every tag and drops empty and duplicate ones,
+// keeping the first occurrence. A nil slice stays nil, so updates can still
+// tell "leave tags alone" from "clear tags".
+func NormalizeTags(tags []string) []string {
+	if tags == nil {
+		return nil
+	}
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		if tag = NormalizeTag(tag); tag != "" && !seen[tag] {
+			seen[tag] = true
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+// TagCount is a tag with the number of notes carrying it
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int64  `json:"count"`
+}
+
+// TagNode is a level of the tag hierarchy. Count is the number of notes
+// tagged with exactly Tag, Total adds the counts of all descendants; a note
+// tagged both lang and lang/go is counted once for each.
+type TagNode struct {
+	Name     string     `json:"name"` // last level of Tag
+	Tag      string     `json:"tag"`
+	Count    int64      `json:"count"`
+	Total    int64      `json:"total"`
+	Children []*TagNode `json:"children,omitempty"`
+}
+
+// BuildTagTree arranges tag counts into a hierarchy by their levels. Parents
+// that are only used through their children, like lang for lang/go, get a
+// node with a zero count. Nodes are sorted by name at every level.
+func BuildTagTree(counts []TagCount) []*TagNode {
+	root := &TagNode{}
+	nodes := map[string]*TagNode{"": root}
+	var nodeFor func(tag string) *TagNode
+	nodeFor = func(tag string) *TagNode {
+		if node, ok := nodes[tag]; ok {
+			return node
+		}
+		parent, name := "", tag
+		if i := strings.LastIndex(tag, TagSeparator); i >= 0 {
+			parent, name = tag[:i], tag[i+1:]
+		}
+		node := &TagNode{Name: name, Tag: tag}
+		nodes[tag] = node
+		p := nodeFor(parent)
+		p.Children = append(p.Children, node)
+		return node
+	}
+
+	for _, count := range counts {
+		nodeFor(count.Tag).Count += count.Count
+	}
+
+	var finish func(node *TagNode) int64
+	finish = func(node *TagNode) int64 {
+		sort.Slice(node.Children, func(i, j int) bool {
+			return node.Children[i].Name < node.Children[j].Name
+		})
+		node.Total = node.Count
+		for _, child := range node.Children {
+			node.Total += finish(child)
+		}
+		return node.Total
+	}
+	finish(root)
+	return root.Children
+}
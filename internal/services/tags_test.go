@@ -0,0 +1,65 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	cases := map[string]string{
+		"Go":                     "go",
+		"  #Machine   Learning ": "machine-learning",
+		" Lang / Go  Modules ":   "lang/go-modules",
+		"lang//go/":              "lang/go",
+		"#":                      "",
+		" / ":                    "",
+		"數據庫":                    "數據庫",
+	}
+	for input, want := range cases {
+		if got := NormalizeTag(input); got != want {
+			t.Errorf("NormalizeTag(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestNormalizeTagsDeduplicates(t *testing.T) {
+	got := NormalizeTags([]string{"Go", "lang/go", " go ", "", "Lang / Go", "db"})
+	want := []string{"go", "lang/go", "db"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags = %v, want %v", got, want)
+	}
+
+	if NormalizeTags(nil) != nil {
+		t.Error("expected nil tags to stay nil")
+	}
+	if got := NormalizeTags([]string{" "}); got == nil || len(got) != 0 {
+		t.Errorf("expected an empty, non-nil slice, got %#v", got)
+	}
+}
+
+func TestBuildTagTree(t *testing.T) {
+	tree := BuildTagTree([]TagCount{
+		{Tag: "db", Count: 2},
+		{Tag: "lang/rust", Count: 1},
+		{Tag: "lang/go", Count: 3},
+		{Tag: "lang/go/generics", Count: 1},
+	})
+
+	if len(tree) != 2 || tree[0].Tag != "db" || tree[1].Tag != "lang" {
+		t.Fatalf("unexpected roots %+v", tree)
+	}
+	lang := tree[1]
+	if lang.Count != 0 || lang.Total != 5 {
+		t.Errorf("expected implicit parent with total 5, got count %d total %d", lang.Count, lang.Total)
+	}
+	if len(lang.Children) != 2 || lang.Children[0].Name != "go" || lang.Children[1].Name != "rust" {
+		t.Fatalf("expected sorted children go and rust, got %+v", lang.Children)
+	}
+	golang := lang.Children[0]
+	if golang.Tag != "lang/go" || golang.Count != 3 || golang.Total != 4 {
+		t.Errorf("unexpected lang/go node %+v", golang)
+	}
+	if len(golang.Children) != 1 || golang.Children[0].Tag != "lang/go/generics" {
+		t.Errorf("unexpected lang/go children %+v", golang.Children)
+	}
+}
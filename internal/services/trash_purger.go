@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	db_sqlc "go-note/internal/db_sqlc"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/joho/godotenv/autoload"
+)
+
+// TrashPurger permanently deletes notes that stayed in the trash longer than
+// the retention period. Chunks, revisions and embedding jobs go with them.
+type TrashPurger struct {
+	queries   *db_sqlc.Queries
+	retention time.Duration
+	interval  time.Duration
+	batchSize int
+}
+
+// NewTrashPurger creates a purger that keeps trashed notes for
+// TRASH_RETENTION_DAYS (default 30) days
+func NewTrashPurger(db *pgxpool.Pool) (*TrashPurger, error) {
+	retentionDays, err := positiveIntEnv("TRASH_RETENTION_DAYS", 30)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TrashPurger{
+		queries:   db_sqlc.New(db),
+		retention: time.Duration(retentionDays) * 24 * time.Hour,
+		interval:  time.Hour,
+		batchSize: 500,
+	}, nil
+}
+
+// Run purges expired notes right away and then every interval until ctx is cancelled
+func (p *TrashPurger) Run(ctx context.Context) {
+	for {
+		if _, err := p.Purge(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("Failed to purge trashed notes: %v", err)
+		}
+
+		select {
+		case <-time.After(p.interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Purge deletes, in batches, every note trashed before now minus the retention period
+func (p *TrashPurger) Purge(ctx context.Context, now time.Time) (int64, error) {
+	deletedBefore := pgtype.Timestamptz{Time: now.Add(-p.retention), Valid: true}
+
+	var total int64
+	for {
+		rows, err := p.queries.PurgeExpiredNotes(ctx, db_sqlc.PurgeExpiredNotesParams{
+			DeletedBefore: deletedBefore,
+			BatchSize:     int32(p.batchSize),
+		})
+		if err != nil {
+			return total, err
+		}
+		total += rows
+
+		if rows < int64(p.batchSize) {
+			if total > 0 {
+				log.Printf("Purged %d notes from the trash", total)
+			}
+			return total, nil
+		}
+	}
+}
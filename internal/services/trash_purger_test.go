@@ -0,0 +1,28 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewTrashPurgerRetention(t *testing.T) {
+	t.Setenv("TRASH_RETENTION_DAYS", "")
+	purger, err := NewTrashPurger(nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if purger.retention != 30*24*time.Hour {
+		t.Errorf("expected 30 day default retention, got %v", purger.retention)
+	}
+
+	t.Setenv("TRASH_RETENTION_DAYS", "7")
+	purger, err = NewTrashPurger(nil)
+	if err != nil || purger.retention != 7*24*time.Hour {
+		t.Errorf("expected 7 day retention, got %v, %v", purger, err)
+	}
+
+	t.Setenv("TRASH_RETENTION_DAYS", "0")
+	if _, err := NewTrashPurger(nil); err == nil {
+		t.Error("expected an error for a zero retention period")
+	}
+}
@@ -0,0 +1,71 @@
+-- Persist generated flashcards as decks and cards
+
+-- Create decks table
+CREATE TABLE decks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create flashcards table
+CREATE TABLE flashcards (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    deck_id UUID NOT NULL REFERENCES decks(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    question TEXT NOT NULL,
+    answer TEXT NOT NULL,
+    explanation TEXT NOT NULL DEFAULT '',
+    difficulty VARCHAR(20) NOT NULL DEFAULT 'Medium',
+    tags TEXT[] DEFAULT '{}',
+    source_note_ids UUID[] DEFAULT '{}', -- notes the card was generated from
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_decks_user_id ON decks(user_id);
+CREATE INDEX idx_flashcards_deck_id ON flashcards(deck_id);
+CREATE INDEX idx_flashcards_user_id ON flashcards(user_id);
+CREATE INDEX idx_flashcards_source_note_ids ON flashcards USING GIN(source_note_ids);
+
+-- Enable Row Level Security
+ALTER TABLE decks ENABLE ROW LEVEL SECURITY;
+ALTER TABLE flashcards ENABLE ROW LEVEL SECURITY;
+
+-- RLS Policies for decks
+CREATE POLICY "Users can view own decks" ON decks
+    FOR SELECT USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can insert own decks" ON decks
+    FOR INSERT WITH CHECK (auth.uid() = user_id);
+
+CREATE POLICY "Users can update own decks" ON decks
+    FOR UPDATE USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can delete own decks" ON decks
+    FOR DELETE USING (auth.uid() = user_id);
+
+-- RLS Policies for flashcards
+CREATE POLICY "Users can view own flashcards" ON flashcards
+    FOR SELECT USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can insert own flashcards" ON flashcards
+    FOR INSERT WITH CHECK (auth.uid() = user_id);
+
+CREATE POLICY "Users can update own flashcards" ON flashcards
+    FOR UPDATE USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can delete own flashcards" ON flashcards
+    FOR DELETE USING (auth.uid() = user_id);
+
+-- Create triggers for updated_at
+CREATE TRIGGER update_decks_updated_at
+    BEFORE UPDATE ON decks
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_flashcards_updated_at
+    BEFORE UPDATE ON flashcards
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,60 @@
+-- Spaced-repetition review scheduling for saved flashcards
+
+-- Current scheduling state of each reviewed card
+CREATE TABLE card_schedules (
+    flashcard_id UUID PRIMARY KEY REFERENCES flashcards(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    algorithm VARCHAR(20) NOT NULL DEFAULT 'sm2',
+    ease_factor DOUBLE PRECISION NOT NULL DEFAULT 2.5,
+    interval_days INTEGER NOT NULL DEFAULT 0,
+    repetitions INTEGER NOT NULL DEFAULT 0,
+    lapses INTEGER NOT NULL DEFAULT 0,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Append-only history of every review
+CREATE TABLE review_logs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    flashcard_id UUID NOT NULL REFERENCES flashcards(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    algorithm VARCHAR(20) NOT NULL,
+    grade SMALLINT NOT NULL CHECK (grade BETWEEN 0 AND 5),
+    ease_factor DOUBLE PRECISION NOT NULL,
+    previous_interval_days INTEGER NOT NULL,
+    interval_days INTEGER NOT NULL,
+    due_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    reviewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_card_schedules_user_due ON card_schedules(user_id, due_at);
+CREATE INDEX idx_review_logs_flashcard_id ON review_logs(flashcard_id, reviewed_at DESC);
+
+-- Enable Row Level Security
+ALTER TABLE card_schedules ENABLE ROW LEVEL SECURITY;
+ALTER TABLE review_logs ENABLE ROW LEVEL SECURITY;
+
+-- RLS Policies for card_schedules
+CREATE POLICY "Users can view own card schedules" ON card_schedules
+    FOR SELECT USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can insert own card schedules" ON card_schedules
+    FOR INSERT WITH CHECK (auth.uid() = user_id);
+
+CREATE POLICY "Users can update own card schedules" ON card_schedules
+    FOR UPDATE USING (auth.uid() = user_id);
+
+-- RLS Policies for review_logs
+CREATE POLICY "Users can view own review logs" ON review_logs
+    FOR SELECT USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can insert own review logs" ON review_logs
+    FOR INSERT WITH CHECK (auth.uid() = user_id);
+
+-- Create triggers for updated_at
+CREATE TRIGGER update_card_schedules_updated_at
+    BEFORE UPDATE ON card_schedules
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
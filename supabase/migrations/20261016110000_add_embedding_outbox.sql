@@ -0,0 +1,43 @@
+-- Asynchronous note embeddings: notes are saved first and a background worker fills in the vector
+
+-- Track whether a note's embedding is up to date
+ALTER TABLE notes ADD COLUMN embedding_status VARCHAR(20) NOT NULL DEFAULT 'pending'
+    CHECK (embedding_status IN ('pending', 'ready', 'failed'));
+
+UPDATE notes SET embedding_status = 'ready' WHERE embedding IS NOT NULL;
+
+-- Outbox of notes waiting for an embedding, one row per note.
+-- generation is bumped on every re-enqueue so a worker holding a stale job
+-- cannot overwrite the embedding of newer content.
+CREATE TABLE embedding_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL UNIQUE REFERENCES notes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'dead')),
+    generation INTEGER NOT NULL DEFAULT 1,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    locked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_embedding_jobs_status_run_at ON embedding_jobs(status, run_at);
+
+-- Enable Row Level Security
+ALTER TABLE embedding_jobs ENABLE ROW LEVEL SECURITY;
+
+-- RLS Policies for embedding_jobs
+CREATE POLICY "Users can view own embedding jobs" ON embedding_jobs
+    FOR SELECT USING (auth.uid() = user_id);
+
+-- Create triggers for updated_at
+CREATE TRIGGER update_embedding_jobs_updated_at
+    BEFORE UPDATE ON embedding_jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+-- Queue existing notes that have no embedding yet
+INSERT INTO embedding_jobs (note_id, user_id)
+SELECT id, user_id FROM notes WHERE embedding IS NULL;
@@ -0,0 +1,41 @@
+-- Passage-level embeddings so long notes are searchable section by section
+
+CREATE TABLE note_chunks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    chunk_index INTEGER NOT NULL,
+    heading TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    start_offset INTEGER NOT NULL, -- character offsets into notes.content
+    end_offset INTEGER NOT NULL,
+    embedding VECTOR(768) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (note_id, chunk_index)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_note_chunks_user_id ON note_chunks(user_id);
+CREATE INDEX idx_note_chunks_embedding ON note_chunks USING hnsw (embedding vector_cosine_ops);
+
+-- Enable Row Level Security
+ALTER TABLE note_chunks ENABLE ROW LEVEL SECURITY;
+
+-- RLS Policies for note_chunks
+CREATE POLICY "Users can view own note chunks" ON note_chunks
+    FOR SELECT USING (auth.uid() = user_id);
+
+-- Re-embed every existing note so its chunks get created
+UPDATE notes SET embedding_status = 'pending';
+
+INSERT INTO embedding_jobs (note_id, user_id)
+SELECT id, user_id FROM notes
+ON CONFLICT (note_id) DO UPDATE
+SET
+    status = 'pending',
+    generation = embedding_jobs.generation + 1,
+    attempts = 0,
+    last_error = NULL,
+    run_at = NOW(),
+    locked_at = NULL,
+    updated_at = NOW();
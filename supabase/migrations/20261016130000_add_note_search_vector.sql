@@ -0,0 +1,10 @@
+-- Full-text index on notes for keyword and hybrid search.
+-- The 'simple' configuration keeps tokens unstemmed, so error codes, names
+-- and acronyms match exactly regardless of the note's language.
+ALTER TABLE notes ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(title, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(content, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_notes_search_vector ON notes USING GIN (search_vector);
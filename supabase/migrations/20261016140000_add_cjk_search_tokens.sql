@@ -0,0 +1,19 @@
+-- CJK-aware full-text search.
+-- The default parser treats a run of Chinese characters as one word, so the
+-- application stores a segmented copy of title and content (CJK runs expanded
+-- into bigrams) and search_vector is built from it. Rows not yet segmented
+-- fall back to the raw text until the startup backfill reaches them.
+ALTER TABLE notes ADD COLUMN search_title TEXT;
+ALTER TABLE notes ADD COLUMN search_content TEXT;
+
+DROP INDEX idx_notes_search_vector;
+ALTER TABLE notes DROP COLUMN search_vector;
+
+ALTER TABLE notes ADD COLUMN search_vector TSVECTOR
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(search_title, title, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(search_content, content, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_notes_search_vector ON notes USING GIN (search_vector);
+CREATE INDEX idx_notes_unsegmented ON notes(id) WHERE search_content IS NULL;
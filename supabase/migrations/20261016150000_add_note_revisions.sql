@@ -0,0 +1,29 @@
+-- Every create, update and restore of a note stores a snapshot, so edits can be reviewed and undone
+
+CREATE TABLE note_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    revision_number INTEGER NOT NULL,
+    title VARCHAR(500) NOT NULL,
+    content TEXT NOT NULL,
+    tags TEXT[] NOT NULL DEFAULT '{}',
+    restored_from INTEGER, -- revision_number this revision was restored from
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE (note_id, revision_number)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_note_revisions_user_id ON note_revisions(user_id);
+
+-- Enable Row Level Security
+ALTER TABLE note_revisions ENABLE ROW LEVEL SECURITY;
+
+-- RLS Policies for note_revisions
+CREATE POLICY "Users can view own note revisions" ON note_revisions
+    FOR SELECT USING (auth.uid() = user_id);
+
+-- Existing notes start their history at their current state
+INSERT INTO note_revisions (note_id, user_id, revision_number, title, content, tags, created_at)
+SELECT id, user_id, 1, title, content, COALESCE(tags, '{}'), updated_at
+FROM notes;
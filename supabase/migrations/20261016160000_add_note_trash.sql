@@ -0,0 +1,6 @@
+-- Deleting a note moves it to the trash; the purge job removes it after the retention period
+
+ALTER TABLE notes ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes for better performance
+CREATE INDEX idx_notes_deleted_at ON notes(user_id, deleted_at DESC) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,40 @@
+-- Nested notebooks for organizing notes
+
+CREATE TABLE notebooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES notebooks(id) ON DELETE CASCADE, -- NULL for top-level notebooks
+    name VARCHAR(255) NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0, -- order among siblings
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Notes outside any notebook, and notes whose notebook was deleted, have no notebook
+ALTER TABLE notes ADD COLUMN notebook_id UUID REFERENCES notebooks(id) ON DELETE SET NULL;
+
+-- Create indexes for better performance
+CREATE INDEX idx_notebooks_user_id ON notebooks(user_id);
+CREATE INDEX idx_notebooks_parent_id ON notebooks(parent_id);
+CREATE INDEX idx_notes_notebook_id ON notes(notebook_id);
+
+-- Enable Row Level Security
+ALTER TABLE notebooks ENABLE ROW LEVEL SECURITY;
+
+-- RLS Policies for notebooks
+CREATE POLICY "Users can view own notebooks" ON notebooks
+    FOR SELECT USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can insert own notebooks" ON notebooks
+    FOR INSERT WITH CHECK (auth.uid() = user_id);
+
+CREATE POLICY "Users can update own notebooks" ON notebooks
+    FOR UPDATE USING (auth.uid() = user_id);
+
+CREATE POLICY "Users can delete own notebooks" ON notebooks
+    FOR DELETE USING (auth.uid() = user_id);
+
+-- Create triggers for updated_at
+CREATE TRIGGER update_notebooks_updated_at
+    BEFORE UPDATE ON notebooks
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,32 @@
+-- Links between notes, parsed from [[wiki links]] and markdown links in note content.
+-- A link whose target has no matching note yet is stored unresolved (target_note_id
+-- NULL) and is resolved when a note with that title is saved.
+
+CREATE TABLE note_links (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    source_note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    target_note_id UUID REFERENCES notes(id) ON DELETE SET NULL,
+    target_title TEXT NOT NULL DEFAULT '', -- empty for links that name the target by ID
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('wiki', 'markdown')),
+    alias TEXT NOT NULL DEFAULT '', -- display text, when it differs from the target
+    position INTEGER NOT NULL, -- character offset of the link in the source content
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Notes saved before links were tracked are indexed by the startup backfill
+ALTER TABLE notes ADD COLUMN links_indexed_at TIMESTAMP WITH TIME ZONE;
+
+-- Create indexes for better performance
+CREATE INDEX idx_note_links_source_note_id ON note_links(source_note_id);
+CREATE INDEX idx_note_links_target_note_id ON note_links(target_note_id);
+CREATE INDEX idx_note_links_unresolved ON note_links(user_id, lower(target_title)) WHERE target_note_id IS NULL;
+CREATE INDEX idx_notes_user_lower_title ON notes(user_id, lower(title));
+CREATE INDEX idx_notes_links_unindexed ON notes(id) WHERE links_indexed_at IS NULL;
+
+-- Enable Row Level Security
+ALTER TABLE note_links ENABLE ROW LEVEL SECURITY;
+
+-- Links are maintained by the application when notes are saved
+CREATE POLICY "Users can view own note links" ON note_links
+    FOR SELECT USING (auth.uid() = user_id);
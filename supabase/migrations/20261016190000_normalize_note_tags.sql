@@ -0,0 +1,43 @@
+-- Tags are now normalized by the application when notes are saved: lowercased,
+-- trimmed, runs of whitespace replaced by "-", a leading "#" dropped and empty
+-- segments of hierarchical tags ("lang/go") removed. Bring the tags of existing
+-- notes and their revisions in line so listing, filtering, renaming and
+-- restoring see one spelling per tag.
+
+CREATE FUNCTION normalize_tag(tag TEXT) RETURNS TEXT AS $$
+    SELECT string_agg(segment, '/' ORDER BY position)
+    FROM (
+        SELECT
+            regexp_replace(regexp_replace(part, '^\s+|\s+$', '', 'g'), '\s+', '-', 'g') AS segment,
+            position
+        FROM unnest(string_to_array(lower(ltrim(btrim(tag), '#')), '/')) WITH ORDINALITY AS parts(part, position)
+    ) segments
+    WHERE segment <> ''
+$$ LANGUAGE sql IMMUTABLE;
+
+-- Normalizes every tag of a list, dropping empty and duplicate tags and
+-- keeping the position of each tag's first spelling
+CREATE FUNCTION normalize_tags(tags TEXT[]) RETURNS TEXT[] AS $$
+    SELECT ARRAY(
+        SELECT tag
+        FROM (
+            SELECT normalize_tag(t) AS tag, MIN(ord) AS first_position
+            FROM unnest(tags) WITH ORDINALITY AS u(t, ord)
+            GROUP BY normalize_tag(t)
+        ) deduplicated
+        WHERE tag IS NOT NULL
+        ORDER BY first_position
+    )
+$$ LANGUAGE sql IMMUTABLE;
+
+UPDATE notes
+SET tags = normalize_tags(tags)
+WHERE cardinality(tags) > 0 AND tags IS DISTINCT FROM normalize_tags(tags);
+
+-- Revisions too, so diffs and restores see the same spelling as the notes
+UPDATE note_revisions
+SET tags = normalize_tags(tags)
+WHERE cardinality(tags) > 0 AND tags IS DISTINCT FROM normalize_tags(tags);
+
+DROP FUNCTION normalize_tags(TEXT[]);
+DROP FUNCTION normalize_tag(TEXT);
@@ -0,0 +1,39 @@
+-- Imports run in the background: the upload is stored with its job and a
+-- worker parses and saves it, reporting progress as it goes
+CREATE TABLE import_jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES auth.users(id) ON DELETE CASCADE,
+    format VARCHAR(20) NOT NULL CHECK (format IN ('markdown', 'enex', 'notion')),
+    filename TEXT NOT NULL,
+    dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+    notebook_id UUID REFERENCES notebooks(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'processing', 'completed', 'failed')),
+    payload BYTEA, -- the uploaded export, dropped once the job finishes
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    report JSONB,
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    locked_at TIMESTAMP WITH TIME ZONE,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_import_jobs_user_id_created_at ON import_jobs(user_id, created_at DESC);
+CREATE INDEX idx_import_jobs_status_created_at ON import_jobs(status, created_at)
+    WHERE status IN ('pending', 'processing');
+
+-- Enable Row Level Security
+ALTER TABLE import_jobs ENABLE ROW LEVEL SECURITY;
+
+-- RLS Policies for import_jobs
+CREATE POLICY "Users can view own import jobs" ON import_jobs
+    FOR SELECT USING (auth.uid() = user_id);
+
+-- Create triggers for updated_at
+CREATE TRIGGER update_import_jobs_updated_at
+    BEFORE UPDATE ON import_jobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,26 @@
+-- updated_at of a note is when the user last changed it. Bookkeeping writes
+-- (embeddings, search text, link indexing) no longer move it, and a query
+-- that sets updated_at itself, such as an import keeping the source's
+-- timestamp, keeps the value it wrote.
+
+CREATE OR REPLACE FUNCTION update_note_updated_at_column()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.updated_at IS NOT DISTINCT FROM OLD.updated_at AND (
+        NEW.title IS DISTINCT FROM OLD.title
+        OR NEW.content IS DISTINCT FROM OLD.content
+        OR NEW.tags IS DISTINCT FROM OLD.tags
+        OR NEW.notebook_id IS DISTINCT FROM OLD.notebook_id
+        OR NEW.deleted_at IS DISTINCT FROM OLD.deleted_at
+    ) THEN
+        NEW.updated_at = NOW();
+    END IF;
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+DROP TRIGGER update_notes_updated_at ON notes;
+
+CREATE TRIGGER update_notes_updated_at
+    BEFORE UPDATE ON notes
+    FOR EACH ROW EXECUTE FUNCTION update_note_updated_at_column();
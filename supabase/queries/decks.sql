@@ -0,0 +1,38 @@
+-- name: CreateDeck :one
+INSERT INTO decks (user_id, name, description)
+VALUES ($1, $2, $3)
+RETURNING id, user_id, name, description, created_at, updated_at;
+
+-- name: GetDeck :one
+SELECT id, user_id, name, description, created_at, updated_at
+FROM decks
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListUserDecks :many
+SELECT
+    d.id,
+    d.user_id,
+    d.name,
+    d.description,
+    d.created_at,
+    d.updated_at,
+    COUNT(f.id) AS card_count
+FROM decks d
+LEFT JOIN flashcards f ON f.deck_id = d.id
+WHERE d.user_id = $1
+GROUP BY d.id
+ORDER BY d.created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: UpdateDeck :one
+UPDATE decks
+SET
+    name = COALESCE(sqlc.narg('name'), name),
+    description = COALESCE(sqlc.narg('description'), description),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+RETURNING id, user_id, name, description, created_at, updated_at;
+
+-- name: DeleteDeck :execrows
+DELETE FROM decks
+WHERE id = $1 AND user_id = $2;
@@ -0,0 +1,62 @@
+-- name: EnqueueEmbeddingJob :exec
+-- Re-enqueueing a note resets its job and bumps the generation
+INSERT INTO embedding_jobs (note_id, user_id)
+VALUES ($1, $2)
+ON CONFLICT (note_id) DO UPDATE
+SET
+    status = 'pending',
+    generation = embedding_jobs.generation + 1,
+    attempts = 0,
+    last_error = NULL,
+    run_at = NOW(),
+    locked_at = NULL,
+    updated_at = NOW();
+
+-- name: EnqueueEmbeddingJobs :exec
+-- Enqueues many notes in one statement, see EnqueueEmbeddingJob
+INSERT INTO embedding_jobs (note_id, user_id)
+SELECT note_id, sqlc.arg('user_id')
+FROM unnest(sqlc.arg('note_ids')::uuid[]) AS note_id
+ON CONFLICT (note_id) DO UPDATE
+SET
+    status = 'pending',
+    generation = embedding_jobs.generation + 1,
+    attempts = 0,
+    last_error = NULL,
+    run_at = NOW(),
+    locked_at = NULL,
+    updated_at = NOW();
+
+-- name: ClaimEmbeddingJobs :many
+-- Jobs stuck in processing since before stale_before belong to a crashed worker and are reclaimed
+UPDATE embedding_jobs
+SET
+    status = 'processing',
+    attempts = attempts + 1,
+    locked_at = NOW(),
+    updated_at = NOW()
+WHERE id IN (
+    SELECT j.id
+    FROM embedding_jobs j
+    WHERE
+        (j.status = 'pending' AND j.run_at <= NOW())
+        OR (j.status = 'processing' AND j.locked_at < sqlc.arg('stale_before')::timestamptz)
+    ORDER BY j.run_at
+    LIMIT sqlc.arg('batch_size')
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, note_id, user_id, generation, attempts;
+
+-- name: CompleteEmbeddingJob :execrows
+DELETE FROM embedding_jobs
+WHERE id = $1 AND generation = $2;
+
+-- name: RetryEmbeddingJob :exec
+UPDATE embedding_jobs
+SET status = 'pending', run_at = $3, last_error = $4, locked_at = NULL, updated_at = NOW()
+WHERE id = $1 AND generation = $2;
+
+-- name: DeadLetterEmbeddingJob :execrows
+UPDATE embedding_jobs
+SET status = 'dead', last_error = $3, locked_at = NULL, updated_at = NOW()
+WHERE id = $1 AND generation = $2;
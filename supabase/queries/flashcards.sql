@@ -0,0 +1,41 @@
+-- name: CreateFlashcard :one
+INSERT INTO flashcards (deck_id, user_id, question, answer, explanation, difficulty, tags, source_note_ids)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, deck_id, user_id, question, answer, explanation, difficulty, tags, source_note_ids, created_at, updated_at;
+
+-- name: GetFlashcard :one
+SELECT id, deck_id, user_id, question, answer, explanation, difficulty, tags, source_note_ids, created_at, updated_at
+FROM flashcards
+WHERE id = $1 AND user_id = $2;
+
+-- name: LockFlashcard :one
+-- Locks the user's flashcard until the transaction ends, so concurrent reviews
+-- of it apply one after another even before it has a schedule
+SELECT id, deck_id, user_id, question, answer, explanation, difficulty, tags, source_note_ids, created_at, updated_at
+FROM flashcards
+WHERE id = $1 AND user_id = $2
+FOR UPDATE;
+
+-- name: ListDeckFlashcards :many
+SELECT id, deck_id, user_id, question, answer, explanation, difficulty, tags, source_note_ids, created_at, updated_at
+FROM flashcards
+WHERE deck_id = $1 AND user_id = $2
+ORDER BY created_at ASC
+LIMIT $3 OFFSET $4;
+
+-- name: UpdateFlashcard :one
+UPDATE flashcards
+SET
+    deck_id = COALESCE(sqlc.narg('deck_id'), deck_id),
+    question = COALESCE(sqlc.narg('question'), question),
+    answer = COALESCE(sqlc.narg('answer'), answer),
+    explanation = COALESCE(sqlc.narg('explanation'), explanation),
+    difficulty = COALESCE(sqlc.narg('difficulty'), difficulty),
+    tags = COALESCE(sqlc.narg('tags'), tags),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+RETURNING id, deck_id, user_id, question, answer, explanation, difficulty, tags, source_note_ids, created_at, updated_at;
+
+-- name: DeleteFlashcard :execrows
+DELETE FROM flashcards
+WHERE id = $1 AND user_id = $2;
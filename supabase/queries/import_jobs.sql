@@ -0,0 +1,53 @@
+-- name: CreateImportJob :one
+INSERT INTO import_jobs (user_id, format, filename, dry_run, notebook_id, payload)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, user_id, format, filename, dry_run, notebook_id, status, total, processed, report, error, started_at, finished_at, created_at, updated_at;
+
+-- name: GetImportJob :one
+SELECT id, user_id, format, filename, dry_run, notebook_id, status, total, processed, report, error, started_at, finished_at, created_at, updated_at
+FROM import_jobs
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListImportJobs :many
+SELECT id, user_id, format, filename, dry_run, notebook_id, status, total, processed, report, error, started_at, finished_at, created_at, updated_at
+FROM import_jobs
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: ClaimImportJob :one
+-- Claims the oldest waiting job. Jobs whose worker stopped reporting progress
+-- before stale_before belong to a crashed worker and are reclaimed.
+UPDATE import_jobs
+SET
+    status = 'processing',
+    attempts = attempts + 1,
+    locked_at = NOW(),
+    started_at = COALESCE(started_at, NOW())
+WHERE id = (
+    SELECT j.id
+    FROM import_jobs j
+    WHERE
+        j.status = 'pending'
+        OR (j.status = 'processing' AND j.locked_at < sqlc.arg('stale_before')::timestamptz)
+    ORDER BY j.created_at
+    LIMIT 1
+    FOR UPDATE SKIP LOCKED
+)
+RETURNING id, user_id, format, filename, dry_run, notebook_id, payload, attempts;
+
+-- name: UpdateImportJobProgress :exec
+-- Also renews the job's lock, so a long import is not taken for a crashed one
+UPDATE import_jobs
+SET total = $2, processed = $3, locked_at = NOW()
+WHERE id = $1 AND status = 'processing';
+
+-- name: CompleteImportJob :exec
+UPDATE import_jobs
+SET status = 'completed', report = $2, processed = total, payload = NULL, locked_at = NULL, finished_at = NOW()
+WHERE id = $1;
+
+-- name: FailImportJob :exec
+UPDATE import_jobs
+SET status = 'failed', error = $2, payload = NULL, locked_at = NULL, finished_at = NOW()
+WHERE id = $1;
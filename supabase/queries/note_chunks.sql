@@ -0,0 +1,7 @@
+-- name: CreateNoteChunk :exec
+INSERT INTO note_chunks (note_id, user_id, chunk_index, heading, content, start_offset, end_offset, embedding)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: DeleteNoteChunks :exec
+DELETE FROM note_chunks
+WHERE note_id = $1;
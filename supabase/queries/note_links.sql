@@ -0,0 +1,108 @@
+-- name: DeleteNoteLinks :exec
+DELETE FROM note_links
+WHERE source_note_id = $1;
+
+-- name: CreateNoteLink :exec
+-- Resolves the target by ID when the link names one, otherwise by case-insensitive title
+INSERT INTO note_links (source_note_id, user_id, target_note_id, target_title, kind, alias, position)
+VALUES (
+    sqlc.arg('source_note_id'),
+    sqlc.arg('user_id'),
+    (
+        SELECT n.id
+        FROM notes n
+        WHERE n.user_id = sqlc.arg('user_id')
+            AND n.deleted_at IS NULL
+            AND CASE
+                WHEN sqlc.narg('target_note_id')::uuid IS NOT NULL THEN n.id = sqlc.narg('target_note_id')::uuid
+                ELSE lower(n.title) = lower(sqlc.arg('target_title'))
+            END
+        ORDER BY n.updated_at DESC
+        LIMIT 1
+    ),
+    sqlc.arg('target_title'),
+    sqlc.arg('kind'),
+    sqlc.arg('alias'),
+    sqlc.arg('position')
+);
+
+-- name: ResolveNoteLinks :execrows
+-- Points unresolved links whose title matches at the given note
+UPDATE note_links
+SET target_note_id = sqlc.arg('target_note_id')
+WHERE user_id = sqlc.arg('user_id')
+    AND target_note_id IS NULL
+    AND target_title <> ''
+    AND lower(target_title) = lower(sqlc.arg('title'));
+
+-- name: MarkNoteLinksIndexed :exec
+UPDATE notes
+SET links_indexed_at = NOW()
+WHERE id = $1;
+
+-- name: ListUnindexedLinkNotes :many
+SELECT id, user_id, title, content
+FROM notes
+WHERE links_indexed_at IS NULL
+LIMIT $1;
+
+-- name: ListOutgoingLinks :many
+-- Links to trashed notes are reported without a target, like unresolved links
+SELECT
+    l.id,
+    t.id AS target_note_id,
+    COALESCE(t.title, l.target_title)::text AS target_title,
+    l.kind,
+    l.alias,
+    l.position
+FROM note_links l
+LEFT JOIN notes t ON t.id = l.target_note_id AND t.deleted_at IS NULL
+WHERE l.source_note_id = $1 AND l.user_id = $2
+ORDER BY l.position;
+
+-- name: ListBacklinks :many
+-- Returns the notes linking to a note, with the text around each link
+SELECT
+    l.id,
+    l.source_note_id,
+    s.title AS source_title,
+    l.kind,
+    l.alias,
+    l.position,
+    substring(s.content FROM GREATEST(l.position - 80, 0) + 1 FOR 200)::text AS context,
+    s.updated_at AS source_updated_at
+FROM note_links l
+JOIN notes s ON s.id = l.source_note_id
+WHERE l.target_note_id = sqlc.arg('target_note_id')
+    AND l.user_id = sqlc.arg('user_id')
+    AND s.deleted_at IS NULL
+ORDER BY s.updated_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListUnresolvedLinks :many
+-- Groups links with no live target by title, most referenced first
+SELECT
+    MIN(l.target_title)::text AS target_title,
+    COUNT(DISTINCT l.source_note_id) AS source_count,
+    array_agg(DISTINCT l.source_note_id)::uuid[] AS source_note_ids
+FROM note_links l
+JOIN notes s ON s.id = l.source_note_id AND s.deleted_at IS NULL
+LEFT JOIN notes t ON t.id = l.target_note_id AND t.deleted_at IS NULL
+WHERE l.user_id = sqlc.arg('user_id')
+    AND t.id IS NULL
+    AND l.target_title <> ''
+GROUP BY lower(l.target_title)
+ORDER BY source_count DESC, target_title
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: ListLinkEdges :many
+-- Returns the resolved links between the user's live notes from or to any of
+-- the given notes, once per pair
+SELECT DISTINCT l.source_note_id, l.target_note_id
+FROM note_links l
+JOIN notes s ON s.id = l.source_note_id AND s.deleted_at IS NULL
+JOIN notes t ON t.id = l.target_note_id AND t.deleted_at IS NULL
+WHERE
+    l.user_id = sqlc.arg('user_id')
+    AND l.source_note_id <> l.target_note_id
+    AND (l.source_note_id = ANY(sqlc.arg('note_ids')::uuid[]) OR l.target_note_id = ANY(sqlc.arg('note_ids')::uuid[]));
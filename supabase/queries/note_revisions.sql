@@ -0,0 +1,45 @@
+-- name: CreateNoteRevision :one
+-- Snapshots the note's current title, content and tags as its next revision
+INSERT INTO note_revisions (note_id, user_id, revision_number, title, content, tags, restored_from)
+SELECT
+    n.id,
+    n.user_id,
+    COALESCE((SELECT MAX(r.revision_number) FROM note_revisions r WHERE r.note_id = n.id), 0) + 1,
+    n.title,
+    n.content,
+    COALESCE(n.tags, '{}'),
+    sqlc.narg('restored_from')::int
+FROM notes n
+WHERE n.id = sqlc.arg('note_id')
+RETURNING id, note_id, user_id, revision_number, title, content, tags, restored_from, created_at;
+
+-- name: CreateNoteRevisions :exec
+-- Snapshots many notes at once, see CreateNoteRevision
+INSERT INTO note_revisions (note_id, user_id, revision_number, title, content, tags)
+SELECT
+    n.id,
+    n.user_id,
+    COALESCE((SELECT MAX(r.revision_number) FROM note_revisions r WHERE r.note_id = n.id), 0) + 1,
+    n.title,
+    n.content,
+    COALESCE(n.tags, '{}')
+FROM notes n
+WHERE n.id = ANY(sqlc.arg('note_ids')::uuid[]);
+
+-- name: ListNoteRevisions :many
+SELECT id, note_id, user_id, revision_number, title, tags, restored_from, created_at
+FROM note_revisions
+WHERE note_id = $1 AND user_id = $2
+ORDER BY revision_number DESC
+LIMIT $3 OFFSET $4;
+
+-- name: GetNoteRevision :one
+SELECT id, note_id, user_id, revision_number, title, content, tags, restored_from, created_at
+FROM note_revisions
+WHERE note_id = $1 AND user_id = $2 AND revision_number = $3;
+
+-- name: GetNoteRevisionNumber :one
+-- Returns the note's latest revision number, which versions it for ETags
+SELECT COALESCE(MAX(revision_number), 0)::int
+FROM note_revisions
+WHERE note_id = $1;
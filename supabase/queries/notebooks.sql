@@ -0,0 +1,89 @@
+-- name: CreateNotebook :one
+-- New notebooks go after their existing siblings
+INSERT INTO notebooks (user_id, parent_id, name, position)
+SELECT
+    sqlc.arg('user_id')::uuid,
+    sqlc.narg('parent_id')::uuid,
+    sqlc.arg('name')::text,
+    COALESCE(MAX(position) + 1, 0)
+FROM notebooks
+WHERE user_id = sqlc.arg('user_id')::uuid AND parent_id IS NOT DISTINCT FROM sqlc.narg('parent_id')::uuid
+RETURNING id, user_id, parent_id, name, position, created_at, updated_at;
+
+-- name: GetNotebook :one
+SELECT id, user_id, parent_id, name, position, created_at, updated_at
+FROM notebooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListNotebooks :many
+-- note_count only counts notes directly in the notebook, not in its children
+SELECT
+    nb.id,
+    nb.user_id,
+    nb.parent_id,
+    nb.name,
+    nb.position,
+    nb.created_at,
+    nb.updated_at,
+    COUNT(n.id) AS note_count
+FROM notebooks nb
+LEFT JOIN notes n ON n.notebook_id = nb.id AND n.deleted_at IS NULL
+WHERE nb.user_id = $1
+GROUP BY nb.id
+ORDER BY nb.position, nb.name;
+
+-- name: GetNotebookSubtreeIDs :many
+-- Returns the notebook and all of its descendants. UNION stops at notebooks
+-- already visited, so even a parent cycle cannot recurse forever.
+WITH RECURSIVE subtree AS (
+    SELECT nb.id FROM notebooks nb WHERE nb.id = $1 AND nb.user_id = $2
+    UNION
+    SELECT child.id FROM notebooks child JOIN subtree s ON child.parent_id = s.id
+)
+SELECT id FROM subtree;
+
+-- name: LockNotebooks :exec
+-- Locks the user's notebooks so concurrent moves see each other's new parents
+SELECT id FROM notebooks
+WHERE user_id = $1
+FOR UPDATE;
+
+-- name: UpdateNotebook :one
+UPDATE notebooks
+SET
+    name = COALESCE(sqlc.narg('name'), name),
+    position = COALESCE(sqlc.narg('position'), position),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+RETURNING id, user_id, parent_id, name, position, created_at, updated_at;
+
+-- name: MoveNotebook :one
+-- Moves the notebook under a new parent, after its new siblings
+UPDATE notebooks
+SET
+    parent_id = sqlc.narg('parent_id')::uuid,
+    position = (
+        SELECT COALESCE(MAX(s.position) + 1, 0)
+        FROM notebooks s
+        WHERE s.user_id = sqlc.arg('user_id') AND s.parent_id IS NOT DISTINCT FROM sqlc.narg('parent_id')::uuid
+    ),
+    updated_at = NOW()
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id')
+RETURNING id, user_id, parent_id, name, position, created_at, updated_at;
+
+-- name: DeleteNotebook :execrows
+-- Child notebooks are deleted by the parent_id foreign key
+DELETE FROM notebooks
+WHERE id = $1 AND user_id = $2;
+
+-- name: TrashNotebookNotes :execrows
+UPDATE notes
+SET deleted_at = NOW()
+WHERE user_id = sqlc.arg('user_id') AND notebook_id = ANY(sqlc.arg('notebook_ids')::uuid[]) AND deleted_at IS NULL;
+
+-- name: ListNotebookNotes :many
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
+FROM notes
+WHERE user_id = sqlc.arg('user_id') AND notebook_id = ANY(sqlc.arg('notebook_ids')::uuid[]) AND deleted_at IS NULL
+ORDER BY created_at DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
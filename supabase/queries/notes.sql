@@ -1,37 +1,299 @@
 -- name: CreateNote :one
-INSERT INTO notes (user_id, title, content, embedding, tags)
-VALUES ($1, $2, $3, $4, $5)
-RETURNING id, user_id, title, content, tags, created_at, updated_at;
+INSERT INTO notes (user_id, title, content, tags, search_title, search_content, notebook_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at;
+
+-- name: ImportNote :one
+-- Creates a note keeping the timestamps it had where it was imported from
+INSERT INTO notes (user_id, title, content, tags, search_title, search_content, notebook_id, created_at, updated_at)
+VALUES (
+    sqlc.arg('user_id'),
+    sqlc.arg('title'),
+    sqlc.arg('content'),
+    sqlc.arg('tags'),
+    sqlc.arg('search_title'),
+    sqlc.arg('search_content'),
+    sqlc.arg('notebook_id'),
+    COALESCE(sqlc.narg('created_at')::timestamptz, NOW()),
+    COALESCE(sqlc.narg('updated_at')::timestamptz, sqlc.narg('created_at')::timestamptz, NOW())
+)
+RETURNING id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at;
+
+-- name: FindNotesByContentHash :many
+-- Finds the user's live notes whose content has one of the given SHA-256 hex digests
+SELECT id, encode(sha256(convert_to(content, 'UTF8')), 'hex')::text AS content_hash
+FROM notes
+WHERE
+    user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND encode(sha256(convert_to(content, 'UTF8')), 'hex') = ANY(sqlc.arg('hashes')::text[]);
+
+-- name: ListOwnedNoteIDs :many
+-- Returns which of the given note IDs belong to the user, trashed notes included
+SELECT id
+FROM notes
+WHERE user_id = sqlc.arg('user_id') AND id = ANY(sqlc.arg('ids')::uuid[]);
 
 -- name: GetNote :one
-SELECT id, user_id, title, content, tags, created_at, updated_at
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
 FROM notes
-WHERE id = $1;
+WHERE id = $1 AND deleted_at IS NULL;
+
+-- name: LockNote :one
+-- Locks the user's live note until the transaction ends. The row returned is
+-- its latest version, even when the lock had to wait for another edit.
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
+FROM notes
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
+FOR UPDATE;
 
 -- name: GetUserNotes :many
-SELECT id, user_id, title, content, tags, created_at, updated_at
+-- Sorts by sort (created_at, updated_at or title) with id breaking ties. Pages
+-- after the first pass the last row's id with its sort value as cursor_time or
+-- cursor_title, and get the rows following it (keyset pagination).
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at
 FROM notes
-WHERE user_id = $1
-ORDER BY created_at DESC
-LIMIT $2 OFFSET $3;
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (sqlc.narg('tags_any')::text[] IS NULL OR tags && sqlc.narg('tags_any')::text[])
+    AND (sqlc.narg('tags_all')::text[] IS NULL OR tags @> sqlc.narg('tags_all')::text[])
+    AND (sqlc.narg('created_after')::timestamptz IS NULL OR created_at >= sqlc.narg('created_after')::timestamptz)
+    AND (sqlc.narg('created_before')::timestamptz IS NULL OR created_at < sqlc.narg('created_before')::timestamptz)
+    AND (sqlc.narg('updated_after')::timestamptz IS NULL OR updated_at >= sqlc.narg('updated_after')::timestamptz)
+    AND (sqlc.narg('updated_before')::timestamptz IS NULL OR updated_at < sqlc.narg('updated_before')::timestamptz)
+    AND (sqlc.narg('cursor_id')::uuid IS NULL OR CASE
+        WHEN sqlc.arg('sort')::text = 'title' AND sqlc.arg('descending')::bool
+            THEN (title, id) < (sqlc.narg('cursor_title')::text, sqlc.narg('cursor_id')::uuid)
+        WHEN sqlc.arg('sort')::text = 'title'
+            THEN (title, id) > (sqlc.narg('cursor_title')::text, sqlc.narg('cursor_id')::uuid)
+        WHEN sqlc.arg('sort')::text = 'updated_at' AND sqlc.arg('descending')::bool
+            THEN (updated_at, id) < (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+        WHEN sqlc.arg('sort')::text = 'updated_at'
+            THEN (updated_at, id) > (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+        WHEN sqlc.arg('descending')::bool
+            THEN (created_at, id) < (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+        ELSE (created_at, id) > (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+    END)
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::text = 'title' AND NOT sqlc.arg('descending')::bool THEN title END ASC,
+    CASE WHEN sqlc.arg('sort')::text = 'title' AND sqlc.arg('descending')::bool THEN title END DESC,
+    CASE WHEN sqlc.arg('sort')::text = 'updated_at' AND NOT sqlc.arg('descending')::bool THEN updated_at END ASC,
+    CASE WHEN sqlc.arg('sort')::text = 'updated_at' AND sqlc.arg('descending')::bool THEN updated_at END DESC,
+    CASE WHEN sqlc.arg('sort')::text = 'created_at' AND NOT sqlc.arg('descending')::bool THEN created_at END ASC,
+    CASE WHEN sqlc.arg('sort')::text = 'created_at' AND sqlc.arg('descending')::bool THEN created_at END DESC,
+    CASE WHEN NOT sqlc.arg('descending')::bool THEN id END ASC,
+    CASE WHEN sqlc.arg('descending')::bool THEN id END DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountUserNotes :one
+-- Counts the notes GetUserNotes pages through with the same filters
+SELECT COUNT(*)
+FROM notes
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (sqlc.narg('tags_any')::text[] IS NULL OR tags && sqlc.narg('tags_any')::text[])
+    AND (sqlc.narg('tags_all')::text[] IS NULL OR tags @> sqlc.narg('tags_all')::text[])
+    AND (sqlc.narg('created_after')::timestamptz IS NULL OR created_at >= sqlc.narg('created_after')::timestamptz)
+    AND (sqlc.narg('created_before')::timestamptz IS NULL OR created_at < sqlc.narg('created_before')::timestamptz)
+    AND (sqlc.narg('updated_after')::timestamptz IS NULL OR updated_at >= sqlc.narg('updated_after')::timestamptz)
+    AND (sqlc.narg('updated_before')::timestamptz IS NULL OR updated_at < sqlc.narg('updated_before')::timestamptz);
+
+-- name: ListNotesForExport :many
+-- Pages through all of the user's live notes in id order, after_id being the
+-- last id of the previous page
+SELECT id, title, content, tags, notebook_id, created_at, updated_at
+FROM notes
+WHERE user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND (sqlc.narg('after_id')::uuid IS NULL OR id > sqlc.narg('after_id')::uuid)
+ORDER BY id
+LIMIT sqlc.arg('limit');
 
 -- name: UpdateNote :one
+-- Changing the title or content marks the embedding as stale until the worker refreshes it
 UPDATE notes
 SET 
-    title = COALESCE($2, title),
-    content = COALESCE($3, content),
-    embedding = COALESCE($4, embedding),
-    tags = COALESCE($5, tags),
+    title = $2,
+    content = $3,
+    tags = COALESCE($4, tags),
+    search_title = $6,
+    search_content = $7,
+    embedding_status = CASE
+        WHEN title <> $2 OR content <> $3 THEN 'pending'
+        ELSE embedding_status
+    END,
     updated_at = NOW()
-WHERE id = $1 AND user_id = $6
-RETURNING id, user_id, title, content, tags, created_at, updated_at;
+WHERE id = $1 AND user_id = $5 AND deleted_at IS NULL
+RETURNING id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at;
 
--- name: DeleteNote :exec
+-- name: DeleteNote :execrows
+-- Moves the note to the trash; PurgeNote or the purge job deletes it for good
+UPDATE notes
+SET deleted_at = NOW()
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: SetNoteNotebook :execrows
+-- A NULL notebook_id moves the note out of every notebook
+UPDATE notes
+SET notebook_id = $3
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL;
+
+-- name: AddNoteTags :execrows
+-- Appends the tags the note does not carry yet, keeping the existing order
+UPDATE notes
+SET tags = COALESCE(tags, '{}') || ARRAY(
+    SELECT DISTINCT t
+    FROM unnest(sqlc.arg('tags')::text[]) AS t
+    WHERE NOT t = ANY(COALESCE(tags, '{}'))
+)
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL;
+
+-- name: RemoveNoteTags :execrows
+UPDATE notes
+SET tags = ARRAY(
+    SELECT t
+    FROM unnest(COALESCE(tags, '{}')) AS t
+    WHERE NOT t = ANY(sqlc.arg('tags')::text[])
+)
+WHERE id = sqlc.arg('id') AND user_id = sqlc.arg('user_id') AND deleted_at IS NULL;
+
+-- name: ListTagCounts :many
+-- Counts the user's live notes per tag, optionally only prefix and its descendants
+SELECT t.tag::text AS tag, COUNT(*) AS note_count
+FROM notes n, unnest(n.tags) AS t(tag)
+WHERE
+    n.user_id = sqlc.arg('user_id')
+    AND n.deleted_at IS NULL
+    AND (
+        sqlc.narg('prefix')::text IS NULL
+        OR t.tag = sqlc.narg('prefix')::text
+        OR left(t.tag, length(sqlc.narg('prefix')::text) + 1) = sqlc.narg('prefix')::text || '/'
+    )
+GROUP BY t.tag
+ORDER BY t.tag;
+
+-- name: RetagNotes :many
+-- Replaces the source tags on all of the user's notes, trashed ones included,
+-- by target, or removes them when target is NULL. With include_children their
+-- descendants move along (lang/go/generics becomes golang/generics when lang/go
+-- is renamed to golang) or are removed too. Tags the replacement duplicates
+-- keep their first position. Returns the IDs of the notes that changed.
+WITH retagged AS (
+    SELECT
+        n.id,
+        ARRAY(
+            SELECT deduplicated.tag
+            FROM (
+                SELECT mapped.tag, MIN(mapped.position) AS first_position
+                FROM (
+                    SELECT
+                        CASE
+                            WHEN t.tag = ANY(sqlc.arg('sources')::text[]) THEN sqlc.narg('target')::text
+                            WHEN sqlc.arg('include_children')::bool AND parent.source IS NOT NULL
+                                THEN sqlc.narg('target')::text || substr(t.tag, length(parent.source) + 1)
+                            ELSE t.tag
+                        END AS tag,
+                        t.position
+                    FROM unnest(n.tags) WITH ORDINALITY AS t(tag, position)
+                    LEFT JOIN LATERAL (
+                        SELECT s.source
+                        FROM unnest(sqlc.arg('sources')::text[]) AS s(source)
+                        WHERE left(t.tag, length(s.source) + 1) = s.source || '/'
+                        ORDER BY length(s.source) DESC
+                        LIMIT 1
+                    ) parent ON true
+                ) mapped
+                WHERE mapped.tag IS NOT NULL
+                GROUP BY mapped.tag
+            ) deduplicated
+            ORDER BY deduplicated.first_position
+        ) AS tags
+    FROM notes n
+    WHERE n.user_id = sqlc.arg('user_id') AND cardinality(n.tags) > 0
+)
+UPDATE notes n
+SET tags = retagged.tags
+FROM retagged
+WHERE n.id = retagged.id AND n.tags IS DISTINCT FROM retagged.tags
+RETURNING n.id;
+
+-- name: ListTrashedNotes :many
+SELECT id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at, deleted_at
+FROM notes
+WHERE user_id = $1 AND deleted_at IS NOT NULL
+ORDER BY deleted_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: RestoreNote :one
+UPDATE notes
+SET deleted_at = NULL
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL
+RETURNING id, user_id, title, content, tags, embedding_status, notebook_id, created_at, updated_at;
+
+-- name: PurgeNote :execrows
+DELETE FROM notes
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL;
+
+-- name: EmptyTrash :execrows
+DELETE FROM notes
+WHERE user_id = $1 AND deleted_at IS NOT NULL;
+
+-- name: PurgeExpiredNotes :execrows
+-- Permanently deletes up to batch_size notes trashed before deleted_before
 DELETE FROM notes
-WHERE id = $1 AND user_id = $2;
+WHERE id IN (
+    SELECT id FROM notes
+    WHERE deleted_at < sqlc.arg('deleted_before')
+    LIMIT sqlc.arg('batch_size')
+);
 
 
 -- name: SearchNotesBySimilarity :many
+-- Matches chunks of notes passing the optional filters, ranks notes by their
+-- best chunk and returns up to passages_per_note passages for each of the top notes
+WITH chunk_matches AS (
+    SELECT
+        c.note_id,
+        c.chunk_index,
+        c.heading,
+        c.content,
+        c.start_offset,
+        c.end_offset,
+        (1 - (c.embedding <=> sqlc.arg('query_embedding')::vector))::float AS similarity
+    FROM note_chunks c
+    JOIN notes fn ON fn.id = c.note_id
+    WHERE
+        c.user_id = sqlc.arg('user_id')
+        AND fn.deleted_at IS NULL
+        AND 1 - (c.embedding <=> sqlc.arg('query_embedding')::vector) > sqlc.arg('threshold')::float
+        AND (sqlc.narg('tags_any')::text[] IS NULL OR fn.tags && sqlc.narg('tags_any')::text[])
+        AND (sqlc.narg('tags_all')::text[] IS NULL OR fn.tags @> sqlc.narg('tags_all')::text[])
+        AND (sqlc.narg('created_after')::timestamptz IS NULL OR fn.created_at >= sqlc.narg('created_after')::timestamptz)
+        AND (sqlc.narg('created_before')::timestamptz IS NULL OR fn.created_at < sqlc.narg('created_before')::timestamptz)
+        AND (sqlc.narg('updated_after')::timestamptz IS NULL OR fn.updated_at >= sqlc.narg('updated_after')::timestamptz)
+        AND (sqlc.narg('updated_before')::timestamptz IS NULL OR fn.updated_at < sqlc.narg('updated_before')::timestamptz)
+        AND (sqlc.narg('notebook_ids')::uuid[] IS NULL OR fn.notebook_id = ANY(sqlc.narg('notebook_ids')::uuid[]))
+),
+ranked_chunks AS (
+    SELECT
+        cm.note_id,
+        cm.chunk_index,
+        cm.heading,
+        cm.content,
+        cm.start_offset,
+        cm.end_offset,
+        cm.similarity,
+        ROW_NUMBER() OVER (PARTITION BY cm.note_id ORDER BY cm.similarity DESC) AS passage_rank,
+        MAX(cm.similarity) OVER (PARTITION BY cm.note_id) AS note_similarity
+    FROM chunk_matches cm
+),
+top_notes AS (
+    SELECT rc.note_id, rc.note_similarity
+    FROM ranked_chunks rc
+    WHERE rc.passage_rank = 1
+    ORDER BY rc.note_similarity DESC
+    LIMIT sqlc.arg('limit')
+)
 SELECT 
     n.id,
     n.user_id,
@@ -40,17 +302,191 @@ SELECT
     n.tags,
     n.created_at,
     n.updated_at,
-    (1 - (n.embedding <=> $1::vector))::float AS similarity
-FROM notes n
-WHERE 
-    n.user_id = $2
-    AND n.embedding IS NOT NULL
-    AND 1 - (n.embedding <=> $1::vector) > $3::float
-ORDER BY n.embedding <=> $1::vector
-LIMIT $4;
+    t.note_similarity::float AS similarity,
+    rc.chunk_index,
+    rc.heading,
+    rc.content AS passage,
+    rc.start_offset,
+    rc.end_offset,
+    rc.similarity AS passage_similarity
+FROM top_notes t
+JOIN notes n ON n.id = t.note_id
+JOIN ranked_chunks rc ON rc.note_id = t.note_id AND rc.passage_rank <= sqlc.arg('passages_per_note')
+ORDER BY t.note_similarity DESC, n.id, rc.passage_rank;
 
 -- name: GetNoteForFlashcard :one
 SELECT id, user_id, title, content, tags, created_at
 FROM notes
-WHERE id = $1 AND user_id = $2
+WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL
 LIMIT 1;
+
+-- name: GetNoteEmbeddingDimension :one
+-- pgvector stores the declared VECTOR(n) size as the column's type modifier
+SELECT atttypmod::int AS dimension
+FROM pg_attribute
+WHERE attrelid = 'notes'::regclass AND attname = 'embedding';
+
+-- name: GetNoteChunkEmbeddingDimension :one
+SELECT atttypmod::int AS dimension
+FROM pg_attribute
+WHERE attrelid = 'note_chunks'::regclass AND attname = 'embedding';
+
+-- name: UpdateNoteEmbedding :exec
+UPDATE notes
+SET embedding = $2, embedding_status = 'ready'
+WHERE id = $1;
+
+-- name: SetNoteEmbeddingStatus :exec
+UPDATE notes
+SET embedding_status = $2
+WHERE id = $1;
+
+-- name: SearchNotesByKeyword :many
+-- query is a to_tsquery expression over segmented text, see services.BuildSearchQuery
+SELECT
+    n.id,
+    n.user_id,
+    n.title,
+    n.content,
+    n.tags,
+    n.created_at,
+    n.updated_at,
+    ts_rank_cd(n.search_vector, query)::float AS rank
+FROM notes n, to_tsquery('simple', sqlc.arg('query')) query
+WHERE
+    n.user_id = sqlc.arg('user_id')
+    AND n.deleted_at IS NULL
+    AND n.search_vector @@ query
+    AND (sqlc.narg('tags_any')::text[] IS NULL OR n.tags && sqlc.narg('tags_any')::text[])
+    AND (sqlc.narg('tags_all')::text[] IS NULL OR n.tags @> sqlc.narg('tags_all')::text[])
+    AND (sqlc.narg('created_after')::timestamptz IS NULL OR n.created_at >= sqlc.narg('created_after')::timestamptz)
+    AND (sqlc.narg('created_before')::timestamptz IS NULL OR n.created_at < sqlc.narg('created_before')::timestamptz)
+    AND (sqlc.narg('updated_after')::timestamptz IS NULL OR n.updated_at >= sqlc.narg('updated_after')::timestamptz)
+    AND (sqlc.narg('updated_before')::timestamptz IS NULL OR n.updated_at < sqlc.narg('updated_before')::timestamptz)
+    AND (sqlc.narg('notebook_ids')::uuid[] IS NULL OR n.notebook_id = ANY(sqlc.narg('notebook_ids')::uuid[]))
+ORDER BY rank DESC, n.updated_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CountSearchTags :many
+-- Counts the tags of every note a search matches, not only of its top results:
+-- notes with a chunk above threshold when query_embedding is set, or matching
+-- the keyword query when it is set, narrowed by the same filters as the searches
+SELECT t.tag::text AS tag, COUNT(DISTINCT n.id)::int AS count
+FROM notes n
+CROSS JOIN LATERAL unnest(n.tags) AS t(tag)
+WHERE
+    n.user_id = sqlc.arg('user_id')
+    AND n.deleted_at IS NULL
+    AND (
+        (sqlc.narg('query_embedding')::vector IS NOT NULL AND EXISTS (
+            SELECT 1
+            FROM note_chunks c
+            WHERE
+                c.note_id = n.id
+                AND 1 - (c.embedding <=> sqlc.narg('query_embedding')::vector) > sqlc.arg('threshold')::float
+        ))
+        OR (sqlc.narg('query')::text IS NOT NULL AND n.search_vector @@ to_tsquery('simple', sqlc.narg('query')::text))
+    )
+    AND (sqlc.narg('tags_any')::text[] IS NULL OR n.tags && sqlc.narg('tags_any')::text[])
+    AND (sqlc.narg('tags_all')::text[] IS NULL OR n.tags @> sqlc.narg('tags_all')::text[])
+    AND (sqlc.narg('created_after')::timestamptz IS NULL OR n.created_at >= sqlc.narg('created_after')::timestamptz)
+    AND (sqlc.narg('created_before')::timestamptz IS NULL OR n.created_at < sqlc.narg('created_before')::timestamptz)
+    AND (sqlc.narg('updated_after')::timestamptz IS NULL OR n.updated_at >= sqlc.narg('updated_after')::timestamptz)
+    AND (sqlc.narg('updated_before')::timestamptz IS NULL OR n.updated_at < sqlc.narg('updated_before')::timestamptz)
+    AND (sqlc.narg('notebook_ids')::uuid[] IS NULL OR n.notebook_id = ANY(sqlc.narg('notebook_ids')::uuid[]))
+GROUP BY t.tag
+ORDER BY count DESC, tag;
+
+-- name: ListUnsegmentedNotes :many
+SELECT id, title, content
+FROM notes
+WHERE search_content IS NULL
+LIMIT $1;
+
+-- name: UpdateNoteSearchText :exec
+UPDATE notes
+SET search_title = $2, search_content = $3
+WHERE id = $1;
+
+-- name: ListGraphNotes :many
+SELECT id, title, tags, notebook_id
+FROM notes
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY updated_at DESC
+LIMIT $2;
+
+-- name: ListGraphNotesByID :many
+SELECT id, title, tags, notebook_id
+FROM notes
+WHERE user_id = sqlc.arg('user_id') AND id = ANY(sqlc.arg('note_ids')::uuid[]) AND deleted_at IS NULL;
+
+-- name: ListTaggedGraphNoteIDs :many
+-- Returns up to limit of the user's most recent live notes carrying any of the
+-- tags, leaving out the excluded notes
+SELECT id
+FROM notes
+WHERE
+    user_id = sqlc.arg('user_id')
+    AND deleted_at IS NULL
+    AND tags && sqlc.arg('tags')::text[]
+    AND NOT id = ANY(sqlc.arg('exclude')::uuid[])
+ORDER BY updated_at DESC
+LIMIT sqlc.arg('limit');
+
+-- name: ListSemanticNeighbors :many
+-- Returns up to k nearest notes by stored embedding for each of the given notes
+SELECT
+    n.id AS note_id,
+    nb.id AS neighbor_id,
+    nb.similarity
+FROM notes n
+CROSS JOIN LATERAL (
+    SELECT
+        m.id,
+        (1 - (m.embedding <=> n.embedding))::float AS similarity
+    FROM notes m
+    WHERE m.user_id = n.user_id
+        AND m.id <> n.id
+        AND m.deleted_at IS NULL
+        AND m.embedding IS NOT NULL
+    ORDER BY m.embedding <=> n.embedding
+    LIMIT sqlc.arg('k')
+) nb
+WHERE n.user_id = sqlc.arg('user_id')
+    AND n.id = ANY(sqlc.arg('note_ids')::uuid[])
+    AND n.deleted_at IS NULL
+    AND n.embedding IS NOT NULL
+    AND nb.similarity >= sqlc.arg('threshold')::float
+ORDER BY n.id, nb.similarity DESC;
+
+-- name: ListRelatedNotes :many
+-- Ranks the user's other notes by similarity to the note's stored embedding;
+-- linked is set when either note links to the other
+SELECT
+    m.id,
+    m.title,
+    m.tags,
+    m.notebook_id,
+    m.updated_at,
+    (1 - (m.embedding <=> n.embedding))::float AS similarity,
+    l.linked
+FROM notes n
+JOIN notes m ON m.user_id = n.user_id
+    AND m.id <> n.id
+    AND m.deleted_at IS NULL
+    AND m.embedding IS NOT NULL
+CROSS JOIN LATERAL (
+    SELECT EXISTS (
+        SELECT 1
+        FROM note_links nl
+        WHERE (nl.source_note_id = n.id AND nl.target_note_id = m.id)
+            OR (nl.source_note_id = m.id AND nl.target_note_id = n.id)
+    ) AS linked
+) l
+WHERE n.id = sqlc.arg('note_id')
+    AND n.user_id = sqlc.arg('user_id')
+    AND n.embedding IS NOT NULL
+    AND (NOT sqlc.arg('exclude_linked')::bool OR NOT l.linked)
+    AND 1 - (m.embedding <=> n.embedding) >= sqlc.arg('threshold')::float
+ORDER BY m.embedding <=> n.embedding
+LIMIT sqlc.arg('limit');
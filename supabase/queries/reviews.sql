@@ -0,0 +1,55 @@
+-- name: GetCardSchedule :one
+SELECT flashcard_id, user_id, algorithm, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at, created_at, updated_at
+FROM card_schedules
+WHERE flashcard_id = $1 AND user_id = $2;
+
+-- name: UpsertCardSchedule :one
+INSERT INTO card_schedules (flashcard_id, user_id, algorithm, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+ON CONFLICT (flashcard_id) DO UPDATE
+SET
+    algorithm = EXCLUDED.algorithm,
+    ease_factor = EXCLUDED.ease_factor,
+    interval_days = EXCLUDED.interval_days,
+    repetitions = EXCLUDED.repetitions,
+    lapses = EXCLUDED.lapses,
+    due_at = EXCLUDED.due_at,
+    last_reviewed_at = EXCLUDED.last_reviewed_at,
+    updated_at = NOW()
+RETURNING flashcard_id, user_id, algorithm, ease_factor, interval_days, repetitions, lapses, due_at, last_reviewed_at, created_at, updated_at;
+
+-- name: CreateReviewLog :one
+INSERT INTO review_logs (flashcard_id, user_id, algorithm, grade, ease_factor, previous_interval_days, interval_days, due_at, reviewed_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, flashcard_id, user_id, algorithm, grade, ease_factor, previous_interval_days, interval_days, due_at, reviewed_at;
+
+-- name: ListCardReviewLogs :many
+SELECT id, flashcard_id, user_id, algorithm, grade, ease_factor, previous_interval_days, interval_days, due_at, reviewed_at
+FROM review_logs
+WHERE flashcard_id = $1 AND user_id = $2
+ORDER BY reviewed_at DESC
+LIMIT $3;
+
+-- name: ListDueFlashcards :many
+-- Cards without a schedule have never been reviewed and are always due.
+SELECT
+    f.id,
+    f.deck_id,
+    f.question,
+    f.answer,
+    f.explanation,
+    f.difficulty,
+    f.tags,
+    f.source_note_ids,
+    s.ease_factor,
+    s.interval_days,
+    s.repetitions,
+    s.due_at
+FROM flashcards f
+LEFT JOIN card_schedules s ON s.flashcard_id = f.id
+WHERE
+    f.user_id = sqlc.arg('user_id')
+    AND (s.due_at IS NULL OR s.due_at <= sqlc.arg('now')::timestamptz)
+    AND (sqlc.narg('deck_id')::uuid IS NULL OR f.deck_id = sqlc.narg('deck_id')::uuid)
+ORDER BY COALESCE(s.due_at, f.created_at) ASC
+LIMIT sqlc.arg('limit');
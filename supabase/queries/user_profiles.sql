@@ -30,12 +30,55 @@ DELETE FROM user_profiles
 WHERE id = $1;
 
 -- name: ListUserProfiles :many
+-- Keyset pagination like GetUserNotes; sort is created_at, updated_at or
+-- username, with profiles lacking a username sorted as an empty one
 SELECT id, username, display_name, avatar_url, preferences, created_at, updated_at
 FROM user_profiles
-ORDER BY created_at DESC
-LIMIT $1 OFFSET $2;
+WHERE (sqlc.narg('created_after')::timestamptz IS NULL OR created_at >= sqlc.narg('created_after')::timestamptz)
+    AND (sqlc.narg('created_before')::timestamptz IS NULL OR created_at < sqlc.narg('created_before')::timestamptz)
+    AND (sqlc.narg('updated_after')::timestamptz IS NULL OR updated_at >= sqlc.narg('updated_after')::timestamptz)
+    AND (sqlc.narg('updated_before')::timestamptz IS NULL OR updated_at < sqlc.narg('updated_before')::timestamptz)
+    AND (sqlc.narg('cursor_id')::uuid IS NULL OR CASE
+        WHEN sqlc.arg('sort')::text = 'username' AND sqlc.arg('descending')::bool
+            THEN (COALESCE(username, ''), id) < (sqlc.narg('cursor_username')::text, sqlc.narg('cursor_id')::uuid)
+        WHEN sqlc.arg('sort')::text = 'username'
+            THEN (COALESCE(username, ''), id) > (sqlc.narg('cursor_username')::text, sqlc.narg('cursor_id')::uuid)
+        WHEN sqlc.arg('sort')::text = 'updated_at' AND sqlc.arg('descending')::bool
+            THEN (updated_at, id) < (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+        WHEN sqlc.arg('sort')::text = 'updated_at'
+            THEN (updated_at, id) > (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+        WHEN sqlc.arg('descending')::bool
+            THEN (created_at, id) < (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+        ELSE (created_at, id) > (sqlc.narg('cursor_time')::timestamptz, sqlc.narg('cursor_id')::uuid)
+    END)
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::text = 'username' AND NOT sqlc.arg('descending')::bool THEN COALESCE(username, '') END ASC,
+    CASE WHEN sqlc.arg('sort')::text = 'username' AND sqlc.arg('descending')::bool THEN COALESCE(username, '') END DESC,
+    CASE WHEN sqlc.arg('sort')::text = 'updated_at' AND NOT sqlc.arg('descending')::bool THEN updated_at END ASC,
+    CASE WHEN sqlc.arg('sort')::text = 'updated_at' AND sqlc.arg('descending')::bool THEN updated_at END DESC,
+    CASE WHEN sqlc.arg('sort')::text = 'created_at' AND NOT sqlc.arg('descending')::bool THEN created_at END ASC,
+    CASE WHEN sqlc.arg('sort')::text = 'created_at' AND sqlc.arg('descending')::bool THEN created_at END DESC,
+    CASE WHEN NOT sqlc.arg('descending')::bool THEN id END ASC,
+    CASE WHEN sqlc.arg('descending')::bool THEN id END DESC
+LIMIT sqlc.arg('limit') OFFSET sqlc.arg('offset');
+
+-- name: CountUserProfiles :one
+-- Counts the profiles ListUserProfiles pages through with the same filters
+SELECT COUNT(*)
+FROM user_profiles
+WHERE (sqlc.narg('created_after')::timestamptz IS NULL OR created_at >= sqlc.narg('created_after')::timestamptz)
+    AND (sqlc.narg('created_before')::timestamptz IS NULL OR created_at < sqlc.narg('created_before')::timestamptz)
+    AND (sqlc.narg('updated_after')::timestamptz IS NULL OR updated_at >= sqlc.narg('updated_after')::timestamptz)
+    AND (sqlc.narg('updated_before')::timestamptz IS NULL OR updated_at < sqlc.narg('updated_before')::timestamptz);
 
 -- name: CheckUsernameExists :one
 SELECT EXISTS(
     SELECT 1 FROM user_profiles WHERE username = $1
 ) as exists;
+
+-- name: LockUserProfile :one
+-- Locks the profile until the transaction ends and returns when it last changed
+SELECT updated_at
+FROM user_profiles
+WHERE id = $1
+FOR UPDATE;